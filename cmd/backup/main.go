@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"url_profile/internal/app"
+	"url_profile/internal/blob"
+	"url_profile/internal/config"
+	"url_profile/internal/services/backup"
+	"url_profile/internal/store/migrator"
+	sqlitestore "url_profile/internal/store/sqlite"
+)
+
+const usage = `usage: backup [flags] <command> [args]
+
+commands:
+  create            snapshot the database into blob storage, safe while the
+                    server is running, and rotate old snapshots out
+  list              list snapshots, newest first
+  restore <name>    verify a snapshot (a name from list or a local path),
+                    migrate it up if its schema is older and swap it in for
+                    storage_path; stop the server first
+
+flags:
+`
+
+func main() {
+	var configPath string
+
+	flag.StringVar(&configPath, "config", os.Getenv("CONFIG_PATH"), "path to the app config")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usageError("a command is required")
+	}
+	cmd, args := flag.Arg(0), flag.Args()[1:]
+
+	cfg := config.MustLoadByPath(configPath)
+	if cfg.Storage.Driver != "sqlite" {
+		log.Fatalf("backups are only supported for sqlite, not %q", cfg.Storage.Driver)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	ctx := context.Background()
+	opts := backup.Options{Keep: cfg.Backup.Keep, TempDir: cfg.Backup.TempDir}
+
+	blobs, _, err := app.NewBlobStorage(*cfg)
+	if err != nil {
+		log.Fatalf("failed to open blob storage: %v", err)
+	}
+
+	switch cmd {
+	case "create":
+		err = create(ctx, cfg, logger, blobs, opts)
+	case "list":
+		err = list(ctx, backup.New(logger, nil, blobs, opts))
+	case "restore":
+		if len(args) != 1 {
+			usageError("restore takes a snapshot name or path")
+		}
+		err = restore(ctx, cfg, logger, backup.New(logger, nil, blobs, opts), args[0])
+	default:
+		usageError("unknown command " + cmd)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func create(ctx context.Context, cfg *config.Config, logger *slog.Logger, blobs blob.Storage, opts backup.Options) error {
+	lite := cfg.Storage.SQLite
+	db := sqlitestore.New(sqlitestore.Config{
+		Path:         cfg.StoragePath,
+		JournalMode:  lite.JournalMode,
+		Synchronous:  lite.Synchronous,
+		BusyTimeout:  lite.BusyTimeout,
+		ForeignKeys:  lite.ForeignKeys,
+		CacheSize:    lite.CacheSize,
+		MaxOpenConns: 1,
+	}, logger)
+	defer db.Close()
+
+	snap, err := backup.New(logger, db, blobs, opts).Backup(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(snap.Name)
+	return nil
+}
+
+func list(ctx context.Context, svc *backup.Service) error {
+	snaps, err := svc.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSIZE\tCREATED")
+	for _, s := range snaps {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", s.Name, s.Size, s.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+	}
+
+	return tw.Flush()
+}
+
+func restore(ctx context.Context, cfg *config.Config, logger *slog.Logger, svc *backup.Service, arg string) error {
+	snapshot, err := svc.Open(ctx, arg)
+	if errors.Is(err, backup.ErrInvalidName) {
+		// Not a name from list, take it as a local snapshot path.
+		snapshot, err = os.Open(arg)
+	}
+	if err != nil {
+		return err
+	}
+	defer snapshot.Close()
+
+	return backup.Restore(ctx, logger, snapshot, cfg.StoragePath, func(ctx context.Context, db string) error {
+		if err := sqlitestore.Verify(ctx, db); err != nil {
+			return err
+		}
+
+		v, latest, err := migrator.Version("sqlite", db)
+		if err != nil {
+			return err
+		}
+		if v == 0 {
+			return errors.New("snapshot has no schema version")
+		}
+		if v == latest {
+			return nil
+		}
+
+		// An older snapshot is brought up to this binary's schema before
+		// the swap, so a failed migration leaves the live database alone
+		// and the restored one is ready to serve.
+		logger.Info("backup: migrating the snapshot",
+			slog.Uint64("version", uint64(v)),
+			slog.Uint64("latest", uint64(latest)))
+		defer os.Remove(db + ".migrate.lock")
+
+		return migrator.Up(ctx, "sqlite", db, logger)
+	})
+}
+
+func usageError(msg string) {
+	fmt.Fprintln(os.Stderr, msg)
+	flag.Usage()
+	os.Exit(2)
+}
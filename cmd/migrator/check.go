@@ -0,0 +1,184 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const sqliteSchema = `
+	SELECT 'column ' || m.name || '.' || p.name || ' ' || p.type || ' notnull=' || p."notnull" ||
+		' default=' || COALESCE(p.dflt_value, '') || ' pk=' || p.pk
+	FROM sqlite_master m, pragma_table_info(m.name) p
+	WHERE m.type = 'table' AND m.name NOT LIKE 'sqlite_%' AND m.name <> ?
+	UNION ALL
+	SELECT 'index ' || name FROM sqlite_master WHERE type = 'index' AND name NOT LIKE 'sqlite_%'
+	UNION ALL
+	SELECT type || ' ' || tbl_name || '.' || name || ' ' || sql FROM sqlite_master WHERE type IN ('trigger', 'view')
+	ORDER BY 1`
+
+const postgresSchema = `
+	SELECT 'column ' || table_name || '.' || column_name || ' ' || data_type || ' nullable=' || is_nullable ||
+		' default=' || COALESCE(column_default, '')
+	FROM information_schema.columns
+	WHERE table_schema = current_schema() AND table_name <> $1
+	UNION ALL
+	SELECT 'index ' || indexname FROM pg_indexes WHERE schemaname = current_schema() AND tablename <> $1
+	UNION ALL
+	SELECT 'trigger ' || event_object_table || '.' || trigger_name || ' ' || action_timing || ' ' ||
+		event_manipulation || ' ' || action_orientation || ' ' || COALESCE(action_condition, '') || ' ' || action_statement
+	FROM information_schema.triggers WHERE trigger_schema = current_schema()
+	UNION ALL
+	SELECT 'view ' || table_name || ' ' || COALESCE(view_definition, '')
+	FROM information_schema.views WHERE table_schema = current_schema()
+	UNION ALL
+	SELECT 'function ' || p.proname || '(' || pg_get_function_identity_arguments(p.oid) || ') ' || p.prosrc
+	FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace WHERE n.nspname = current_schema()
+	ORDER BY 1`
+
+// check walks an empty database through every migration: up, down, and up
+// again, comparing the schema after each down with the one before the up.
+// It finishes by rolling everything back. Meant for CI on a scratch
+// database.
+func check(m *migrate.Migrate, driver string, storagePath string, migrationTable string, dir string) error {
+	if _, _, err := m.Version(); !errors.Is(err, migrate.ErrNilVersion) {
+		return errors.New("check needs an empty database")
+	}
+
+	files, err := listMigrations(dir)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, f := range files {
+		if !f.HasUp || !f.HasDown {
+			missing = append(missing, fmt.Sprintf("%d_%s", f.Version, f.Name))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("migrations without an up or down file: %s", strings.Join(missing, ", "))
+	}
+
+	schema, closeDB, err := schemaReader(driver, storagePath, migrationTable)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	empty, err := schema()
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		before, err := schema()
+		if err != nil {
+			return err
+		}
+
+		if err := m.Steps(1); err != nil {
+			return fmt.Errorf("up %d_%s: %w", f.Version, f.Name, err)
+		}
+		if err := m.Steps(-1); err != nil {
+			return fmt.Errorf("down %d_%s: %w", f.Version, f.Name, err)
+		}
+
+		after, err := schema()
+		if err != nil {
+			return err
+		}
+		if diff := schemaDiff(before, after); diff != "" {
+			return fmt.Errorf("down %d_%s does not restore the schema:\n%s", f.Version, f.Name, diff)
+		}
+
+		if err := m.Steps(1); err != nil {
+			return fmt.Errorf("up %d_%s after down: %w", f.Version, f.Name, err)
+		}
+		log.Printf("%d_%s: ok", f.Version, f.Name)
+	}
+
+	if err := m.Down(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("down all: %w", err)
+	}
+
+	after, err := schema()
+	if err != nil {
+		return err
+	}
+	if diff := schemaDiff(empty, after); diff != "" {
+		return fmt.Errorf("rolling back everything leaves:\n%s", diff)
+	}
+
+	return nil
+}
+
+// schemaReader opens a second connection that describes tables, columns,
+// indexes, triggers and views as sorted lines. Trigger, view and function
+// bodies are compared with runs of whitespace collapsed, so a down file may
+// indent a recreated trigger differently.
+func schemaReader(driver string, storagePath string, migrationTable string) (func() ([]string, error), func(), error) {
+	var (
+		db  *sql.DB
+		q   string
+		err error
+	)
+	switch driver {
+	case "sqlite":
+		db, err = sql.Open("sqlite3", storagePath)
+		q = sqliteSchema
+	case "postgres":
+		db, err = sql.Open("pgx", storagePath)
+		q = postgresSchema
+	default:
+		err = fmt.Errorf("unknown driver %q", driver)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	read := func() ([]string, error) {
+		rows, err := db.Query(q, migrationTable)
+		if err != nil {
+			return nil, fmt.Errorf("read schema: %w", err)
+		}
+		defer rows.Close()
+
+		var lines []string
+		for rows.Next() {
+			var line string
+			if err := rows.Scan(&line); err != nil {
+				return nil, fmt.Errorf("read schema: %w", err)
+			}
+			lines = append(lines, strings.Join(strings.Fields(line), " "))
+		}
+
+		return lines, rows.Err()
+	}
+
+	return read, func() { db.Close() }, nil
+}
+
+// schemaDiff lists lines missing from after with "-" and new ones with "+".
+func schemaDiff(before []string, after []string) string {
+	var b strings.Builder
+	for _, l := range before {
+		if !slices.Contains(after, l) {
+			b.WriteString("  - " + l + "\n")
+		}
+	}
+	for _, l := range after {
+		if !slices.Contains(before, l) {
+			b.WriteString("  + " + l + "\n")
+		}
+	}
+
+	return b.String()
+}
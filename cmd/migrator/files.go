@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// migrationFile is one version in the migration directory.
+type migrationFile struct {
+	Version uint
+	Name    string
+	HasUp   bool
+	HasDown bool
+}
+
+// listMigrations reads the migration directory in version order. Other
+// files and subdirectories (like migrations/postgres) are ignored.
+func listMigrations(dir string) ([]migrationFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint]*migrationFile)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		m, err := source.Parse(e.Name())
+		if err != nil {
+			continue
+		}
+
+		f, ok := byVersion[m.Version]
+		if !ok {
+			f = &migrationFile{Version: m.Version, Name: m.Identifier}
+			byVersion[m.Version] = f
+		}
+
+		switch m.Direction {
+		case source.Up:
+			f.HasUp = true
+		case source.Down:
+			f.HasDown = true
+		}
+	}
+
+	res := make([]migrationFile, 0, len(byVersion))
+	for _, f := range byVersion {
+		res = append(res, *f)
+	}
+	slices.SortFunc(res, func(a, b migrationFile) int { return int(a.Version) - int(b.Version) })
+
+	return res, nil
+}
+
+var migrationName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// create adds empty up and down files numbered after the last migration.
+func create(dir string, name string) ([]string, error) {
+	name = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")
+	if !migrationName.MatchString(name) {
+		return nil, fmt.Errorf("migration name %q may only contain letters, digits and underscores", name)
+	}
+
+	existing, err := listMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var next uint = 1
+	if len(existing) > 0 {
+		next = existing[len(existing)-1].Version + 1
+	}
+
+	var files []string
+	for _, direction := range []source.Direction{source.Up, source.Down} {
+		path := filepath.Join(dir, fmt.Sprintf("%d_%s.%s.sql", next, name, direction))
+
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err != nil {
+			return files, err
+		}
+		f.Close()
+
+		files = append(files, path)
+	}
+
+	return files, nil
+}
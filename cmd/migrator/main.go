@@ -5,44 +5,205 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"strconv"
+	"url_profile/internal/store/migrator"
 
 	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
+const usage = `usage: migrator [flags] [command] [args]
+
+commands:
+  up [n]           apply all pending migrations, or the next n (default)
+  down [n]         roll back the last n migrations, 1 if n is omitted
+  goto <version>   migrate up or down to version
+  version          print the current version
+  force <version>  set the version without running anything, to recover
+                   from a dirty state; -1 means no version
+  status           list applied and pending migrations
+  create <name>    add empty numbered up/down files to the migration path
+  check            on an empty database, apply and roll back every
+                   migration one by one and verify the schema round-trips
+
+flags:
+`
+
 func main() {
-	var storagePath, migrationPath, migrationTable string
+	var driver, storagePath, migrationPath, migrationTable string
 
-	flag.StringVar(&storagePath, "storage", "", "path to storage")
+	flag.StringVar(&driver, "driver", "sqlite", "database driver: sqlite or postgres")
+	flag.StringVar(&storagePath, "storage", "", "path to storage, or the DSN for postgres")
 	flag.StringVar(&migrationPath, "migration-path", "", "path to migration")
-	flag.StringVar(&migrationTable, "migration-table", "migrations", "name of migration")
+	flag.StringVar(&migrationTable, "migration-table", migrator.Table, "name of migration")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
 
 	flag.Parse()
 
-	if storagePath == "" {
-		panic("storage-path is required")
+	cmd, args := "up", flag.Args()
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+
+	err := run(driver, storagePath, migrationPath, migrationTable, cmd, args)
+
+	var uerr usageError
+	if errors.As(err, &uerr) {
+		fmt.Fprintln(os.Stderr, uerr)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
 	}
+}
 
+// run carries out cmd. It returns instead of exiting, so the migrate
+// instance it opens is always closed.
+func run(driver, storagePath, migrationPath, migrationTable, cmd string, args []string) error {
 	if migrationPath == "" {
-		panic("migration-path is required")
+		return errors.New("migration-path is required")
+	}
+
+	if cmd == "create" {
+		if len(args) != 1 {
+			return usageError("create takes a name")
+		}
+
+		files, err := create(migrationPath, args[0])
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			log.Println("created", f)
+		}
+		return nil
 	}
 
-	m, err := migrate.New(
-		"file://"+migrationPath,
-		fmt.Sprintf("sqlite3://%s?x-migrations-table=%s&_busy_timeout=5000", storagePath, migrationTable))
+	if storagePath == "" {
+		return errors.New("storage is required")
+	}
+
+	databaseURL, err := migrator.DatabaseURL(driver, storagePath, migrationTable)
+	if err != nil {
+		return err
+	}
 
+	m, err := migrate.New("file://"+migrationPath, databaseURL)
 	if err != nil {
-		panic(err)
+		return err
 	}
+	defer m.Close()
 
-	if err := m.Up(); err != nil {
-		if errors.Is(err, migrate.ErrNoChange) {
-			log.Println("no migrations to apply")
-			return
+	switch cmd {
+	case "up":
+		var n int
+		if n, err = optionalCount(args, 0); err != nil {
+			return err
+		}
+		if n == 0 {
+			err = m.Up()
+		} else {
+			err = m.Steps(n)
+		}
+	case "down":
+		var n int
+		if n, err = optionalCount(args, 1); err != nil {
+			return err
+		}
+		err = m.Steps(-n)
+	case "goto":
+		var v int
+		if v, err = versionArg(cmd, args); err != nil {
+			return err
 		}
-		panic(err)
+		if v < 0 {
+			return usageError("goto needs a version >= 0")
+		}
+		err = m.Migrate(uint(v))
+	case "force":
+		var v int
+		if v, err = versionArg(cmd, args); err != nil {
+			return err
+		}
+		err = m.Force(v)
+	case "version":
+		err = printVersion(m)
+	case "status":
+		err = status(m, migrationPath)
+	case "check":
+		err = check(m, driver, storagePath, migrationTable, migrationPath)
+	default:
+		return usageError("unknown command " + cmd)
 	}
 
-	log.Println("migrations applied successfully")
+	if errors.Is(err, migrate.ErrNoChange) {
+		log.Println("no migrations to apply")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if cmd != "version" && cmd != "status" {
+		log.Println(cmd, "done")
+	}
+
+	return nil
+}
+
+func printVersion(m *migrate.Migrate) error {
+	v, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Println("no migrations applied")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		fmt.Printf("%d (dirty)\n", v)
+	} else {
+		fmt.Println(v)
+	}
+
+	return nil
 }
+
+// optionalCount parses the [n] argument of up and down.
+func optionalCount(args []string, def int) (int, error) {
+	if len(args) == 0 {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 || len(args) > 1 {
+		return 0, usageError("n must be a positive number")
+	}
+
+	return n, nil
+}
+
+func versionArg(cmd string, args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, usageError(cmd + " takes a version")
+	}
+
+	v, err := strconv.Atoi(args[0])
+	if err != nil || v < -1 {
+		return 0, usageError("invalid version " + args[0])
+	}
+
+	return v, nil
+}
+
+// usageError is a mistake in the command line; main prints the usage for
+// it and exits with 2.
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
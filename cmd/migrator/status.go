@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// status prints every migration in the directory with its state.
+func status(m *migrate.Migrate, dir string) error {
+	files, err := listMigrations(dir)
+	if err != nil {
+		return err
+	}
+
+	current, dirty, err := m.Version()
+	applied := true
+	if errors.Is(err, migrate.ErrNilVersion) {
+		applied = false
+	} else if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATE")
+	for _, f := range files {
+		state := "pending"
+		switch {
+		case applied && f.Version == current && dirty:
+			state = "dirty"
+		case applied && f.Version <= current:
+			state = "applied"
+		}
+		if !f.HasDown {
+			state += " (no down)"
+		}
+
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", f.Version, f.Name, state)
+	}
+
+	return tw.Flush()
+}
@@ -1,24 +1,146 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
+	handler "url_profile/internal/app/server/http/handlers"
 	transport "url_profile/internal/app/server/http/transporter"
 	"url_profile/internal/config"
+	"url_profile/internal/lib/linkurl"
 	authservice "url_profile/internal/services/auth"
-	sqlitestore "url_profile/internal/store/sqlite"
+	"url_profile/internal/services/backup"
+	"url_profile/internal/services/events"
+	"url_profile/internal/services/linkhealth"
+	"url_profile/internal/services/linkio"
+	"url_profile/internal/services/linkpreview"
+	"url_profile/internal/services/media"
+	"url_profile/internal/services/sections"
+	"url_profile/internal/services/theme"
+	"url_profile/internal/services/trash"
 )
 
+// shutdownTimeout is how long in-flight requests get to finish after
+// SIGINT or SIGTERM.
+const shutdownTimeout = 10 * time.Second
+
 func Start(cfg config.Config, logger *slog.Logger) error {
-	store := sqlitestore.New(cfg.StoragePath, logger)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := prepareSchema(ctx, cfg, logger); err != nil {
+		return fmt.Errorf("failed to prepare database schema: %w", err)
+	}
+
+	db, err := newStore(ctx, cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to open storage: %w", err)
+	}
+	defer func() {
+		if err := closeStore(db); err != nil {
+			logger.Error("failed to close storage", slog.String("error", err.Error()))
+		}
+	}()
+
+	store := db
+	var cacheStats handler.CacheStats
+	if cfg.ProfileCache.Enabled {
+		cache, closeCache := newProfileCache(cfg, logger, db)
+		defer closeCache()
+
+		store = cachedStore{appStore: db, cache: cache}
+		cacheStats = cache
+	}
 	authService := authservice.New(logger, store, store)
 	duration, err := time.ParseDuration(cfg.TokenTTL)
 	if err != nil {
 		panic(fmt.Errorf("failed to parse TokenTTL: %w", err))
 	}
-	router := transport.NewRouter(logger, authService, cfg.Secret, duration)
+	hub := events.New(cfg.Events.BufferSize, cfg.Events.MaxConnections, cfg.Events.Idle)
+
+	checker := linkhealth.New(logger, store, linkhealth.Options{
+		Interval:        cfg.LinkHealth.Interval,
+		Timeout:         cfg.LinkHealth.Timeout,
+		Concurrency:     cfg.LinkHealth.Concurrency,
+		PerHostInterval: cfg.LinkHealth.PerHostInterval,
+		MaxRedirects:    cfg.LinkHealth.MaxRedirects,
+		AllowPrivate:    cfg.LinkHealth.AllowPrivate,
+	})
+	if cfg.LinkHealth.Enabled {
+		go checker.Run(ctx)
+	}
+
+	previews := linkpreview.New(logger, store, linkpreview.Options{
+		Enabled:      cfg.LinkPreview.Enabled,
+		Timeout:      cfg.LinkPreview.Timeout,
+		MaxBytes:     cfg.LinkPreview.MaxBytes,
+		TTL:          cfg.LinkPreview.TTL,
+		MaxRedirects: cfg.LinkPreview.MaxRedirects,
+		AllowPrivate: cfg.LinkPreview.AllowPrivate,
+	})
+
+	urls := linkurl.New(cfg.Links.AppSchemes)
+	themes := theme.New(logger, store)
+
+	blobs, signer, err := NewBlobStorage(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open blob storage: %w", err)
+	}
+	if cfg.Blob.Driver == "local" && cfg.Blob.Local.SigningKey == "" {
+		logger.Warn("blob.local.signing_key is not set, download URLs will not survive a restart")
+	}
+	images := media.New(logger, store, blobs, media.Options{
+		MaxBytes: cfg.Media.MaxUploadBytes,
+		BaseURL:  cfg.Media.BaseURL,
+	})
+
+	sectionService := sections.New(logger, store)
+
+	trashService := trash.New(logger, store, trash.Options{
+		Retention:     cfg.Trash.Retention,
+		PurgeInterval: cfg.Trash.PurgeInterval,
+	})
+	go trashService.Run(ctx)
+
+	linkIO := linkio.New(logger, store, urls, blobs, linkio.Options{URLTTL: cfg.Blob.URLTTL})
+
+	// Only sqlite can snapshot itself; the admin API answers 501 otherwise.
+	var backups handler.BackupService
+	if snap, ok := db.(backup.Snapshotter); ok {
+		backups = backup.New(logger, snap, blobs, backup.Options{
+			Keep:    cfg.Backup.Keep,
+			TempDir: cfg.Backup.TempDir,
+		})
+	}
+
+	router := transport.NewRouter(logger, authService, hub, checker, previews, themes, images, sectionService, trashService, linkIO, backups, cacheStats, trashService, blobs, signer, urls, cfg.Secret, cfg.Admin.Token, cfg.HTTPCache.CacheControl, duration, cfg.Events.Heartbeat, cfg.Media.MaxUploadBytes, cfg.Blob.URLTTL)
+
+	srv := &http.Server{Addr: cfg.Addr, Handler: router}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe() //TODO: configure TLS: need white ip, so we'll wait
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// Event streams stay open until the client leaves; cut them off.
+		logger.Warn("closing remaining connections", slog.String("error", err.Error()))
+		return srv.Close()
+	}
 
-	return http.ListenAndServe(cfg.Addr, router) //TODO: configure TLS: need white ip, so we'll wait
+	return nil
 }
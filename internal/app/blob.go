@@ -0,0 +1,52 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"url_profile/internal/blob"
+	localblob "url_profile/internal/blob/local"
+	s3blob "url_profile/internal/blob/s3"
+	"url_profile/internal/config"
+)
+
+// NewBlobStorage opens the backend selected by cfg.Blob.Driver. The rest of
+// the app only sees blob.Storage. The signer verifies the download URLs the
+// local backend issues.
+func NewBlobStorage(cfg config.Config) (blob.Storage, *blob.Signer, error) {
+	// S3 presigns on its own, so with it the signer never issues a URL and
+	// a random key makes /files refuse everything.
+	key := cfg.Blob.Local.SigningKey
+	if key == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		key = hex.EncodeToString(b)
+	}
+	signer := blob.NewSigner(key, cfg.Blob.Local.PublicURL)
+
+	switch cfg.Blob.Driver {
+	case "", "local":
+		dir := cfg.Blob.Local.Dir
+		if dir == "" {
+			dir = cfg.Media.Dir
+		}
+
+		s, err := localblob.New(dir, signer)
+		return s, signer, err
+	case "s3":
+		s, err := s3blob.New(s3blob.Config{
+			Endpoint:  cfg.Blob.S3.Endpoint,
+			Region:    cfg.Blob.S3.Region,
+			Bucket:    cfg.Blob.S3.Bucket,
+			AccessKey: cfg.Blob.S3.AccessKey,
+			SecretKey: cfg.Blob.S3.SecretKey,
+			UseSSL:    cfg.Blob.S3.UseSSL,
+			PathStyle: cfg.Blob.S3.PathStyle,
+		}, nil)
+		return s, signer, err
+	default:
+		return nil, nil, fmt.Errorf("unknown blob driver %q", cfg.Blob.Driver)
+	}
+}
@@ -0,0 +1,194 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/config"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/services/profilecache"
+	"url_profile/internal/store"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// cachedStore serves public profiles through the profile cache and drops a
+// user's entry after every write made on the user's behalf that changes
+// what UserByUsername returns, including its version: the profile ETag is
+// built from it, so an entry with a stale version would answer 304 for a
+// changed theme or image.
+//
+// Link previews are saved by URL, so a preview write drops the entry of
+// every profile linking to it.
+type cachedStore struct {
+	appStore
+	cache *profilecache.Cache
+}
+
+func (s cachedStore) UserByUsername(name string) (*models.User, error) {
+	return s.cache.Profile(name, s.appStore.UserByUsername)
+}
+
+func (s cachedStore) UpdateAboutMe(id int, text string, ifVersion int) error {
+	return s.invalidate(id, s.appStore.UpdateAboutMe(id, text, ifVersion))
+}
+
+func (s cachedStore) AddLink(userID int, link requestModel.ReqLink) (int, error) {
+	id, err := s.appStore.AddLink(userID, link)
+	return id, s.invalidate(userID, err)
+}
+
+func (s cachedStore) UpdateLink(userID int, link *requestModel.ReqUpdateLink, ifVersion int) error {
+	return s.invalidate(userID, s.appStore.UpdateLink(userID, link, ifVersion))
+}
+
+func (s cachedStore) DeleteLink(userID int, linkID int, ifVersion int) error {
+	return s.invalidate(userID, s.appStore.DeleteLink(userID, linkID, ifVersion))
+}
+
+func (s cachedStore) ChangeLinks(userID int, changes []models.LinkChange) error {
+	return s.invalidate(userID, s.appStore.ChangeLinks(userID, changes))
+}
+
+func (s cachedStore) SetLinkHidden(userID int, linkID int, hidden bool) error {
+	return s.invalidate(userID, s.appStore.SetLinkHidden(userID, linkID, hidden))
+}
+
+func (s cachedStore) RestoreLink(userID int, linkID int, deletedAfter time.Time) error {
+	return s.invalidate(userID, s.appStore.RestoreLink(userID, linkID, deletedAfter))
+}
+
+// DeleteSection ungroups the section's links, which changes their
+// section_id.
+func (s cachedStore) DeleteSection(userID int, sectionID int) error {
+	return s.invalidate(userID, s.appStore.DeleteSection(userID, sectionID))
+}
+
+func (s cachedStore) CreateSection(userID int, title string, position *int, collapsed bool) (*models.LinkSection, error) {
+	sec, err := s.appStore.CreateSection(userID, title, position, collapsed)
+	return sec, s.invalidate(userID, err)
+}
+
+func (s cachedStore) UpdateSection(section models.LinkSection) error {
+	return s.invalidate(section.UserID, s.appStore.UpdateSection(section))
+}
+
+func (s cachedStore) SaveTheme(userID int, theme models.Theme) error {
+	return s.invalidate(userID, s.appStore.SaveTheme(userID, theme))
+}
+
+func (s cachedStore) SaveProfileImage(img models.ProfileImage) error {
+	return s.invalidate(img.UserID, s.appStore.SaveProfileImage(img))
+}
+
+func (s cachedStore) DeleteProfileImage(userID int, kind string) error {
+	return s.invalidate(userID, s.appStore.DeleteProfileImage(userID, kind))
+}
+
+func (s cachedStore) ImportLinks(userID int, rows []models.ImportedLink) (int, error) {
+	n, err := s.appStore.ImportLinks(userID, rows)
+	return n, s.invalidate(userID, err)
+}
+
+// SaveLinkPreview drops the entries of the profiles linking to the URL:
+// the write may have bumped their preview version.
+func (s cachedStore) SaveLinkPreview(preview models.LinkPreview) error {
+	if err := s.appStore.SaveLinkPreview(preview); err != nil {
+		return err
+	}
+
+	owners, err := s.appStore.PreviewOwners(preview.URL)
+	if err != nil {
+		return err
+	}
+	for _, id := range owners {
+		s.cache.Invalidate(id)
+	}
+
+	return nil
+}
+
+// InTx drops the entry of every user the unit of work wrote for once it
+// has committed.
+func (s cachedStore) InTx(ctx context.Context, fn func(tx store.Tx) error) error {
+	tx := &cachedTx{}
+	err := s.appStore.InTx(ctx, func(inner store.Tx) error {
+		tx.Tx = inner
+		return fn(tx)
+	})
+	if err == nil {
+		for _, id := range tx.touched {
+			s.cache.Invalidate(id)
+		}
+	}
+	return err
+}
+
+// cachedTx records whose profiles a unit of work changes.
+type cachedTx struct {
+	store.Tx
+	touched []int
+}
+
+func (tx *cachedTx) UpdateAboutMe(id int, text string, ifVersion int) error {
+	tx.touched = append(tx.touched, id)
+	return tx.Tx.UpdateAboutMe(id, text, ifVersion)
+}
+
+func (tx *cachedTx) SaveTheme(userID int, theme models.Theme) error {
+	tx.touched = append(tx.touched, userID)
+	return tx.Tx.SaveTheme(userID, theme)
+}
+
+func (tx *cachedTx) AddLink(userID int, link requestModel.ReqLink) (int, error) {
+	tx.touched = append(tx.touched, userID)
+	return tx.Tx.AddLink(userID, link)
+}
+
+// invalidate drops the entry once the write went through and passes err
+// on.
+func (s cachedStore) invalidate(userID int, err error) error {
+	if err == nil {
+		s.cache.Invalidate(userID)
+	}
+	return err
+}
+
+// newProfileCache builds the cache selected by cfg.ProfileCache. The
+// returned close func releases the Redis client, if any.
+func newProfileCache(cfg config.Config, log *slog.Logger, db appStore) (*profilecache.Cache, func() error) {
+	pc := cfg.ProfileCache
+	opts := profilecache.Options{
+		Size: pc.Size,
+		TTL:  pc.TTL,
+	}
+
+	closeFn := func() error { return nil }
+	if pc.Driver == "redis" {
+		client := redis.NewClient(&redis.Options{
+			Addr:     pc.Redis.Addr,
+			Password: pc.Redis.Password,
+			DB:       pc.Redis.DB,
+		})
+		// An unreachable Redis only costs cache misses, so it does not
+		// stop the start.
+		if err := client.Ping(context.Background()).Err(); err != nil {
+			log.Warn("profile cache: redis is unreachable", slog.String("error", err.Error()))
+		}
+
+		opts.Redis = client
+		opts.Prefix = pc.Redis.Prefix
+		closeFn = client.Close
+	}
+
+	username := func(userID int) (string, error) {
+		u, err := db.UserById(userID)
+		if err != nil {
+			return "", err
+		}
+		return u.Username, nil
+	}
+
+	return profilecache.New(log, username, opts), closeFn
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+	"url_profile/internal/app/server/http/handlers/viewModel"
+	"url_profile/internal/blob"
+	"url_profile/internal/services/backup"
+	"url_profile/internal/services/profilecache"
+
+	"github.com/gorilla/mux"
+)
+
+type BackupService interface {
+	Backup(ctx context.Context) (*backup.Snapshot, error)
+	List(ctx context.Context) ([]backup.Snapshot, error)
+	Download(ctx context.Context, name string, ttl time.Duration) (*blob.Download, error)
+}
+
+type CacheStats interface {
+	Stats() profilecache.Stats
+}
+
+type AdminHandler struct {
+	log *slog.Logger
+	// backups is nil when the storage backend cannot take snapshots.
+	backups BackupService
+	// cache is nil when the profile cache is disabled.
+	cache CacheStats
+	// urlTTL is how long backup download URLs stay valid.
+	urlTTL time.Duration
+}
+
+func NewAdminHandlers(log *slog.Logger, backups BackupService, cache CacheStats, urlTTL time.Duration) *AdminHandler {
+	return &AdminHandler{
+		log:     log,
+		backups: backups,
+		cache:   cache,
+		urlTTL:  urlTTL,
+	}
+}
+
+func (h *AdminHandler) HandlerBackup() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.backups == nil {
+			sendError(w, http.StatusNotImplemented, fmt.Errorf("backups are only supported for sqlite"))
+			return
+		}
+
+		snap, err := h.backups.Backup(r.Context())
+		if err != nil {
+			h.log.Error("backup failed", slog.String("error", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("backup failed"))
+			return
+		}
+
+		respond(w, http.StatusCreated, snapshotView(*snap))
+	}
+}
+
+func (h *AdminHandler) HandlerListBackups() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.backups == nil {
+			sendError(w, http.StatusNotImplemented, fmt.Errorf("backups are only supported for sqlite"))
+			return
+		}
+
+		snaps, err := h.backups.List(r.Context())
+		if err != nil {
+			h.log.Error("failed to list backups", slog.String("error", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		res := make([]viewModel.SnapshotView, 0, len(snaps))
+		for _, s := range snaps {
+			res = append(res, snapshotView(s))
+		}
+
+		respond(w, http.StatusOK, res)
+	}
+}
+
+// HandlerDownloadBackup answers with a temporary URL for one snapshot.
+func (h *AdminHandler) HandlerDownloadBackup() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.backups == nil {
+			sendError(w, http.StatusNotImplemented, fmt.Errorf("backups are only supported for sqlite"))
+			return
+		}
+
+		d, err := h.backups.Download(r.Context(), mux.Vars(r)["name"], h.urlTTL)
+		if err != nil {
+			if errors.Is(err, backup.ErrNotFound) || errors.Is(err, backup.ErrInvalidName) {
+				sendError(w, http.StatusNotFound, backup.ErrNotFound)
+				return
+			}
+
+			h.log.Error("failed to presign backup", slog.String("error", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		respond(w, http.StatusOK, viewModel.DownloadView{URL: d.URL, ExpiresAt: d.ExpiresAt})
+	}
+}
+
+func (h *AdminHandler) HandlerCacheStats() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.cache == nil {
+			respond(w, http.StatusOK, viewModel.CacheStatsView{})
+			return
+		}
+
+		stats := h.cache.Stats()
+		respond(w, http.StatusOK, viewModel.CacheStatsView{
+			Enabled: true,
+			Hits:    stats.Hits,
+			Misses:  stats.Misses,
+		})
+	}
+}
+
+func snapshotView(s backup.Snapshot) viewModel.SnapshotView {
+	return viewModel.SnapshotView{
+		Name:      s.Name,
+		Size:      s.Size,
+		CreatedAt: s.CreatedAt,
+	}
+}
@@ -6,26 +6,32 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
-	"strings"
 	"time"
 	"url_profile/internal/app/server/http/handlers/requestModel"
 	"url_profile/internal/lib/jwt"
+	"url_profile/internal/lib/linkblock"
+	"url_profile/internal/lib/linkurl"
 	"url_profile/internal/store"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// signUpFields maps user columns to the sign-up request fields.
+var signUpFields = map[string]string{"username": "login"}
+
 type AuthHandlers struct {
 	log      *slog.Logger
 	service  UserService
+	urls     *linkurl.Validator
 	secret   string
 	tokenTTL time.Duration
 }
 
-func NewAuthHandlers(log *slog.Logger, service UserService, secret string, tokenTTL time.Duration) *AuthHandlers {
+func NewAuthHandlers(log *slog.Logger, service UserService, urls *linkurl.Validator, secret string, tokenTTL time.Duration) *AuthHandlers {
 	return &AuthHandlers{
 		log:      log,
 		service:  service,
+		urls:     urls,
 		secret:   secret,
 		tokenTTL: tokenTTL,
 	}
@@ -34,7 +40,6 @@ func NewAuthHandlers(log *slog.Logger, service UserService, secret string, token
 func (h *AuthHandlers) HandleSignUp() http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		validLinks := make([]requestModel.ReqLink, 0)
 		req := &requestModel.SignUpModel{}
 
 		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
@@ -56,17 +61,35 @@ func (h *AuthHandlers) HandleSignUp() http.HandlerFunc {
 			return
 		}
 
-		for _, l := range req.Links {
-			if strings.Trim(l.LinkName, " ") != "" && strings.Trim(l.LinkPath, " ") != "" {
-				validLinks = append(validLinks, l)
+		// Every link is checked so the client learns about all of them at
+		// once; nothing is dropped silently.
+		verr := &requestModel.ValidationError{}
+		for i := range req.Links {
+			if err := normalizeSignUpLink(&req.Links[i], h.urls); err != nil {
+				verr.Merge(fmt.Sprintf("links[%d]", i), err)
 			}
 		}
 
-		req.Links = validLinks
+		if err := verr.OrNil(); err != nil {
+			h.log.Debug("Invalid links", slog.String("err", err.Error()))
+			sendValidationError(w, err)
+			return
+		}
+
 		code, u, err := h.service.CreateUser(req)
 		if err != nil {
+			var invalid *store.InvalidLinksError
+			if errors.As(err, &invalid) {
+				verr := &requestModel.ValidationError{}
+				for _, l := range invalid.Links {
+					verr.Add(fmt.Sprintf("links[%d].%s", l.Index, l.Field), l.Err)
+				}
+				sendValidationError(w, verr)
+				return
+			}
+
 			if code == http.StatusConflict {
-				sendError(w, http.StatusConflict, err)
+				sendConflict(w, err, signUpFields)
 				return
 			}
 			sendError(w, http.StatusInternalServerError, fmt.Errorf("internal server error"))
@@ -86,6 +109,22 @@ func (h *AuthHandlers) HandleSignUp() http.HandlerFunc {
 	}
 }
 
+// normalizeSignUpLink validates a link sent with the sign-up form. Unlike
+// addLink it does not fall back to the page title, so plain links need a
+// name.
+func normalizeSignUpLink(l *requestModel.ReqLink, urls *linkurl.Validator) error {
+	verr := &requestModel.ValidationError{}
+	if err := l.Normalize(urls, linkurl.Options{}); err != nil {
+		errors.As(err, &verr)
+	}
+
+	if l.Type == linkblock.TypeLink && l.LinkName == "" {
+		verr.Add("link_name", fmt.Errorf("link_name is required"))
+	}
+
+	return verr.OrNil()
+}
+
 func (h *AuthHandlers) HandleLogin() http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
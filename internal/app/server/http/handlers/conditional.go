@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"url_profile/internal/domain/models"
+)
+
+// profileETag names one version of a user's profile. The user ID keeps
+// accounts apart on /api/profile, which serves whoever is logged in, and
+// across a username that was freed and taken again. The preview version
+// follows a dot: it tells GET responses apart, but If-Match only pins
+// the version the user's own writes move, see ifMatchVersion.
+func profileETag(u *models.User) string {
+	return profileTag(u.ID) + profileVersion(u) + `"`
+}
+
+// ownerETag names one version of the profile as its owner sees it. Link
+// health is only on that view and moves no version, so the last check is
+// appended after another dot.
+func ownerETag(u *models.User, health map[int]models.LinkHealth) string {
+	checked := healthCheckedAt(health)
+	if checked.IsZero() {
+		return profileETag(u)
+	}
+	return profileTag(u.ID) + profileVersion(u) + "." + strconv.FormatInt(checked.UnixMilli(), 36) + `"`
+}
+
+func profileVersion(u *models.User) string {
+	return strconv.Itoa(u.Version) + "." + strconv.Itoa(u.PreviewVersion)
+}
+
+// ownerModified is the Last-Modified of the owner's profile.
+func ownerModified(u *models.User, health map[int]models.LinkHealth) time.Time {
+	if checked := healthCheckedAt(health); checked.After(u.UpdatedAt) {
+		return checked
+	}
+	return u.UpdatedAt
+}
+
+func healthCheckedAt(health map[int]models.LinkHealth) time.Time {
+	var last time.Time
+	for _, lh := range health {
+		if lh.CheckedAt.After(last) {
+			last = lh.CheckedAt
+		}
+	}
+	return last
+}
+
+func profileTag(userID int) string {
+	return fmt.Sprintf(`"%d-`, userID)
+}
+
+// linkETag names one version of a link.
+func linkETag(l *models.Link) string {
+	return linkTag(l.ID) + strconv.Itoa(l.Version) + `"`
+}
+
+func linkTag(linkID int) string {
+	return fmt.Sprintf(`"link-%d-`, linkID)
+}
+
+// notModified sets ETag and Last-Modified and answers 304 when the
+// request's validators still match. If-Modified-Since only counts when the
+// request has no If-None-Match, as RFC 9110 asks.
+func notModified(w http.ResponseWriter, r *http.Request, etag string, modified time.Time) bool {
+	w.Header().Set("ETag", etag)
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if !etagMatches(inm, etag) {
+			return false
+		}
+	} else {
+		since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+		if err != nil || modified.IsZero() || modified.Truncate(time.Second).After(since) {
+			return false
+		}
+	}
+
+	// A 304 carries the validators but no body headers.
+	w.Header().Del("Content-Type")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches reports whether a comma separated If-None-Match list names
+// etag, comparing weakly.
+func etagMatches(list string, etag string) bool {
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion reads the version an If-Match header pins for the
+// resource whose ETags start with tag; anything after a dot in the tag
+// is ignored. Without the header, or with "*",
+// it returns 0: write unconditionally. ok is false when the header names
+// no version of this resource, a precondition that can never hold.
+func ifMatchVersion(r *http.Request, tag string) (version int, ok bool) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return 0, true
+	}
+
+	for _, etag := range strings.Split(header, ",") {
+		etag = strings.TrimSpace(etag)
+		if etag == "*" {
+			return 0, true
+		}
+
+		// If-Match compares strongly, so weak tags never match.
+		rest, found := strings.CutPrefix(etag, tag)
+		if !found {
+			continue
+		}
+		rest, quoted := strings.CutSuffix(rest, `"`)
+		rest, _, _ = strings.Cut(rest, ".")
+		v, err := strconv.Atoi(rest)
+		if err == nil && v > 0 && quoted {
+			return v, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"url_profile/internal/app/server/http/constants"
+	"url_profile/internal/services/events"
+)
+
+type EventPublisher interface {
+	Publish(userID int, eventType string, data any)
+}
+
+type EventSubscriber interface {
+	Subscribe(userID int, lastEventID uint64) (*events.Subscription, []events.Event, error)
+}
+
+type EventsHandler struct {
+	log       *slog.Logger
+	hub       EventSubscriber
+	heartbeat time.Duration
+}
+
+func NewEventsHandlers(log *slog.Logger, hub EventSubscriber, heartbeat time.Duration) *EventsHandler {
+	return &EventsHandler{
+		log:       log,
+		hub:       hub,
+		heartbeat: heartbeat,
+	}
+}
+
+func (h *EventsHandler) HandlerStream() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(consts.CtxUserIdKey).(int)
+
+		var lastID uint64
+		if v := r.Header.Get("Last-Event-ID"); v != "" {
+			id, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				sendError(w, http.StatusBadRequest, fmt.Errorf("invalid Last-Event-ID"))
+				return
+			}
+			lastID = id
+		}
+
+		sub, missed, err := h.hub.Subscribe(userID, lastID)
+		if err != nil {
+			if errors.Is(err, events.ErrTooManySubscribers) {
+				sendError(w, http.StatusTooManyRequests, err)
+				return
+			}
+
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+		defer sub.Close()
+
+		rc := http.NewResponseController(w)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		if err := rc.Flush(); err != nil {
+			h.log.Error("event stream cannot be flushed", slog.String("error", err.Error()))
+			return
+		}
+
+		for _, ev := range missed {
+			if err := writeEvent(w, ev); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		ticker := time.NewTicker(h.heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+
+			case ev, ok := <-sub.C:
+				if !ok {
+					h.log.Debug("event stream dropped", slog.Int("user_id", userID))
+					return
+				}
+
+				if err := writeEvent(w, ev); err != nil {
+					return
+				}
+			}
+
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, ev events.Event) error {
+	data, err := json.Marshal(map[string]any{
+		"time": ev.Time,
+		"data": ev.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+	return err
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"url_profile/internal/blob"
+
+	"github.com/gorilla/mux"
+)
+
+type BlobReader interface {
+	Get(ctx context.Context, key string) (io.ReadCloser, *blob.Info, error)
+}
+
+type SignatureVerifier interface {
+	Verify(key string, expires string, signature string) error
+}
+
+type FilesHandler struct {
+	log    *slog.Logger
+	blobs  BlobReader
+	signer SignatureVerifier
+}
+
+func NewFilesHandlers(log *slog.Logger, blobs BlobReader, signer SignatureVerifier) *FilesHandler {
+	return &FilesHandler{
+		log:    log,
+		blobs:  blobs,
+		signer: signer,
+	}
+}
+
+// HandlerDownload serves objects behind URLs issued by blob.Signer.
+func (h *FilesHandler) HandlerDownload() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := mux.Vars(r)["key"]
+		q := r.URL.Query()
+
+		if err := h.signer.Verify(key, q.Get("expires"), q.Get("signature")); err != nil {
+			sendError(w, http.StatusForbidden, err)
+			return
+		}
+
+		rc, info, err := h.blobs.Get(r.Context(), key)
+		if err != nil {
+			if errors.Is(err, blob.ErrNotFound) || errors.Is(err, blob.ErrInvalidKey) {
+				sendError(w, http.StatusNotFound, blob.ErrNotFound)
+				return
+			}
+
+			h.log.Error("failed to open file", slog.String("key", key), slog.String("error", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+		defer rc.Close()
+
+		if info.ContentType != "" {
+			w.Header().Set("Content-Type", info.ContentType)
+		} else {
+			w.Header().Set("Content-Type", "application/octet-stream")
+		}
+		if info.Size >= 0 {
+			w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+		}
+		// Exports may be HTML; never render them on the app's origin.
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(key)}))
+		w.Header().Set("Cache-Control", "private, no-store")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+
+		io.Copy(w, rc)
+	}
+}
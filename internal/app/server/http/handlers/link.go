@@ -1,25 +1,56 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 	"url_profile/internal/app/server/http/constants"
 	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/app/server/http/handlers/viewModel"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/lib/linkblock"
+	"url_profile/internal/lib/linkurl"
+	"url_profile/internal/services/events"
+	"url_profile/internal/services/linkpreview"
 	"url_profile/internal/store"
+
+	"github.com/gorilla/mux"
 )
 
+type LinkPreviewer interface {
+	Preview(ctx context.Context, url string) (*models.LinkPreview, error)
+	Refresh(ctx context.Context, url string) (*models.LinkPreview, error)
+	Previews(userID int) (map[string]models.LinkPreview, error)
+}
+
+type LinkVisibility interface {
+	SetHidden(userID int, linkID int, hidden bool) error
+}
+
 type LinkHandler struct {
-	log     *slog.Logger
-	service UserService
+	log        *slog.Logger
+	service    UserService
+	events     EventPublisher
+	previews   LinkPreviewer
+	visibility LinkVisibility
+	urls       *linkurl.Validator
 }
 
-func NewLinkHandlers(log *slog.Logger, service UserService) *LinkHandler {
+func NewLinkHandlers(log *slog.Logger, service UserService, events EventPublisher, previews LinkPreviewer, visibility LinkVisibility, urls *linkurl.Validator) *LinkHandler {
 	return &LinkHandler{
-		log:     log,
-		service: service,
+		log:        log,
+		service:    service,
+		events:     events,
+		previews:   previews,
+		visibility: visibility,
+		urls:       urls,
 	}
 }
 
@@ -40,6 +71,63 @@ func (h *LinkHandler) HandlerLink() http.HandlerFunc {
 	}
 }
 
+// HandlerFollowLink redirects a visitor to the link target and reports the
+// click to the owner's event stream.
+func (h *LinkHandler) HandlerFollowLink() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		linkID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid link id"))
+			return
+		}
+
+		link, err := h.service.Link(linkID)
+		if err != nil {
+			if errors.Is(err, store.ErrLinkNotFound) {
+				sendError(w, http.StatusNotFound, err)
+				return
+			}
+
+			h.log.Debug("Find Link Return Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		if link.Hidden || (link.Type != linkblock.TypeVCard && !linkblock.Followable(link.Type)) {
+			sendError(w, http.StatusNotFound, store.ErrLinkNotFound)
+			return
+		}
+
+		h.events.Publish(link.UserID, events.TypeClick, map[string]any{
+			"link_id":   link.ID,
+			"link_name": link.LinkName,
+			"link_path": link.LinkPath,
+			"referer":   r.Referer(),
+		})
+
+		if link.Type == linkblock.TypeVCard {
+			h.serveVCard(w, link)
+			return
+		}
+
+		http.Redirect(w, r, link.LinkPath, http.StatusFound)
+	}
+}
+
+func (h *LinkHandler) serveVCard(w http.ResponseWriter, link *models.Link) {
+	var card linkblock.VCardPayload
+	if err := json.Unmarshal(link.Payload, &card); err != nil {
+		h.log.Error("invalid vcard payload", slog.Int("link_id", link.ID), slog.String("error", err.Error()))
+		sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/vcard; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "contact.vcf"}))
+	w.WriteHeader(http.StatusOK)
+	w.Write(card.VCard())
+}
+
 func (s *LinkHandler) handlerAddLink() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID := r.Context().Value(consts.CtxUserIdKey).(int)
@@ -49,53 +137,368 @@ func (s *LinkHandler) handlerAddLink() http.HandlerFunc {
 			return
 		}
 
-		for _, link := range links {
-			if link.LinkName == "" || link.LinkPath == "" {
-				sendError(w, http.StatusBadRequest, fmt.Errorf("link_name and link_path are required"))
+		opts, err := s.urlOptions(userID)
+		if err != nil {
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		verr := &requestModel.ValidationError{}
+		for i := range links {
+			if err := links[i].Normalize(s.urls, opts); err != nil {
+				verr.Merge(fmt.Sprintf("[%d]", i), err)
+			}
+		}
+		if err := verr.OrNil(); err != nil {
+			sendValidationError(w, err)
+			return
+		}
+
+		for i := range links {
+			if !s.completeLink(w, r, &links[i]) {
 				return
 			}
+		}
+
+		// One bad link fails the whole request, so nothing is half added.
+		if _, err := s.service.AddLinks(r.Context(), userID, links); err != nil {
+			s.addLinkFailed(w, err)
+			return
+		}
 
-			if err := s.service.AddLink(userID, link); err != nil {
-				if errors.Is(err, store.ErrLinkAlreadyExists) {
-					sendError(w, http.StatusConflict, err)
-					return
-				}
+		respond(w, http.StatusOK, nil)
+	}
+}
+
+// HandlerCreateLink adds one link and answers 201 with the link, its ID
+// and ETag.
+func (h *LinkHandler) HandlerCreateLink() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(consts.CtxUserIdKey).(int)
+		var link requestModel.ReqLink
+		if err := json.NewDecoder(r.Body).Decode(&link); err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+			return
+		}
 
-				if errors.Is(err, store.ErrUserNotFound) {
-					sendError(w, http.StatusNotFound, err)
-					return
-				}
+		opts, err := h.urlOptions(userID)
+		if err != nil {
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
 
-				s.log.Debug("Error Create Link", slog.String("error", err.Error()))
-				sendError(w, http.StatusInternalServerError, err)
+		if err := link.Normalize(h.urls, opts); err != nil {
+			sendValidationError(w, err)
+			return
+		}
+
+		id, ok := h.addLink(w, r, userID, link)
+		if !ok {
+			return
+		}
+
+		created, err := h.service.Link(id)
+		if err != nil {
+			h.log.Debug("Find Link Return Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		w.Header().Set("Location", fmt.Sprintf("/api/profile/links/%d", id))
+		w.Header().Set("ETag", linkETag(created))
+		respond(w, http.StatusCreated, ownLinkView(created))
+	}
+}
+
+// addLink saves a normalized link, naming it from its preview when the
+// name is missing. On failure it has already answered and ok is false.
+func (h *LinkHandler) addLink(w http.ResponseWriter, r *http.Request, userID int, link requestModel.ReqLink) (id int, ok bool) {
+	if !h.completeLink(w, r, &link) {
+		return 0, false
+	}
+
+	id, err := h.service.AddLink(userID, link)
+	if err != nil {
+		h.addLinkFailed(w, err)
+		return 0, false
+	}
+
+	return id, true
+}
+
+// completeLink names a plain link after its preview when the name is
+// missing and answers 400 if it still lacks a name or path.
+func (h *LinkHandler) completeLink(w http.ResponseWriter, r *http.Request, link *requestModel.ReqLink) bool {
+	if link.Type != linkblock.TypeLink {
+		return true
+	}
+
+	if link.LinkName == "" {
+		link.LinkName = h.nameFromPreview(r.Context(), link.LinkPath)
+	}
+
+	if link.LinkName == "" || link.LinkPath == "" {
+		sendError(w, http.StatusBadRequest, fmt.Errorf("link_name and link_path are required"))
+		return false
+	}
+
+	return true
+}
+
+// addLinkFailed answers a failed link insert.
+func (h *LinkHandler) addLinkFailed(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, store.ErrLinkAlreadyExists):
+		sendConflict(w, err, nil)
+	case errors.Is(err, store.ErrUserNotFound):
+		sendError(w, http.StatusNotFound, err)
+	case errors.Is(err, store.ErrSectionNotFound):
+		sendValidationError(w, &requestModel.ValidationError{Fields: []requestModel.FieldError{
+			{Field: "section_id", Message: err.Error()},
+		}})
+	default:
+		h.log.Debug("Error Create Link", slog.String("error", err.Error()))
+		sendError(w, http.StatusInternalServerError, err)
+	}
+}
+
+// HandlerListLinks returns all of the user's links, hidden ones included.
+func (h *LinkHandler) HandlerListLinks() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(consts.CtxUserIdKey).(int)
+		u, err := h.service.UserById(userID)
+		if err != nil {
+			if errors.Is(err, store.ErrUserNotFound) {
+				sendError(w, http.StatusNotFound, err)
 				return
 			}
+
+			h.log.Debug("Find User Return Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
 		}
 
-		respond(w, http.StatusOK, nil)
+		links := make([]viewModel.OwnLinkView, 0, len(u.Links))
+		for i := range u.Links {
+			links = append(links, ownLinkView(&u.Links[i]))
+		}
+
+		respond(w, http.StatusOK, links)
 	}
 }
 
-func (h *LinkHandler) handlerUpdateLink() http.HandlerFunc {
+// HandlerGetLink returns one of the user's links with its ETag.
+func (h *LinkHandler) HandlerGetLink() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID := r.Context().Value(consts.CtxUserIdKey).(int)
-		link := &requestModel.ReqUpdateLink{}
-		if err := json.NewDecoder(r.Body).Decode(&link); err != nil {
+		linkID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid link id"))
+			return
+		}
+
+		l, err := h.service.Link(linkID)
+		if err != nil || l.UserID != userID {
+			if err == nil || errors.Is(err, store.ErrLinkNotFound) {
+				sendError(w, http.StatusNotFound, store.ErrLinkNotFound)
+				return
+			}
+
+			h.log.Debug("Find Link Return Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		if notModified(w, r, linkETag(l), time.Time{}) {
+			return
+		}
+
+		respond(w, http.StatusOK, ownLinkView(l))
+	}
+}
+
+// urlOptions returns the link normalization options the user has chosen.
+func (h *LinkHandler) urlOptions(userID int) (linkurl.Options, error) {
+	settings, err := h.service.Settings(userID)
+	if err != nil {
+		h.log.Debug("Find Settings Return Error:", slog.String("err", err.Error()))
+		return linkurl.Options{}, err
+	}
+
+	return linkurl.Options{StripTracking: settings.StripTracking}, nil
+}
+
+// nameFromPreview fills in a missing link name from the page title, falling
+// back to the host. It returns "" when previews are disabled.
+func (s *LinkHandler) nameFromPreview(ctx context.Context, path string) string {
+	p, err := s.previews.Preview(ctx, path)
+	if errors.Is(err, linkpreview.ErrDisabled) {
+		return ""
+	}
+
+	if err == nil && p.Title != "" {
+		return p.Title
+	}
+
+	if u, err := url.Parse(path); err == nil {
+		return u.Host
+	}
+	return ""
+}
+
+func (h *LinkHandler) HandlerRefreshPreview() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(consts.CtxUserIdKey).(int)
+		linkID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid link id"))
+			return
+		}
+
+		link, err := h.service.Link(linkID)
+		if err != nil || link.UserID != userID {
+			if err == nil || errors.Is(err, store.ErrLinkNotFound) {
+				sendError(w, http.StatusNotFound, store.ErrLinkNotFound)
+				return
+			}
+
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		p, err := h.previews.Refresh(r.Context(), link.LinkPath)
+		if err != nil {
+			switch {
+			case errors.Is(err, linkpreview.ErrDisabled):
+				sendError(w, http.StatusServiceUnavailable, err)
+			case errors.Is(err, linkpreview.ErrBlockedAddress), errors.Is(err, linkpreview.ErrBadScheme):
+				sendError(w, http.StatusBadRequest, err)
+			case errors.Is(err, store.ErrDatabaseOperation):
+				sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			default:
+				sendError(w, http.StatusBadGateway, fmt.Errorf("failed to fetch link metadata: %v", err))
+			}
+			return
+		}
+
+		respond(w, http.StatusOK, previewView(*p))
+	}
+}
+
+// HandlerSetVisibility hides a link from the public profile or shows it again.
+func (h *LinkHandler) HandlerSetVisibility() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(consts.CtxUserIdKey).(int)
+		linkID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid link id"))
+			return
+		}
+
+		req := &requestModel.ReqLinkVisibility{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
 			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
 			return
 		}
 
-		if err := h.service.UpdateLink(userID, link); err != nil {
+		if err := h.visibility.SetHidden(userID, linkID, req.Hidden); err != nil {
 			if errors.Is(err, store.ErrLinkNotFound) {
-				sendError(w, http.StatusNotFound, fmt.Errorf(""))
+				sendError(w, http.StatusNotFound, err)
 				return
 			}
-			sendError(w, http.StatusInternalServerError, fmt.Errorf(""))
+
+			h.log.Debug("Set Visibility Return Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
 		}
 
-		respond(w, http.StatusOK, nil)
+		respond(w, http.StatusOK, req)
+	}
+}
+
+func previewView(p models.LinkPreview) *viewModel.LinkPreviewView {
+	return &viewModel.LinkPreviewView{
+		Title:       p.Title,
+		Description: p.Description,
+		Favicon:     p.Favicon,
+		Image:       p.Image,
+	}
+}
+
+func (h *LinkHandler) handlerUpdateLink() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(consts.CtxUserIdKey).(int)
+		link := &requestModel.ReqUpdateLink{}
+		if err := json.NewDecoder(r.Body).Decode(&link); err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+			return
+		}
+
+		h.updateLink(w, r, userID, link)
+	}
+}
+
+// HandlerPutLink replaces the fields of the link named in the path and
+// answers with the updated link.
+func (h *LinkHandler) HandlerPutLink() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(consts.CtxUserIdKey).(int)
+		linkID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid link id"))
+			return
+		}
+
+		link := &requestModel.ReqUpdateLink{}
+		if err := json.NewDecoder(r.Body).Decode(&link); err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+			return
+		}
+
+		if link.LinkID != 0 && link.LinkID != linkID {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("link_id does not match the link in the path"))
+			return
+		}
+		link.LinkID = linkID
+
+		h.updateLink(w, r, userID, link)
+	}
+}
+
+// updateLink normalizes and saves a full update, honouring If-Match, and
+// answers with the updated link.
+func (h *LinkHandler) updateLink(w http.ResponseWriter, r *http.Request, userID int, link *requestModel.ReqUpdateLink) {
+	ifVersion, ok := ifMatchVersion(r, linkTag(link.LinkID))
+	if !ok {
+		h.linkPreconditionFailed(w, userID, link.LinkID)
+		return
 	}
 
+	opts, err := h.urlOptions(userID)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+		return
+	}
+
+	if err := link.Normalize(h.urls, opts); err != nil {
+		sendValidationError(w, err)
+		return
+	}
+
+	if err := h.service.UpdateLink(userID, link, ifVersion); err != nil {
+		h.linkWriteFailed(w, userID, link.LinkID, err)
+		return
+	}
+
+	updated, err := h.service.Link(link.LinkID)
+	if err != nil {
+		h.log.Debug("Find Link Return Error:", slog.String("err", err.Error()))
+		sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+		return
+	}
+
+	w.Header().Set("ETag", linkETag(updated))
+	respond(w, http.StatusOK, ownLinkView(updated))
 }
 
 func (h *LinkHandler) handlerDeleteLink() http.HandlerFunc {
@@ -110,14 +513,105 @@ func (h *LinkHandler) handlerDeleteLink() http.HandlerFunc {
 			return
 		}
 
-		if err := h.service.DeleteLink(userID, link.LinkID); err != nil {
-			if errors.Is(err, store.ErrLinkNotFound) {
-				sendError(w, http.StatusNotFound, err)
-				return
-			}
-			sendError(w, http.StatusInternalServerError, fmt.Errorf(""))
+		if h.deleteLink(w, r, userID, link.LinkID) {
+			respond(w, http.StatusOK, nil)
+		}
+	}
+}
+
+// HandlerRemoveLink moves the link named in the path to the trash.
+func (h *LinkHandler) HandlerRemoveLink() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(consts.CtxUserIdKey).(int)
+		linkID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid link id"))
+			return
 		}
 
-		respond(w, http.StatusOK, nil)
+		if h.deleteLink(w, r, userID, linkID) {
+			respond(w, http.StatusNoContent, nil)
+		}
+	}
+}
+
+// deleteLink trashes the link, honouring If-Match. On failure it has
+// already answered and returns false.
+func (h *LinkHandler) deleteLink(w http.ResponseWriter, r *http.Request, userID int, linkID int) bool {
+	ifVersion, ok := ifMatchVersion(r, linkTag(linkID))
+	if !ok {
+		h.linkPreconditionFailed(w, userID, linkID)
+		return false
+	}
+
+	if err := h.service.DeleteLink(userID, linkID, ifVersion); err != nil {
+		switch {
+		case errors.Is(err, store.ErrLinkNotFound):
+			sendError(w, http.StatusNotFound, err)
+		case errors.Is(err, store.ErrVersionMismatch):
+			h.linkPreconditionFailed(w, userID, linkID)
+		default:
+			h.log.Debug("Delete Link Return Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+		}
+		return false
+	}
+
+	return true
+}
+
+// linkWriteFailed answers a failed link update.
+func (h *LinkHandler) linkWriteFailed(w http.ResponseWriter, userID int, linkID int, err error) {
+	switch {
+	case errors.Is(err, store.ErrLinkNotFound):
+		sendError(w, http.StatusNotFound, err)
+	case errors.Is(err, store.ErrVersionMismatch):
+		h.linkPreconditionFailed(w, userID, linkID)
+	case errors.Is(err, store.ErrLinkAlreadyExists):
+		sendConflict(w, err, nil)
+	case errors.Is(err, store.ErrSectionNotFound):
+		sendValidationError(w, &requestModel.ValidationError{Fields: []requestModel.FieldError{
+			{Field: "section_id", Message: err.Error()},
+		}})
+	default:
+		h.log.Debug("Update Link Return Error:", slog.String("err", err.Error()))
+		sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+	}
+}
+
+// linkPreconditionFailed answers a stale If-Match with 412 and the link as
+// it is now, so the client can merge and retry with the new ETag.
+func (h *LinkHandler) linkPreconditionFailed(w http.ResponseWriter, userID int, linkID int) {
+	l, err := h.service.Link(linkID)
+	if err != nil || l.UserID != userID {
+		if err == nil || errors.Is(err, store.ErrLinkNotFound) {
+			sendError(w, http.StatusNotFound, store.ErrLinkNotFound)
+			return
+		}
+
+		h.log.Debug("Find Link Return Error:", slog.String("err", err.Error()))
+		sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+		return
+	}
+
+	w.Header().Set("ETag", linkETag(l))
+	respond(w, http.StatusPreconditionFailed, ownLinkView(l))
+}
+
+func ownLinkView(l *models.Link) viewModel.OwnLinkView {
+	return viewModel.OwnLinkView{
+		LinkView: viewModel.LinkView{
+			ID:        l.ID,
+			Type:      l.Type,
+			Render:    linkblock.Render(l.Type, l.Payload),
+			LinkName:  l.LinkName,
+			LinkColor: l.LinkColor,
+			LinkPath:  l.LinkPath,
+			Payload:   l.Payload,
+		},
+		SectionID: l.SectionID,
+		Hidden:    l.Hidden,
+		Version:   l.Version,
+		ETag:      linkETag(l),
 	}
 }
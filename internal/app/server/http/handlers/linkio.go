@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"url_profile/internal/app/server/http/constants"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/app/server/http/handlers/viewModel"
+	"url_profile/internal/blob"
+	"url_profile/internal/services/linkio"
+	"url_profile/internal/store"
+)
+
+// maxImportBytes bounds the upload; a thousand links fit comfortably.
+const maxImportBytes = 2 << 20
+
+type LinkIOService interface {
+	Import(userID int, format linkio.Format, r io.Reader, dryRun bool) (*linkio.Result, error)
+	Export(userID int, format linkio.Format, w io.Writer) error
+	ExportFile(ctx context.Context, userID int, format linkio.Format) (*blob.Download, error)
+}
+
+type LinkIOHandler struct {
+	log     *slog.Logger
+	service LinkIOService
+}
+
+func NewLinkIOHandlers(log *slog.Logger, service LinkIOService) *LinkIOHandler {
+	return &LinkIOHandler{
+		log:     log,
+		service: service,
+	}
+}
+
+// HandlerImport takes the file as the raw request body. The format comes from
+// ?format= or, failing that, the Content-Type header.
+func (h *LinkIOHandler) HandlerImport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("format")
+		if name == "" {
+			name = r.Header.Get("Content-Type")
+		}
+		format, err := linkio.ParseFormat(name)
+		if err != nil {
+			sendError(w, http.StatusUnsupportedMediaType, err)
+			return
+		}
+
+		dryRun := false
+		if v := r.URL.Query().Get("dry_run"); v != "" {
+			if dryRun, err = strconv.ParseBool(v); err != nil {
+				sendError(w, http.StatusBadRequest, fmt.Errorf("invalid dry_run value"))
+				return
+			}
+		}
+
+		body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+		res, err := h.service.Import(r.Context().Value(consts.CtxUserIdKey).(int), format, body, dryRun)
+		if err != nil {
+			var (
+				verr   *requestModel.ValidationError
+				tooBig *http.MaxBytesError
+			)
+			switch {
+			case errors.As(err, &verr):
+				sendValidationError(w, err)
+			case errors.As(err, &tooBig):
+				sendError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("import file must be at most %d bytes", maxImportBytes))
+			case errors.Is(err, linkio.ErrMalformed), errors.Is(err, linkio.ErrEmpty), errors.Is(err, linkio.ErrTooManyRows):
+				sendError(w, http.StatusBadRequest, err)
+			case errors.Is(err, store.ErrLinkAlreadyExists):
+				sendConflict(w, err, nil)
+			default:
+				h.log.Debug("Import Links Return Error:", slog.String("err", err.Error()))
+				sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			}
+			return
+		}
+
+		if !res.DryRun {
+			respond(w, http.StatusCreated, viewModel.ImportResultView{
+				Imported:        len(res.Links),
+				SectionsCreated: res.SectionsCreated,
+			})
+			return
+		}
+
+		report := viewModel.ImportReportView{
+			DryRun: true,
+			Total:  res.Total,
+			Valid:  len(res.Links),
+			Errors: []requestModel.FieldError{},
+			Links:  make([]viewModel.ImportedLinkView, 0, len(res.Links)),
+		}
+		if res.Errors != nil {
+			report.Errors = res.Errors.Fields
+		}
+		for _, l := range res.Links {
+			report.Links = append(report.Links, viewModel.ImportedLinkView{
+				Type:      l.Link.Type,
+				LinkName:  l.Link.LinkName,
+				LinkColor: l.Link.LinkColor,
+				LinkPath:  l.Link.LinkPath,
+				Payload:   l.Link.Payload,
+				Hidden:    l.Link.Hidden,
+				Section:   l.Section,
+			})
+		}
+
+		respond(w, http.StatusOK, report)
+	}
+}
+
+func (h *LinkIOHandler) HandlerExport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, ok := exportFormat(w, r)
+		if !ok {
+			return
+		}
+
+		// Buffer the file so a failure can still be reported with a status.
+		var buf bytes.Buffer
+		if err := h.service.Export(r.Context().Value(consts.CtxUserIdKey).(int), format, &buf); err != nil {
+			h.log.Debug("Export Links Return Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "links." + string(format)}))
+		w.WriteHeader(http.StatusOK)
+		buf.WriteTo(w)
+	}
+}
+
+// HandlerExportFile stores the export and answers with a temporary download
+// URL instead of the file itself.
+func (h *LinkIOHandler) HandlerExportFile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, ok := exportFormat(w, r)
+		if !ok {
+			return
+		}
+
+		d, err := h.service.ExportFile(r.Context(), r.Context().Value(consts.CtxUserIdKey).(int), format)
+		if err != nil {
+			h.log.Error("Export Links File Return Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		respond(w, http.StatusCreated, viewModel.DownloadView{URL: d.URL, ExpiresAt: d.ExpiresAt})
+	}
+}
+
+// exportFormat reads ?format=, json by default. On failure it has already
+// answered and ok is false.
+func exportFormat(w http.ResponseWriter, r *http.Request) (format linkio.Format, ok bool) {
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		name = string(linkio.FormatJSON)
+	}
+
+	format, err := linkio.ParseFormat(name)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return "", false
+	}
+
+	return format, true
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"url_profile/internal/app/server/http/constants"
+	"url_profile/internal/blob"
+	"url_profile/internal/services/media"
+	"url_profile/internal/store"
+
+	"github.com/gorilla/mux"
+)
+
+type MediaService interface {
+	Upload(ctx context.Context, userID int, kind string, r io.Reader) (map[string]string, error)
+	Delete(ctx context.Context, userID int, kind string) error
+	ImageURLs(userID int) (map[string]map[string]string, error)
+	Open(ctx context.Context, key string) (io.ReadCloser, *blob.Info, error)
+}
+
+type MediaHandler struct {
+	log      *slog.Logger
+	service  MediaService
+	maxBytes int64
+}
+
+func NewMediaHandlers(log *slog.Logger, service MediaService, maxBytes int64) *MediaHandler {
+	return &MediaHandler{
+		log:      log,
+		service:  service,
+		maxBytes: maxBytes,
+	}
+}
+
+// HandlerUpload accepts a multipart form with the image in the "file" field.
+func (h *MediaHandler) HandlerUpload() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(consts.CtxUserIdKey).(int)
+		kind := mux.Vars(r)["kind"]
+
+		// Leave room for the multipart envelope around the file itself.
+		r.Body = http.MaxBytesReader(w, r.Body, h.maxBytes+64<<10)
+
+		mr, err := r.MultipartReader()
+		if err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("multipart/form-data body is required"))
+			return
+		}
+
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					sendError(w, http.StatusBadRequest, fmt.Errorf("file field is required"))
+					return
+				}
+
+				var maxErr *http.MaxBytesError
+				if errors.As(err, &maxErr) {
+					sendError(w, http.StatusRequestEntityTooLarge, media.ErrTooLarge)
+					return
+				}
+
+				sendError(w, http.StatusBadRequest, fmt.Errorf("invalid multipart body: %v", err))
+				return
+			}
+
+			if part.FormName() != "file" {
+				part.Close()
+				continue
+			}
+
+			urls, err := h.service.Upload(r.Context(), userID, kind, part)
+			part.Close()
+			if err != nil {
+				var maxErr *http.MaxBytesError
+				switch {
+				case errors.Is(err, media.ErrTooLarge), errors.As(err, &maxErr):
+					sendError(w, http.StatusRequestEntityTooLarge, media.ErrTooLarge)
+				case errors.Is(err, media.ErrUnsupportedType), errors.Is(err, media.ErrTooManyPixels):
+					sendError(w, http.StatusUnsupportedMediaType, err)
+				case errors.Is(err, media.ErrUnknownKind):
+					sendError(w, http.StatusNotFound, err)
+				default:
+					h.log.Error("image upload failed", slog.String("error", err.Error()))
+					sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+				}
+				return
+			}
+
+			respond(w, http.StatusOK, urls)
+			return
+		}
+	}
+}
+
+func (h *MediaHandler) HandlerDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(consts.CtxUserIdKey).(int)
+
+		if err := h.service.Delete(r.Context(), userID, mux.Vars(r)["kind"]); err != nil {
+			if errors.Is(err, store.ErrImageNotFound) || errors.Is(err, media.ErrUnknownKind) {
+				sendError(w, http.StatusNotFound, err)
+				return
+			}
+
+			h.log.Error("image delete failed", slog.String("error", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		respond(w, http.StatusOK, nil)
+	}
+}
+
+// HandlerServe streams a stored image. Keys contain a per-upload version, so
+// the response can be cached forever.
+func (h *MediaHandler) HandlerServe() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := mux.Vars(r)["key"]
+		etag := strconv.Quote(key)
+
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		rc, info, err := h.service.Open(r.Context(), key)
+		if err != nil {
+			if errors.Is(err, blob.ErrNotFound) || errors.Is(err, blob.ErrInvalidKey) {
+				sendError(w, http.StatusNotFound, blob.ErrNotFound)
+				return
+			}
+
+			h.log.Error("failed to open media", slog.String("key", key), slog.String("error", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+		defer rc.Close()
+
+		w.Header().Set("Content-Type", info.ContentType)
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		w.Header().Set("ETag", etag)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+
+		io.Copy(w, rc)
+	}
+}
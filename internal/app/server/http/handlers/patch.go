@@ -0,0 +1,501 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"url_profile/internal/app/server/http/constants"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/app/server/http/handlers/viewModel"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/lib/jsonpatch"
+	"url_profile/internal/lib/linkurl"
+	"url_profile/internal/store"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+	maxPatchBytes  = 1 << 20
+
+	// patchAttempts bounds how often a patch without If-Match is re-applied
+	// when a concurrent write lands between reading and writing.
+	patchAttempts = 3
+)
+
+// linkDoc is a link as PATCH documents see it. Version is read-only; JSON
+// Patch can test it to pin the version the edit was made against.
+type linkDoc struct {
+	Type      string          `json:"type"`
+	LinkName  string          `json:"link_name"`
+	LinkColor string          `json:"link_color"`
+	LinkPath  string          `json:"link_path"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	SectionID *int            `json:"section_id"`
+	Version   int             `json:"version"`
+}
+
+// profileDoc is the profile as PATCH /api/profile sees it.
+type profileDoc struct {
+	About string              `json:"about"`
+	Theme viewModel.ThemeView `json:"theme"`
+}
+
+func newLinkDoc(l models.Link) linkDoc {
+	return linkDoc{
+		Type:      l.Type,
+		LinkName:  l.LinkName,
+		LinkColor: l.LinkColor,
+		LinkPath:  l.LinkPath,
+		Payload:   l.Payload,
+		SectionID: l.SectionID,
+		Version:   l.Version,
+	}
+}
+
+// readPatch reads a PATCH body of the given media type. Merge patches are
+// also accepted as plain application/json. Any other type gets 415 with
+// the accepted one in Accept-Patch.
+func readPatch(w http.ResponseWriter, r *http.Request, mediaType string) ([]byte, bool) {
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if ct != mediaType && (mediaType != mergePatchType || ct != "application/json") {
+		w.Header().Set("Accept-Patch", mediaType)
+		sendError(w, http.StatusUnsupportedMediaType, fmt.Errorf("patch must be sent as %s", mediaType))
+		return nil, false
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchBytes))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+		return nil, false
+	}
+
+	return body, true
+}
+
+// decodeDoc strictly decodes a patched document: a patch that adds
+// members the document does not have is rejected.
+func decodeDoc(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// updateRequest turns a patched link document into the update it asks for,
+// normalized like a PUT.
+func (d linkDoc) updateRequest(v *linkurl.Validator, linkID int, version int, opts linkurl.Options) (*requestModel.ReqUpdateLink, error) {
+	if d.Version != version {
+		return nil, &requestModel.ValidationError{Fields: []requestModel.FieldError{
+			{Field: "version", Message: "version is read-only"},
+		}}
+	}
+
+	req := &requestModel.ReqUpdateLink{
+		LinkID:    linkID,
+		Type:      d.Type,
+		LinkName:  d.LinkName,
+		LinkColor: d.LinkColor,
+		LinkPath:  d.LinkPath,
+		Payload:   d.Payload,
+		SectionID: d.SectionID,
+	}
+	if err := req.Normalize(v, opts); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// HandlerPatchLink applies a JSON Merge Patch (RFC 7396) to one link: only
+// the fields present in the patch change, null clears a field.
+func (h *LinkHandler) HandlerPatchLink() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(consts.CtxUserIdKey).(int)
+		linkID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid link id"))
+			return
+		}
+
+		patch, ok := readPatch(w, r, mergePatchType)
+		if !ok {
+			return
+		}
+
+		ifVersion, ok := ifMatchVersion(r, linkTag(linkID))
+		if !ok {
+			h.linkPreconditionFailed(w, userID, linkID)
+			return
+		}
+
+		opts, err := h.urlOptions(userID)
+		if err != nil {
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		for attempt := 1; ; attempt++ {
+			l, err := h.service.Link(linkID)
+			if err != nil || l.UserID != userID {
+				if err == nil || errors.Is(err, store.ErrLinkNotFound) {
+					sendError(w, http.StatusNotFound, store.ErrLinkNotFound)
+					return
+				}
+
+				h.log.Debug("Find Link Return Error:", slog.String("err", err.Error()))
+				sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+				return
+			}
+
+			if ifVersion != 0 && ifVersion != l.Version {
+				h.linkPreconditionFailed(w, userID, linkID)
+				return
+			}
+
+			doc, err := json.Marshal(newLinkDoc(*l))
+			if err != nil {
+				sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+				return
+			}
+
+			patched, err := jsonpatch.MergePatch(doc, patch)
+			if err != nil {
+				sendError(w, http.StatusBadRequest, err)
+				return
+			}
+
+			var d linkDoc
+			if err := decodeDoc(patched, &d); err != nil {
+				sendError(w, http.StatusUnprocessableEntity, fmt.Errorf("patched link is invalid: %v", err))
+				return
+			}
+
+			req, err := d.updateRequest(h.urls, linkID, l.Version, opts)
+			if err != nil {
+				sendValidationError(w, err)
+				return
+			}
+
+			err = h.service.UpdateLink(userID, req, l.Version)
+			if errors.Is(err, store.ErrVersionMismatch) && ifVersion == 0 && attempt < patchAttempts {
+				continue
+			}
+			if err != nil {
+				h.linkWriteFailed(w, userID, linkID, err)
+				return
+			}
+
+			break
+		}
+
+		updated, err := h.service.Link(linkID)
+		if err != nil {
+			h.log.Debug("Find Link Return Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		w.Header().Set("ETag", linkETag(updated))
+		respond(w, http.StatusOK, ownLinkView(updated))
+	}
+}
+
+// HandlerPatchLinks applies a JSON Patch (RFC 6902) to the collection of
+// the user's links, an object keyed by link ID. Removing a link deletes
+// it; links cannot be added this way. All changes land together or none
+// does.
+func (h *LinkHandler) HandlerPatchLinks() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(consts.CtxUserIdKey).(int)
+		patch, ok := readPatch(w, r, jsonPatchType)
+		if !ok {
+			return
+		}
+
+		opts, err := h.urlOptions(userID)
+		if err != nil {
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		for attempt := 1; ; attempt++ {
+			u, err := h.service.UserById(userID)
+			if err != nil {
+				h.log.Debug("Find User Return Error:", slog.String("err", err.Error()))
+				sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+				return
+			}
+
+			current := make(map[string]models.Link, len(u.Links))
+			docs := make(map[string]linkDoc, len(u.Links))
+			for _, l := range u.Links {
+				key := strconv.Itoa(l.ID)
+				current[key] = l
+				docs[key] = newLinkDoc(l)
+			}
+
+			doc, err := json.Marshal(docs)
+			if err != nil {
+				sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+				return
+			}
+
+			patched, err := jsonpatch.Apply(doc, patch)
+			if err != nil {
+				switch {
+				case errors.Is(err, jsonpatch.ErrTestFailed):
+					sendError(w, http.StatusConflict, err)
+				case errors.Is(err, jsonpatch.ErrPathNotFound):
+					sendError(w, http.StatusUnprocessableEntity, err)
+				default:
+					sendError(w, http.StatusBadRequest, err)
+				}
+				return
+			}
+
+			changes, status, err := h.linkChanges(patched, current, opts)
+			if err != nil {
+				if status == http.StatusBadRequest {
+					sendValidationError(w, err)
+				} else {
+					sendError(w, status, err)
+				}
+				return
+			}
+
+			err = h.service.ChangeLinks(userID, changes)
+			if errors.Is(err, store.ErrVersionMismatch) && attempt < patchAttempts {
+				continue
+			}
+			if err != nil {
+				switch {
+				case errors.Is(err, store.ErrVersionMismatch):
+					sendError(w, http.StatusConflict, fmt.Errorf("links changed while the patch was applied, retry"))
+				case errors.Is(err, store.ErrLinkNotFound):
+					sendError(w, http.StatusNotFound, err)
+				case errors.Is(err, store.ErrSectionNotFound):
+					sendValidationError(w, &requestModel.ValidationError{Fields: []requestModel.FieldError{
+						{Field: "section_id", Message: err.Error()},
+					}})
+				default:
+					h.log.Debug("Change Links Return Error:", slog.String("err", err.Error()))
+					sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+				}
+				return
+			}
+
+			break
+		}
+
+		u, err := h.service.UserById(userID)
+		if err != nil {
+			h.log.Debug("Find User Return Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		docs := make(map[string]linkDoc, len(u.Links))
+		for _, l := range u.Links {
+			docs[strconv.Itoa(l.ID)] = newLinkDoc(l)
+		}
+
+		respond(w, http.StatusOK, docs)
+	}
+}
+
+// linkChanges compares the patched collection with the current links and
+// returns the writes that turn one into the other, pinned to the versions
+// the patch was applied to. On error it also returns the status to answer
+// with.
+func (h *LinkHandler) linkChanges(patched []byte, current map[string]models.Link, opts linkurl.Options) ([]models.LinkChange, int, error) {
+	var docs map[string]json.RawMessage
+	if err := json.Unmarshal(patched, &docs); err != nil || docs == nil {
+		return nil, http.StatusUnprocessableEntity, fmt.Errorf("patched document must be an object of links")
+	}
+
+	var changes []models.LinkChange
+	verr := &requestModel.ValidationError{}
+	for key, raw := range docs {
+		l, ok := current[key]
+		if !ok {
+			return nil, http.StatusUnprocessableEntity, fmt.Errorf("link %s does not exist; links are created with POST", key)
+		}
+
+		var d linkDoc
+		if err := decodeDoc(raw, &d); err != nil {
+			return nil, http.StatusUnprocessableEntity, fmt.Errorf("patched link %s is invalid: %v", key, err)
+		}
+
+		before, _ := json.Marshal(newLinkDoc(l))
+		after, _ := json.Marshal(d)
+		if bytes.Equal(before, after) {
+			continue
+		}
+
+		req, err := d.updateRequest(h.urls, l.ID, l.Version, opts)
+		if err != nil {
+			verr.Merge(fmt.Sprintf("[%s]", key), err)
+			continue
+		}
+
+		changes = append(changes, models.LinkChange{
+			Link: models.Link{
+				ID:        l.ID,
+				Type:      req.Type,
+				LinkName:  req.LinkName,
+				LinkColor: req.LinkColor,
+				LinkPath:  req.LinkPath,
+				Payload:   req.Payload,
+				SectionID: req.SectionID,
+			},
+			IfVersion: l.Version,
+		})
+	}
+	if err := verr.OrNil(); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	for key, l := range current {
+		if _, ok := docs[key]; !ok {
+			changes = append(changes, models.LinkChange{Link: l, Delete: true, IfVersion: l.Version})
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b models.LinkChange) int { return a.Link.ID - b.Link.ID })
+
+	return changes, 0, nil
+}
+
+// HandlerPatchProfile applies a JSON Merge Patch (RFC 7396) to the
+// profile's about text and theme. Theme fields set to null fall back to
+// the preset.
+func (h *ProfileHandler) HandlerPatchProfile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(consts.CtxUserIdKey).(int)
+		patch, ok := readPatch(w, r, mergePatchType)
+		if !ok {
+			return
+		}
+
+		ifVersion, ok := ifMatchVersion(r, profileTag(userID))
+		if !ok {
+			h.profilePreconditionFailed(w, userID)
+			return
+		}
+
+		for attempt := 1; ; attempt++ {
+			u, err := h.service.UserById(userID)
+			if err != nil {
+				h.log.Debug("Find User Return Error:", slog.String("err", err.Error()))
+				sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+				return
+			}
+
+			if ifVersion != 0 && ifVersion != u.Version {
+				h.profilePreconditionFailed(w, userID)
+				return
+			}
+
+			theme, err := h.themes.Theme(userID)
+			if err != nil {
+				h.log.Debug("Find Theme Return Error:", slog.String("err", err.Error()))
+				sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+				return
+			}
+
+			cur := profileDoc{About: u.AboutText, Theme: *themeView(theme)}
+			doc, err := json.Marshal(cur)
+			if err != nil {
+				sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+				return
+			}
+
+			patched, err := jsonpatch.MergePatch(doc, patch)
+			if err != nil {
+				sendError(w, http.StatusBadRequest, err)
+				return
+			}
+
+			var d profileDoc
+			if err := decodeDoc(patched, &d); err != nil {
+				sendError(w, http.StatusUnprocessableEntity, fmt.Errorf("patched profile is invalid: %v", err))
+				return
+			}
+
+			var about *string
+			if d.About != cur.About {
+				about = &d.About
+			}
+
+			// The theme is validated before anything is written, so an
+			// invalid theme never reaches the store.
+			var t *models.Theme
+			if d.Theme != cur.Theme {
+				t, err = h.themes.ResolveTheme(userID, requestModel.ReqTheme{
+					Preset:      d.Theme.Preset,
+					Background:  d.Theme.Background,
+					Font:        d.Theme.Font,
+					ButtonStyle: d.Theme.ButtonStyle,
+					LinkColor:   d.Theme.LinkColor,
+				})
+				if err != nil {
+					sendValidationError(w, err)
+					return
+				}
+			}
+
+			if about == nil && t == nil {
+				break
+			}
+
+			// Both writes land in one unit pinned to the version the patch
+			// was applied to, so a concurrent change to either fails it.
+			err = h.service.UpdateProfile(r.Context(), userID, about, t, u.Version)
+			if errors.Is(err, store.ErrVersionMismatch) && ifVersion == 0 && attempt < patchAttempts {
+				continue
+			}
+			if err != nil {
+				if errors.Is(err, store.ErrVersionMismatch) {
+					h.profilePreconditionFailed(w, userID)
+					return
+				}
+				h.log.Debug("DataBase Error:", slog.String("err", err.Error()))
+				sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+				return
+			}
+
+			break
+		}
+
+		u, err := h.service.UserById(userID)
+		if err != nil {
+			h.log.Debug("Find User Return Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		health, err := h.linkHealth(userID)
+		if err != nil {
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		uv, err := h.ownerProfile(u, health)
+		if err != nil {
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		w.Header().Set("ETag", ownerETag(u, health))
+		respond(w, http.StatusOK, uv)
+	}
+}
@@ -7,34 +7,70 @@ import (
 	"log/slog"
 	"net/http"
 	"url_profile/internal/app/server/http/constants"
+	"url_profile/internal/app/server/http/handlers/requestModel"
 	"url_profile/internal/app/server/http/handlers/viewModel"
 	"url_profile/internal/domain/models"
+	"url_profile/internal/lib/linkblock"
+	"url_profile/internal/services/events"
 	"url_profile/internal/store"
 
 	"github.com/gorilla/mux"
 )
 
+type LinkHealthProvider interface {
+	Health(userID int) (map[int]models.LinkHealth, error)
+}
+
 type ProfileHandler struct {
-	log     *slog.Logger
-	service UserService
+	log      *slog.Logger
+	service  UserService
+	events   EventPublisher
+	health   LinkHealthProvider
+	previews LinkPreviewer
+	themes   ThemeService
+	media    MediaService
+	sections SectionService
 }
 
-func NewProfileHandlers(log *slog.Logger, service UserService) *ProfileHandler {
+func NewProfileHandlers(log *slog.Logger, service UserService, events EventPublisher, health LinkHealthProvider, previews LinkPreviewer, themes ThemeService, media MediaService, sections SectionService) *ProfileHandler {
 	return &ProfileHandler{
-		log:     log,
-		service: service,
+		log:      log,
+		service:  service,
+		events:   events,
+		health:   health,
+		previews: previews,
+		themes:   themes,
+		media:    media,
+		sections: sections,
 	}
 }
 
+type ownerLink struct {
+	models.Link
+	Render  string                     `json:"render"`
+	ETag    string                     `json:"etag"`
+	Health  *viewModel.LinkHealthView  `json:"health"`
+	Preview *viewModel.LinkPreviewView `json:"preview,omitempty"`
+}
+
+type ownerSection struct {
+	viewModel.SectionView
+	Links []ownerLink `json:"links"`
+}
+
+type ownerProfileView struct {
+	Email     string               `json:"email"`
+	Username  string               `json:"username"`
+	AboutText string               `json:"about"`
+	Avatar    map[string]string    `json:"avatar,omitempty"`
+	Banner    map[string]string    `json:"banner,omitempty"`
+	Theme     *viewModel.ThemeView `json:"theme"`
+	Links     []ownerLink          `json:"links"`
+	Sections  []ownerSection       `json:"sections"`
+}
+
 func (h *ProfileHandler) HandlerMyProfile() http.HandlerFunc {
-	type UserView struct {
-		Email     string        `json:"email"`
-		Username  string        `json:"username"`
-		AboutText string        `json:"about"`
-		Links     []models.Link `json:"links"`
-	}
 	return func(w http.ResponseWriter, r *http.Request) {
-		uv := &UserView{}
 		h.log.Debug("UserID in Context: ", slog.Int("ctxID", r.Context().Value(consts.CtxUserIdKey).(int)))
 		u, err := h.service.UserById(r.Context().Value(consts.CtxUserIdKey).(int))
 		if err != nil {
@@ -51,15 +87,101 @@ func (h *ProfileHandler) HandlerMyProfile() http.HandlerFunc {
 
 		h.log.Debug("User", slog.Any("data", u))
 
-		uv.Email = u.Email
-		uv.Username = u.Username
-		uv.AboutText = u.AboutText
-		uv.Links = u.Links
+		health, err := h.linkHealth(u.ID)
+		if err != nil {
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		w.Header().Add("Vary", "Authorization")
+		if notModified(w, r, ownerETag(u, health), ownerModified(u, health)) {
+			return
+		}
+
+		uv, err := h.ownerProfile(u, health)
+		if err != nil {
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
 
 		respond(w, http.StatusOK, uv)
 	}
 }
 
+func (h *ProfileHandler) linkHealth(userID int) (map[int]models.LinkHealth, error) {
+	health, err := h.health.Health(userID)
+	if err != nil {
+		h.log.Debug("Link Health Return Error:", slog.String("err", err.Error()))
+		return nil, err
+	}
+	return health, nil
+}
+
+// ownerProfile builds the profile as its owner sees it: with email, hidden
+// links, link health and the ETags that writes can pin.
+func (h *ProfileHandler) ownerProfile(u *models.User, health map[int]models.LinkHealth) (*ownerProfileView, error) {
+	previews, err := h.previews.Previews(u.ID)
+	if err != nil {
+		h.log.Debug("Link Previews Return Error:", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	theme, err := h.themes.Theme(u.ID)
+	if err != nil {
+		h.log.Debug("Find Theme Return Error:", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	images, err := h.media.ImageURLs(u.ID)
+	if err != nil {
+		h.log.Debug("Find Images Return Error:", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	sections, err := h.sections.Sections(u.ID)
+	if err != nil {
+		h.log.Debug("Find Sections Return Error:", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	uv := &ownerProfileView{
+		Email:     u.Email,
+		Username:  u.Username,
+		AboutText: u.AboutText,
+		Avatar:    images[models.ImageAvatar],
+		Banner:    images[models.ImageBanner],
+		Theme:     themeView(theme),
+	}
+	ungrouped, grouped := groupLinks(sections, u.Links, func(l models.Link) ownerLink {
+		ol := ownerLink{Link: l, Render: linkblock.Render(l.Type, l.Payload), ETag: linkETag(&l)}
+		if lh, ok := health[l.ID]; ok {
+			ol.Health = &viewModel.LinkHealthView{
+				Status:     lh.Status,
+				StatusCode: lh.StatusCode,
+				Error:      lh.Error,
+				CheckedAt:  lh.CheckedAt,
+				FailStreak: lh.FailStreak,
+			}
+		}
+		if p, ok := previews[l.LinkPath]; ok {
+			ol.Preview = previewView(p)
+		}
+		return ol
+	})
+
+	uv.Links = ungrouped
+	uv.Sections = make([]ownerSection, 0, len(sections))
+	for _, sec := range sections {
+		links := grouped[sec.ID]
+		if links == nil {
+			links = []ownerLink{}
+		}
+		uv.Sections = append(uv.Sections, ownerSection{SectionView: sectionView(sec), Links: links})
+	}
+
+	return uv, nil
+}
+
 func (h *ProfileHandler) HandlerGetProfile() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
@@ -83,31 +205,111 @@ func (h *ProfileHandler) HandlerGetProfile() http.HandlerFunc {
 			return
 		}
 
-		links := make([]viewModel.LinkView, 0, len(u.Links))
-		for _, l := range u.Links {
-			links = append(links, viewModel.LinkView{
+		// A view answered from the client's cache is still a view.
+		h.events.Publish(u.ID, events.TypeView, map[string]string{
+			"username": u.Username,
+			"referer":  r.Referer(),
+		})
+
+		if notModified(w, r, profileETag(u), u.UpdatedAt) {
+			return
+		}
+
+		previews, err := h.previews.Previews(u.ID)
+		if err != nil {
+			h.log.Debug("Link Previews Return Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		theme, err := h.themes.Theme(u.ID)
+		if err != nil {
+			h.log.Debug("Find Theme Return Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		images, err := h.media.ImageURLs(u.ID)
+		if err != nil {
+			h.log.Debug("Find Images Return Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		sections, err := h.sections.Sections(u.ID)
+		if err != nil {
+			h.log.Debug("Find Sections Return Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		links, grouped := groupLinks(sections, u.Links, func(l models.Link) viewModel.LinkView {
+			lv := viewModel.LinkView{
+				ID:        l.ID,
+				Type:      l.Type,
+				Render:    linkblock.Render(l.Type, l.Payload),
+				Payload:   l.Payload,
 				LinkName:  l.LinkName,
 				LinkColor: l.LinkColor,
 				LinkPath:  l.LinkPath,
-			})
+			}
+			if p, ok := previews[l.LinkPath]; ok {
+				lv.Preview = previewView(p)
+			}
+			return lv
+		})
+
+		sectionViews := make([]viewModel.ProfileSectionView, 0, len(sections))
+		for _, sec := range sections {
+			links := grouped[sec.ID]
+			if links == nil {
+				links = []viewModel.LinkView{}
+			}
+			sectionViews = append(sectionViews, viewModel.ProfileSectionView{SectionView: sectionView(sec), Links: links})
 		}
 
 		uv := &viewModel.UserView{
 			Username:  u.Username,
 			AboutText: u.AboutText,
+			Avatar:    images[models.ImageAvatar],
+			Banner:    images[models.ImageBanner],
+			Theme:     themeView(theme),
 			Links:     links,
+			Sections:  sectionViews,
 		}
 
 		respond(w, http.StatusOK, uv)
 	}
 }
 
+// groupLinks splits links into the ungrouped list and per-section lists.
+// Links pointing at an unknown section are treated as ungrouped.
+func groupLinks[T any](sections []models.LinkSection, links []models.Link, view func(models.Link) T) ([]T, map[int][]T) {
+	known := make(map[int]bool, len(sections))
+	for _, s := range sections {
+		known[s.ID] = true
+	}
+
+	ungrouped := make([]T, 0, len(links))
+	grouped := make(map[int][]T, len(sections))
+	for _, l := range links {
+		if l.SectionID != nil && known[*l.SectionID] {
+			grouped[*l.SectionID] = append(grouped[*l.SectionID], view(l))
+			continue
+		}
+		ungrouped = append(ungrouped, view(l))
+	}
+
+	return ungrouped, grouped
+}
+
 func (h *ProfileHandler) HandlerUpdateAboutMe() http.HandlerFunc {
 	type ReqText struct {
 		Text string `json:"text"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(consts.CtxUserIdKey).(int)
 		text := &ReqText{}
 		if err := json.NewDecoder(r.Body).Decode(text); err != nil {
 			h.log.Debug("DECODE ERROR:", slog.String("err", err.Error()))
@@ -115,16 +317,102 @@ func (h *ProfileHandler) HandlerUpdateAboutMe() http.HandlerFunc {
 			return
 		}
 
-		if err := h.service.UpdateAboutMe(r.Context().Value(consts.CtxUserIdKey).(int), text.Text); err != nil {
+		ifVersion, ok := ifMatchVersion(r, profileTag(userID))
+		if !ok {
+			h.profilePreconditionFailed(w, userID)
+			return
+		}
+
+		if err := h.service.UpdateAboutMe(userID, text.Text, ifVersion); err != nil {
+			if errors.Is(err, store.ErrVersionMismatch) {
+				h.profilePreconditionFailed(w, userID)
+				return
+			}
 			if errors.Is(err, store.ErrNoRowsAffected) {
 				h.log.Debug("DataBase Error:", slog.String("err", err.Error()))
 				sendError(w, http.StatusConflict, err)
+				return
 			}
 			h.log.Debug("DataBase Error:", slog.String("err", err.Error()))
 			sendError(w, http.StatusInternalServerError, err)
 			return
 		}
 
+		if u, err := h.service.UserById(userID); err == nil {
+			if health, err := h.linkHealth(userID); err == nil {
+				w.Header().Set("ETag", ownerETag(u, health))
+			}
+		}
+
 		respond(w, http.StatusOK, nil)
 	}
 }
+
+// profilePreconditionFailed answers a stale If-Match with 412 and the
+// profile as it is now.
+func (h *ProfileHandler) profilePreconditionFailed(w http.ResponseWriter, userID int) {
+	u, err := h.service.UserById(userID)
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("user not found"))
+			return
+		}
+
+		h.log.Debug("Find User Return Error:", slog.String("err", err.Error()))
+		sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+		return
+	}
+
+	health, err := h.linkHealth(userID)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+		return
+	}
+
+	uv, err := h.ownerProfile(u, health)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+		return
+	}
+
+	w.Header().Set("ETag", ownerETag(u, health))
+	respond(w, http.StatusPreconditionFailed, uv)
+}
+
+func (h *ProfileHandler) HandlerGetSettings() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		settings, err := h.service.Settings(r.Context().Value(consts.CtxUserIdKey).(int))
+		if err != nil {
+			if errors.Is(err, store.ErrUserNotFound) {
+				sendError(w, http.StatusBadRequest, fmt.Errorf("user not found"))
+				return
+			}
+
+			h.log.Debug("Find Settings Return Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		respond(w, http.StatusOK, viewModel.SettingsView{StripTracking: settings.StripTracking})
+	}
+}
+
+func (h *ProfileHandler) HandlerUpdateSettings() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &requestModel.ReqSettings{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			h.log.Debug("DECODE ERROR:", slog.String("err", err.Error()))
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid input data"))
+			return
+		}
+
+		settings := models.Settings{StripTracking: req.StripTracking}
+		if err := h.service.UpdateSettings(r.Context().Value(consts.CtxUserIdKey).(int), settings); err != nil {
+			h.log.Debug("DataBase Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		respond(w, http.StatusOK, viewModel.SettingsView{StripTracking: settings.StripTracking})
+	}
+}
@@ -1,21 +1,32 @@
 package requestModel
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
+	"strings"
 )
 
+// ReqLink is any profile block. Type defaults to "link"; other types carry
+// their data in Payload and get LinkPath derived from it.
 type ReqLink struct {
-	LinkName  string `json:"link_name"`
-	LinkColor string `json:"link_color"`
-	LinkPath  string `json:"link_path"`
+	Type      string          `json:"type"`
+	LinkName  string          `json:"link_name"`
+	LinkColor string          `json:"link_color"`
+	LinkPath  string          `json:"link_path"`
+	Payload   json.RawMessage `json:"payload"`
+	SectionID *int            `json:"section_id"`
+	Hidden    bool            `json:"hidden"`
 }
 
 type ReqUpdateLink struct {
-	LinkID    int    `json:"link_id"`
-	LinkName  string `json:"link_name"`
-	LinkColor string `json:"link_color"`
-	LinkPath  string `json:"link_path"`
+	LinkID    int             `json:"link_id"`
+	Type      string          `json:"type"`
+	LinkName  string          `json:"link_name"`
+	LinkColor string          `json:"link_color"`
+	LinkPath  string          `json:"link_path"`
+	Payload   json.RawMessage `json:"payload"`
+	SectionID *int            `json:"section_id"`
 }
 
 type SignUpModel struct {
@@ -26,6 +37,30 @@ type SignUpModel struct {
 	Links    []ReqLink `json:"links"`
 }
 
+type ReqSettings struct {
+	StripTracking bool `json:"strip_tracking"`
+}
+
+type ReqTheme struct {
+	Preset      string `json:"preset"`
+	Background  string `json:"background"`
+	Font        string `json:"font"`
+	ButtonStyle string `json:"button_style"`
+	LinkColor   string `json:"link_color"`
+}
+
+// ReqSection is used for both create and update. On update, omitted fields
+// keep their current value.
+type ReqSection struct {
+	Title     *string `json:"title"`
+	Position  *int    `json:"position"`
+	Collapsed *bool   `json:"collapsed"`
+}
+
+type ReqLinkVisibility struct {
+	Hidden bool `json:"hidden"`
+}
+
 type LoginModel struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
@@ -40,6 +75,10 @@ func (sm *SignUpModel) Validate() error {
 		return fmt.Errorf("invalid login")
 	}
 
+	if reservedUsernames[strings.ToLower(sm.Username)] {
+		return fmt.Errorf("login %q is reserved", sm.Username)
+	}
+
 	if ok := isValidPassword(sm.Password); !ok {
 		return fmt.Errorf("invalid password")
 	}
@@ -53,6 +92,21 @@ func isValidEmail(email string) bool {
 	return match
 }
 
+// reservedUsernames are the fixed paths under /api/profile. A public
+// profile with one of these names would be shadowed by the owner routes.
+var reservedUsernames = map[string]bool{
+	"about":    true,
+	"avatar":   true,
+	"banner":   true,
+	"events":   true,
+	"link":     true,
+	"links":    true,
+	"sections": true,
+	"settings": true,
+	"theme":    true,
+	"trash":    true,
+}
+
 func isValidUsername(username string) bool {
 	matched, _ := regexp.MatchString(`^[a-zA-Z0-9_]{3,20}$`, username)
 	return matched
@@ -0,0 +1,131 @@
+package requestModel
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"url_profile/internal/lib/color"
+	"url_profile/internal/lib/linkblock"
+	"url_profile/internal/lib/linkurl"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Add(field string, err error) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: err.Error()})
+}
+
+// Merge appends the fields of another validation error under prefix, e.g.
+// "links[2]" turns "link_path" into "links[2].link_path".
+func (e *ValidationError) Merge(prefix string, err error) {
+	var other *ValidationError
+	if !errors.As(err, &other) {
+		e.Add(prefix, err)
+		return
+	}
+
+	for _, f := range other.Fields {
+		e.Fields = append(e.Fields, FieldError{Field: prefix + "." + f.Field, Message: f.Message})
+	}
+}
+
+func (e *ValidationError) OrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Normalize validates the block for its type and rewrites path and payload
+// to their canonical form.
+func (l *ReqLink) Normalize(v *linkurl.Validator, opts linkurl.Options) error {
+	verr := &ValidationError{}
+
+	b, err := linkblock.Normalize(linkblock.Block{
+		Type:    l.Type,
+		Name:    l.LinkName,
+		Path:    l.LinkPath,
+		Payload: l.Payload,
+	}, v, opts)
+	if err != nil {
+		verr.addBlock(err)
+	}
+	l.Type, l.LinkName, l.LinkPath, l.Payload = b.Type, b.Name, b.Path, b.Payload
+
+	if c, err := normalizeColor(l.LinkColor); err != nil {
+		verr.Add("link_color", err)
+	} else {
+		l.LinkColor = c
+	}
+
+	return verr.OrNil()
+}
+
+func (l *ReqUpdateLink) Normalize(v *linkurl.Validator, opts linkurl.Options) error {
+	verr := &ValidationError{}
+
+	b, err := linkblock.Normalize(linkblock.Block{
+		Type:    l.Type,
+		Name:    l.LinkName,
+		Path:    l.LinkPath,
+		Payload: l.Payload,
+	}, v, opts)
+	if err != nil {
+		verr.addBlock(err)
+	}
+	l.Type, l.LinkName, l.LinkPath, l.Payload = b.Type, b.Name, b.Path, b.Payload
+
+	if l.LinkName == "" && l.Type == linkblock.TypeLink {
+		verr.Add("link_name", fmt.Errorf("link_name is required"))
+	}
+
+	if c, err := normalizeColor(l.LinkColor); err != nil {
+		verr.Add("link_color", err)
+	} else {
+		l.LinkColor = c
+	}
+
+	return verr.OrNil()
+}
+
+// addBlock unpacks the (possibly joined) field errors of a block.
+func (e *ValidationError) addBlock(err error) {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			e.addBlock(err)
+		}
+		return
+	}
+
+	var ferr *linkblock.FieldError
+	if errors.As(err, &ferr) {
+		e.Add(ferr.Field, ferr.Err)
+		return
+	}
+
+	e.Add("payload", err)
+}
+
+// normalizeColor accepts an empty color, which means "use the theme default".
+func normalizeColor(c string) (string, error) {
+	if strings.TrimSpace(c) == "" {
+		return "", nil
+	}
+
+	return color.Normalize(c)
+}
@@ -2,13 +2,51 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/store"
 )
 
 func sendError(w http.ResponseWriter, code int, err error) {
 	respond(w, code, map[string]string{"error": err.Error()})
 }
 
+// sendValidationError reports field-level problems as 400 with a "fields" list.
+func sendValidationError(w http.ResponseWriter, err error) {
+	var verr *requestModel.ValidationError
+	if !errors.As(err, &verr) {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	respond(w, http.StatusBadRequest, map[string]any{
+		"error":  "validation failed",
+		"fields": verr.Fields,
+	})
+}
+
+// sendConflict reports a unique constraint violation as 409. When the store
+// knows the offending column it is listed in "fields"; fields renames
+// columns whose request field is called differently.
+func sendConflict(w http.ResponseWriter, err error, fields map[string]string) {
+	var conflict *store.ConflictError
+	if !errors.As(err, &conflict) || conflict.Column == "" {
+		sendError(w, http.StatusConflict, err)
+		return
+	}
+
+	field := conflict.Column
+	if f, ok := fields[field]; ok {
+		field = f
+	}
+
+	respond(w, http.StatusConflict, map[string]any{
+		"error":  conflict.Err.Error(),
+		"fields": []requestModel.FieldError{{Field: field, Message: conflict.Err.Error()}},
+	})
+}
+
 func respond(w http.ResponseWriter, code int, data interface{}) {
 	if data != nil {
 		w.Header().Add("Content-Type", "application/json")
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"url_profile/internal/app/server/http/constants"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/app/server/http/handlers/viewModel"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+
+	"github.com/gorilla/mux"
+)
+
+type SectionService interface {
+	Sections(userID int) ([]models.LinkSection, error)
+	Create(userID int, req requestModel.ReqSection) (*models.LinkSection, error)
+	Update(userID int, sectionID int, req requestModel.ReqSection) (*models.LinkSection, error)
+	Delete(userID int, sectionID int) error
+}
+
+type SectionHandler struct {
+	log     *slog.Logger
+	service SectionService
+}
+
+func NewSectionHandlers(log *slog.Logger, service SectionService) *SectionHandler {
+	return &SectionHandler{
+		log:     log,
+		service: service,
+	}
+}
+
+func (h *SectionHandler) HandlerList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sections, err := h.service.Sections(r.Context().Value(consts.CtxUserIdKey).(int))
+		if err != nil {
+			h.log.Debug("Find Sections Return Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		res := make([]viewModel.SectionView, 0, len(sections))
+		for _, s := range sections {
+			res = append(res, sectionView(s))
+		}
+
+		respond(w, http.StatusOK, res)
+	}
+}
+
+func (h *SectionHandler) HandlerCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := requestModel.ReqSection{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.log.Debug("DECODE ERROR:", slog.String("err", err.Error()))
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid input data"))
+			return
+		}
+
+		sec, err := h.service.Create(r.Context().Value(consts.CtxUserIdKey).(int), req)
+		if err != nil {
+			h.sendServiceError(w, err)
+			return
+		}
+
+		respond(w, http.StatusCreated, sectionView(*sec))
+	}
+}
+
+func (h *SectionHandler) HandlerUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sectionID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid section id"))
+			return
+		}
+
+		req := requestModel.ReqSection{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.log.Debug("DECODE ERROR:", slog.String("err", err.Error()))
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid input data"))
+			return
+		}
+
+		sec, err := h.service.Update(r.Context().Value(consts.CtxUserIdKey).(int), sectionID, req)
+		if err != nil {
+			h.sendServiceError(w, err)
+			return
+		}
+
+		respond(w, http.StatusOK, sectionView(*sec))
+	}
+}
+
+func (h *SectionHandler) HandlerDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sectionID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid section id"))
+			return
+		}
+
+		if err := h.service.Delete(r.Context().Value(consts.CtxUserIdKey).(int), sectionID); err != nil {
+			h.sendServiceError(w, err)
+			return
+		}
+
+		respond(w, http.StatusOK, nil)
+	}
+}
+
+func (h *SectionHandler) sendServiceError(w http.ResponseWriter, err error) {
+	var verr *requestModel.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		sendValidationError(w, err)
+	case errors.Is(err, store.ErrSectionNotFound):
+		sendError(w, http.StatusNotFound, err)
+	default:
+		h.log.Debug("DataBase Error:", slog.String("err", err.Error()))
+		sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+	}
+}
+
+func sectionView(s models.LinkSection) viewModel.SectionView {
+	return viewModel.SectionView{
+		ID:        s.ID,
+		Title:     s.Title,
+		Position:  s.Position,
+		Collapsed: s.Collapsed,
+	}
+}
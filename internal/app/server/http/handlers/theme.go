@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"url_profile/internal/app/server/http/constants"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/app/server/http/handlers/viewModel"
+	"url_profile/internal/domain/models"
+)
+
+type ThemeService interface {
+	Presets() []models.Theme
+	Theme(userID int) (*models.Theme, error)
+	UpdateTheme(userID int, req requestModel.ReqTheme) (*models.Theme, error)
+	ResolveTheme(userID int, req requestModel.ReqTheme) (*models.Theme, error)
+}
+
+type ThemeHandler struct {
+	log     *slog.Logger
+	service ThemeService
+}
+
+func NewThemeHandlers(log *slog.Logger, service ThemeService) *ThemeHandler {
+	return &ThemeHandler{
+		log:     log,
+		service: service,
+	}
+}
+
+func (h *ThemeHandler) HandlerPresets() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		presets := h.service.Presets()
+		res := make([]*viewModel.ThemeView, 0, len(presets))
+		for _, p := range presets {
+			res = append(res, themeView(&p))
+		}
+
+		respond(w, http.StatusOK, res)
+	}
+}
+
+func (h *ThemeHandler) HandlerGetTheme() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t, err := h.service.Theme(r.Context().Value(consts.CtxUserIdKey).(int))
+		if err != nil {
+			h.log.Debug("Find Theme Return Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		respond(w, http.StatusOK, themeView(t))
+	}
+}
+
+func (h *ThemeHandler) HandlerUpdateTheme() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := requestModel.ReqTheme{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.log.Debug("DECODE ERROR:", slog.String("err", err.Error()))
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid input data"))
+			return
+		}
+
+		t, err := h.service.UpdateTheme(r.Context().Value(consts.CtxUserIdKey).(int), req)
+		if err != nil {
+			var verr *requestModel.ValidationError
+			if errors.As(err, &verr) {
+				sendValidationError(w, err)
+				return
+			}
+
+			h.log.Debug("DataBase Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		respond(w, http.StatusOK, themeView(t))
+	}
+}
+
+func themeView(t *models.Theme) *viewModel.ThemeView {
+	return &viewModel.ThemeView{
+		Preset:      t.Preset,
+		Background:  t.Background,
+		Font:        t.Font,
+		ButtonStyle: t.ButtonStyle,
+		LinkColor:   t.LinkColor,
+	}
+}
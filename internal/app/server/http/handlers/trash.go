@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"url_profile/internal/app/server/http/constants"
+	"url_profile/internal/app/server/http/handlers/viewModel"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/lib/linkblock"
+	"url_profile/internal/services/trash"
+	"url_profile/internal/store"
+
+	"github.com/gorilla/mux"
+)
+
+type TrashService interface {
+	Retention() time.Duration
+	Trash(userID int) ([]models.Link, error)
+	Restore(userID int, linkID int) error
+	Purge(userID int, linkID int) error
+}
+
+type TrashHandler struct {
+	log     *slog.Logger
+	service TrashService
+}
+
+func NewTrashHandlers(log *slog.Logger, service TrashService) *TrashHandler {
+	return &TrashHandler{
+		log:     log,
+		service: service,
+	}
+}
+
+func (h *TrashHandler) HandlerList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		links, err := h.service.Trash(r.Context().Value(consts.CtxUserIdKey).(int))
+		if err != nil {
+			h.log.Debug("Find Trash Return Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		res := make([]viewModel.TrashedLinkView, 0, len(links))
+		for _, l := range links {
+			res = append(res, viewModel.TrashedLinkView{
+				LinkView: viewModel.LinkView{
+					ID:        l.ID,
+					Type:      l.Type,
+					Render:    linkblock.Render(l.Type, l.Payload),
+					LinkName:  l.LinkName,
+					LinkColor: l.LinkColor,
+					LinkPath:  l.LinkPath,
+					Payload:   l.Payload,
+				},
+				DeletedAt: *l.DeletedAt,
+				PurgeAt:   l.DeletedAt.Add(h.service.Retention()),
+			})
+		}
+
+		respond(w, http.StatusOK, res)
+	}
+}
+
+func (h *TrashHandler) HandlerRestore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		linkID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid link id"))
+			return
+		}
+
+		if err := h.service.Restore(r.Context().Value(consts.CtxUserIdKey).(int), linkID); err != nil {
+			switch {
+			case errors.Is(err, trash.ErrExpired):
+				sendError(w, http.StatusGone, err)
+			case errors.Is(err, store.ErrLinkNotFound):
+				sendError(w, http.StatusNotFound, err)
+			default:
+				h.log.Debug("Restore Link Return Error:", slog.String("err", err.Error()))
+				sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			}
+			return
+		}
+
+		respond(w, http.StatusOK, nil)
+	}
+}
+
+func (h *TrashHandler) HandlerPurge() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		linkID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid link id"))
+			return
+		}
+
+		if err := h.service.Purge(r.Context().Value(consts.CtxUserIdKey).(int), linkID); err != nil {
+			if errors.Is(err, store.ErrLinkNotFound) {
+				sendError(w, http.StatusNotFound, err)
+				return
+			}
+
+			h.log.Debug("Purge Link Return Error:", slog.String("err", err.Error()))
+			sendError(w, http.StatusInternalServerError, fmt.Errorf("server internal error"))
+			return
+		}
+
+		respond(w, http.StatusOK, nil)
+	}
+}
@@ -1,13 +1,132 @@
 package viewModel
 
+import (
+	"encoding/json"
+	"time"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+)
+
 type LinkView struct {
-	LinkName  string `json:"link_name"`
-	LinkColor string `json:"link_color"`
-	LinkPath  string `json:"link_path"`
+	ID   int    `json:"id"`
+	Type string `json:"type"`
+	// Render tells the client how to draw the block: button, icon_row,
+	// embed, heading, text or download.
+	Render    string           `json:"render"`
+	LinkName  string           `json:"link_name"`
+	LinkColor string           `json:"link_color"`
+	LinkPath  string           `json:"link_path"`
+	Payload   json.RawMessage  `json:"payload,omitempty"`
+	Preview   *LinkPreviewView `json:"preview,omitempty"`
+}
+
+// OwnLinkView is a link as its owner edits it. ETag goes into If-Match to
+// make a write conditional on this version.
+type OwnLinkView struct {
+	LinkView
+	SectionID *int   `json:"section_id"`
+	Hidden    bool   `json:"hidden"`
+	Version   int    `json:"version"`
+	ETag      string `json:"etag"`
 }
 
 type UserView struct {
-	Username  string     `json:"username"`
-	AboutText string     `json:"about"`
-	Links     []LinkView `json:"links"`
+	Username  string            `json:"username"`
+	AboutText string            `json:"about"`
+	Avatar    map[string]string `json:"avatar,omitempty"`
+	Banner    map[string]string `json:"banner,omitempty"`
+	Theme     *ThemeView        `json:"theme"`
+	// Links holds only ungrouped links; grouped ones are nested in Sections.
+	Links    []LinkView           `json:"links"`
+	Sections []ProfileSectionView `json:"sections"`
+}
+
+type TrashedLinkView struct {
+	LinkView
+	DeletedAt time.Time `json:"deleted_at"`
+	// PurgeAt is when the link stops being restorable.
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+type SectionView struct {
+	ID        int    `json:"id"`
+	Title     string `json:"title"`
+	Position  int    `json:"position"`
+	Collapsed bool   `json:"collapsed"`
+}
+
+// ProfileSectionView is a section with its links as the public profile
+// shows it; empty sections carry an empty list, like the owner's view.
+type ProfileSectionView struct {
+	SectionView
+	Links []LinkView `json:"links"`
+}
+
+type ThemeView struct {
+	Preset      string `json:"preset"`
+	Background  string `json:"background"`
+	Font        string `json:"font"`
+	ButtonStyle string `json:"button_style"`
+	LinkColor   string `json:"link_color"`
+}
+
+type LinkHealthView struct {
+	Status     string    `json:"status"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	CheckedAt  time.Time `json:"checked_at"`
+	FailStreak int       `json:"fail_streak"`
+}
+
+type LinkPreviewView struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Favicon     string `json:"favicon"`
+	Image       string `json:"image"`
+}
+
+type SettingsView struct {
+	StripTracking bool `json:"strip_tracking"`
+}
+
+type ImportedLinkView struct {
+	Type      string          `json:"type"`
+	LinkName  string          `json:"link_name"`
+	LinkColor string          `json:"link_color"`
+	LinkPath  string          `json:"link_path"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	Hidden    bool            `json:"hidden"`
+	Section   string          `json:"section,omitempty"`
+}
+
+// ImportReportView is the dry-run answer: what would be imported and which
+// rows need fixing first.
+type ImportReportView struct {
+	DryRun bool                      `json:"dry_run"`
+	Total  int                       `json:"total"`
+	Valid  int                       `json:"valid"`
+	Errors []requestModel.FieldError `json:"errors"`
+	Links  []ImportedLinkView        `json:"links"`
+}
+
+type ImportResultView struct {
+	Imported        int `json:"imported"`
+	SectionsCreated int `json:"sections_created"`
+}
+
+type SnapshotView struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DownloadView is a temporary link to a file in blob storage.
+type DownloadView struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type CacheStatsView struct {
+	Enabled bool  `json:"enabled"`
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
 }
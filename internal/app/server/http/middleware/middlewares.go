@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
 	jwt_go "github.com/golang-jwt/jwt/v5"
@@ -13,6 +14,7 @@ import (
 	"url_profile/internal/lib/jwt"
 
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 )
 
 func SetRequestID(next http.Handler) http.Handler {
@@ -87,3 +89,60 @@ func AuthMiddleware(log *slog.Logger, secret string) func(http.Handler) http.Han
 		})
 	}
 }
+
+// AdminMiddleware lets through requests that carry "Bearer <token>". With
+// an empty token the admin API is switched off and answers 404.
+func AdminMiddleware(log *slog.Logger, token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				http.NotFound(w, r)
+				return
+			}
+
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				log.Warn("admin request rejected", slog.String("remote_addr", r.RemoteAddr))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CacheControl sends the Cache-Control header configured for the matched
+// route, looked up by its mux name. Handlers that set their own keep it,
+// and error responses never carry one so a CDN does not cache them.
+func CacheControl(policies map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := mux.CurrentRoute(r)
+			if route == nil || policies[route.GetName()] == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(&cacheControlWriter{ResponseWriter: w, value: policies[route.GetName()]}, r)
+		})
+	}
+}
+
+// Deprecated marks responses of a route kept only for old clients: it sends
+// "Deprecation: true" and a Link to the route that replaces it. Path
+// variables such as {id} in successor are filled in from the request.
+func Deprecated(successor string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			link := successor
+			for k, v := range mux.Vars(r) {
+				link = strings.ReplaceAll(link, "{"+k+"}", v)
+			}
+
+			w.Header().Set("Deprecation", "true")
+			w.Header().Add("Link", "<"+link+">; rel=\"successor-version\"")
+			next.ServeHTTP(w, r)
+		})
+	}
+}
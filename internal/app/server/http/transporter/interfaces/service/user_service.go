@@ -1,6 +1,7 @@
 package serviceinterface
 
 import (
+	"context"
 	"url_profile/internal/app/server/http/handlers/requestModel"
 	"url_profile/internal/domain/models"
 )
@@ -10,8 +11,14 @@ type UserService interface {
 	User(email string) (*models.User, error)
 	UserById(id int) (*models.User, error)
 	UserByUsername(name string) (*models.User, error)
-	UpdateAboutMe(id int, text string) error
-	AddLink(userID int, link requestModel.ReqLink) error
-	UpdateLink(userID int, link *requestModel.ReqUpdateLink) error
-	DeleteLink(userID int, linkID int) error
+	UpdateAboutMe(id int, text string, ifVersion int) error
+	UpdateProfile(ctx context.Context, id int, about *string, theme *models.Theme, ifVersion int) error
+	Settings(id int) (*models.Settings, error)
+	UpdateSettings(id int, settings models.Settings) error
+	Link(linkID int) (*models.Link, error)
+	AddLink(userID int, link requestModel.ReqLink) (int, error)
+	AddLinks(ctx context.Context, userID int, links []requestModel.ReqLink) ([]int, error)
+	UpdateLink(userID int, link *requestModel.ReqUpdateLink, ifVersion int) error
+	DeleteLink(userID int, linkID int, ifVersion int) error
+	ChangeLinks(userID int, changes []models.LinkChange) error
 }
@@ -13,31 +13,91 @@ func New(
 	authHandler *handler.AuthHandlers,
 	profileHandler *handler.ProfileHandler,
 	linkHandler *handler.LinkHandler,
+	eventsHandler *handler.EventsHandler,
+	themeHandler *handler.ThemeHandler,
+	mediaHandler *handler.MediaHandler,
+	filesHandler *handler.FilesHandler,
+	sectionHandler *handler.SectionHandler,
+	trashHandler *handler.TrashHandler,
+	linkIOHandler *handler.LinkIOHandler,
+	adminHandler *handler.AdminHandler,
 	log *slog.Logger,
-	secret string) *mux.Router {
+	secret string,
+	adminToken string,
+	cacheControl map[string]string) *mux.Router {
 
 	r := mux.NewRouter()
 
 	r.Use(middleware.SetRequestID)
 	r.Use(middleware.LogRequest(log))
 	r.Use(handlers.CORS(handlers.AllowedOrigins([]string{"*"})))
+	r.Use(middleware.CacheControl(cacheControl)) //by route name, see http_cache in the config
 
 	//PUBLIC ROUTES
 	r.HandleFunc("/api/auth/sign-up", authHandler.HandleSignUp()).Methods(http.MethodPost)
 	r.HandleFunc("/api/auth/login", authHandler.HandleLogin()).Methods(http.MethodPost)
 
-	//PUBLIC ROUTES
-	public := r.PathPrefix("/api/profile").Subrouter()
-	public.HandleFunc("/{username}", profileHandler.HandlerGetProfile()).Methods(http.MethodGet)
+	r.HandleFunc("/api/go/{id:[0-9]+}", linkHandler.HandlerFollowLink()).Methods(http.MethodGet)
+	r.HandleFunc("/api/themes", themeHandler.HandlerPresets()).Methods(http.MethodGet).Name("themes")
+	r.HandleFunc("/media/{key:.+}", mediaHandler.HandlerServe()).Methods(http.MethodGet)
+	r.HandleFunc("/files/{key:.+}", filesHandler.HandlerDownload()).Methods(http.MethodGet)
+
+	//ADMIN ROUTES
+	admin := r.PathPrefix("/api/admin").Subrouter()
+	admin.Use(middleware.AdminMiddleware(log, adminToken))
+	admin.HandleFunc("/backups", adminHandler.HandlerBackup()).Methods(http.MethodPost)
+	admin.HandleFunc("/backups", adminHandler.HandlerListBackups()).Methods(http.MethodGet)
+	admin.HandleFunc("/backups/{name}", adminHandler.HandlerDownloadBackup()).Methods(http.MethodGet)
+	admin.HandleFunc("/cache", adminHandler.HandlerCacheStats()).Methods(http.MethodGet)
 
-	//PRIVATE ROUTES
+	//PRIVATE ROUTES (registered before /{username} so fixed paths win; their
+	//names are reserved at sign-up, see requestModel.reservedUsernames)
 	private := r.PathPrefix("/api/profile").Subrouter()
 	private.Use(middleware.AuthMiddleware(log, secret)) //auth middleware check and verified token
-	private.HandleFunc("", profileHandler.HandlerMyProfile()).Methods(http.MethodGet)
+	private.HandleFunc("", profileHandler.HandlerMyProfile()).Methods(http.MethodGet).Name("my_profile")
+	private.HandleFunc("", profileHandler.HandlerPatchProfile()).Methods(http.MethodPatch)
 	//ABOUT
 	private.HandleFunc("/about", profileHandler.HandlerUpdateAboutMe()).Methods(http.MethodPost)
+	//SETTINGS
+	private.HandleFunc("/settings", profileHandler.HandlerGetSettings()).Methods(http.MethodGet)
+	private.HandleFunc("/settings", profileHandler.HandlerUpdateSettings()).Methods(http.MethodPut)
 	//lINKS
-	private.HandleFunc("/link", linkHandler.HandlerLink()).Methods(http.MethodPost, http.MethodPut, http.MethodDelete)
+	private.Handle("/link", middleware.Deprecated("/api/profile/links")(linkHandler.HandlerLink())).Methods(http.MethodPost, http.MethodPut, http.MethodDelete)
+	private.Handle("/link/{id:[0-9]+}/preview", middleware.Deprecated("/api/profile/links/{id}/preview")(linkHandler.HandlerRefreshPreview())).Methods(http.MethodPost)
+	private.Handle("/link/{id:[0-9]+}/visibility", middleware.Deprecated("/api/profile/links/{id}/visibility")(linkHandler.HandlerSetVisibility())).Methods(http.MethodPut)
+	private.HandleFunc("/links", linkHandler.HandlerListLinks()).Methods(http.MethodGet)
+	private.HandleFunc("/links", linkHandler.HandlerCreateLink()).Methods(http.MethodPost)
+	private.HandleFunc("/links", linkHandler.HandlerPatchLinks()).Methods(http.MethodPatch)
+	private.HandleFunc("/links/{id:[0-9]+}", linkHandler.HandlerGetLink()).Methods(http.MethodGet)
+	private.HandleFunc("/links/{id:[0-9]+}", linkHandler.HandlerPutLink()).Methods(http.MethodPut)
+	private.HandleFunc("/links/{id:[0-9]+}", linkHandler.HandlerPatchLink()).Methods(http.MethodPatch)
+	private.HandleFunc("/links/{id:[0-9]+}", linkHandler.HandlerRemoveLink()).Methods(http.MethodDelete)
+	private.HandleFunc("/links/{id:[0-9]+}/preview", linkHandler.HandlerRefreshPreview()).Methods(http.MethodPost)
+	private.HandleFunc("/links/{id:[0-9]+}/visibility", linkHandler.HandlerSetVisibility()).Methods(http.MethodPut)
+	private.HandleFunc("/links/import", linkIOHandler.HandlerImport()).Methods(http.MethodPost)
+	private.HandleFunc("/links/export", linkIOHandler.HandlerExport()).Methods(http.MethodGet)
+	private.HandleFunc("/links/export", linkIOHandler.HandlerExportFile()).Methods(http.MethodPost)
+	//TRASH
+	private.HandleFunc("/trash", trashHandler.HandlerList()).Methods(http.MethodGet)
+	private.HandleFunc("/trash/{id:[0-9]+}/restore", trashHandler.HandlerRestore()).Methods(http.MethodPost)
+	private.HandleFunc("/trash/{id:[0-9]+}", trashHandler.HandlerPurge()).Methods(http.MethodDelete)
+	//SECTIONS
+	private.HandleFunc("/sections", sectionHandler.HandlerList()).Methods(http.MethodGet)
+	private.HandleFunc("/sections", sectionHandler.HandlerCreate()).Methods(http.MethodPost)
+	private.HandleFunc("/sections/{id:[0-9]+}", sectionHandler.HandlerUpdate()).Methods(http.MethodPut)
+	private.HandleFunc("/sections/{id:[0-9]+}", sectionHandler.HandlerDelete()).Methods(http.MethodDelete)
+	//IMAGES
+	private.HandleFunc("/{kind:avatar|banner}", mediaHandler.HandlerUpload()).Methods(http.MethodPost)
+	private.HandleFunc("/{kind:avatar|banner}", mediaHandler.HandlerDelete()).Methods(http.MethodDelete)
+	//THEME
+	private.HandleFunc("/theme", themeHandler.HandlerGetTheme()).Methods(http.MethodGet)
+	private.HandleFunc("/theme", themeHandler.HandlerUpdateTheme()).Methods(http.MethodPut)
+	//EVENTS
+	private.HandleFunc("/events", eventsHandler.HandlerStream()).Methods(http.MethodGet)
+
+	//PUBLIC ROUTES
+	public := r.PathPrefix("/api/profile").Subrouter()
+	public.HandleFunc("/{username}", profileHandler.HandlerGetProfile()).Methods(http.MethodGet).Name("profile")
 
 	return r
 }
@@ -7,12 +7,22 @@ import (
 	"url_profile/internal/app/server/http/handlers"
 	serviceinterface "url_profile/internal/app/server/http/transporter/interfaces/service"
 	"url_profile/internal/app/server/http/transporter/router"
+	"url_profile/internal/lib/linkurl"
+	"url_profile/internal/services/events"
 )
 
-func NewRouter(log *slog.Logger, userService serviceinterface.UserService, secret string, tokenTTL time.Duration) *mux.Router {
-	authHandler := handler.NewAuthHandlers(log, userService, secret, tokenTTL)
-	profileHandler := handler.NewProfileHandlers(log, userService)
-	linkHandler := handler.NewLinkHandlers(log, userService)
+func NewRouter(log *slog.Logger, userService serviceinterface.UserService, hub *events.Hub, health handler.LinkHealthProvider, previews handler.LinkPreviewer, themes handler.ThemeService, media handler.MediaService, sections handler.SectionService, trash handler.TrashService, linkIO handler.LinkIOService, backups handler.BackupService, cacheStats handler.CacheStats, visibility handler.LinkVisibility, blobs handler.BlobReader, signer handler.SignatureVerifier, urls *linkurl.Validator, secret string, adminToken string, cacheControl map[string]string, tokenTTL time.Duration, heartbeat time.Duration, maxUpload int64, urlTTL time.Duration) *mux.Router {
+	authHandler := handler.NewAuthHandlers(log, userService, urls, secret, tokenTTL)
+	profileHandler := handler.NewProfileHandlers(log, userService, hub, health, previews, themes, media, sections)
+	linkHandler := handler.NewLinkHandlers(log, userService, hub, previews, visibility, urls)
+	eventsHandler := handler.NewEventsHandlers(log, hub, heartbeat)
+	themeHandler := handler.NewThemeHandlers(log, themes)
+	mediaHandler := handler.NewMediaHandlers(log, media, maxUpload)
+	filesHandler := handler.NewFilesHandlers(log, blobs, signer)
+	sectionHandler := handler.NewSectionHandlers(log, sections)
+	trashHandler := handler.NewTrashHandlers(log, trash)
+	linkIOHandler := handler.NewLinkIOHandlers(log, linkIO)
+	adminHandler := handler.NewAdminHandlers(log, backups, cacheStats, urlTTL)
 
-	return router.New(authHandler, profileHandler, linkHandler, log, secret)
+	return router.New(authHandler, profileHandler, linkHandler, eventsHandler, themeHandler, mediaHandler, filesHandler, sectionHandler, trashHandler, linkIOHandler, adminHandler, log, secret, adminToken, cacheControl)
 }
@@ -0,0 +1,697 @@
+package transporter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/blob"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/lib/jwt"
+	"url_profile/internal/lib/linkurl"
+	authservice "url_profile/internal/services/auth"
+	"url_profile/internal/services/events"
+	"url_profile/internal/services/linkpreview"
+	"url_profile/internal/services/theme"
+	memorystore "url_profile/internal/store/memory"
+
+	"github.com/gorilla/mux"
+)
+
+const testSecret = "test-secret"
+
+// The memory store keeps users, links and themes. The rest of what the
+// profile shows is empty here.
+type noHealth struct{}
+
+func (noHealth) Health(userID int) (map[int]models.LinkHealth, error) { return nil, nil }
+
+type noPreviews struct{}
+
+func (noPreviews) Preview(ctx context.Context, url string) (*models.LinkPreview, error) {
+	return nil, linkpreview.ErrDisabled
+}
+
+func (noPreviews) Refresh(ctx context.Context, url string) (*models.LinkPreview, error) {
+	return nil, linkpreview.ErrDisabled
+}
+
+func (noPreviews) Previews(userID int) (map[string]models.LinkPreview, error) { return nil, nil }
+
+type noMedia struct{}
+
+func (noMedia) Upload(ctx context.Context, userID int, kind string, r io.Reader) (map[string]string, error) {
+	return nil, errors.New("media is not available in tests")
+}
+
+func (noMedia) Delete(ctx context.Context, userID int, kind string) error { return nil }
+
+func (noMedia) ImageURLs(userID int) (map[string]map[string]string, error) { return nil, nil }
+
+func (noMedia) Open(ctx context.Context, key string) (io.ReadCloser, *blob.Info, error) {
+	return nil, nil, blob.ErrNotFound
+}
+
+type noSections struct{}
+
+func (noSections) Sections(userID int) ([]models.LinkSection, error) { return nil, nil }
+
+func (noSections) Create(userID int, req requestModel.ReqSection) (*models.LinkSection, error) {
+	return nil, errors.New("sections are not available in tests")
+}
+
+func (noSections) Update(userID int, sectionID int, req requestModel.ReqSection) (*models.LinkSection, error) {
+	return nil, errors.New("sections are not available in tests")
+}
+
+func (noSections) Delete(userID int, sectionID int) error { return nil }
+
+// testServer is the full router over the memory store, with alice signed
+// in.
+type testServer struct {
+	t      *testing.T
+	router *mux.Router
+	hub    *events.Hub
+	user   *models.User
+	token  string // "Bearer <jwt>", as sign-up hands it out
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	db := memorystore.New()
+	hub := events.New(16, 3, time.Minute)
+	cacheControl := map[string]string{"profile": "public, no-cache"}
+
+	router := NewRouter(log, authservice.New(log, db, db), hub, noHealth{}, noPreviews{}, theme.New(log, db),
+		noMedia{}, noSections{}, nil, nil, nil, nil, nil, nil, nil, linkurl.New(nil),
+		testSecret, "", cacheControl, time.Hour, 50*time.Millisecond, 1<<20, time.Minute)
+
+	u, err := db.CreateUser("alice@example.com", "alice", []byte("x"), "hello", nil)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	token, err := jwt.NewToken(u, time.Hour, testSecret)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+
+	return &testServer{t: t, router: router, hub: hub, user: u, token: token}
+}
+
+// do sends a request as alice. header holds name, value pairs.
+func (s *testServer) do(method string, path string, body string, header ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", s.token)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// decode reads a JSON response body into v.
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("response %q: %v", rec.Body.String(), err)
+	}
+}
+
+func wantStatus(t *testing.T, rec *httptest.ResponseRecorder, want int) {
+	t.Helper()
+
+	if rec.Code != want {
+		t.Fatalf("status = %d, want %d; body %s", rec.Code, want, rec.Body.String())
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	for _, path := range []string{"/api/profile/alice", "/api/profile"} {
+		t.Run(path, func(t *testing.T) {
+			s := newTestServer(t)
+
+			first := s.do(http.MethodGet, path, "")
+			wantStatus(t, first, http.StatusOK)
+			etag, modified := first.Header().Get("ETag"), first.Header().Get("Last-Modified")
+			if etag == "" || modified == "" {
+				t.Fatalf("ETag %q, Last-Modified %q: want both", etag, modified)
+			}
+
+			for _, tt := range []struct {
+				name   string
+				header []string
+				want   int
+			}{
+				{"matching If-None-Match", []string{"If-None-Match", etag}, http.StatusNotModified},
+				{"weak If-None-Match", []string{"If-None-Match", "W/" + etag}, http.StatusNotModified},
+				{"If-None-Match list", []string{"If-None-Match", `"0-1.0", ` + etag}, http.StatusNotModified},
+				{"other If-None-Match", []string{"If-None-Match", `"0-1.0"`}, http.StatusOK},
+				{"If-Modified-Since", []string{"If-Modified-Since", modified}, http.StatusNotModified},
+				{"earlier If-Modified-Since", []string{"If-Modified-Since", "Mon, 02 Jan 2006 15:04:05 GMT"}, http.StatusOK},
+				{"If-None-Match wins", []string{"If-None-Match", `"0-1.0"`, "If-Modified-Since", modified}, http.StatusOK},
+			} {
+				rec := s.do(http.MethodGet, path, "", tt.header...)
+				if rec.Code != tt.want {
+					t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+				}
+				if rec.Code == http.StatusNotModified {
+					if rec.Body.Len() != 0 {
+						t.Errorf("%s: 304 with a body %q", tt.name, rec.Body.String())
+					}
+					if got := rec.Header().Get("ETag"); got != etag {
+						t.Errorf("%s: 304 ETag = %q, want %q", tt.name, got, etag)
+					}
+				}
+			}
+
+			wantStatus(t, s.do(http.MethodPost, "/api/profile/about", `{"text":"changed"}`), http.StatusOK)
+
+			rec := s.do(http.MethodGet, path, "", "If-None-Match", etag)
+			wantStatus(t, rec, http.StatusOK)
+			if rec.Header().Get("ETag") == etag {
+				t.Errorf("ETag %q did not change with the about text", etag)
+			}
+		})
+	}
+}
+
+func TestProfileCacheControl(t *testing.T) {
+	s := newTestServer(t)
+
+	if got := s.do(http.MethodGet, "/api/profile/alice", "").Header().Get("Cache-Control"); got != "public, no-cache" {
+		t.Errorf("public profile Cache-Control = %q, want the configured one", got)
+	}
+	if got := s.do(http.MethodGet, "/api/profile", "").Header().Get("Vary"); got != "Authorization" {
+		t.Errorf("own profile Vary = %q, want Authorization", got)
+	}
+}
+
+// linkView is the part of a link response the tests look at.
+type linkView struct {
+	ID        int    `json:"id"`
+	LinkName  string `json:"link_name"`
+	LinkColor string `json:"link_color"`
+	LinkPath  string `json:"link_path"`
+	Version   int    `json:"version"`
+	ETag      string `json:"etag"`
+}
+
+// profileView is the part of the owner's profile the tests look at.
+type profileView struct {
+	About string `json:"about"`
+	Theme struct {
+		Preset    string `json:"preset"`
+		LinkColor string `json:"link_color"`
+	} `json:"theme"`
+	// Owner links carry the model's field names.
+	Links []struct {
+		ID       int
+		LinkName string
+	} `json:"links"`
+}
+
+// addLink creates a link through POST /api/profile/links.
+func (s *testServer) addLink(name string, path string) linkView {
+	s.t.Helper()
+
+	rec := s.do(http.MethodPost, "/api/profile/links", `{"link_name":"`+name+`","link_path":"`+path+`"}`)
+	wantStatus(s.t, rec, http.StatusCreated)
+
+	var l linkView
+	decode(s.t, rec, &l)
+	return l
+}
+
+// profile reads the owner's profile.
+func (s *testServer) profile() (profileView, string) {
+	s.t.Helper()
+
+	rec := s.do(http.MethodGet, "/api/profile", "")
+	wantStatus(s.t, rec, http.StatusOK)
+
+	var p profileView
+	decode(s.t, rec, &p)
+	return p, rec.Header().Get("ETag")
+}
+
+func TestIfMatchProfile(t *testing.T) {
+	s := newTestServer(t)
+	_, stale := s.profile()
+
+	rec := s.do(http.MethodPost, "/api/profile/about", `{"text":"first"}`, "If-Match", stale)
+	wantStatus(t, rec, http.StatusOK)
+	current := rec.Header().Get("ETag")
+	if current == "" || current == stale {
+		t.Fatalf("ETag after the write = %q, want a new one", current)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		header []string
+	}{
+		{"about", http.MethodPost, "/api/profile/about", `{"text":"lost"}`, nil},
+		{"merge patch", http.MethodPatch, "/api/profile", `{"about":"lost","theme":{"link_color":"#ff0000"}}`,
+			[]string{"Content-Type", "application/merge-patch+json"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, ifMatch := range []string{stale, `"999-1.0"`, `W/` + current} {
+				rec := s.do(tt.method, tt.path, tt.body, append([]string{"If-Match", ifMatch}, tt.header...)...)
+				wantStatus(t, rec, http.StatusPreconditionFailed)
+				if got := rec.Header().Get("ETag"); got != current {
+					t.Errorf("If-Match %s: 412 ETag = %q, want %q", ifMatch, got, current)
+				}
+
+				var p profileView
+				decode(t, rec, &p)
+				if p.About != "first" {
+					t.Errorf("If-Match %s: 412 body about = %q, want the current one", ifMatch, p.About)
+				}
+			}
+
+			if p, _ := s.profile(); p.About != "first" || p.Theme.LinkColor == "#ff0000" {
+				t.Errorf("a failed precondition wrote: %+v", p)
+			}
+		})
+	}
+
+	// The preview counter after the dot is not pinned.
+	dot := strings.Index(current, ".")
+	rec = s.do(http.MethodPost, "/api/profile/about", `{"text":"second"}`, "If-Match", current[:dot]+`.7"`)
+	wantStatus(t, rec, http.StatusOK)
+
+	rec = s.do(http.MethodPatch, "/api/profile", `{"about":"third","theme":{"link_color":"#ff0000"}}`,
+		"If-Match", rec.Header().Get("ETag"), "Content-Type", "application/merge-patch+json")
+	wantStatus(t, rec, http.StatusOK)
+	if p, _ := s.profile(); p.About != "third" || p.Theme.LinkColor != "#ff0000" {
+		t.Errorf("profile after the patch: %+v", p)
+	}
+
+	wantStatus(t, s.do(http.MethodPost, "/api/profile/about", `{"text":"any"}`, "If-Match", "*"), http.StatusOK)
+}
+
+func TestIfMatchLink(t *testing.T) {
+	s := newTestServer(t)
+	l := s.addLink("site", "https://alice.dev")
+	other := s.addLink("blog", "https://alice.blog")
+	path := "/api/profile/links/" + strconv.Itoa(l.ID)
+
+	rec := s.do(http.MethodPut, path, `{"link_name":"home","link_path":"https://alice.dev"}`, "If-Match", l.ETag)
+	wantStatus(t, rec, http.StatusOK)
+	var updated linkView
+	decode(t, rec, &updated)
+	if updated.LinkName != "home" || updated.ETag == l.ETag || rec.Header().Get("ETag") != updated.ETag {
+		t.Fatalf("PUT answered %+v with ETag %q", updated, rec.Header().Get("ETag"))
+	}
+
+	for _, tt := range []struct {
+		name    string
+		method  string
+		body    string
+		ifMatch string
+	}{
+		{"PUT at a stale version", http.MethodPut, `{"link_name":"lost","link_path":"https://alice.dev"}`, l.ETag},
+		{"PUT with another link's ETag", http.MethodPut, `{"link_name":"lost","link_path":"https://alice.dev"}`, other.ETag},
+		{"merge patch at a stale version", http.MethodPatch, `{"link_name":"lost"}`, l.ETag},
+		{"DELETE at a stale version", http.MethodDelete, "", l.ETag},
+	} {
+		rec := s.do(tt.method, path, tt.body, "If-Match", tt.ifMatch, "Content-Type", "application/merge-patch+json")
+		if rec.Code != http.StatusPreconditionFailed {
+			t.Errorf("%s: status = %d, want 412", tt.name, rec.Code)
+			continue
+		}
+
+		var got linkView
+		decode(t, rec, &got)
+		if got.LinkName != "home" || got.ETag != updated.ETag || rec.Header().Get("ETag") != updated.ETag {
+			t.Errorf("%s: 412 answered %+v with ETag %q, want the current link", tt.name, got, rec.Header().Get("ETag"))
+		}
+	}
+
+	wantStatus(t, s.do(http.MethodDelete, path, "", "If-Match", updated.ETag), http.StatusNoContent)
+	wantStatus(t, s.do(http.MethodPut, path, `{"link_name":"x","link_path":"https://alice.dev"}`, "If-Match", updated.ETag), http.StatusNotFound)
+}
+
+func TestMergePatchLink(t *testing.T) {
+	s := newTestServer(t)
+	l := s.addLink("site", "https://alice.dev")
+	path := "/api/profile/links/" + strconv.Itoa(l.ID)
+	wantStatus(t, s.do(http.MethodPut, path, `{"link_name":"site","link_color":"#ff0000","link_path":"https://alice.dev"}`), http.StatusOK)
+
+	for _, tt := range []struct {
+		name  string
+		patch string
+		want  linkView
+	}{
+		{"one field", `{"link_name":"home"}`, linkView{LinkName: "home", LinkColor: "#ff0000", LinkPath: "https://alice.dev"}},
+		{"null clears", `{"link_color":null}`, linkView{LinkName: "home", LinkPath: "https://alice.dev"}},
+		{"empty patch", `{}`, linkView{LinkName: "home", LinkPath: "https://alice.dev"}},
+	} {
+		rec := s.do(http.MethodPatch, path, tt.patch, "Content-Type", "application/merge-patch+json")
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, want 200; body %s", tt.name, rec.Code, rec.Body.String())
+			continue
+		}
+
+		var got linkView
+		decode(t, rec, &got)
+		if got.LinkName != tt.want.LinkName || got.LinkColor != tt.want.LinkColor || got.LinkPath != tt.want.LinkPath {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		name        string
+		contentType string
+		patch       string
+		want        int
+	}{
+		{"plain JSON is a merge patch", "application/json", `{"link_name":"site"}`, http.StatusOK},
+		{"JSON Patch media type", "application/json-patch+json", `[]`, http.StatusUnsupportedMediaType},
+		{"unknown member", "application/merge-patch+json", `{"clicks":1}`, http.StatusUnprocessableEntity},
+		{"read-only version", "application/merge-patch+json", `{"version":100}`, http.StatusBadRequest},
+		{"invalid URL", "application/merge-patch+json", `{"link_path":"javascript:alert(1)"}`, http.StatusBadRequest},
+	} {
+		rec := s.do(http.MethodPatch, path, tt.patch, "Content-Type", tt.contentType)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d; body %s", tt.name, rec.Code, tt.want, rec.Body.String())
+		}
+		if rec.Code == http.StatusUnsupportedMediaType && rec.Header().Get("Accept-Patch") != "application/merge-patch+json" {
+			t.Errorf("%s: Accept-Patch = %q", tt.name, rec.Header().Get("Accept-Patch"))
+		}
+	}
+}
+
+func TestJSONPatchLinks(t *testing.T) {
+	s := newTestServer(t)
+	a := s.addLink("a", "https://a.example")
+	b := s.addLink("b", "https://b.example")
+	c := s.addLink("c", "https://c.example")
+	pa, pb, pc := "/"+strconv.Itoa(a.ID), "/"+strconv.Itoa(b.ID), "/"+strconv.Itoa(c.ID)
+
+	patchLinks := func(patch string) *httptest.ResponseRecorder {
+		t.Helper()
+		return s.do(http.MethodPatch, "/api/profile/links", patch, "Content-Type", "application/json-patch+json")
+	}
+	names := func() map[int]string {
+		t.Helper()
+		p, _ := s.profile()
+		res := make(map[int]string, len(p.Links))
+		for _, l := range p.Links {
+			res[l.ID] = l.LinkName
+		}
+		return res
+	}
+
+	before := names()
+	for _, tt := range []struct {
+		name  string
+		patch string
+		want  int
+	}{
+		{"failed test", `[{"op":"replace","path":"` + pa + `/link_name","value":"x"},
+			{"op":"test","path":"` + pb + `/version","value":99}]`, http.StatusConflict},
+		{"missing link", `[{"op":"replace","path":"/999/link_name","value":"x"}]`, http.StatusUnprocessableEntity},
+		{"new link", `[{"op":"add","path":"/999","value":{"link_name":"x","link_path":"https://x.example"}}]`, http.StatusUnprocessableEntity},
+		{"one invalid link", `[{"op":"replace","path":"` + pa + `/link_name","value":"x"},
+			{"op":"replace","path":"` + pb + `/link_path","value":"ftp://b.example"}]`, http.StatusBadRequest},
+		{"not a patch", `{"op":"remove"}`, http.StatusBadRequest},
+	} {
+		rec := patchLinks(tt.patch)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d; body %s", tt.name, rec.Code, tt.want, rec.Body.String())
+		}
+	}
+	if got := names(); !maps.Equal(got, before) {
+		t.Fatalf("rejected patches changed links: %v, want %v", got, before)
+	}
+
+	rec := patchLinks(`[
+		{"op":"test","path":"` + pa + `/version","value":` + strconv.Itoa(a.Version) + `},
+		{"op":"replace","path":"` + pa + `/link_name","value":"first"},
+		{"op":"copy","from":"` + pa + `/link_color","path":"` + pb + `/link_color"},
+		{"op":"replace","path":"` + pb + `/link_name","value":"second"},
+		{"op":"remove","path":"` + pc + `"}
+	]`)
+	wantStatus(t, rec, http.StatusOK)
+
+	var docs map[string]linkView
+	decode(t, rec, &docs)
+	if len(docs) != 2 || docs[pa[1:]].LinkName != "first" || docs[pb[1:]].LinkName != "second" {
+		t.Errorf("patched links = %+v", docs)
+	}
+	if got, want := names(), map[int]string{a.ID: "first", b.ID: "second"}; !maps.Equal(got, want) {
+		t.Errorf("links after the patch = %v, want %v", got, want)
+	}
+}
+
+func TestMergePatchProfile(t *testing.T) {
+	s := newTestServer(t)
+	merge := func(patch string) *httptest.ResponseRecorder {
+		t.Helper()
+		return s.do(http.MethodPatch, "/api/profile", patch, "Content-Type", "application/merge-patch+json")
+	}
+
+	wantStatus(t, merge(`{"theme":{"link_color":"#ff0000"}}`), http.StatusOK)
+	rec := merge(`{"about":"new"}`)
+	wantStatus(t, rec, http.StatusOK)
+
+	var p profileView
+	decode(t, rec, &p)
+	if p.About != "new" || p.Theme.LinkColor != "#ff0000" {
+		t.Errorf("profile after patching the about text: %+v", p)
+	}
+
+	rec = merge(`{"theme":{"preset":"dark","background":null,"font":null,"button_style":null,"link_color":null}}`)
+	wantStatus(t, rec, http.StatusOK)
+	decode(t, rec, &p)
+	if p.Theme.Preset != "dark" || p.Theme.LinkColor != "#f5f5f5" {
+		t.Errorf("theme after nulling the fields = %+v, want the dark preset", p.Theme)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		patch string
+		want  int
+	}{
+		{"unknown member", `{"email":"x@example.com"}`, http.StatusUnprocessableEntity},
+		{"invalid theme", `{"about":"lost","theme":{"preset":"nope"}}`, http.StatusBadRequest},
+		{"broken JSON", `{"about":`, http.StatusBadRequest},
+	} {
+		rec := merge(tt.patch)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d; body %s", tt.name, rec.Code, tt.want, rec.Body.String())
+		}
+	}
+	if p, _ := s.profile(); p.About != "new" {
+		t.Errorf("a rejected patch changed the about text to %q", p.About)
+	}
+}
+
+func TestLinkRoutes(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := s.do(http.MethodPost, "/api/profile/links", `{"link_name":"site","link_path":"https://alice.dev"}`)
+	wantStatus(t, rec, http.StatusCreated)
+
+	var created linkView
+	decode(t, rec, &created)
+	location := rec.Header().Get("Location")
+	if want := "/api/profile/links/" + strconv.Itoa(created.ID); location != want {
+		t.Errorf("Location = %q, want %q", location, want)
+	}
+	if etag := rec.Header().Get("ETag"); etag == "" || etag != created.ETag {
+		t.Errorf("ETag header %q, body etag %q: want them equal", etag, created.ETag)
+	}
+	if rec.Header().Get("Deprecation") != "" {
+		t.Errorf("the new route is marked deprecated")
+	}
+
+	rec = s.do(http.MethodGet, location, "")
+	wantStatus(t, rec, http.StatusOK)
+
+	var got linkView
+	decode(t, rec, &got)
+	if got.ID != created.ID || got.LinkName != "site" {
+		t.Errorf("GET %s = %+v, want the created link", location, got)
+	}
+
+	wantStatus(t, s.do(http.MethodPost, "/api/profile/links", `{"link_name":"bad","link_path":"javascript:alert(1)"}`), http.StatusBadRequest)
+
+	rec = s.do(http.MethodDelete, location, "")
+	wantStatus(t, rec, http.StatusNoContent)
+	if rec.Body.Len() != 0 {
+		t.Errorf("204 with a body %q", rec.Body.String())
+	}
+
+	wantStatus(t, s.do(http.MethodGet, location, ""), http.StatusNotFound)
+	wantStatus(t, s.do(http.MethodDelete, location, ""), http.StatusNotFound)
+}
+
+func TestLegacyLinkRoutes(t *testing.T) {
+	s := newTestServer(t)
+
+	wantDeprecated := func(rec *httptest.ResponseRecorder, successor string) {
+		t.Helper()
+		if got := rec.Header().Get("Deprecation"); got != "true" {
+			t.Errorf("Deprecation = %q, want true", got)
+		}
+		if got, want := rec.Header().Get("Link"), "<"+successor+`>; rel="successor-version"`; got != want {
+			t.Errorf("Link = %q, want %q", got, want)
+		}
+	}
+
+	rec := s.do(http.MethodPost, "/api/profile/link", `[{"link_name":"a","link_path":"https://a.example"},{"link_name":"b","link_path":"https://b.example"}]`)
+	wantStatus(t, rec, http.StatusOK)
+	wantDeprecated(rec, "/api/profile/links")
+
+	p, _ := s.profile()
+	if len(p.Links) != 2 {
+		t.Fatalf("legacy add created %d links, want 2", len(p.Links))
+	}
+	id := strconv.Itoa(p.Links[0].ID)
+
+	// Previews are off here, so the refresh fails, but the alias still
+	// names its successor.
+	rec = s.do(http.MethodPost, "/api/profile/link/"+id+"/preview", "")
+	wantStatus(t, rec, http.StatusServiceUnavailable)
+	wantDeprecated(rec, "/api/profile/links/"+id+"/preview")
+
+	rec = s.do(http.MethodDelete, "/api/profile/link", `{"id":`+id+`}`)
+	wantStatus(t, rec, http.StatusOK)
+	wantDeprecated(rec, "/api/profile/links")
+
+	if p, _ := s.profile(); len(p.Links) != 1 {
+		t.Errorf("%d links after the legacy delete, want 1", len(p.Links))
+	}
+}
+
+// readEvent reads the next event from an event stream, skipping
+// heartbeats, and returns its id and type.
+func readEvent(t *testing.T, r *bufio.Reader) (id string, typ string, data string) {
+	t.Helper()
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading the event stream: %v", err)
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && typ != "":
+			return id, typ, data
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			typ = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	s := newTestServer(t)
+	l := s.addLink("site", "https://alice.dev")
+
+	srv := httptest.NewServer(s.router)
+	t.Cleanup(srv.Close)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	stream := func(lastID string) (*http.Response, *bufio.Reader) {
+		t.Helper()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		t.Cleanup(cancel)
+
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/profile/events", nil)
+		req.Header.Set("Authorization", s.token)
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d, want 200", resp.StatusCode)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+		}
+		return resp, bufio.NewReader(resp.Body)
+	}
+
+	follow := func() {
+		t.Helper()
+
+		resp, err := client.Get(srv.URL + "/api/go/" + strconv.Itoa(l.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusFound {
+			t.Fatalf("follow status = %d, want 302", resp.StatusCode)
+		}
+	}
+
+	first, r := stream("")
+	follow()
+
+	id, typ, data := readEvent(t, r)
+	if typ != "click" {
+		t.Fatalf("event type = %q, want click", typ)
+	}
+
+	var ev struct {
+		Data struct {
+			LinkID int `json:"link_id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(data), &ev); err != nil || ev.Data.LinkID != l.ID {
+		t.Errorf("event data %s: want link_id %d (%v)", data, l.ID, err)
+	}
+	first.Body.Close()
+
+	// Events published while disconnected are replayed after Last-Event-ID.
+	follow()
+	follow()
+	_, r = stream(id)
+	for range 2 {
+		next, typ, _ := readEvent(t, r)
+		n, _ := strconv.Atoi(next)
+		last, _ := strconv.Atoi(id)
+		if typ != "click" || n <= last {
+			t.Errorf("replayed event %s %q, want a click after %s", next, typ, id)
+		}
+		id = next
+	}
+
+	wantStatus(t, s.do(http.MethodGet, "/api/profile/events", "", "Last-Event-ID", "x"), http.StatusBadRequest)
+}
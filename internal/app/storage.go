@@ -0,0 +1,96 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"url_profile/internal/config"
+	authservice "url_profile/internal/services/auth"
+	"url_profile/internal/services/linkhealth"
+	"url_profile/internal/services/linkio"
+	"url_profile/internal/services/linkpreview"
+	"url_profile/internal/services/media"
+	"url_profile/internal/services/sections"
+	"url_profile/internal/services/theme"
+	"url_profile/internal/services/trash"
+	"url_profile/internal/store"
+	"url_profile/internal/store/migrator"
+	postgresstore "url_profile/internal/store/postgres"
+	sqlitestore "url_profile/internal/store/sqlite"
+)
+
+// appStore is everything the services need from the database. Both the
+// sqlite and the postgres store implement it.
+type appStore interface {
+	authservice.UserSaver
+	authservice.UserProvider
+	linkhealth.LinkStore
+	linkpreview.PreviewStore
+	theme.ThemeStore
+	media.ImageStore
+	sections.SectionStore
+	trash.TrashStore
+	linkio.LinkStore
+	store.UnitOfWork
+	// PreviewOwners lets cachedStore find the profiles a preview write
+	// changes.
+	PreviewOwners(url string) ([]int, error)
+}
+
+// prepareSchema applies the embedded migrations when migrate_on_start is
+// set. Otherwise the schema has to be current already. Either way it
+// refuses a schema newer than this binary.
+func prepareSchema(ctx context.Context, cfg config.Config, log *slog.Logger) error {
+	storage := cfg.StoragePath
+	if cfg.Storage.Driver == "postgres" {
+		storage = cfg.Storage.Postgres.DSN
+	}
+
+	if cfg.Storage.MigrateOnStart {
+		return migrator.Up(ctx, cfg.Storage.Driver, storage, log)
+	}
+
+	return migrator.Check(cfg.Storage.Driver, storage)
+}
+
+// newStore opens the backend selected by cfg.Storage.Driver.
+func newStore(ctx context.Context, cfg config.Config, log *slog.Logger) (appStore, error) {
+	switch cfg.Storage.Driver {
+	case "", "sqlite":
+		lite := cfg.Storage.SQLite
+		return sqlitestore.New(sqlitestore.Config{
+			Path:         cfg.StoragePath,
+			JournalMode:  lite.JournalMode,
+			Synchronous:  lite.Synchronous,
+			BusyTimeout:  lite.BusyTimeout,
+			ForeignKeys:  lite.ForeignKeys,
+			CacheSize:    lite.CacheSize,
+			MaxOpenConns: lite.MaxOpenConns,
+			MaxIdleConns: lite.MaxIdleConns,
+		}, log), nil
+	case "postgres":
+		pg := cfg.Storage.Postgres
+		return postgresstore.New(ctx, postgresstore.Config{
+			DSN:               pg.DSN,
+			MaxConns:          pg.MaxConns,
+			MinConns:          pg.MinConns,
+			MaxConnLifetime:   pg.MaxConnLifetime,
+			MaxConnIdleTime:   pg.MaxConnIdleTime,
+			HealthCheckPeriod: pg.HealthCheckPeriod,
+			ConnectTimeout:    pg.ConnectTimeout,
+		}, log)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Storage.Driver)
+	}
+}
+
+// closeStore releases the connections of whichever backend newStore opened.
+func closeStore(s appStore) error {
+	switch s := s.(type) {
+	case interface{ Close() error }:
+		return s.Close()
+	case interface{ Close() }:
+		s.Close()
+	}
+	return nil
+}
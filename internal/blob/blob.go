@@ -0,0 +1,39 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+type Info struct {
+	Key         string
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// Download is a presigned URL handed to a client.
+type Download struct {
+	URL       string
+	ExpiresAt time.Time
+}
+
+// Storage keeps binary objects (avatars, exports, backups) under slash
+// separated keys. Implementations must be safe for concurrent use.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, *Info, error)
+	Delete(ctx context.Context, key string) error
+	// List returns the objects whose keys start with prefix, sorted by key.
+	List(ctx context.Context, prefix string) ([]Info, error)
+	// Presign returns a URL that allows anyone holding it to download the
+	// object until ttl passes.
+	Presign(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
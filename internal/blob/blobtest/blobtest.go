@@ -0,0 +1,166 @@
+// Package blobtest holds the behaviour every blob.Storage backend must share.
+package blobtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+	"url_profile/internal/blob"
+)
+
+// Run exercises s through put/get/overwrite/delete/presign. fetch downloads a
+// presigned URL and returns its body; it lets each backend decide how its
+// URLs are served.
+func Run(t *testing.T, s blob.Storage, fetch func(url string) ([]byte, error)) {
+	ctx := context.Background()
+
+	t.Run("put and get", func(t *testing.T) {
+		data := []byte("hello blob")
+		if err := s.Put(ctx, "avatar/1/a.png", bytes.NewReader(data), "image/png"); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+
+		rc, info, err := s.Get(ctx, "avatar/1/a.png")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		defer rc.Close()
+
+		got, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("got %q, want %q", got, data)
+		}
+		if info.Size != int64(len(data)) {
+			t.Errorf("size = %d, want %d", info.Size, len(data))
+		}
+		if info.ContentType != "image/png" {
+			t.Errorf("content type = %q, want image/png", info.ContentType)
+		}
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		if err := s.Put(ctx, "over.txt", strings.NewReader("one"), "text/plain"); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+		if err := s.Put(ctx, "over.txt", strings.NewReader("two"), "text/plain"); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+
+		if got := read(t, s, "over.txt"); got != "two" {
+			t.Fatalf("got %q, want two", got)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		if _, _, err := s.Get(ctx, "nope/missing.png"); !errors.Is(err, blob.ErrNotFound) {
+			t.Fatalf("get missing: err = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := s.Put(ctx, "del/x.bin", strings.NewReader("x"), "application/octet-stream"); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+		if err := s.Delete(ctx, "del/x.bin"); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, _, err := s.Get(ctx, "del/x.bin"); !errors.Is(err, blob.ErrNotFound) {
+			t.Fatalf("get after delete: err = %v, want ErrNotFound", err)
+		}
+		if err := s.Delete(ctx, "del/x.bin"); err != nil {
+			t.Fatalf("second delete: %v", err)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		for _, key := range []string{"list/b/2.txt", "list/a.txt", "list/b/1.txt", "listing.txt", "other/list/x.txt"} {
+			if err := s.Put(ctx, key, strings.NewReader(key), "text/plain"); err != nil {
+				t.Fatalf("put %s: %v", key, err)
+			}
+		}
+
+		tests := []struct {
+			prefix string
+			want   []string
+		}{
+			{"list/", []string{"list/a.txt", "list/b/1.txt", "list/b/2.txt"}},
+			{"list/b/", []string{"list/b/1.txt", "list/b/2.txt"}},
+			{"list/b/1", []string{"list/b/1.txt"}},
+			{"list", []string{"list/a.txt", "list/b/1.txt", "list/b/2.txt", "listing.txt"}},
+			{"missing/", nil},
+		}
+
+		for _, tt := range tests {
+			got, err := s.List(ctx, tt.prefix)
+			if err != nil {
+				t.Fatalf("list %q: %v", tt.prefix, err)
+			}
+
+			keys := make([]string, 0, len(got))
+			for _, info := range got {
+				keys = append(keys, info.Key)
+				if info.Size != int64(len(info.Key)) {
+					t.Errorf("list %q: %s has size %d, want %d", tt.prefix, info.Key, info.Size, len(info.Key))
+				}
+			}
+			if strings.Join(keys, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("list %q = %v, want %v", tt.prefix, keys, tt.want)
+			}
+		}
+	})
+
+	t.Run("invalid keys", func(t *testing.T) {
+		for _, key := range []string{"", "/abs", "../up", "a/../../b", "a//b"} {
+			if err := s.Put(ctx, key, strings.NewReader("x"), ""); !errors.Is(err, blob.ErrInvalidKey) {
+				t.Errorf("put %q: err = %v, want ErrInvalidKey", key, err)
+			}
+		}
+	})
+
+	t.Run("presign", func(t *testing.T) {
+		if err := s.Put(ctx, "signed/file name.txt", strings.NewReader("signed"), "text/plain"); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+
+		u, err := s.Presign(ctx, "signed/file name.txt", time.Minute)
+		if err != nil {
+			t.Fatalf("presign: %v", err)
+		}
+
+		got, err := fetch(u)
+		if err != nil {
+			t.Fatalf("fetch %s: %v", u, err)
+		}
+		if string(got) != "signed" {
+			t.Fatalf("got %q, want signed", got)
+		}
+
+		if _, err := fetch(strings.Replace(u, "signed/", "signed/x", 1)); err == nil {
+			t.Fatalf("tampered url was accepted")
+		}
+	})
+}
+
+func read(t *testing.T, s blob.Storage, key string) string {
+	t.Helper()
+
+	rc, _, err := s.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("get %s: %v", key, err)
+	}
+	defer rc.Close()
+
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("read %s: %v", key, err)
+	}
+
+	return string(b)
+}
@@ -0,0 +1,170 @@
+package localblob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"url_profile/internal/blob"
+)
+
+// Storage keeps objects as plain files under a root directory. Presigned
+// URLs point back at the app, which checks them with the same signer.
+type Storage struct {
+	root   string
+	signer *blob.Signer
+}
+
+func New(root string, signer *blob.Signer) (*Storage, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &Storage{
+		root:   root,
+		signer: signer,
+	}, nil
+}
+
+func (s *Storage) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temp file first so readers never see a half written object.
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), p)
+}
+
+func (s *Storage) Get(ctx context.Context, key string) (io.ReadCloser, *blob.Info, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, blob.ErrNotFound
+		}
+		return nil, nil, err
+	}
+
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	return f, &blob.Info{
+		Key:         key,
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+		Size:        st.Size(),
+		ModTime:     st.ModTime(),
+	}, nil
+}
+
+func (s *Storage) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Storage) List(ctx context.Context, prefix string) ([]blob.Info, error) {
+	// Only the directory the prefix points into has to be walked.
+	dir := s.root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		p, err := s.path(prefix[:i])
+		if err != nil {
+			return nil, err
+		}
+		dir = p
+	}
+
+	var res []blob.Info
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".tmp-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		res = append(res, blob.Info{
+			Key:         key,
+			ContentType: mime.TypeByExtension(path.Ext(key)),
+			Size:        info.Size(),
+			ModTime:     info.ModTime(),
+		})
+		return nil
+	})
+
+	return res, err
+}
+
+func (s *Storage) Presign(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if _, err := s.path(key); err != nil {
+		return "", err
+	}
+
+	return s.signer.URL(key, ttl), nil
+}
+
+// path maps a key to a file below root, rejecting anything that would
+// escape it.
+func (s *Storage) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if key == "" || clean == "/" || strings.Contains(key, "\\") || clean != "/"+key {
+		return "", blob.ErrInvalidKey
+	}
+
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
@@ -0,0 +1,44 @@
+package localblob
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"url_profile/internal/blob"
+	"url_profile/internal/blob/blobtest"
+)
+
+func TestStorage(t *testing.T) {
+	signer := blob.NewSigner("test-secret", "/files")
+
+	s, err := New(t.TempDir(), signer)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	// Mirrors what the /files handler does with a presigned URL.
+	fetch := func(raw string) ([]byte, error) {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		key := strings.TrimPrefix(u.Path, "/files/")
+		q := u.Query()
+		if err := signer.Verify(key, q.Get("expires"), q.Get("signature")); err != nil {
+			return nil, err
+		}
+
+		rc, _, err := s.Get(context.Background(), key)
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+
+		return io.ReadAll(rc)
+	}
+
+	blobtest.Run(t, s, fetch)
+}
@@ -0,0 +1,279 @@
+package s3blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+	"url_profile/internal/blob"
+)
+
+// maxPresignTTL is the longest expiry S3 accepts for query authentication.
+const maxPresignTTL = 7 * 24 * time.Hour
+
+type Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+	// PathStyle addresses objects as endpoint/bucket/key instead of
+	// bucket.endpoint/key. MinIO and most self-hosted servers need it.
+	PathStyle bool
+}
+
+// Storage talks to any S3 compatible service over its REST API, signing
+// requests with AWS Signature Version 4.
+type Storage struct {
+	client *http.Client
+	cfg    Config
+	creds  credentials
+	now    func() time.Time
+}
+
+func New(cfg Config, client *http.Client) (*Storage, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("s3: endpoint and bucket are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &Storage{
+		client: client,
+		cfg:    cfg,
+		creds: credentials{
+			accessKey: cfg.AccessKey,
+			secretKey: cfg.SecretKey,
+			region:    cfg.Region,
+		},
+		now: time.Now,
+	}, nil
+}
+
+func (s *Storage) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return err
+	}
+
+	// The payload hash is part of the signature, so the body has to be read
+	// up front. Objects stored here are small images and exports.
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(body)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.do(req, hex.EncodeToString(sum[:]))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
+
+func (s *Storage) Get(ctx context.Context, key string) (io.ReadCloser, *blob.Info, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := s.do(req, emptyPayload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := checkResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, nil, err
+	}
+
+	info := &blob.Info{
+		Key:         key,
+		ContentType: resp.Header.Get("Content-Type"),
+		Size:        resp.ContentLength,
+	}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = t
+	}
+
+	return resp.Body, info, nil
+}
+
+func (s *Storage) Delete(ctx context.Context, key string) error {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req, emptyPayload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// S3 answers 204 for missing keys too; keep Delete idempotent for
+	// servers that report 404 instead.
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	return checkResponse(resp)
+}
+
+type listResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List pages through ListObjectsV2. S3 does not report content types in
+// listings, so Info.ContentType is left empty.
+func (s *Storage) List(ctx context.Context, prefix string) ([]blob.Info, error) {
+	var (
+		res   []blob.Info
+		token string
+	)
+	for {
+		u := s.bucketURL()
+		q := url.Values{}
+		q.Set("list-type", "2")
+		q.Set("prefix", prefix)
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		u.RawQuery = q.Encode()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := s.do(req, emptyPayload)
+		if err != nil {
+			return nil, err
+		}
+
+		var page listResult
+		err = checkResponse(resp)
+		if err == nil {
+			err = xml.NewDecoder(resp.Body).Decode(&page)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range page.Contents {
+			res = append(res, blob.Info{Key: c.Key, Size: c.Size, ModTime: c.LastModified})
+		}
+
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return res, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+func (s *Storage) Presign(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+
+	if ttl > maxPresignTTL {
+		ttl = maxPresignTTL
+	}
+
+	return s.creds.presignURL(http.MethodGet, u, ttl, s.now()), nil
+}
+
+func (s *Storage) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.creds.signRequest(req, payloadHash, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3: %s %s: %w", req.Method, req.URL.Path, err)
+	}
+
+	return resp, nil
+}
+
+func (s *Storage) objectURL(key string) (*url.URL, error) {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean("/"+key) != "/"+key {
+		return nil, blob.ErrInvalidKey
+	}
+
+	u := s.bucketURL()
+	u.Path += key
+
+	return u, nil
+}
+
+// bucketURL addresses the bucket itself; its path ends with a slash.
+func (s *Storage) bucketURL() *url.URL {
+	scheme := "http"
+	if s.cfg.UseSSL {
+		scheme = "https"
+	}
+
+	u := &url.URL{Scheme: scheme}
+	if s.cfg.PathStyle {
+		u.Host = s.cfg.Endpoint
+		u.Path = "/" + s.cfg.Bucket + "/"
+	} else {
+		u.Host = s.cfg.Bucket + "." + s.cfg.Endpoint
+		u.Path = "/"
+	}
+
+	return u
+}
+
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return blob.ErrNotFound
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("s3: unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+}
@@ -3,16 +3,183 @@ package config
 import (
 	"flag"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	Env         string `yaml:"env" env-required:"true"`
-	Addr        string `yaml:"addr" env-required:"true"`
-	StoragePath string `yaml:"storage_path" env-required:"true"`
-	TokenTTL    string `yaml:"token_ttl" env-required:"true"`
-	Secret      string `yaml:"secret" env-required:"true"`
+	Env          string       `yaml:"env" env-required:"true"`
+	Addr         string       `yaml:"addr" env-required:"true"`
+	StoragePath  string       `yaml:"storage_path"`
+	Storage      Storage      `yaml:"storage"`
+	TokenTTL     string       `yaml:"token_ttl" env-required:"true"`
+	Secret       string       `yaml:"secret" env-required:"true"`
+	Events       Events       `yaml:"events"`
+	LinkHealth   LinkHealth   `yaml:"link_health"`
+	LinkPreview  LinkPreview  `yaml:"link_preview"`
+	Links        Links        `yaml:"links"`
+	Media        Media        `yaml:"media"`
+	Blob         Blob         `yaml:"blob"`
+	Trash        Trash        `yaml:"trash"`
+	Backup       Backup       `yaml:"backup"`
+	ProfileCache ProfileCache `yaml:"profile_cache"`
+	Admin        Admin        `yaml:"admin"`
+	HTTPCache    HTTPCache    `yaml:"http_cache"`
+}
+
+type Storage struct {
+	// Driver selects the database: "sqlite" (uses storage_path) or "postgres".
+	Driver   string   `yaml:"driver" env-default:"sqlite"`
+	SQLite   SQLite   `yaml:"sqlite"`
+	Postgres Postgres `yaml:"postgres"`
+	// MigrateOnStart applies the migrations embedded in the binary before
+	// the server starts.
+	MigrateOnStart bool `yaml:"migrate_on_start" env:"MIGRATE_ON_START" env-default:"false"`
+}
+
+type SQLite struct {
+	JournalMode string        `yaml:"journal_mode" env-default:"WAL"`
+	Synchronous string        `yaml:"synchronous" env-default:"NORMAL"`
+	BusyTimeout time.Duration `yaml:"busy_timeout" env-default:"5s"`
+	ForeignKeys bool          `yaml:"foreign_keys" env-default:"true"`
+	// CacheSize is in pages when positive and in KiB when negative.
+	CacheSize int `yaml:"cache_size" env-default:"-20000"`
+	// MaxOpenConns and MaxIdleConns size the read pool, 0 means one per CPU.
+	// Writes always share a single connection.
+	MaxOpenConns int `yaml:"max_open_conns" env-default:"0"`
+	MaxIdleConns int `yaml:"max_idle_conns" env-default:"0"`
+}
+
+type Postgres struct {
+	DSN               string        `yaml:"dsn" env:"POSTGRES_DSN"`
+	MaxConns          int32         `yaml:"max_conns" env-default:"10"`
+	MinConns          int32         `yaml:"min_conns" env-default:"0"`
+	MaxConnLifetime   time.Duration `yaml:"max_conn_lifetime" env-default:"1h"`
+	MaxConnIdleTime   time.Duration `yaml:"max_conn_idle_time" env-default:"30m"`
+	HealthCheckPeriod time.Duration `yaml:"health_check_period" env-default:"1m"`
+	ConnectTimeout    time.Duration `yaml:"connect_timeout" env-default:"5s"`
+}
+
+type Events struct {
+	Heartbeat      time.Duration `yaml:"heartbeat" env-default:"15s"`
+	BufferSize     int           `yaml:"buffer_size" env-default:"100"`
+	MaxConnections int           `yaml:"max_connections" env-default:"3"`
+	// Idle is how long the replay buffer of a user nobody listens to is
+	// kept after the last event.
+	Idle time.Duration `yaml:"idle" env-default:"10m"`
+}
+
+type LinkHealth struct {
+	Enabled         bool          `yaml:"enabled" env-default:"true"`
+	Interval        time.Duration `yaml:"interval" env-default:"1h"`
+	Timeout         time.Duration `yaml:"timeout" env-default:"10s"`
+	Concurrency     int           `yaml:"concurrency" env-default:"8"`
+	PerHostInterval time.Duration `yaml:"per_host_interval" env-default:"1s"`
+	MaxRedirects    int           `yaml:"max_redirects" env-default:"5"`
+	AllowPrivate    bool          `yaml:"allow_private" env-default:"false"`
+}
+
+type LinkPreview struct {
+	Enabled      bool          `yaml:"enabled" env-default:"false"`
+	Timeout      time.Duration `yaml:"timeout" env-default:"5s"`
+	MaxBytes     int64         `yaml:"max_bytes" env-default:"1048576"`
+	TTL          time.Duration `yaml:"ttl" env-default:"24h"`
+	MaxRedirects int           `yaml:"max_redirects" env-default:"5"`
+	AllowPrivate bool          `yaml:"allow_private" env-default:"false"`
+}
+
+type Trash struct {
+	// Retention is how long deleted links can be restored.
+	Retention     time.Duration `yaml:"retention" env-default:"720h"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env-default:"1h"`
+}
+
+type ProfileCache struct {
+	Enabled bool `yaml:"enabled" env-default:"true"`
+	// Driver is "memory", a cache per process, or "redis", shared by all
+	// instances.
+	Driver string        `yaml:"driver" env-default:"memory"`
+	Size   int           `yaml:"size" env-default:"10000"`
+	TTL    time.Duration `yaml:"ttl" env-default:"1m"`
+	Redis  Redis         `yaml:"redis"`
+}
+
+type Redis struct {
+	Addr     string `yaml:"addr" env:"REDIS_ADDR" env-default:"localhost:6379"`
+	Password string `yaml:"password" env:"REDIS_PASSWORD"`
+	DB       int    `yaml:"db" env-default:"0"`
+	Prefix   string `yaml:"prefix" env-default:"url_profile:"`
+}
+
+// Backup snapshots are kept in blob storage under backups/.
+type Backup struct {
+	// Keep is how many snapshots are kept, the oldest go first; 0 keeps all.
+	Keep int `yaml:"keep" env-default:"7"`
+	// TempDir stages a snapshot before upload; empty is the system temp dir.
+	TempDir string `yaml:"temp_dir" env:"BACKUP_TEMP_DIR"`
+}
+
+type Admin struct {
+	// Token guards /api/admin; the admin API is off while it is empty.
+	Token string `yaml:"token" env:"ADMIN_TOKEN"`
+}
+
+type HTTPCache struct {
+	// CacheControl maps route names (profile, my_profile, themes) to the
+	// Cache-Control header they send. Listed routes replace the defaults,
+	// an empty value sends none.
+	CacheControl map[string]string `yaml:"cache_control"`
+}
+
+// defaultCacheControl lets browsers and CDNs keep profiles but revalidate
+// them with the ETag on every view.
+var defaultCacheControl = map[string]string{
+	"profile":    "public, no-cache",
+	"my_profile": "private, no-cache",
+}
+
+type Links struct {
+	// AppSchemes extends the http/https/mailto/tel allowlist, e.g. ["tg", "spotify"].
+	AppSchemes []string `yaml:"app_schemes"`
+}
+
+type Media struct {
+	// Dir is where uploads were kept before blob storage existed. It is
+	// still the default of blob.local.dir so existing files stay reachable.
+	Dir            string `yaml:"dir" env-default:"./storage/media"`
+	BaseURL        string `yaml:"base_url" env-default:"/media"`
+	MaxUploadBytes int64  `yaml:"max_upload_bytes" env-default:"5242880"`
+}
+
+type Blob struct {
+	// Driver selects the backend: "local" or "s3".
+	Driver string    `yaml:"driver" env-default:"local"`
+	Local  LocalBlob `yaml:"local"`
+	S3     S3Blob    `yaml:"s3"`
+	// URLTTL is how long download URLs for exports and backups stay valid.
+	URLTTL time.Duration `yaml:"url_ttl" env-default:"15m"`
+}
+
+type LocalBlob struct {
+	// Dir defaults to media.dir.
+	Dir string `yaml:"dir"`
+	// PublicURL is where the app serves presigned downloads of local files.
+	PublicURL string `yaml:"public_url" env-default:"/files"`
+	// SigningKey signs those downloads. When empty a random key is used, so
+	// URLs stop working on restart and only work on the instance that
+	// issued them.
+	SigningKey string `yaml:"signing_key" env:"BLOB_SIGNING_KEY"`
+}
+
+type S3Blob struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region" env-default:"us-east-1"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key" env:"S3_ACCESS_KEY"`
+	SecretKey string `yaml:"secret_key" env:"S3_SECRET_KEY"`
+	UseSSL    bool   `yaml:"use_ssl" env-default:"true"`
+	PathStyle bool   `yaml:"path_style" env-default:"false"`
 }
 
 func MustLoad() *Config {
@@ -35,6 +202,40 @@ func MustLoadByPath(path string) *Config {
 		panic("Fail to parse config")
 	}
 
+	switch cfg.Storage.Driver {
+	case "sqlite":
+		if cfg.StoragePath == "" {
+			panic("storage_path is required for the sqlite driver")
+		}
+	case "postgres":
+		if cfg.Storage.Postgres.DSN == "" {
+			panic("storage.postgres.dsn is required for the postgres driver")
+		}
+	}
+
+	if cfg.Events.Heartbeat <= 0 {
+		panic("events.heartbeat must be positive")
+	}
+	if cfg.Events.BufferSize <= 0 {
+		panic("events.buffer_size must be positive")
+	}
+	if cfg.Events.MaxConnections < 0 {
+		panic("events.max_connections must not be negative")
+	}
+
+	if cfg.ProfileCache.Enabled && cfg.ProfileCache.Driver != "memory" && cfg.ProfileCache.Driver != "redis" {
+		panic("profile_cache.driver must be memory or redis")
+	}
+
+	if cfg.HTTPCache.CacheControl == nil {
+		cfg.HTTPCache.CacheControl = make(map[string]string)
+	}
+	for route, value := range defaultCacheControl {
+		if _, ok := cfg.HTTPCache.CacheControl[route]; !ok {
+			cfg.HTTPCache.CacheControl[route] = value
+		}
+	}
+
 	return &cfg
 }
 
@@ -1,9 +1,24 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type Link struct {
 	ID        int
 	UserID    int
+	Type      string
 	LinkName  string
 	LinkColor string
 	LinkPath  string
+	// Payload holds type specific data; empty for plain links.
+	Payload   json.RawMessage
+	SectionID *int
+	// Hidden links are shown to the owner only.
+	Hidden bool
+	// DeletedAt is set while the link sits in the trash.
+	DeletedAt *time.Time
+	// Version goes up on every change to the link.
+	Version int
 }
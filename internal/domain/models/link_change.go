@@ -0,0 +1,10 @@
+package models
+
+// LinkChange is one link of a batch edit: the link's new fields, or its
+// removal when Delete is set. A non-zero IfVersion makes the change
+// conditional on the link version.
+type LinkChange struct {
+	Link      Link
+	Delete    bool
+	IfVersion int
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+const (
+	LinkHealthOK          = "ok"
+	LinkHealthBroken      = "broken"
+	LinkHealthUnreachable = "unreachable"
+)
+
+type LinkHealth struct {
+	LinkID     int
+	Status     string
+	StatusCode int
+	Error      string
+	CheckedAt  time.Time
+	FailStreak int
+}
@@ -0,0 +1,8 @@
+package models
+
+// ImportedLink is one row of a bulk import. Section names the section the
+// link goes to; it is created if the user has none with that title.
+type ImportedLink struct {
+	Link    Link
+	Section string
+}
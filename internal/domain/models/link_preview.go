@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type LinkPreview struct {
+	URL         string
+	Title       string
+	Description string
+	Favicon     string
+	Image       string
+	FetchedAt   time.Time
+}
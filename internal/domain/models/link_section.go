@@ -0,0 +1,11 @@
+package models
+
+// LinkSection groups links under a header on the public profile. Links
+// without a section are shown above all sections.
+type LinkSection struct {
+	ID        int
+	UserID    int
+	Title     string
+	Position  int
+	Collapsed bool
+}
@@ -0,0 +1,15 @@
+package models
+
+const (
+	ImageAvatar = "avatar"
+	ImageBanner = "banner"
+)
+
+// ProfileImage points at a set of resized variants in blob storage. A new
+// upload gets a new Version so variant URLs can be cached forever.
+type ProfileImage struct {
+	UserID  int
+	Kind    string
+	Version string
+	Format  string
+}
@@ -0,0 +1,9 @@
+package models
+
+type Theme struct {
+	Preset      string
+	Background  string
+	Font        string
+	ButtonStyle string
+	LinkColor   string
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type User struct {
 	ID             int
 	Email          string
@@ -7,4 +9,11 @@ type User struct {
 	HashedPassword []byte
 	AboutText      string
 	Links          []Link
+	// Version goes up whenever the user changes anything the profile
+	// shows, and is what If-Match pins. PreviewVersion goes up when a link
+	// preview the profile shows is fetched anew. UpdatedAt moves with
+	// either.
+	Version        int
+	PreviewVersion int
+	UpdatedAt      time.Time
 }
@@ -0,0 +1,126 @@
+package color
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalid = errors.New("color must be a hex (#rgb, #rrggbb, #rrggbbaa), rgb()/rgba() or named CSS color")
+
+// Normalize validates a CSS color and returns it lower-cased with spaces
+// removed from rgb()/rgba() notation.
+func Normalize(s string) (string, error) {
+	c := strings.ToLower(strings.TrimSpace(s))
+
+	switch {
+	case strings.HasPrefix(c, "#"):
+		if isHex(c[1:]) {
+			return c, nil
+		}
+	case strings.HasPrefix(c, "rgba(") || strings.HasPrefix(c, "rgb("):
+		if res, ok := normalizeRGB(c); ok {
+			return res, nil
+		}
+	default:
+		if named[c] {
+			return c, nil
+		}
+	}
+
+	return "", ErrInvalid
+}
+
+func isHex(s string) bool {
+	switch len(s) {
+	case 3, 4, 6, 8:
+	default:
+		return false
+	}
+
+	for _, r := range s {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func normalizeRGB(c string) (string, bool) {
+	name, args, ok := strings.Cut(c, "(")
+	if !ok || !strings.HasSuffix(args, ")") {
+		return "", false
+	}
+
+	parts := strings.Split(strings.TrimSuffix(args, ")"), ",")
+	if len(parts) != 3 && len(parts) != 4 {
+		return "", false
+	}
+
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+		if i < 3 && !isChannel(parts[i]) {
+			return "", false
+		}
+		if i == 3 && !isAlpha(parts[i]) {
+			return "", false
+		}
+	}
+
+	return name + "(" + strings.Join(parts, ",") + ")", true
+}
+
+func isChannel(s string) bool {
+	if p, ok := strings.CutSuffix(s, "%"); ok {
+		v, err := strconv.ParseFloat(p, 64)
+		return err == nil && v >= 0 && v <= 100
+	}
+
+	v, err := strconv.Atoi(s)
+	return err == nil && v >= 0 && v <= 255
+}
+
+func isAlpha(s string) bool {
+	if p, ok := strings.CutSuffix(s, "%"); ok {
+		v, err := strconv.ParseFloat(p, 64)
+		return err == nil && v >= 0 && v <= 100
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	return err == nil && v >= 0 && v <= 1
+}
+
+var named = map[string]bool{
+	"transparent": true, "aliceblue": true, "antiquewhite": true, "aqua": true, "aquamarine": true,
+	"azure": true, "beige": true, "bisque": true, "black": true, "blanchedalmond": true, "blue": true,
+	"blueviolet": true, "brown": true, "burlywood": true, "cadetblue": true, "chartreuse": true,
+	"chocolate": true, "coral": true, "cornflowerblue": true, "cornsilk": true, "crimson": true,
+	"cyan": true, "darkblue": true, "darkcyan": true, "darkgoldenrod": true, "darkgray": true,
+	"darkgreen": true, "darkgrey": true, "darkkhaki": true, "darkmagenta": true, "darkolivegreen": true,
+	"darkorange": true, "darkorchid": true, "darkred": true, "darksalmon": true, "darkseagreen": true,
+	"darkslateblue": true, "darkslategray": true, "darkslategrey": true, "darkturquoise": true,
+	"darkviolet": true, "deeppink": true, "deepskyblue": true, "dimgray": true, "dimgrey": true,
+	"dodgerblue": true, "firebrick": true, "floralwhite": true, "forestgreen": true, "fuchsia": true,
+	"gainsboro": true, "ghostwhite": true, "gold": true, "goldenrod": true, "gray": true, "green": true,
+	"greenyellow": true, "grey": true, "honeydew": true, "hotpink": true, "indianred": true,
+	"indigo": true, "ivory": true, "khaki": true, "lavender": true, "lavenderblush": true,
+	"lawngreen": true, "lemonchiffon": true, "lightblue": true, "lightcoral": true, "lightcyan": true,
+	"lightgoldenrodyellow": true, "lightgray": true, "lightgreen": true, "lightgrey": true,
+	"lightpink": true, "lightsalmon": true, "lightseagreen": true, "lightskyblue": true,
+	"lightslategray": true, "lightslategrey": true, "lightsteelblue": true, "lightyellow": true,
+	"lime": true, "limegreen": true, "linen": true, "magenta": true, "maroon": true,
+	"mediumaquamarine": true, "mediumblue": true, "mediumorchid": true, "mediumpurple": true,
+	"mediumseagreen": true, "mediumslateblue": true, "mediumspringgreen": true, "mediumturquoise": true,
+	"mediumvioletred": true, "midnightblue": true, "mintcream": true, "mistyrose": true,
+	"moccasin": true, "navajowhite": true, "navy": true, "oldlace": true, "olive": true,
+	"olivedrab": true, "orange": true, "orangered": true, "orchid": true, "palegoldenrod": true,
+	"palegreen": true, "paleturquoise": true, "palevioletred": true, "papayawhip": true,
+	"peachpuff": true, "peru": true, "pink": true, "plum": true, "powderblue": true, "purple": true,
+	"rebeccapurple": true, "red": true, "rosybrown": true, "royalblue": true, "saddlebrown": true,
+	"salmon": true, "sandybrown": true, "seagreen": true, "seashell": true, "sienna": true,
+	"silver": true, "skyblue": true, "slateblue": true, "slategray": true, "slategrey": true,
+	"snow": true, "springgreen": true, "steelblue": true, "tan": true, "teal": true, "thistle": true,
+	"tomato": true, "turquoise": true, "violet": true, "wheat": true, "white": true,
+	"whitesmoke": true, "yellow": true, "yellowgreen": true,
+}
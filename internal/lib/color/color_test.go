@@ -0,0 +1,68 @@
+package color
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"#fff", "#fff"},
+		{"#FFFF", "#ffff"},
+		{" #A1b2C3 ", "#a1b2c3"},
+		{"#a1b2c3d4", "#a1b2c3d4"},
+		{"rgb(0, 128, 255)", "rgb(0,128,255)"},
+		{"RGBA( 10 ,20,30 , 0.5 )", "rgba(10,20,30,0.5)"},
+		{"rgb(100%,0%,50.5%)", "rgb(100%,0%,50.5%)"},
+		{"rgba(0,0,0,40%)", "rgba(0,0,0,40%)"},
+		{"rgba(0,0,0,1)", "rgba(0,0,0,1)"},
+		{"RebeccaPurple", "rebeccapurple"},
+		{"transparent", "transparent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Normalize(tt.in)
+			if err != nil {
+				t.Fatalf("Normalize(%q): %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeRejects(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"#",
+		"#ff",
+		"#fffff",
+		"#fffffffff",
+		"#ggg",
+		"fff",
+		"rgb(256,0,0)",
+		"rgb(-1,0,0)",
+		"rgb(0,0)",
+		"rgb(0,0,0,0,0)",
+		"rgb(0,0,0",
+		"rgb(1.5,0,0)",
+		"rgb(101%,0,0)",
+		"rgba(0,0,0,1.1)",
+		"rgba(0,0,0,-0.1)",
+		"hsl(0,0%,0%)",
+		"notacolor",
+		"red;background:url(x)",
+		"expression(alert(1))",
+	} {
+		t.Run(in, func(t *testing.T) {
+			if got, err := Normalize(in); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Normalize(%q) = %q, %v, want ErrInvalid", in, got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,455 @@
+// Package jsonpatch applies JSON Merge Patch (RFC 7396) and JSON Patch
+// (RFC 6902) documents to JSON values.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidPatch = errors.New("invalid patch document")
+	ErrPathNotFound = errors.New("path not found")
+	ErrTestFailed   = errors.New("test operation failed")
+)
+
+// MergePatch applies an RFC 7396 merge patch to doc: object members in the
+// patch replace those in doc, null members remove them, and any non-object
+// patch replaces doc as a whole.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target any
+	if len(bytes.TrimSpace(doc)) > 0 {
+		if err := unmarshal(doc, &target); err != nil {
+			return nil, fmt.Errorf("invalid document: %v", err)
+		}
+	}
+
+	var p any
+	if err := unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = merge(t[k], v)
+	}
+
+	return t
+}
+
+// operation is one member of an RFC 6902 patch. Value is nil when the
+// member is missing and holds "null" when it is set to null.
+type operation struct {
+	Op    string
+	Path  *string
+	From  *string
+	Value json.RawMessage
+}
+
+// UnmarshalJSON reads the members one by one: encoding/json would decode a
+// null value the same as a missing one and let a repeated member silently
+// win, which RFC 6902 treats as an invalid patch.
+func (op *operation) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil {
+		return err
+	} else if tok != json.Delim('{') {
+		return errors.New("operation must be an object")
+	}
+
+	seen := make(map[string]bool)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		name := tok.(string)
+		if seen[name] {
+			return fmt.Errorf("duplicate member %q", name)
+		}
+		seen[name] = true
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+
+		switch name {
+		case "op":
+			err = json.Unmarshal(raw, &op.Op)
+		case "path":
+			err = json.Unmarshal(raw, &op.Path)
+		case "from":
+			err = json.Unmarshal(raw, &op.From)
+		case "value":
+			op.Value = raw
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Apply applies an RFC 6902 patch, a JSON array of operations, to doc. The
+// operations are applied in order and the first failing one aborts the
+// patch; doc itself is never modified.
+func Apply(doc, patch []byte) ([]byte, error) {
+	var target any
+	if err := unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("invalid document: %v", err)
+	}
+
+	var ops []operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	for i, op := range ops {
+		var err error
+		if target, err = op.apply(target); err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %w", i, op.Op, err)
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+func (op operation) apply(doc any) (any, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: missing path", ErrInvalidPatch)
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: missing value", ErrInvalidPatch)
+		}
+		var v any
+		if err := unmarshal(op.Value, &v); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+
+		switch op.Op {
+		case "add":
+			return add(doc, path, v)
+		case "replace":
+			// The root always exists, so replacing it needs no remove.
+			if len(path) == 0 {
+				return v, nil
+			}
+			if doc, _, err = remove(doc, path); err != nil {
+				return nil, err
+			}
+			return add(doc, path, v)
+		default:
+			cur, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !equal(cur, v) {
+				return nil, fmt.Errorf("%w: %s", ErrTestFailed, *op.Path)
+			}
+			return doc, nil
+		}
+
+	case "remove":
+		doc, _, err = remove(doc, path)
+		return doc, err
+
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: missing from", ErrInvalidPatch)
+		}
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "copy" {
+			v, err := get(doc, from)
+			if err != nil {
+				return nil, err
+			}
+			return add(doc, path, clone(v))
+		}
+
+		if isPrefix(from, path) && len(from) < len(path) {
+			return nil, fmt.Errorf("%w: cannot move a value into itself", ErrInvalidPatch)
+		}
+		doc, v, err := remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, v)
+
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+	}
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into its unescaped tokens.
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if p[0] != '/' {
+		return nil, fmt.Errorf("%w: pointer %q must start with /", ErrInvalidPatch, p)
+	}
+
+	tokens := strings.Split(p[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+func get(node any, path []string) (any, error) {
+	for _, tok := range path {
+		switch n := node.(type) {
+		case map[string]any:
+			v, ok := n[tok]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrPathNotFound, tok)
+			}
+			node = v
+		case []any:
+			i, err := index(tok, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = n[i]
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrPathNotFound, tok)
+		}
+	}
+
+	return node, nil
+}
+
+// add sets the value at path, inserting into arrays, and returns the new
+// node: arrays may be reallocated, so parents store what children return.
+func add(node any, path []string, v any) (any, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+
+	tok, rest := path[0], path[1:]
+	switch n := node.(type) {
+	case map[string]any:
+		if len(rest) == 0 {
+			n[tok] = v
+			return n, nil
+		}
+		child, ok := n[tok]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrPathNotFound, tok)
+		}
+		c, err := add(child, rest, v)
+		if err != nil {
+			return nil, err
+		}
+		n[tok] = c
+		return n, nil
+
+	case []any:
+		if len(rest) == 0 {
+			i := len(n)
+			if tok != "-" {
+				var err error
+				if i, err = index(tok, len(n)); err != nil {
+					return nil, err
+				}
+			}
+			n = append(n, nil)
+			copy(n[i+1:], n[i:])
+			n[i] = v
+			return n, nil
+		}
+		i, err := index(tok, len(n)-1)
+		if err != nil {
+			return nil, err
+		}
+		c, err := add(n[i], rest, v)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = c
+		return n, nil
+
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrPathNotFound, tok)
+	}
+}
+
+// remove deletes the value at path and returns the new node together with
+// the removed value.
+func remove(node any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the document root", ErrInvalidPatch)
+	}
+
+	tok, rest := path[0], path[1:]
+	switch n := node.(type) {
+	case map[string]any:
+		child, ok := n[tok]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: %s", ErrPathNotFound, tok)
+		}
+		if len(rest) == 0 {
+			delete(n, tok)
+			return n, child, nil
+		}
+		c, removed, err := remove(child, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		n[tok] = c
+		return n, removed, nil
+
+	case []any:
+		i, err := index(tok, len(n)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rest) == 0 {
+			removed := n[i]
+			return append(n[:i], n[i+1:]...), removed, nil
+		}
+		c, removed, err := remove(n[i], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		n[i] = c
+		return n, removed, nil
+
+	default:
+		return nil, nil, fmt.Errorf("%w: %s", ErrPathNotFound, tok)
+	}
+}
+
+// index parses an array index token, which must be a canonical decimal no
+// greater than max.
+func index(tok string, max int) (int, error) {
+	if tok == "" || (len(tok) > 1 && tok[0] == '0') || strings.TrimLeft(tok, "0123456789") != "" {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrPathNotFound, tok)
+	}
+
+	i, err := strconv.Atoi(tok)
+	if err != nil || i > max {
+		return 0, fmt.Errorf("%w: array index %s out of range", ErrPathNotFound, tok)
+	}
+
+	return i, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// equal compares JSON values, treating numbers by value so 1 and 1.0 match.
+func equal(a, b any) bool {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fx, errx := x.Float64()
+		fy, erry := y.Float64()
+		return errx == nil && erry == nil && fx == fy
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+func clone(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(x))
+		for k, v := range x {
+			c[k] = clone(v)
+		}
+		return c
+	case []any:
+		c := make([]any, len(x))
+		for i, v := range x {
+			c[i] = clone(v)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// unmarshal decodes a single JSON value, keeping numbers as written.
+func unmarshal(data []byte, v *any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after JSON value")
+	}
+	return nil
+}
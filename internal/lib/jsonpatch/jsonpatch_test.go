@@ -0,0 +1,213 @@
+package jsonpatch
+
+import (
+	"errors"
+	"testing"
+)
+
+// jsonEqual compares two JSON texts by value.
+func jsonEqual(t *testing.T, got, want []byte) bool {
+	t.Helper()
+
+	var g, w any
+	if err := unmarshal(got, &g); err != nil {
+		t.Fatalf("result %s: %v", got, err)
+	}
+	if err := unmarshal(want, &w); err != nil {
+		t.Fatalf("want %s: %v", want, err)
+	}
+	return equal(g, w)
+}
+
+// TestApplyRFC6902 runs the examples of RFC 6902 Appendix A, then the
+// cases they leave out.
+func TestApplyRFC6902(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr error
+	}{
+		{"A.1 adding an object member",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux"}]`,
+			`{"baz":"qux","foo":"bar"}`, nil},
+		{"A.2 adding an array element",
+			`{"foo":["bar","baz"]}`,
+			`[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			`{"foo":["bar","qux","baz"]}`, nil},
+		{"A.3 removing an object member",
+			`{"baz":"qux","foo":"bar"}`,
+			`[{"op":"remove","path":"/baz"}]`,
+			`{"foo":"bar"}`, nil},
+		{"A.4 removing an array element",
+			`{"foo":["bar","qux","baz"]}`,
+			`[{"op":"remove","path":"/foo/1"}]`,
+			`{"foo":["bar","baz"]}`, nil},
+		{"A.5 replacing a value",
+			`{"baz":"qux","foo":"bar"}`,
+			`[{"op":"replace","path":"/baz","value":"boo"}]`,
+			`{"baz":"boo","foo":"bar"}`, nil},
+		{"A.6 moving a value",
+			`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`, nil},
+		{"A.7 moving an array element",
+			`{"foo":["all","grass","cows","eat"]}`,
+			`[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			`{"foo":["all","cows","eat","grass"]}`, nil},
+		{"A.8 testing a value: success",
+			`{"baz":"qux","foo":["a",2,"c"]}`,
+			`[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`, nil},
+		{"A.9 testing a value: error",
+			`{"baz":"qux"}`,
+			`[{"op":"test","path":"/baz","value":"bar"}]`,
+			``, ErrTestFailed},
+		{"A.10 adding a nested member object",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/child","value":{"grandchild":{}}}]`,
+			`{"foo":"bar","child":{"grandchild":{}}}`, nil},
+		{"A.11 ignoring unrecognized elements",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux","xyz":123}]`,
+			`{"foo":"bar","baz":"qux"}`, nil},
+		{"A.12 adding to a nonexistent target",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+			``, ErrPathNotFound},
+		{"A.13 invalid JSON patch document",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux","op":"remove"}]`,
+			``, ErrInvalidPatch},
+		{"A.14 ~ escape ordering",
+			`{"/":9,"~1":10}`,
+			`[{"op":"test","path":"/~01","value":10}]`,
+			`{"/":9,"~1":10}`, nil},
+		{"A.15 comparing strings and numbers",
+			`{"/":9,"~1":10}`,
+			`[{"op":"test","path":"/~01","value":"10"}]`,
+			``, ErrTestFailed},
+		{"A.16 adding an array value",
+			`{"foo":["bar"]}`,
+			`[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+			`{"foo":["bar",["abc","def"]]}`, nil},
+
+		{"add null",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":null}]`,
+			`{"foo":"bar","baz":null}`, nil},
+		{"replace with null",
+			`{"foo":"bar"}`,
+			`[{"op":"replace","path":"/foo","value":null}]`,
+			`{"foo":null}`, nil},
+		{"test null",
+			`{"foo":null}`,
+			`[{"op":"test","path":"/foo","value":null}]`,
+			`{"foo":null}`, nil},
+		{"test null against a value",
+			`{"foo":0}`,
+			`[{"op":"test","path":"/foo","value":null}]`,
+			``, ErrTestFailed},
+		{"missing value",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz"}]`,
+			``, ErrInvalidPatch},
+		{"replace the root",
+			`{"foo":"bar"}`,
+			`[{"op":"replace","path":"","value":[1,2]}]`,
+			`[1,2]`, nil},
+		{"add the root",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"","value":{"baz":1}}]`,
+			`{"baz":1}`, nil},
+		{"remove the root",
+			`{"foo":"bar"}`,
+			`[{"op":"remove","path":""}]`,
+			``, ErrInvalidPatch},
+		{"replace a missing member",
+			`{"foo":"bar"}`,
+			`[{"op":"replace","path":"/baz","value":1}]`,
+			``, ErrPathNotFound},
+		{"copy",
+			`{"foo":{"bar":1}}`,
+			`[{"op":"copy","from":"/foo","path":"/baz"},{"op":"replace","path":"/baz/bar","value":2}]`,
+			`{"foo":{"bar":1},"baz":{"bar":2}}`, nil},
+		{"move into itself",
+			`{"foo":{"bar":1}}`,
+			`[{"op":"move","from":"/foo","path":"/foo/bar"}]`,
+			``, ErrInvalidPatch},
+		{"numbers by value",
+			`{"foo":1}`,
+			`[{"op":"test","path":"/foo","value":1.0}]`,
+			`{"foo":1}`, nil},
+		{"leading zero index",
+			`{"foo":["a","b"]}`,
+			`[{"op":"remove","path":"/foo/01"}]`,
+			``, ErrPathNotFound},
+		{"unknown op",
+			`{}`,
+			`[{"op":"merge","path":"/foo","value":1}]`,
+			``, ErrInvalidPatch},
+		{"missing path",
+			`{}`,
+			`[{"op":"add","value":1}]`,
+			``, ErrInvalidPatch},
+		{"failed op aborts the patch",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":1},{"op":"test","path":"/baz","value":2}]`,
+			``, ErrTestFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Apply() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !jsonEqual(t, got, []byte(tt.want)) {
+				t.Errorf("Apply() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMergePatchRFC7396 runs the examples of RFC 7396 Appendix A.
+func TestMergePatchRFC7396(t *testing.T) {
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.doc+" "+tt.patch, func(t *testing.T) {
+			got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("MergePatch: %v", err)
+			}
+			if !jsonEqual(t, got, []byte(tt.want)) {
+				t.Errorf("MergePatch() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := MergePatch([]byte(`{}`), []byte(`{`)); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("MergePatch with a broken patch: got %v, want ErrInvalidPatch", err)
+	}
+}
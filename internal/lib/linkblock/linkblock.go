@@ -0,0 +1,247 @@
+// Package linkblock validates the typed blocks a profile is built from. Each
+// block keeps a type discriminator and a JSON payload whose shape depends on
+// the type; plain links have an empty payload.
+package linkblock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+	"url_profile/internal/lib/linkurl"
+)
+
+const (
+	TypeLink   = "link"
+	TypeEmail  = "email"
+	TypePhone  = "phone"
+	TypeSocial = "social"
+	TypeVideo  = "video"
+	TypeText   = "text"
+	TypeVCard  = "vcard"
+)
+
+// Render hints tell clients how to draw a block.
+const (
+	RenderButton   = "button"
+	RenderIconRow  = "icon_row"
+	RenderEmbed    = "embed"
+	RenderHeading  = "heading"
+	RenderText     = "text"
+	RenderDownload = "download"
+)
+
+var Types = []string{TypeLink, TypeEmail, TypePhone, TypeSocial, TypeVideo, TypeText, TypeVCard}
+
+var ErrUnknownType = fmt.Errorf("type must be one of %v", Types)
+
+// FieldError reports which part of the block is invalid, e.g. "link_path" or
+// "payload.address".
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string { return e.Field + ": " + e.Err.Error() }
+
+func (e *FieldError) Unwrap() error { return e.Err }
+
+type Block struct {
+	Type    string
+	Name    string
+	Path    string
+	Payload json.RawMessage
+}
+
+// Normalize validates b according to its type and returns it in canonical
+// form. Path is derived from the payload for every type except plain links.
+// Errors are *FieldError, possibly joined.
+func Normalize(b Block, v *linkurl.Validator, opts linkurl.Options) (Block, error) {
+	b.Type = strings.ToLower(strings.TrimSpace(b.Type))
+	if b.Type == "" {
+		b.Type = TypeLink
+	}
+	b.Name = strings.TrimSpace(b.Name)
+
+	switch b.Type {
+	case TypeLink:
+		return normalizeLink(b, v, opts)
+	case TypeEmail:
+		return normalizeEmail(b, v)
+	case TypePhone:
+		return normalizePhone(b, v)
+	case TypeSocial:
+		return normalizeSocial(b)
+	case TypeVideo:
+		return normalizeVideo(b, v, opts)
+	case TypeText:
+		return normalizeText(b)
+	case TypeVCard:
+		return normalizeVCard(b, v)
+	default:
+		return b, &FieldError{Field: "type", Err: ErrUnknownType}
+	}
+}
+
+// Render returns the render hint for a stored block.
+func Render(typ string, payload json.RawMessage) string {
+	switch typ {
+	case TypeSocial:
+		return RenderIconRow
+	case TypeVideo:
+		return RenderEmbed
+	case TypeVCard:
+		return RenderDownload
+	case TypeText:
+		var p TextPayload
+		if json.Unmarshal(payload, &p) == nil && p.Style == TextParagraph {
+			return RenderText
+		}
+		return RenderHeading
+	default:
+		return RenderButton
+	}
+}
+
+// Followable reports whether visitors can be redirected to the block's path.
+func Followable(typ string) bool {
+	switch typ {
+	case TypeSocial, TypeText, TypeVCard:
+		return false
+	default:
+		return true
+	}
+}
+
+func normalizeLink(b Block, v *linkurl.Validator, opts linkurl.Options) (Block, error) {
+	path, err := v.Normalize(b.Path, opts)
+	if err != nil {
+		return b, &FieldError{Field: "link_path", Err: err}
+	}
+
+	b.Path = path
+	b.Payload = nil
+	return b, nil
+}
+
+type EmailPayload struct {
+	Address string `json:"address"`
+	Subject string `json:"subject,omitempty"`
+}
+
+func normalizeEmail(b Block, v *linkurl.Validator) (Block, error) {
+	var p EmailPayload
+	if err := decode(b.Payload, &p); err != nil {
+		return b, err
+	}
+
+	p.Address = strings.TrimPrefix(strings.TrimSpace(p.Address), "mailto:")
+	p.Subject = strings.TrimSpace(p.Subject)
+
+	path, err := v.Normalize("mailto:"+p.Address, linkurl.Options{})
+	if err != nil || strings.Contains(p.Address, "?") {
+		return b, &FieldError{Field: "payload.address", Err: linkurl.ErrEmail}
+	}
+
+	if b.Name == "" {
+		b.Name = p.Address
+	}
+	p.Address = strings.TrimPrefix(path, "mailto:")
+
+	if p.Subject != "" {
+		path += "?subject=" + strings.ReplaceAll(url.QueryEscape(p.Subject), "+", "%20")
+	}
+
+	b.Path = path
+	return withPayload(b, p)
+}
+
+type PhonePayload struct {
+	Number string `json:"number"`
+}
+
+func normalizePhone(b Block, v *linkurl.Validator) (Block, error) {
+	var p PhonePayload
+	if err := decode(b.Payload, &p); err != nil {
+		return b, err
+	}
+
+	path, err := v.Normalize("tel:"+strings.TrimPrefix(strings.TrimSpace(p.Number), "tel:"), linkurl.Options{})
+	if err != nil {
+		return b, &FieldError{Field: "payload.number", Err: err}
+	}
+
+	if b.Name == "" {
+		b.Name = strings.TrimSpace(p.Number)
+	}
+	p.Number = strings.TrimPrefix(path, "tel:")
+	b.Path = path
+	return withPayload(b, p)
+}
+
+type TextPayload struct {
+	Text  string `json:"text"`
+	Style string `json:"style"`
+}
+
+const (
+	TextHeading   = "heading"
+	TextParagraph = "paragraph"
+
+	maxTextLen = 500
+)
+
+func normalizeText(b Block) (Block, error) {
+	var p TextPayload
+	if err := decode(b.Payload, &p); err != nil {
+		return b, err
+	}
+
+	p.Text = strings.TrimSpace(p.Text)
+	if p.Text == "" {
+		return b, &FieldError{Field: "payload.text", Err: errors.New("text is required")}
+	}
+	if utf8.RuneCountInString(p.Text) > maxTextLen {
+		return b, &FieldError{Field: "payload.text", Err: fmt.Errorf("text must be at most %d characters", maxTextLen)}
+	}
+
+	switch p.Style {
+	case "":
+		p.Style = TextHeading
+	case TextHeading, TextParagraph:
+	default:
+		return b, &FieldError{Field: "payload.style", Err: fmt.Errorf("style must be %q or %q", TextHeading, TextParagraph)}
+	}
+
+	b.Path = ""
+	return withPayload(b, p)
+}
+
+// decode parses a payload strictly so typos in field names are reported
+// instead of silently ignored.
+func decode(raw json.RawMessage, dst any) error {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		raw = json.RawMessage("{}")
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return &FieldError{Field: "payload", Err: fmt.Errorf("invalid payload: %v", err)}
+	}
+
+	return nil
+}
+
+func withPayload(b Block, p any) (Block, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return b, &FieldError{Field: "payload", Err: err}
+	}
+
+	b.Payload = raw
+	return b, nil
+}
@@ -0,0 +1,263 @@
+package linkblock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"url_profile/internal/lib/linkurl"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+func TestNormalize(t *testing.T) {
+	v := linkurl.New([]string{"tg"})
+
+	tests := []struct {
+		name        string
+		in          Block
+		opts        linkurl.Options
+		wantName    string
+		wantPath    string
+		wantPayload string
+		wantFields  []string
+	}{
+		// link
+		{name: "link", in: Block{Name: " Site ", Path: "https://example.com"},
+			wantName: "Site", wantPath: "https://example.com"},
+		{name: "link type case", in: Block{Type: " LINK ", Name: "Site", Path: "https://example.com"},
+			wantName: "Site", wantPath: "https://example.com"},
+		{name: "link drops payload", in: Block{Name: "Site", Path: "https://example.com", Payload: json.RawMessage(`{"x":1}`)},
+			wantName: "Site", wantPath: "https://example.com"},
+		{name: "link strips tracking", in: Block{Name: "Site", Path: "https://example.com/?a=1&gclid=x"}, opts: linkurl.Options{StripTracking: true},
+			wantName: "Site", wantPath: "https://example.com/?a=1"},
+		{name: "link app scheme", in: Block{Name: "Tg", Path: "tg://resolve?domain=me"},
+			wantName: "Tg", wantPath: "tg://resolve?domain=me"},
+		{name: "link bad scheme", in: Block{Name: "Js", Path: "javascript:alert(1)"}, wantFields: []string{"link_path"}},
+		{name: "link empty", in: Block{Name: "Empty"}, wantFields: []string{"link_path"}},
+
+		// email
+		{name: "email", in: Block{Type: TypeEmail, Payload: json.RawMessage(`{"address":" mailto:Me@Example.com "}`)},
+			wantName: "Me@Example.com", wantPath: "mailto:Me@example.com", wantPayload: `{"address":"Me@example.com"}`},
+		{name: "email subject", in: Block{Type: TypeEmail, Name: "Write me", Payload: json.RawMessage(`{"address":"me@example.com","subject":"Hi there \u0026 bye"}`)},
+			wantName: "Write me", wantPath: "mailto:me@example.com?subject=Hi%20there%20%26%20bye",
+			wantPayload: `{"address":"me@example.com","subject":"Hi there \u0026 bye"}`},
+		{name: "email no address", in: Block{Type: TypeEmail, Payload: json.RawMessage(`{}`)}, wantFields: []string{"payload.address"}},
+		{name: "email not an address", in: Block{Type: TypeEmail, Payload: json.RawMessage(`{"address":"nobody"}`)}, wantFields: []string{"payload.address"}},
+		{name: "email smuggled query", in: Block{Type: TypeEmail, Payload: json.RawMessage(`{"address":"me@example.com?cc=x@example.com"}`)}, wantFields: []string{"payload.address"}},
+		{name: "email unknown field", in: Block{Type: TypeEmail, Payload: json.RawMessage(`{"adress":"me@example.com"}`)}, wantFields: []string{"payload"}},
+
+		// phone
+		{name: "phone", in: Block{Type: TypePhone, Payload: json.RawMessage(`{"number":"+7 999 123-45-67"}`)},
+			wantName: "+7 999 123-45-67", wantPath: "tel:+79991234567", wantPayload: `{"number":"+79991234567"}`},
+		{name: "phone tel prefix", in: Block{Type: TypePhone, Name: "Call", Payload: json.RawMessage(`{"number":"tel:+79991234567"}`)},
+			wantName: "Call", wantPath: "tel:+79991234567", wantPayload: `{"number":"+79991234567"}`},
+		{name: "phone letters", in: Block{Type: TypePhone, Payload: json.RawMessage(`{"number":"call me"}`)}, wantFields: []string{"payload.number"}},
+		{name: "phone empty", in: Block{Type: TypePhone}, wantFields: []string{"payload.number"}},
+
+		// social
+		{name: "social", in: Block{Type: TypeSocial, Name: "Me", Path: "https://ignored.example", Payload: json.RawMessage(`{"items":[{"platform":" GitHub ","handle":"@octo"},{"platform":"twitter","handle":"octo.cat"}]}`)},
+			wantName: "Me",
+			wantPayload: `{"items":[{"platform":"github","handle":"octo","url":"https://github.com/octo"},` +
+				`{"platform":"twitter","handle":"octo.cat","url":"https://x.com/octo.cat"}]}`},
+		{name: "social url is derived", in: Block{Type: TypeSocial, Payload: json.RawMessage(`{"items":[{"platform":"vk","handle":"me","url":"https://evil.example"}]}`)},
+			wantPayload: `{"items":[{"platform":"vk","handle":"me","url":"https://vk.com/me"}]}`},
+		{name: "social no items", in: Block{Type: TypeSocial, Payload: json.RawMessage(`{"items":[]}`)}, wantFields: []string{"payload.items"}},
+		{name: "social too many items", in: Block{Type: TypeSocial, Payload: json.RawMessage(`{"items":[` + strings.Repeat(`{},`, maxSocialItems) + `{}]}`)},
+			wantFields: []string{"payload.items"}},
+		{name: "social bad items", in: Block{Type: TypeSocial, Payload: json.RawMessage(`{"items":[{"platform":"myspace","handle":"me"},{"platform":"github","handle":"a/b"},{"platform":"gitlab","handle":"me"},{"platform":"GITLAB","handle":"me"}]}`)},
+			wantFields: []string{"payload.items[0].platform", "payload.items[1].handle", "payload.items[3].platform"}},
+
+		// video
+		{name: "youtube watch", in: Block{Type: TypeVideo, Payload: json.RawMessage(`{"url":"https://m.youtube.com/watch?v=dQw4w9WgXcQ&t=1"}`)},
+			wantName: "Video", wantPath: "https://m.youtube.com/watch?v=dQw4w9WgXcQ&t=1",
+			wantPayload: `{"url":"https://m.youtube.com/watch?v=dQw4w9WgXcQ\u0026t=1","provider":"youtube","embed_url":"https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ"}`},
+		{name: "youtube short link", in: Block{Type: TypeVideo, Name: "Clip", Payload: json.RawMessage(`{"url":"https://youtu.be/dQw4w9WgXcQ"}`)},
+			wantName: "Clip", wantPath: "https://youtu.be/dQw4w9WgXcQ",
+			wantPayload: `{"url":"https://youtu.be/dQw4w9WgXcQ","provider":"youtube","embed_url":"https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ"}`},
+		{name: "youtube shorts", in: Block{Type: TypeVideo, Payload: json.RawMessage(`{"url":"https://www.youtube.com/shorts/dQw4w9WgXcQ"}`)},
+			wantName: "Video", wantPath: "https://www.youtube.com/shorts/dQw4w9WgXcQ",
+			wantPayload: `{"url":"https://www.youtube.com/shorts/dQw4w9WgXcQ","provider":"youtube","embed_url":"https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ"}`},
+		{name: "vimeo", in: Block{Type: TypeVideo, Payload: json.RawMessage(`{"url":"https://vimeo.com/76979871","provider":"youtube","embed_url":"https://evil.example"}`)},
+			wantName: "Video", wantPath: "https://vimeo.com/76979871",
+			wantPayload: `{"url":"https://vimeo.com/76979871","provider":"vimeo","embed_url":"https://player.vimeo.com/video/76979871"}`},
+		{name: "video other host", in: Block{Type: TypeVideo, Payload: json.RawMessage(`{"url":"https://example.com/watch?v=dQw4w9WgXcQ"}`)}, wantFields: []string{"payload.url"}},
+		{name: "video bad id", in: Block{Type: TypeVideo, Payload: json.RawMessage(`{"url":"https://youtu.be/short"}`)}, wantFields: []string{"payload.url"}},
+		{name: "video youtube channel", in: Block{Type: TypeVideo, Payload: json.RawMessage(`{"url":"https://www.youtube.com/@someone"}`)}, wantFields: []string{"payload.url"}},
+		{name: "video app scheme", in: Block{Type: TypeVideo, Payload: json.RawMessage(`{"url":"tg://resolve?domain=me"}`)}, wantFields: []string{"payload.url"}},
+		{name: "video no url", in: Block{Type: TypeVideo}, wantFields: []string{"payload.url"}},
+
+		// text
+		{name: "text", in: Block{Type: TypeText, Name: "About", Path: "https://ignored.example", Payload: json.RawMessage(`{"text":"  Hello  "}`)},
+			wantName: "About", wantPayload: `{"text":"Hello","style":"heading"}`},
+		{name: "text paragraph", in: Block{Type: TypeText, Payload: json.RawMessage(`{"text":"Hello","style":"paragraph"}`)},
+			wantPayload: `{"text":"Hello","style":"paragraph"}`},
+		{name: "text at limit", in: Block{Type: TypeText, Payload: json.RawMessage(`{"text":"` + strings.Repeat("я", maxTextLen) + `"}`)},
+			wantPayload: `{"text":"` + strings.Repeat("я", maxTextLen) + `","style":"heading"}`},
+		{name: "text too long", in: Block{Type: TypeText, Payload: json.RawMessage(`{"text":"` + strings.Repeat("я", maxTextLen+1) + `"}`)}, wantFields: []string{"payload.text"}},
+		{name: "text blank", in: Block{Type: TypeText, Payload: json.RawMessage(`{"text":"   "}`)}, wantFields: []string{"payload.text"}},
+		{name: "text bad style", in: Block{Type: TypeText, Payload: json.RawMessage(`{"text":"Hello","style":"bold"}`)}, wantFields: []string{"payload.style"}},
+		{name: "text not an object", in: Block{Type: TypeText, Payload: json.RawMessage(`"Hello"`)}, wantFields: []string{"payload"}},
+
+		// vcard
+		{name: "vcard", in: Block{Type: TypeVCard, Path: "https://ignored.example", Payload: json.RawMessage(`{"full_name":" Ivan Petrov ","email":"Ivan@Example.com","phone":"+7 999 123-45-67","url":"https://example.com"}`)},
+			wantName:    "Ivan Petrov",
+			wantPayload: `{"full_name":"Ivan Petrov","email":"Ivan@example.com","phone":"+79991234567","url":"https://example.com"}`},
+		{name: "vcard no name", in: Block{Type: TypeVCard, Payload: json.RawMessage(`{"org":"Acme"}`)}, wantFields: []string{"payload.full_name"}},
+		{name: "vcard bad fields", in: Block{Type: TypeVCard, Payload: json.RawMessage(`{"full_name":"Ivan","email":"nobody","phone":"call me","url":"javascript:alert(1)","note":"` + strings.Repeat("x", maxVCardField+1) + `"}`)},
+			wantFields: []string{"payload.note", "payload.email", "payload.phone", "payload.url"}},
+
+		{name: "unknown type", in: Block{Type: "carousel"}, wantFields: []string{"type"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.in, v, tt.opts)
+
+			if tt.wantFields != nil {
+				if fields := errorFields(err); !slices.Equal(fields, tt.wantFields) {
+					t.Fatalf("error fields = %q, want %q (err: %v)", fields, tt.wantFields, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize() error = %v", err)
+			}
+
+			if got.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", got.Name, tt.wantName)
+			}
+			if got.Path != tt.wantPath {
+				t.Errorf("Path = %q, want %q", got.Path, tt.wantPath)
+			}
+			if string(got.Payload) != tt.wantPayload {
+				t.Errorf("Payload = %s, want %s", got.Payload, tt.wantPayload)
+			}
+		})
+	}
+}
+
+func TestNormalizeIsIdempotent(t *testing.T) {
+	v := linkurl.New(nil)
+
+	blocks := []Block{
+		{Type: TypeEmail, Payload: json.RawMessage(`{"address":"me@example.com","subject":"Hi"}`)},
+		{Type: TypePhone, Payload: json.RawMessage(`{"number":"+7 999 123-45-67"}`)},
+		{Type: TypeSocial, Payload: json.RawMessage(`{"items":[{"platform":"github","handle":"@octo"}]}`)},
+		{Type: TypeVideo, Payload: json.RawMessage(`{"url":"https://youtu.be/dQw4w9WgXcQ"}`)},
+		{Type: TypeText, Payload: json.RawMessage(`{"text":"Hello"}`)},
+		{Type: TypeVCard, Payload: json.RawMessage(`{"full_name":"Ivan","phone":"+7 999 123-45-67"}`)},
+	}
+
+	// Stored blocks are normalized again when they are edited, so a second
+	// pass must not change them.
+	for _, b := range blocks {
+		once, err := Normalize(b, v, linkurl.Options{})
+		if err != nil {
+			t.Fatalf("%s: %v", b.Type, err)
+		}
+		twice, err := Normalize(once, v, linkurl.Options{})
+		if err != nil {
+			t.Fatalf("%s: second pass: %v", b.Type, err)
+		}
+		if twice.Name != once.Name || twice.Path != once.Path || !bytes.Equal(twice.Payload, once.Payload) {
+			t.Errorf("%s: %+v changed to %+v", b.Type, once, twice)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		typ        string
+		payload    string
+		want       string
+		followable bool
+	}{
+		{TypeLink, "", RenderButton, true},
+		{TypeEmail, `{"address":"me@example.com"}`, RenderButton, true},
+		{TypePhone, `{"number":"+79991234567"}`, RenderButton, true},
+		{TypeSocial, `{"items":[]}`, RenderIconRow, false},
+		{TypeVideo, `{"url":"https://youtu.be/dQw4w9WgXcQ"}`, RenderEmbed, true},
+		{TypeText, `{"text":"Hi","style":"heading"}`, RenderHeading, false},
+		{TypeText, `{"text":"Hi","style":"paragraph"}`, RenderText, false},
+		{TypeText, `broken`, RenderHeading, false},
+		{TypeVCard, `{"full_name":"Ivan"}`, RenderDownload, false},
+	}
+
+	for _, tt := range tests {
+		if got := Render(tt.typ, json.RawMessage(tt.payload)); got != tt.want {
+			t.Errorf("Render(%q, %s) = %q, want %q", tt.typ, tt.payload, got, tt.want)
+		}
+		if got := Followable(tt.typ); got != tt.followable {
+			t.Errorf("Followable(%q) = %v, want %v", tt.typ, got, tt.followable)
+		}
+	}
+}
+
+func TestVCard(t *testing.T) {
+	tests := []struct {
+		name string
+		p    VCardPayload
+	}{
+		{"minimal", VCardPayload{FullName: "Cher"}},
+		{"full", VCardPayload{
+			FullName: "Ivan Ivanovich Petrov",
+			Org:      "Acme; Sons, Ltd",
+			Title:    `Head of R\D`,
+			Email:    "ivan@example.com",
+			Phone:    "+79991234567",
+			URL:      "https://example.com/a,b;c",
+			Note:     "First line\nsecond line\r\nthird",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.p.VCard()
+			golden := filepath.Join("testdata", tt.name+".vcf")
+
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("VCard() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+// errorFields lists the fields of the joined *FieldError values in err.
+func errorFields(err error) []string {
+	if err == nil {
+		return nil
+	}
+
+	var errs []error
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = j.Unwrap()
+	} else {
+		errs = []error{err}
+	}
+
+	res := make([]string, 0, len(errs))
+	for _, e := range errs {
+		var fe *FieldError
+		if !errors.As(e, &fe) {
+			return append(res, "<"+e.Error()+">")
+		}
+		res = append(res, fe.Field)
+	}
+	return res
+}
@@ -0,0 +1,103 @@
+package linkblock
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const maxSocialItems = 12
+
+// platforms maps a known platform to its profile URL template.
+var platforms = map[string]string{
+	"github":     "https://github.com/%s",
+	"gitlab":     "https://gitlab.com/%s",
+	"x":          "https://x.com/%s",
+	"twitter":    "https://x.com/%s",
+	"instagram":  "https://www.instagram.com/%s",
+	"facebook":   "https://www.facebook.com/%s",
+	"linkedin":   "https://www.linkedin.com/in/%s",
+	"youtube":    "https://www.youtube.com/@%s",
+	"tiktok":     "https://www.tiktok.com/@%s",
+	"twitch":     "https://www.twitch.tv/%s",
+	"telegram":   "https://t.me/%s",
+	"vk":         "https://vk.com/%s",
+	"reddit":     "https://www.reddit.com/user/%s",
+	"pinterest":  "https://www.pinterest.com/%s",
+	"spotify":    "https://open.spotify.com/user/%s",
+	"soundcloud": "https://soundcloud.com/%s",
+	"behance":    "https://www.behance.net/%s",
+	"dribbble":   "https://dribbble.com/%s",
+}
+
+var handleRe = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// Platforms returns the supported social platforms in alphabetical order.
+func Platforms() []string {
+	res := make([]string, 0, len(platforms))
+	for p := range platforms {
+		res = append(res, p)
+	}
+	sort.Strings(res)
+	return res
+}
+
+type SocialItem struct {
+	Platform string `json:"platform"`
+	Handle   string `json:"handle"`
+	URL      string `json:"url"`
+}
+
+type SocialPayload struct {
+	Items []SocialItem `json:"items"`
+}
+
+func normalizeSocial(b Block) (Block, error) {
+	var p SocialPayload
+	if err := decode(b.Payload, &p); err != nil {
+		return b, err
+	}
+
+	if len(p.Items) == 0 {
+		return b, &FieldError{Field: "payload.items", Err: errors.New("at least one item is required")}
+	}
+	if len(p.Items) > maxSocialItems {
+		return b, &FieldError{Field: "payload.items", Err: fmt.Errorf("at most %d items are allowed", maxSocialItems)}
+	}
+
+	var errs []error
+	seen := make(map[string]bool, len(p.Items))
+	for i := range p.Items {
+		it := &p.Items[i]
+		field := fmt.Sprintf("payload.items[%d]", i)
+
+		it.Platform = strings.ToLower(strings.TrimSpace(it.Platform))
+		tpl, ok := platforms[it.Platform]
+		if !ok {
+			errs = append(errs, &FieldError{Field: field + ".platform", Err: fmt.Errorf("platform must be one of %v", Platforms())})
+			continue
+		}
+		if seen[it.Platform] {
+			errs = append(errs, &FieldError{Field: field + ".platform", Err: errors.New("platform is listed twice")})
+			continue
+		}
+		seen[it.Platform] = true
+
+		it.Handle = strings.TrimPrefix(strings.TrimSpace(it.Handle), "@")
+		if !handleRe.MatchString(it.Handle) {
+			errs = append(errs, &FieldError{Field: field + ".handle", Err: errors.New("handle may contain only letters, digits, '_', '.' and '-'")})
+			continue
+		}
+
+		it.URL = fmt.Sprintf(tpl, it.Handle)
+	}
+
+	if len(errs) > 0 {
+		return b, errors.Join(errs...)
+	}
+
+	b.Path = ""
+	return withPayload(b, p)
+}
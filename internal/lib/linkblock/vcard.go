@@ -0,0 +1,125 @@
+package linkblock
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+	"url_profile/internal/lib/linkurl"
+)
+
+const maxVCardField = 200
+
+type VCardPayload struct {
+	FullName string `json:"full_name"`
+	Org      string `json:"org,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Phone    string `json:"phone,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Note     string `json:"note,omitempty"`
+}
+
+func normalizeVCard(b Block, v *linkurl.Validator) (Block, error) {
+	var p VCardPayload
+	if err := decode(b.Payload, &p); err != nil {
+		return b, err
+	}
+
+	var errs []error
+	fields := []struct {
+		name string
+		val  *string
+	}{
+		{"full_name", &p.FullName}, {"org", &p.Org}, {"title", &p.Title},
+		{"email", &p.Email}, {"phone", &p.Phone}, {"url", &p.URL}, {"note", &p.Note},
+	}
+	for _, f := range fields {
+		*f.val = strings.TrimSpace(*f.val)
+		if utf8.RuneCountInString(*f.val) > maxVCardField {
+			errs = append(errs, &FieldError{Field: "payload." + f.name, Err: errors.New("value is too long")})
+		}
+	}
+
+	if p.FullName == "" {
+		errs = append(errs, &FieldError{Field: "payload.full_name", Err: errors.New("full_name is required")})
+	}
+
+	if p.Email != "" {
+		if res, err := v.Normalize("mailto:"+p.Email, linkurl.Options{}); err != nil {
+			errs = append(errs, &FieldError{Field: "payload.email", Err: err})
+		} else {
+			p.Email = strings.TrimPrefix(res, "mailto:")
+		}
+	}
+
+	if p.Phone != "" {
+		if res, err := v.Normalize("tel:"+p.Phone, linkurl.Options{}); err != nil {
+			errs = append(errs, &FieldError{Field: "payload.phone", Err: err})
+		} else {
+			p.Phone = strings.TrimPrefix(res, "tel:")
+		}
+	}
+
+	if p.URL != "" {
+		if res, err := v.Normalize(p.URL, linkurl.Options{}); err != nil {
+			errs = append(errs, &FieldError{Field: "payload.url", Err: err})
+		} else {
+			p.URL = res
+		}
+	}
+
+	if len(errs) > 0 {
+		return b, errors.Join(errs...)
+	}
+
+	if b.Name == "" {
+		b.Name = p.FullName
+	}
+	b.Path = ""
+	return withPayload(b, p)
+}
+
+// VCard renders the payload as a vCard 3.0 document.
+func (p VCardPayload) VCard() []byte {
+	var sb strings.Builder
+	line := func(s string) { sb.WriteString(s + "\r\n") }
+
+	line("BEGIN:VCARD")
+	line("VERSION:3.0")
+	line("FN:" + escapeVCard(p.FullName))
+
+	// N is required by 3.0; put everything but the last word into given names.
+	family, given := p.FullName, ""
+	if i := strings.LastIndex(p.FullName, " "); i > 0 {
+		given, family = p.FullName[:i], p.FullName[i+1:]
+	}
+	line("N:" + escapeVCard(family) + ";" + escapeVCard(given) + ";;;")
+
+	if p.Org != "" {
+		line("ORG:" + escapeVCard(p.Org))
+	}
+	if p.Title != "" {
+		line("TITLE:" + escapeVCard(p.Title))
+	}
+	if p.Email != "" {
+		line("EMAIL;TYPE=INTERNET:" + escapeVCard(p.Email))
+	}
+	if p.Phone != "" {
+		line("TEL;TYPE=CELL:" + escapeVCard(p.Phone))
+	}
+	if p.URL != "" {
+		line("URL:" + escapeVCard(p.URL))
+	}
+	if p.Note != "" {
+		line("NOTE:" + escapeVCard(p.Note))
+	}
+	line("END:VCARD")
+
+	return []byte(sb.String())
+}
+
+var vcardEscaper = strings.NewReplacer(`\`, `\\`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`, ",", `\,`, ";", `\;`)
+
+func escapeVCard(s string) string {
+	return vcardEscaper.Replace(s)
+}
@@ -0,0 +1,85 @@
+package linkblock
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"url_profile/internal/lib/linkurl"
+)
+
+var (
+	ErrVideoProvider = errors.New("only YouTube and Vimeo videos can be embedded")
+
+	youtubeIDRe = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	vimeoIDRe   = regexp.MustCompile(`^[0-9]{1,12}$`)
+)
+
+type VideoPayload struct {
+	URL      string `json:"url"`
+	Provider string `json:"provider"`
+	EmbedURL string `json:"embed_url"`
+}
+
+func normalizeVideo(b Block, v *linkurl.Validator, opts linkurl.Options) (Block, error) {
+	var p VideoPayload
+	if err := decode(b.Payload, &p); err != nil {
+		return b, err
+	}
+
+	// Clients fill in url only; provider and embed_url are derived.
+	raw, err := v.Normalize(p.URL, opts)
+	if err != nil {
+		return b, &FieldError{Field: "payload.url", Err: err}
+	}
+
+	u, _ := url.Parse(raw)
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return b, &FieldError{Field: "payload.url", Err: ErrVideoProvider}
+	}
+
+	provider, embed, ok := embedURL(u)
+	if !ok {
+		return b, &FieldError{Field: "payload.url", Err: ErrVideoProvider}
+	}
+
+	p = VideoPayload{URL: raw, Provider: provider, EmbedURL: embed}
+	if b.Name == "" {
+		b.Name = "Video"
+	}
+	b.Path = raw
+	return withPayload(b, p)
+}
+
+func embedURL(u *url.URL) (provider string, embed string, ok bool) {
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	host = strings.TrimPrefix(host, "m.")
+	segs := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	var id string
+	switch host {
+	case "youtube.com", "music.youtube.com":
+		switch {
+		case len(segs) == 1 && segs[0] == "watch":
+			id = u.Query().Get("v")
+		case len(segs) == 2 && (segs[0] == "shorts" || segs[0] == "embed" || segs[0] == "live"):
+			id = segs[1]
+		}
+	case "youtu.be":
+		if len(segs) == 1 {
+			id = segs[0]
+		}
+	case "vimeo.com", "player.vimeo.com":
+		last := segs[len(segs)-1]
+		if vimeoIDRe.MatchString(last) {
+			return "vimeo", "https://player.vimeo.com/video/" + last, true
+		}
+		return "", "", false
+	}
+
+	if !youtubeIDRe.MatchString(id) {
+		return "", "", false
+	}
+
+	return "youtube", "https://www.youtube-nocookie.com/embed/" + id, true
+}
@@ -0,0 +1,226 @@
+package linkurl
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+)
+
+var (
+	ErrEmpty       = errors.New("link is empty")
+	ErrWhitespace  = errors.New("link must not contain whitespace or control characters")
+	ErrNotAbsolute = errors.New("link must be an absolute URL with a scheme")
+	ErrScheme      = errors.New("link scheme is not allowed")
+	ErrHost        = errors.New("link host is invalid")
+	ErrUserInfo    = errors.New("link must not contain credentials")
+	ErrEmail       = errors.New("mailto link must contain an email address")
+	ErrPhone       = errors.New("tel link must contain a phone number")
+	ErrOpaque      = errors.New("link has no target")
+)
+
+var defaultSchemes = []string{"http", "https", "mailto", "tel"}
+
+// Schemes that run code or inline a document in the visitor's browser.
+// They are never accepted, even when configured as app schemes.
+var blockedSchemes = map[string]bool{
+	"javascript": true,
+	"data":       true,
+	"vbscript":   true,
+}
+
+// Query parameters that only serve ad and analytics attribution.
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"gbraid":  true,
+	"wbraid":  true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_hsenc":  true,
+	"_hsmi":   true,
+	"mkt_tok": true,
+}
+
+type Options struct {
+	StripTracking bool
+}
+
+type Validator struct {
+	schemes map[string]bool
+}
+
+// New returns a validator that accepts http, https, mailto, tel and the
+// given app schemes (e.g. "tg", "spotify"). javascript, data and vbscript
+// are dropped from appSchemes.
+func New(appSchemes []string) *Validator {
+	v := &Validator{schemes: make(map[string]bool)}
+	for _, s := range defaultSchemes {
+		v.schemes[s] = true
+	}
+	for _, s := range appSchemes {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if !blockedSchemes[s] {
+			v.schemes[s] = true
+		}
+	}
+
+	return v
+}
+
+// Normalize validates raw and returns its canonical form.
+func (v *Validator) Normalize(raw string, opts Options) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", ErrEmpty
+	}
+
+	// Phone numbers are commonly written with spaces: "tel:+7 999 123-45-67".
+	if len(raw) > 4 && strings.EqualFold(raw[:4], "tel:") {
+		raw = strings.ReplaceAll(raw, " ", "")
+	}
+
+	if strings.IndexFunc(raw, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		return "", ErrWhitespace
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return "", ErrNotAbsolute
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if !v.schemes[u.Scheme] {
+		return "", ErrScheme
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return normalizeWeb(u, opts)
+	case "mailto":
+		return normalizeMailto(u)
+	case "tel":
+		return normalizeTel(u)
+	default:
+		if u.Opaque == "" && u.Host == "" && u.Path == "" {
+			return "", ErrOpaque
+		}
+		return u.String(), nil
+	}
+}
+
+func normalizeWeb(u *url.URL, opts Options) (string, error) {
+	if u.Opaque != "" || u.Host == "" {
+		return "", ErrNotAbsolute
+	}
+
+	if u.User != nil {
+		return "", ErrUserInfo
+	}
+
+	host, port := u.Hostname(), u.Port()
+	if ip := net.ParseIP(host); ip == nil {
+		ascii, err := idna.Lookup.ToASCII(strings.TrimSuffix(host, "."))
+		if err != nil || ascii == "" || !strings.Contains(ascii, ".") {
+			return "", ErrHost
+		}
+		host = ascii
+	} else if ip.To4() == nil {
+		host = "[" + host + "]"
+	}
+
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+
+	u.Host = host
+	if port != "" {
+		u.Host += ":" + port
+	}
+
+	if opts.StripTracking && u.RawQuery != "" {
+		u.RawQuery = stripTracking(u.RawQuery)
+	}
+
+	return u.String(), nil
+}
+
+func normalizeMailto(u *url.URL) (string, error) {
+	addr := u.Opaque
+	if addr == "" {
+		addr = u.Path
+	}
+
+	at := strings.LastIndex(addr, "@")
+	if at <= 0 || at == len(addr)-1 {
+		return "", ErrEmail
+	}
+
+	domain, err := idna.Lookup.ToASCII(addr[at+1:])
+	if err != nil || !strings.Contains(domain, ".") {
+		return "", ErrEmail
+	}
+
+	res := "mailto:" + addr[:at+1] + domain
+	if u.RawQuery != "" {
+		res += "?" + u.RawQuery
+	}
+
+	return res, nil
+}
+
+func normalizeTel(u *url.URL) (string, error) {
+	num := u.Opaque
+	if num == "" {
+		num = u.Path
+	}
+
+	var b strings.Builder
+	digits := 0
+	for i, r := range num {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+			digits++
+		case r == '+' && i == 0:
+			b.WriteRune(r)
+		case r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", ErrPhone
+		}
+	}
+
+	if digits < 3 || digits > 15 {
+		return "", ErrPhone
+	}
+
+	return "tel:" + b.String(), nil
+}
+
+// stripTracking drops utm_* and known click-id parameters while keeping the
+// order of everything else.
+func stripTracking(rawQuery string) string {
+	parts := strings.Split(rawQuery, "&")
+	kept := parts[:0]
+	for _, p := range parts {
+		key, _, _ := strings.Cut(p, "=")
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		key = strings.ToLower(key)
+
+		if strings.HasPrefix(key, "utm_") || trackingParams[key] {
+			continue
+		}
+		kept = append(kept, p)
+	}
+
+	return strings.Join(kept, "&")
+}
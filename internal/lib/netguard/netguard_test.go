@@ -0,0 +1,83 @@
+package netguard
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+
+		{"127.0.0.1", false},
+		{"127.255.255.254", false},
+		{"::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"172.31.255.255", false},
+		{"192.168.0.1", false},
+		{"fd00::1", false},
+
+		// Link-local, including the cloud metadata endpoint.
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+
+		// Carrier-grade NAT.
+		{"100.64.0.1", false},
+		{"100.127.255.255", false},
+		{"100.128.0.1", true},
+
+		// IPv4-mapped IPv6 must not smuggle an internal address through.
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		{"::ffff:8.8.8.8", true},
+
+		// NAT64 and documentation ranges.
+		{"64:ff9b::a00:1", false},
+		{"2001:db8::1", false},
+		{"192.0.2.1", false},
+
+		{"224.0.0.1", false},
+		{"ff02::1", false},
+		{"255.255.255.255", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := IsPublic(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("IsPublic(%s) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestControl(t *testing.T) {
+	tests := []struct {
+		address string
+		blocked bool
+	}{
+		{"8.8.8.8:443", false},
+		{"[2606:4700:4700::1111]:80", false},
+		{"127.0.0.1:80", true},
+		{"[::ffff:192.168.1.1]:8080", true},
+		{"[fe80::1%eth0]:80", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := Control("tcp", tt.address, nil)
+			if got := errors.Is(err, ErrBlockedAddress); got != tt.blocked {
+				t.Errorf("Control(%s) = %v, blocked %v", tt.address, err, tt.blocked)
+			}
+		})
+	}
+}
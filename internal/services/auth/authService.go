@@ -1,8 +1,8 @@
 package authservice
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"log/slog"
 	"net/http"
 	"url_profile/internal/app/server/http/handlers/requestModel"
@@ -20,10 +20,15 @@ type UserProvider interface {
 	User(email string) (*models.User, error)
 	UserById(id int) (*models.User, error)
 	UserByUsername(name string) (*models.User, error)
-	UpdateAboutMe(id int, text string) error
-	AddLink(userID int, link requestModel.ReqLink) error
-	UpdateLink(userID int, link *requestModel.ReqUpdateLink) error
-	DeleteLink(userID int, linkID int) error
+	UpdateAboutMe(id int, text string, ifVersion int) error
+	Settings(id int) (*models.Settings, error)
+	UpdateSettings(id int, settings models.Settings) error
+	Link(linkID int) (*models.Link, error)
+	AddLink(userID int, link requestModel.ReqLink) (int, error)
+	UpdateLink(userID int, link *requestModel.ReqUpdateLink, ifVersion int) error
+	DeleteLink(userID int, linkID int, ifVersion int) error
+	ChangeLinks(userID int, changes []models.LinkChange) error
+	store.UnitOfWork
 }
 
 type AuthService struct {
@@ -41,12 +46,12 @@ func New(log *slog.Logger, userSaver UserSaver, userProvider UserProvider) *Auth
 }
 
 func (a *AuthService) CreateUser(user *requestModel.SignUpModel) (int, *models.User, error) {
-	if _, err := a.userProvider.User(user.Email); err != store.ErrUserNotFound {
-		return http.StatusConflict, nil, fmt.Errorf("user with email %s already exists", user.Email)
+	if _, err := a.userProvider.User(user.Email); !errors.Is(err, store.ErrUserNotFound) {
+		return http.StatusConflict, nil, &store.ConflictError{Column: "email", Err: store.ErrUserAlreadyExists}
 	}
 
-	if _, err := a.userProvider.UserByUsername(user.Username); err != store.ErrUserNotFound {
-		return http.StatusConflict, nil, fmt.Errorf("user with username %s already exists", user.Username)
+	if _, err := a.userProvider.UserByUsername(user.Username); !errors.Is(err, store.ErrUserNotFound) {
+		return http.StatusConflict, nil, &store.ConflictError{Column: "username", Err: store.ErrUserAlreadyExists}
 	}
 
 	hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
@@ -57,8 +62,15 @@ func (a *AuthService) CreateUser(user *requestModel.SignUpModel) (int, *models.U
 
 	u, err := a.userSaver.CreateUser(user.Email, user.Username, hash, user.About, user.Links)
 	if err != nil {
+		// The checks above race with concurrent sign-ups; the store's
+		// error still names the conflicting column.
 		if errors.Is(err, store.ErrUserAlreadyExists) {
-			return http.StatusConflict, nil, store.ErrUserAlreadyExists
+			return http.StatusConflict, nil, err
+		}
+
+		var invalid *store.InvalidLinksError
+		if errors.As(err, &invalid) {
+			return http.StatusBadRequest, nil, invalid
 		}
 
 		if errors.Is(err, store.ErrUserRetrievalFailed) {
@@ -110,32 +122,131 @@ func (a *AuthService) UserByUsername(name string) (*models.User, error) {
 	return u, nil
 }
 
-func (a *AuthService) UpdateAboutMe(id int, text string) error {
-	if err := a.userProvider.UpdateAboutMe(id, text); err != nil {
+// UpdateAboutMe changes the about text. A non-zero ifVersion makes it
+// conditional on the profile version and fails with
+// store.ErrVersionMismatch when the profile changed since.
+func (a *AuthService) UpdateAboutMe(id int, text string, ifVersion int) error {
+	if err := a.userProvider.UpdateAboutMe(id, text, ifVersion); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateProfile writes the about text and the theme, whichever is not nil,
+// in one unit of work: both land or neither does. A non-zero ifVersion is
+// checked once, before either write, and pins the whole change.
+func (a *AuthService) UpdateProfile(ctx context.Context, id int, about *string, theme *models.Theme, ifVersion int) error {
+	return a.userProvider.InTx(ctx, func(tx store.Tx) error {
+		if err := tx.LockProfile(id, ifVersion); err != nil {
+			return err
+		}
+
+		if about != nil {
+			if err := tx.UpdateAboutMe(id, *about, 0); err != nil {
+				return err
+			}
+		}
+
+		if theme != nil {
+			if err := tx.SaveTheme(id, *theme); err != nil {
+				a.log.Debug("Failed to save theme", slog.String("error", err.Error()))
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (a *AuthService) Settings(id int) (*models.Settings, error) {
+	settings, err := a.userProvider.Settings(id)
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			return nil, store.ErrUserNotFound
+		}
+
+		return nil, store.ErrDatabaseOperation
+	}
+
+	return settings, nil
+}
+
+func (a *AuthService) UpdateSettings(id int, settings models.Settings) error {
+	if err := a.userProvider.UpdateSettings(id, settings); err != nil {
 		return err
 	}
+
 	return nil
 }
 
-func (a *AuthService) AddLink(userID int, link requestModel.ReqLink) error {
-	if err := a.userProvider.AddLink(userID, link); err != nil {
+func (a *AuthService) Link(linkID int) (*models.Link, error) {
+	l, err := a.userProvider.Link(linkID)
+	if err != nil {
+		if errors.Is(err, store.ErrLinkNotFound) {
+			return nil, store.ErrLinkNotFound
+		}
+
+		return nil, store.ErrDatabaseOperation
+	}
+
+	return l, nil
+}
+
+// AddLink saves the link and returns its ID.
+func (a *AuthService) AddLink(userID int, link requestModel.ReqLink) (int, error) {
+	id, err := a.userProvider.AddLink(userID, link)
+	if err != nil {
 		a.log.Debug("Failet to save link", slog.String("error", err.Error()))
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// AddLinks adds the links in one unit of work: either all of them are saved
+// or none is.
+func (a *AuthService) AddLinks(ctx context.Context, userID int, links []requestModel.ReqLink) ([]int, error) {
+	ids := make([]int, 0, len(links))
+	err := a.userProvider.InTx(ctx, func(tx store.Tx) error {
+		for _, link := range links {
+			id, err := tx.AddLink(userID, link)
+			if err != nil {
+				a.log.Debug("Failet to save link", slog.String("error", err.Error()))
+				return err
+			}
+			ids = append(ids, id)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// UpdateLink and DeleteLink take ifVersion like UpdateAboutMe, against the
+// link version.
+func (a *AuthService) UpdateLink(userID int, link *requestModel.ReqUpdateLink, ifVersion int) error {
+	if err := a.userProvider.UpdateLink(userID, link, ifVersion); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (a *AuthService) UpdateLink(userID int, link *requestModel.ReqUpdateLink) error {
-	if err := a.userProvider.UpdateLink(userID, link); err != nil {
+func (a *AuthService) DeleteLink(userID int, linkID int, ifVersion int) error {
+	if err := a.userProvider.DeleteLink(userID, linkID, ifVersion); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (a *AuthService) DeleteLink(userID int, linkID int) error {
-	if err := a.userProvider.DeleteLink(userID, linkID); err != nil {
+// ChangeLinks applies a batch of link updates and deletions atomically.
+func (a *AuthService) ChangeLinks(userID int, changes []models.LinkChange) error {
+	if err := a.userProvider.ChangeLinks(userID, changes); err != nil {
 		return err
 	}
 
@@ -0,0 +1,106 @@
+package authservice
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/store"
+	memorystore "url_profile/internal/store/memory"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newService() *AuthService {
+	s := memorystore.New()
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), s, s)
+}
+
+func TestCreateUser(t *testing.T) {
+	a := newService()
+
+	code, u, err := a.CreateUser(&requestModel.SignUpModel{
+		Email:    "alice@example.com",
+		Username: "alice",
+		Password: "secret",
+		Links:    []requestModel.ReqLink{{LinkName: "site", LinkPath: "https://alice.dev"}},
+	})
+	if err != nil || code != http.StatusCreated {
+		t.Fatalf("CreateUser: %d %v", code, err)
+	}
+
+	got, err := a.UserById(u.ID)
+	if err != nil {
+		t.Fatalf("UserById: %v", err)
+	}
+	if err := bcrypt.CompareHashAndPassword(got.HashedPassword, []byte("secret")); err != nil {
+		t.Errorf("stored hash does not match the password: %v", err)
+	}
+	if len(got.Links) != 1 {
+		t.Errorf("user has %d links, want 1", len(got.Links))
+	}
+}
+
+func TestCreateUserConflict(t *testing.T) {
+	a := newService()
+
+	if _, _, err := a.CreateUser(&requestModel.SignUpModel{Email: "alice@example.com", Username: "alice", Password: "secret"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	for column, m := range map[string]requestModel.SignUpModel{
+		"email":    {Email: "alice@example.com", Username: "other", Password: "secret"},
+		"username": {Email: "other@example.com", Username: "alice", Password: "secret"},
+	} {
+		code, _, err := a.CreateUser(&m)
+		if code != http.StatusConflict {
+			t.Errorf("CreateUser(%s, %s): got %d %v, want 409", m.Email, m.Username, code, err)
+		}
+
+		var conflict *store.ConflictError
+		if !errors.As(err, &conflict) || conflict.Column != column {
+			t.Errorf("CreateUser(%s, %s): got %v, want a conflict on %s", m.Email, m.Username, err, column)
+		}
+	}
+}
+
+func TestCreateUserInvalidLinks(t *testing.T) {
+	a := newService()
+	section := 1
+
+	code, _, err := a.CreateUser(&requestModel.SignUpModel{
+		Email:    "alice@example.com",
+		Username: "alice",
+		Password: "secret",
+		Links: []requestModel.ReqLink{
+			{LinkName: "site", LinkPath: "https://alice.dev"},
+			{LinkName: "blog", LinkPath: "https://alice.dev/blog", SectionID: &section},
+		},
+	})
+	if code != http.StatusBadRequest {
+		t.Fatalf("CreateUser: got %d %v, want 400", code, err)
+	}
+
+	// The sign-up handler maps Index straight back onto the request.
+	var invalid *store.InvalidLinksError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("CreateUser: got %v, want *store.InvalidLinksError", err)
+	}
+	if len(invalid.Links) != 1 || invalid.Links[0].Index != 1 || invalid.Links[0].Field != "section_id" {
+		t.Errorf("rejected links: %+v, want link 1 on section_id", invalid.Links)
+	}
+
+	if _, err := a.User("alice@example.com"); err == nil {
+		t.Errorf("user was created despite the rejected link")
+	}
+}
+
+func TestUserNotFound(t *testing.T) {
+	a := newService()
+
+	if _, err := a.User("nobody@example.com"); !errors.Is(err, store.ErrUserNotFound) {
+		t.Errorf("User: got %v, want store.ErrUserNotFound", err)
+	}
+}
@@ -0,0 +1,247 @@
+// Package backup takes compressed snapshots of the database while the
+// server runs, keeps the newest few in blob storage and swaps one back in
+// on restore.
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"url_profile/internal/blob"
+)
+
+var (
+	ErrNotFound    = errors.New("snapshot not found")
+	ErrInvalidName = errors.New("invalid snapshot name")
+)
+
+const (
+	// keyPrefix is where snapshots live in blob storage.
+	keyPrefix = "backups/"
+	prefix    = "snapshot-"
+	suffix    = ".db.gz"
+	// stamp sorts lexically in time order, so names sort like snapshots.
+	stamp = "20060102T150405.000Z"
+)
+
+// Snapshotter writes a consistent copy of the live database to path.
+type Snapshotter interface {
+	Snapshot(ctx context.Context, path string) error
+}
+
+type Options struct {
+	// Keep is how many snapshots survive rotation; 0 keeps all of them.
+	Keep int
+	// TempDir is where a snapshot is staged and compressed before it is
+	// uploaded; empty means the system temp dir. It needs room for an
+	// uncompressed copy of the database.
+	TempDir string
+}
+
+type Snapshot struct {
+	Name      string
+	Size      int64
+	CreatedAt time.Time
+}
+
+type Service struct {
+	log   *slog.Logger
+	db    Snapshotter
+	blobs blob.Storage
+	opts  Options
+	now   func() time.Time
+}
+
+func New(log *slog.Logger, db Snapshotter, blobs blob.Storage, opts Options) *Service {
+	return &Service{
+		log:   log,
+		db:    db,
+		blobs: blobs,
+		opts:  opts,
+		now:   time.Now,
+	}
+}
+
+// Backup snapshots the database, gzips it, uploads it to blob storage and
+// rotates old snapshots out. A snapshot only appears under its name once it
+// is complete.
+func (s *Service) Backup(ctx context.Context) (*Snapshot, error) {
+	dir, err := os.MkdirTemp(s.opts.TempDir, ".backup-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	createdAt := s.now().UTC()
+	name := prefix + createdAt.Format(stamp) + suffix
+	raw := filepath.Join(dir, strings.TrimSuffix(name, ".gz"))
+
+	if err := s.db.Snapshot(ctx, raw); err != nil {
+		return nil, fmt.Errorf("failed to snapshot database: %w", err)
+	}
+
+	size, err := s.upload(ctx, raw, filepath.Join(dir, name), name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("backup: snapshot taken",
+		slog.String("name", name),
+		slog.Int64("size", size))
+
+	if err := s.rotate(ctx); err != nil {
+		s.log.Error("backup: failed to rotate snapshots", slog.String("error", err.Error()))
+	}
+
+	return &Snapshot{Name: name, Size: size, CreatedAt: createdAt}, nil
+}
+
+// List returns the stored snapshots, newest first.
+func (s *Service) List(ctx context.Context) ([]Snapshot, error) {
+	objects, err := s.blobs.List(ctx, keyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Snapshot
+	for _, o := range objects {
+		name := strings.TrimPrefix(o.Key, keyPrefix)
+		createdAt, ok := parseName(name)
+		if !ok {
+			continue
+		}
+		res = append(res, Snapshot{Name: name, Size: o.Size, CreatedAt: createdAt})
+	}
+
+	slices.SortFunc(res, func(a, b Snapshot) int { return strings.Compare(b.Name, a.Name) })
+
+	return res, nil
+}
+
+// Open reads a snapshot by a name from List.
+func (s *Service) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	key, err := snapshotKey(name)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, _, err := s.blobs.Get(ctx, key)
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+
+	return rc, err
+}
+
+// Download returns a presigned URL for a snapshot that is valid for ttl.
+func (s *Service) Download(ctx context.Context, name string, ttl time.Duration) (*blob.Download, error) {
+	rc, err := s.Open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	rc.Close()
+
+	key, _ := snapshotKey(name)
+	u, err := s.blobs.Presign(ctx, key, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &blob.Download{URL: u, ExpiresAt: s.now().Add(ttl).UTC()}, nil
+}
+
+// upload compresses the raw snapshot into gz and stores it as name,
+// returning the compressed size.
+func (s *Service) upload(ctx context.Context, raw string, gz string, name string) (int64, error) {
+	size, err := compress(raw, gz)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.Open(gz)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	if err := s.blobs.Put(ctx, keyPrefix+name, f, "application/gzip"); err != nil {
+		return 0, fmt.Errorf("failed to upload snapshot: %w", err)
+	}
+
+	return size, nil
+}
+
+func (s *Service) rotate(ctx context.Context) error {
+	if s.opts.Keep <= 0 {
+		return nil
+	}
+
+	snapshots, err := s.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, old := range snapshots[min(s.opts.Keep, len(snapshots)):] {
+		if err := s.blobs.Delete(ctx, keyPrefix+old.Name); err != nil {
+			return err
+		}
+		s.log.Info("backup: snapshot rotated out", slog.String("name", old.Name))
+	}
+
+	return nil
+}
+
+func snapshotKey(name string) (string, error) {
+	if _, ok := parseName(name); !ok || strings.ContainsAny(name, "/\\") {
+		return "", ErrInvalidName
+	}
+
+	return keyPrefix + name, nil
+}
+
+func parseName(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse(stamp, strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix))
+	return t, err == nil
+}
+
+// compress gzips src into dst and returns the compressed size.
+func compress(src string, dst string) (int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		return 0, fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return 0, fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+
+	info, err := out.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	return info.Size(), out.Close()
+}
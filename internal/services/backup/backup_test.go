@@ -0,0 +1,196 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"url_profile/internal/blob"
+	localblob "url_profile/internal/blob/local"
+)
+
+// fakeDB "snapshots" by writing its contents to the path, like VACUUM INTO
+// it refuses an existing file.
+type fakeDB struct {
+	contents []byte
+}
+
+func (f *fakeDB) Snapshot(_ context.Context, path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(f.contents)
+	return err
+}
+
+func newService(t *testing.T, db Snapshotter, keep int) (*Service, *time.Time) {
+	t.Helper()
+
+	blobs, err := localblob.New(t.TempDir(), blob.NewSigner("test", "/files"))
+	if err != nil {
+		t.Fatalf("blob storage: %v", err)
+	}
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), db, blobs, Options{
+		Keep:    keep,
+		TempDir: t.TempDir(),
+	})
+	s.now = func() time.Time { return now }
+
+	return s, &now
+}
+
+func TestBackupRotates(t *testing.T) {
+	s, now := newService(t, &fakeDB{contents: []byte("db")}, 2)
+	ctx := context.Background()
+
+	var names []string
+	for i := 0; i < 3; i++ {
+		snap, err := s.Backup(ctx)
+		if err != nil {
+			t.Fatalf("Backup %d: %v", i, err)
+		}
+		names = append(names, snap.Name)
+		*now = now.Add(time.Second)
+	}
+
+	got, err := s.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(got) != 2 || got[0].Name != names[2] || got[1].Name != names[1] {
+		t.Fatalf("List = %v, want the two newest of %v", got, names)
+	}
+
+	// Nothing but the kept snapshots is left in storage or in the temp dir.
+	objects, err := s.blobs.List(ctx, "")
+	if err != nil {
+		t.Fatalf("blob List: %v", err)
+	}
+	if len(objects) != 2 {
+		t.Errorf("blob storage has %d objects, want 2", len(objects))
+	}
+	entries, err := os.ReadDir(s.opts.TempDir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("temp dir has %d entries left, want none", len(entries))
+	}
+}
+
+func TestOpenAndDownload(t *testing.T) {
+	s, _ := newService(t, &fakeDB{}, 0)
+	ctx := context.Background()
+
+	snap, err := s.Backup(ctx)
+	if err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+
+	rc, err := s.Open(ctx, snap.Name)
+	if err != nil {
+		t.Fatalf("Open(%q): %v", snap.Name, err)
+	}
+	rc.Close()
+
+	d, err := s.Download(ctx, snap.Name, time.Minute)
+	if err != nil {
+		t.Fatalf("Download(%q): %v", snap.Name, err)
+	}
+	if !strings.HasPrefix(d.URL, "/files/backups/"+snap.Name+"?") {
+		t.Errorf("download url %q does not point at the snapshot", d.URL)
+	}
+	if want := s.now().Add(time.Minute); !d.ExpiresAt.Equal(want) {
+		t.Errorf("expires at %v, want %v", d.ExpiresAt, want)
+	}
+
+	for _, name := range []string{"../" + snap.Name, "backups/" + snap.Name, "app.db"} {
+		if _, err := s.Open(ctx, name); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("Open(%q): got %v, want ErrInvalidName", name, err)
+		}
+	}
+	if _, err := s.Download(ctx, "snapshot-20200101T000000.000Z.db.gz", time.Minute); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Download of a missing snapshot: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	s, _ := newService(t, &fakeDB{contents: []byte("snapshot")}, 0)
+	ctx := context.Background()
+
+	snap, err := s.Backup(ctx)
+	if err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	open := func() io.Reader {
+		rc, err := s.Open(ctx, snap.Name)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		t.Cleanup(func() { rc.Close() })
+		return rc
+	}
+
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+	write(t, dbPath, "live")
+	write(t, dbPath+"-wal", "live wal")
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	rejected := errors.New("rejected")
+	err = Restore(ctx, log, open(), dbPath, func(context.Context, string) error { return rejected })
+	if !errors.Is(err, rejected) {
+		t.Fatalf("Restore with a failing check: got %v, want %v", err, rejected)
+	}
+	if got := read(t, dbPath); got != "live" {
+		t.Fatalf("rejected snapshot replaced the database: %q", got)
+	}
+
+	var verified string
+	err = Restore(ctx, log, open(), dbPath, func(_ context.Context, p string) error {
+		verified = read(t, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	if verified != "snapshot" {
+		t.Errorf("verifier saw %q, want the decompressed snapshot", verified)
+	}
+	if got := read(t, dbPath); got != "snapshot" {
+		t.Errorf("database = %q after restore, want the snapshot", got)
+	}
+	if _, err := os.Stat(dbPath + "-wal"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("old WAL is still next to the restored database: %v", err)
+	}
+	if got := read(t, dbPath+".pre-restore"); got != "live" {
+		t.Errorf("previous database = %q, want it kept as .pre-restore", got)
+	}
+}
+
+func write(t *testing.T, path string, s string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(s), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func read(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(bytes.TrimSpace(b))
+}
@@ -0,0 +1,89 @@
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+)
+
+// Verifier checks a decompressed snapshot before it replaces the database,
+// typically its integrity and schema version.
+type Verifier func(ctx context.Context, path string) error
+
+// Restore decompresses the gzipped snapshot next to dbPath, verifies it and
+// swaps it in. The database it replaces, with its -wal and -shm files, is kept as
+// dbPath.pre-restore. The server must be stopped: a running process keeps
+// the old file open and would write into it.
+func Restore(ctx context.Context, log *slog.Logger, snapshot io.Reader, dbPath string, verify Verifier) error {
+	tmp := dbPath + ".restore"
+	if err := decompress(snapshot, tmp); err != nil {
+		return err
+	}
+	defer removeDB(tmp)
+
+	if err := verify(ctx, tmp); err != nil {
+		return fmt.Errorf("snapshot rejected: %w", err)
+	}
+
+	old := dbPath + ".pre-restore"
+	if err := removeDB(old); err != nil {
+		return err
+	}
+
+	// A WAL left behind would be replayed into the restored file.
+	for _, ext := range []string{"", "-wal", "-shm"} {
+		err := os.Rename(dbPath+ext, old+ext)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to move the current database aside: %w", err)
+		}
+	}
+
+	if err := os.Rename(tmp, dbPath); err != nil {
+		return fmt.Errorf("failed to swap in the snapshot: %w", err)
+	}
+
+	log.Info("backup: snapshot restored",
+		slog.String("database", dbPath),
+		slog.String("previous", old))
+
+	return nil
+}
+
+func decompress(in io.Reader, dst string) error {
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	defer zr.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, zr); err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if err := out.Sync(); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+// removeDB removes a database file together with its -wal and -shm files.
+func removeDB(path string) error {
+	for _, ext := range []string{"", "-wal", "-shm"} {
+		if err := os.Remove(path + ext); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package events
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	TypeView  = "view"
+	TypeClick = "click"
+)
+
+var ErrTooManySubscribers = errors.New("too many open event streams")
+
+type Event struct {
+	ID   uint64
+	Type string
+	Data any
+	Time time.Time
+}
+
+// DefaultIdle is how long a stream nobody listens to keeps its buffer when
+// New is given no idle time.
+const DefaultIdle = 10 * time.Minute
+
+// Hub is an in-process pub/sub for per-user profile events. It keeps a short
+// ring buffer of recent events for every user so reconnecting clients can
+// resume from Last-Event-ID. Streams without subscribers are dropped once
+// they have seen no event for the idle time.
+type Hub struct {
+	mu         sync.Mutex
+	seq        uint64
+	bufferSize int
+	maxSubs    int
+	idle       time.Duration
+	streams    map[int]*stream
+	lastSweep  time.Time
+	now        func() time.Time
+}
+
+type stream struct {
+	ring []Event
+	head int
+	size int
+	subs map[*Subscription]struct{}
+	// last is when the stream last saw an event or a subscriber.
+	last time.Time
+}
+
+type Subscription struct {
+	C      <-chan Event
+	ch     chan Event
+	hub    *Hub
+	userID int
+	once   sync.Once
+}
+
+func New(bufferSize int, maxSubs int, idle time.Duration) *Hub {
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+	if idle <= 0 {
+		idle = DefaultIdle
+	}
+
+	return &Hub{
+		bufferSize: bufferSize,
+		maxSubs:    maxSubs,
+		idle:       idle,
+		streams:    make(map[int]*stream),
+		now:        time.Now,
+	}
+}
+
+// Publish stores the event in the user's ring buffer and fans it out to every
+// open subscription. Subscribers that cannot keep up are closed; they are
+// expected to reconnect with Last-Event-ID and replay from the buffer.
+func (h *Hub) Publish(userID int, eventType string, data any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	ev := Event{
+		ID:   h.seq,
+		Type: eventType,
+		Data: data,
+		Time: h.now().UTC(),
+	}
+
+	h.sweepLocked(ev.Time)
+	st := h.stream(userID)
+	st.push(ev)
+
+	for sub := range st.subs {
+		select {
+		case sub.ch <- ev:
+		default:
+			h.dropLocked(sub)
+		}
+	}
+}
+
+// Subscribe opens a stream for userID. If lastEventID is not zero, buffered
+// events newer than it are returned so the caller can replay them before
+// reading from the subscription.
+func (h *Hub) Subscribe(userID int, lastEventID uint64) (*Subscription, []Event, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	h.sweepLocked(now)
+	st := h.stream(userID)
+	st.last = now
+	if h.maxSubs > 0 && len(st.subs) >= h.maxSubs {
+		return nil, nil, ErrTooManySubscribers
+	}
+
+	ch := make(chan Event, h.bufferSize)
+	sub := &Subscription{
+		C:      ch,
+		ch:     ch,
+		hub:    h,
+		userID: userID,
+	}
+	st.subs[sub] = struct{}{}
+
+	var missed []Event
+	if lastEventID != 0 {
+		missed = st.since(lastEventID)
+	}
+
+	return sub, missed, nil
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.hub.dropLocked(s)
+}
+
+func (h *Hub) dropLocked(sub *Subscription) {
+	sub.once.Do(func() {
+		st, ok := h.streams[sub.userID]
+		if !ok {
+			return
+		}
+
+		delete(st.subs, sub)
+		close(sub.ch)
+		if len(st.subs) == 0 && st.size == 0 {
+			delete(h.streams, sub.userID)
+		}
+	})
+}
+
+// sweepLocked drops the streams nobody listens to that have been idle for
+// longer than h.idle. It walks all streams at most once per idle period.
+func (h *Hub) sweepLocked(now time.Time) {
+	if now.Sub(h.lastSweep) < h.idle {
+		return
+	}
+	h.lastSweep = now
+
+	for userID, st := range h.streams {
+		if len(st.subs) == 0 && now.Sub(st.last) >= h.idle {
+			delete(h.streams, userID)
+		}
+	}
+}
+
+func (h *Hub) stream(userID int) *stream {
+	st, ok := h.streams[userID]
+	if !ok {
+		st = &stream{
+			ring: make([]Event, h.bufferSize),
+			subs: make(map[*Subscription]struct{}),
+		}
+		h.streams[userID] = st
+	}
+
+	return st
+}
+
+func (st *stream) push(ev Event) {
+	st.ring[st.head] = ev
+	st.head = (st.head + 1) % len(st.ring)
+	if st.size < len(st.ring) {
+		st.size++
+	}
+	st.last = ev.Time
+}
+
+func (st *stream) since(id uint64) []Event {
+	res := make([]Event, 0, st.size)
+	start := (st.head - st.size + len(st.ring)) % len(st.ring)
+	for i := 0; i < st.size; i++ {
+		ev := st.ring[(start+i)%len(st.ring)]
+		if ev.ID > id {
+			res = append(res, ev)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,210 @@
+package events
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// clock is a settable time source for the hub.
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func newHub(t *testing.T, bufferSize, maxSubs int, idle time.Duration) (*Hub, *clock) {
+	t.Helper()
+
+	c := &clock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	h := New(bufferSize, maxSubs, idle)
+	h.now = c.now
+	return h, c
+}
+
+func ids(evs []Event) []uint64 {
+	res := make([]uint64, 0, len(evs))
+	for _, ev := range evs {
+		res = append(res, ev.ID)
+	}
+	return res
+}
+
+func equalIDs(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPublishReachesSubscribers(t *testing.T) {
+	h, _ := newHub(t, 10, 0, time.Minute)
+
+	sub, missed, err := h.Subscribe(1, 0)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer sub.Close()
+	if len(missed) != 0 {
+		t.Errorf("a fresh subscription replayed %v", ids(missed))
+	}
+
+	h.Publish(2, TypeView, nil)
+	h.Publish(1, TypeClick, "x")
+
+	select {
+	case ev := <-sub.C:
+		if ev.Type != TypeClick || ev.Data != "x" {
+			t.Errorf("got %+v, want the click of user 1", ev)
+		}
+	default:
+		t.Fatal("no event delivered")
+	}
+
+	select {
+	case ev := <-sub.C:
+		t.Errorf("got an event of another user: %+v", ev)
+	default:
+	}
+}
+
+func TestReplayFromLastEventID(t *testing.T) {
+	h, _ := newHub(t, 3, 0, time.Minute)
+
+	for i := 0; i < 5; i++ {
+		h.Publish(1, TypeView, i)
+	}
+
+	tests := []struct {
+		name   string
+		lastID uint64
+		want   []uint64
+	}{
+		{"no Last-Event-ID", 0, []uint64{}},
+		{"resume in the buffer", 3, []uint64{4, 5}},
+		{"up to date", 5, []uint64{}},
+		// Events 1 and 2 fell out of the ring, the rest is replayed.
+		{"older than the buffer", 1, []uint64{3, 4, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, missed, err := h.Subscribe(1, tt.lastID)
+			if err != nil {
+				t.Fatalf("Subscribe: %v", err)
+			}
+			defer sub.Close()
+
+			if got := ids(missed); !equalIDs(got, tt.want) {
+				t.Errorf("replayed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnectionLimit(t *testing.T) {
+	h, _ := newHub(t, 10, 2, time.Minute)
+
+	a, _, err := h.Subscribe(1, 0)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if _, _, err := h.Subscribe(1, 0); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	if _, _, err := h.Subscribe(1, 0); !errors.Is(err, ErrTooManySubscribers) {
+		t.Errorf("third Subscribe: got %v, want ErrTooManySubscribers", err)
+	}
+	if _, _, err := h.Subscribe(2, 0); err != nil {
+		t.Errorf("the limit is per user, Subscribe of another user: %v", err)
+	}
+
+	a.Close()
+	a.Close()
+	if _, _, err := h.Subscribe(1, 0); err != nil {
+		t.Errorf("Subscribe after Close: %v", err)
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	h, _ := newHub(t, 2, 0, time.Minute)
+
+	sub, _, err := h.Subscribe(1, 0)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		h.Publish(1, TypeView, i)
+	}
+
+	var got []uint64
+	for ev := range sub.C {
+		got = append(got, ev.ID)
+	}
+	if !equalIDs(got, []uint64{1, 2}) {
+		t.Errorf("read %v before the channel closed, want [1 2]", got)
+	}
+
+	// The dropped client resumes from the buffer.
+	sub, missed, err := h.Subscribe(1, 2)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer sub.Close()
+	if got := ids(missed); !equalIDs(got, []uint64{3}) {
+		t.Errorf("replayed %v, want [3]", got)
+	}
+}
+
+func TestIdleStreamsAreEvicted(t *testing.T) {
+	h, c := newHub(t, 10, 0, time.Minute)
+
+	// Viewed profiles nobody watches.
+	h.Publish(1, TypeView, nil)
+	h.Publish(2, TypeView, nil)
+
+	watched, _, err := h.Subscribe(3, 0)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer watched.Close()
+
+	empty, _, err := h.Subscribe(4, 0)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	empty.Close()
+	if _, ok := h.streams[4]; ok {
+		t.Error("the stream of a closed subscription without events was kept")
+	}
+
+	c.t = c.t.Add(30 * time.Second)
+	h.Publish(2, TypeView, nil)
+
+	c.t = c.t.Add(45 * time.Second)
+	h.Publish(5, TypeView, nil)
+
+	if _, ok := h.streams[1]; ok {
+		t.Error("idle stream 1 was not evicted")
+	}
+	for _, userID := range []int{2, 3, 5} {
+		if _, ok := h.streams[userID]; !ok {
+			t.Errorf("stream %d was evicted", userID)
+		}
+	}
+
+	// A client that reconnects within the idle time still gets its replay.
+	sub, missed, err := h.Subscribe(2, 2)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer sub.Close()
+	if len(missed) != 1 {
+		t.Errorf("replayed %v, want the one event after 2", ids(missed))
+	}
+}
@@ -0,0 +1,241 @@
+package linkhealth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/lib/netguard"
+)
+
+type LinkStore interface {
+	AllLinks() ([]models.Link, error)
+	SaveLinkHealth(health models.LinkHealth) error
+	LinkHealth(userID int) ([]models.LinkHealth, error)
+}
+
+type Options struct {
+	Interval        time.Duration
+	Timeout         time.Duration
+	Concurrency     int
+	PerHostInterval time.Duration
+	MaxRedirects    int
+	UserAgent       string
+	// AllowPrivate lets the checker reach loopback and private networks.
+	// Results go back to link owners, so leave it off outside development.
+	AllowPrivate bool
+}
+
+// Checker periodically probes every stored link and records the outcome.
+type Checker struct {
+	log    *slog.Logger
+	store  LinkStore
+	opts   Options
+	client *http.Client
+
+	mu       sync.Mutex
+	nextSlot map[string]time.Time
+}
+
+func New(log *slog.Logger, store LinkStore, opts Options) *Checker {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+
+	if opts.UserAgent == "" {
+		opts.UserAgent = "url_profile-linkcheck/1.0"
+	}
+
+	c := &Checker{
+		log:      log,
+		store:    store,
+		opts:     opts,
+		nextSlot: make(map[string]time.Time),
+	}
+
+	// Every connection, redirects included, dials through the guard, so
+	// status codes and errors cannot be used to probe internal hosts.
+	dialer := netguard.Dialer(opts.Timeout, opts.AllowPrivate)
+	c.client = &http.Client{
+		Timeout: opts.Timeout,
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   opts.Timeout,
+			ResponseHeaderTimeout: opts.Timeout,
+			MaxIdleConns:          opts.Concurrency,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > opts.MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", opts.MaxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to %s is not followed", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+
+	return c
+}
+
+// Run checks all links every Interval until ctx is cancelled.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		c.CheckAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Checker) CheckAll(ctx context.Context) {
+	links, err := c.store.AllLinks()
+	if err != nil {
+		c.log.Error("link health: failed to load links", slog.String("error", err.Error()))
+		return
+	}
+
+	c.mu.Lock()
+	clear(c.nextSlot)
+	c.mu.Unlock()
+
+	sem := make(chan struct{}, c.opts.Concurrency)
+	var wg sync.WaitGroup
+
+	for _, l := range links {
+		u, err := url.Parse(l.LinkPath)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(l models.Link, host string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := c.waitHost(ctx, host); err != nil {
+				return
+			}
+
+			h := c.Check(ctx, l.LinkPath)
+			h.LinkID = l.ID
+			if err := c.store.SaveLinkHealth(h); err != nil {
+				c.log.Error("link health: failed to save result",
+					slog.Int("link_id", l.ID),
+					slog.String("error", err.Error()))
+			}
+		}(l, strings.ToLower(u.Host))
+	}
+
+	wg.Wait()
+}
+
+// Check probes target with HEAD and falls back to GET when HEAD fails, since
+// plenty of servers answer HEAD with 403/405 while serving GET fine.
+func (c *Checker) Check(ctx context.Context, target string) models.LinkHealth {
+	res := models.LinkHealth{CheckedAt: time.Now().UTC()}
+
+	code, err := c.probe(ctx, http.MethodHead, target)
+	if err != nil || code >= http.StatusBadRequest {
+		code, err = c.probe(ctx, http.MethodGet, target)
+	}
+
+	switch {
+	case err != nil:
+		res.Status = models.LinkHealthUnreachable
+		res.Error = err.Error()
+	case code >= 400:
+		res.Status = models.LinkHealthBroken
+		res.StatusCode = code
+	default:
+		res.Status = models.LinkHealthOK
+		res.StatusCode = code
+	}
+
+	return res
+}
+
+// Health returns the latest check results for the user's links keyed by link ID.
+func (c *Checker) Health(userID int) (map[int]models.LinkHealth, error) {
+	list, err := c.store.LinkHealth(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[int]models.LinkHealth, len(list))
+	for _, h := range list {
+		res[h.LinkID] = h
+	}
+
+	return res, nil
+}
+
+func (c *Checker) probe(ctx context.Context, method string, target string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", c.opts.UserAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	return resp.StatusCode, nil
+}
+
+// waitHost spaces out requests to the same host by PerHostInterval.
+func (c *Checker) waitHost(ctx context.Context, host string) error {
+	if c.opts.PerHostInterval <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	now := time.Now()
+	slot := c.nextSlot[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	c.nextSlot[host] = slot.Add(c.opts.PerHostInterval)
+	c.mu.Unlock()
+
+	wait := time.Until(slot)
+	if wait <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,202 @@
+package linkhealth
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/lib/netguard"
+)
+
+type fakeStore struct {
+	mu     sync.Mutex
+	links  []models.Link
+	health map[int]models.LinkHealth
+}
+
+func (f *fakeStore) AllLinks() ([]models.Link, error) {
+	return f.links, nil
+}
+
+func (f *fakeStore) SaveLinkHealth(h models.LinkHealth) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	prev := f.health[h.LinkID]
+	if h.Status == models.LinkHealthOK {
+		h.FailStreak = 0
+	} else {
+		h.FailStreak = prev.FailStreak + 1
+	}
+	f.health[h.LinkID] = h
+	return nil
+}
+
+func (f *fakeStore) LinkHealth(userID int) ([]models.LinkHealth, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var res []models.LinkHealth
+	for _, h := range f.health {
+		res = append(res, h)
+	}
+	return res, nil
+}
+
+func newTestServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+// newTestChecker may reach the loopback test server.
+func newTestChecker(store LinkStore) *Checker {
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, Options{
+		Interval:     time.Hour,
+		Timeout:      100 * time.Millisecond,
+		Concurrency:  4,
+		MaxRedirects: 3,
+		AllowPrivate: true,
+	})
+}
+
+func TestPrivateTargetsAreRefused(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+
+	c := New(slog.New(slog.NewTextHandler(io.Discard, nil)), &fakeStore{}, Options{
+		Interval:     time.Hour,
+		Timeout:      time.Second,
+		MaxRedirects: 3,
+	})
+
+	for _, target := range []string{
+		srv.URL + "/ok",
+		"http://localhost:" + strconv.Itoa(srv.Listener.Addr().(*net.TCPAddr).Port) + "/ok",
+		"http://10.0.0.1/",
+		"http://192.168.1.1:8080/admin",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/",
+		"http://[::ffff:127.0.0.1]/",
+	} {
+		t.Run(target, func(t *testing.T) {
+			h := c.Check(context.Background(), target)
+			if h.Status != models.LinkHealthUnreachable || h.StatusCode != 0 {
+				t.Fatalf("got %s/%d, want unreachable without a status code", h.Status, h.StatusCode)
+			}
+			if !strings.Contains(h.Error, netguard.ErrBlockedAddress.Error()) {
+				t.Errorf("error %q does not say the address is blocked", h.Error)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+
+	c := newTestChecker(&fakeStore{})
+
+	tests := []struct {
+		path   string
+		status string
+		code   int
+	}{
+		{"/ok", models.LinkHealthOK, http.StatusOK},
+		{"/no-head", models.LinkHealthOK, http.StatusOK},
+		{"/moved", models.LinkHealthOK, http.StatusOK},
+		{"/gone", models.LinkHealthBroken, http.StatusGone},
+		{"/loop", models.LinkHealthUnreachable, 0},
+		{"/slow", models.LinkHealthUnreachable, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			h := c.Check(context.Background(), srv.URL+tt.path)
+			if h.Status != tt.status || h.StatusCode != tt.code {
+				t.Fatalf("got %s/%d, want %s/%d (err %q)", h.Status, h.StatusCode, tt.status, tt.code, h.Error)
+			}
+		})
+	}
+}
+
+func TestCheckAllTracksFailStreak(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+
+	store := &fakeStore{
+		links: []models.Link{
+			{ID: 1, UserID: 1, LinkPath: srv.URL + "/ok"},
+			{ID: 2, UserID: 1, LinkPath: srv.URL + "/gone"},
+			{ID: 3, UserID: 1, LinkPath: "mailto:someone@example.com"},
+		},
+		health: make(map[int]models.LinkHealth),
+	}
+	c := newTestChecker(store)
+
+	c.CheckAll(context.Background())
+	c.CheckAll(context.Background())
+
+	health, err := c.Health(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := health[1]; got.Status != models.LinkHealthOK || got.FailStreak != 0 {
+		t.Fatalf("link 1: got %+v", got)
+	}
+
+	if got := health[2]; got.Status != models.LinkHealthBroken || got.FailStreak != 2 {
+		t.Fatalf("link 2: got %+v", got)
+	}
+
+	if _, ok := health[3]; ok {
+		t.Fatal("non-http link must not be checked")
+	}
+}
+
+func TestPerHostRateLimit(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+
+	store := &fakeStore{health: make(map[int]models.LinkHealth)}
+	for i := 1; i <= 3; i++ {
+		store.links = append(store.links, models.Link{ID: i, LinkPath: srv.URL + "/ok"})
+	}
+
+	c := newTestChecker(store)
+	c.opts.PerHostInterval = 50 * time.Millisecond
+
+	start := time.Now()
+	c.CheckAll(context.Background())
+
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("three requests to one host finished in %s, expected spacing", elapsed)
+	}
+}
@@ -0,0 +1,131 @@
+package linkio
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/lib/linkblock"
+)
+
+func sectionTitles(sections []models.LinkSection) map[int]string {
+	res := make(map[int]string, len(sections))
+	for _, s := range sections {
+		res[s.ID] = s.Title
+	}
+	return res
+}
+
+func sectionOf(l models.Link, titles map[int]string) string {
+	if l.SectionID == nil {
+		return ""
+	}
+	return titles[*l.SectionID]
+}
+
+func writeJSON(w io.Writer, links []models.Link, sections []models.LinkSection) error {
+	titles := sectionTitles(sections)
+
+	res := make([]jsonLink, 0, len(links))
+	for _, l := range links {
+		res = append(res, jsonLink{
+			Type:      l.Type,
+			LinkName:  l.LinkName,
+			LinkColor: l.LinkColor,
+			LinkPath:  l.LinkPath,
+			Payload:   l.Payload,
+			Hidden:    l.Hidden,
+			Section:   sectionOf(l, titles),
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(res)
+}
+
+func writeCSV(w io.Writer, links []models.Link, sections []models.LinkSection) error {
+	titles := sectionTitles(sections)
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"type", "link_name", "link_color", "link_path", "payload", "hidden", "section"})
+	for _, l := range links {
+		cw.Write([]string{
+			l.Type,
+			l.LinkName,
+			l.LinkColor,
+			l.LinkPath,
+			string(l.Payload),
+			strconv.FormatBool(l.Hidden),
+			sectionOf(l, titles),
+		})
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeBookmarks exports links with a followable URL as a Netscape bookmark
+// file, one folder per section. Text, social and vCard blocks have no single
+// URL and are left out.
+func writeBookmarks(w io.Writer, links []models.Link, sections []models.LinkSection) error {
+	grouped := make(map[int][]models.Link)
+	var top []models.Link
+	for _, l := range links {
+		if !linkblock.Followable(l.Type) || l.LinkPath == "" {
+			continue
+		}
+		if l.SectionID != nil {
+			grouped[*l.SectionID] = append(grouped[*l.SectionID], l)
+			continue
+		}
+		top = append(top, l)
+	}
+
+	known := make(map[int]bool, len(sections))
+	for _, s := range sections {
+		known[s.ID] = true
+	}
+	for id, ls := range grouped {
+		if !known[id] {
+			top = append(top, ls...)
+		}
+	}
+
+	bw := &errWriter{w: w}
+	bw.printf("<!DOCTYPE NETSCAPE-Bookmark-file-1>\n")
+	bw.printf("<META HTTP-EQUIV=\"Content-Type\" CONTENT=\"text/html; charset=UTF-8\">\n")
+	bw.printf("<TITLE>Bookmarks</TITLE>\n<H1>Bookmarks</H1>\n<DL><p>\n")
+	for _, l := range top {
+		writeBookmark(bw, l, "    ")
+	}
+	for _, s := range sections {
+		bw.printf("    <DT><H3>%s</H3>\n    <DL><p>\n", html.EscapeString(s.Title))
+		for _, l := range grouped[s.ID] {
+			writeBookmark(bw, l, "        ")
+		}
+		bw.printf("    </DL><p>\n")
+	}
+	bw.printf("</DL><p>\n")
+
+	return bw.err
+}
+
+func writeBookmark(w *errWriter, l models.Link, indent string) {
+	w.printf("%s<DT><A HREF=\"%s\">%s</A>\n", indent, html.EscapeString(l.LinkPath), html.EscapeString(l.LinkName))
+}
+
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) printf(format string, args ...any) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = fmt.Fprintf(e.w, format, args...)
+}
@@ -0,0 +1,267 @@
+// Package linkio imports links in bulk from CSV, JSON or browser bookmark
+// files and exports a profile's links in the same formats.
+package linkio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/url"
+	"strings"
+	"time"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/blob"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/lib/linkblock"
+	"url_profile/internal/lib/linkurl"
+	"url_profile/internal/services/sections"
+)
+
+// MaxRows caps a single import so one request can't hold the write lock for
+// long.
+const MaxRows = 1000
+
+var (
+	ErrUnknownFormat = errors.New("format must be one of json, csv, html")
+	ErrMalformed     = errors.New("malformed import file")
+	ErrTooManyRows   = fmt.Errorf("import is limited to %d links", MaxRows)
+	ErrEmpty         = errors.New("import file contains no links")
+)
+
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+	FormatHTML Format = "html"
+)
+
+// ParseFormat accepts a format name ("csv") or a media type ("text/csv").
+func ParseFormat(s string) (Format, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if mt, _, err := mime.ParseMediaType(s); err == nil {
+		s = mt
+	}
+
+	switch s {
+	case "json", "application/json":
+		return FormatJSON, nil
+	case "csv", "text/csv":
+		return FormatCSV, nil
+	case "html", "htm", "text/html":
+		return FormatHTML, nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	default:
+		return "application/json"
+	}
+}
+
+type LinkStore interface {
+	UserById(id int) (*models.User, error)
+	Settings(id int) (*models.Settings, error)
+	Sections(userID int) ([]models.LinkSection, error)
+	ImportLinks(userID int, rows []models.ImportedLink) (int, error)
+}
+
+// Result describes an import. Links holds the normalized rows that passed
+// validation; on a dry run nothing is written.
+type Result struct {
+	DryRun          bool
+	Total           int
+	Links           []models.ImportedLink
+	Errors          *requestModel.ValidationError
+	SectionsCreated int
+}
+
+type Options struct {
+	// URLTTL is how long the download URL of an exported file is valid.
+	URLTTL time.Duration
+}
+
+type Service struct {
+	log   *slog.Logger
+	store LinkStore
+	urls  *linkurl.Validator
+	blobs blob.Storage
+	opts  Options
+}
+
+func New(log *slog.Logger, store LinkStore, urls *linkurl.Validator, blobs blob.Storage, opts Options) *Service {
+	return &Service{
+		log:   log,
+		store: store,
+		urls:  urls,
+		blobs: blobs,
+		opts:  opts,
+	}
+}
+
+// Import parses r and validates every row. Unless dryRun is set, the links
+// are stored all at once; if any row is invalid nothing is stored and the
+// returned error is a *requestModel.ValidationError naming the rows.
+func (s *Service) Import(userID int, format Format, r io.Reader, dryRun bool) (*Result, error) {
+	rows, err := parse(format, r)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrEmpty
+	}
+	if len(rows) > MaxRows {
+		return nil, ErrTooManyRows
+	}
+
+	settings, err := s.store.Settings(userID)
+	if err != nil {
+		return nil, err
+	}
+	opts := linkurl.Options{StripTracking: settings.StripTracking}
+
+	res := &Result{DryRun: dryRun, Total: len(rows)}
+	verr := &requestModel.ValidationError{}
+	for _, row := range rows {
+		l, err := s.normalize(row, opts)
+		if err != nil {
+			verr.Merge(fmt.Sprintf("rows[%d]", row.Line), err)
+			continue
+		}
+		res.Links = append(res.Links, l)
+	}
+	res.Errors, _ = verr.OrNil().(*requestModel.ValidationError)
+
+	if dryRun {
+		return res, nil
+	}
+	if res.Errors != nil {
+		return nil, res.Errors
+	}
+
+	res.SectionsCreated, err = s.store.ImportLinks(userID, res.Links)
+	if err != nil {
+		s.log.Debug("Failed to import links", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (s *Service) normalize(row Row, opts linkurl.Options) (models.ImportedLink, error) {
+	verr := &requestModel.ValidationError{}
+
+	l := row.Link
+	if err := l.Normalize(s.urls, opts); err != nil {
+		errors.As(err, &verr)
+	}
+
+	// Bookmarks and spreadsheets often come without titles; the host is a
+	// better name than rejecting the row. Previews are not fetched here, a
+	// thousand-row import would take minutes.
+	if l.Type == linkblock.TypeLink && l.LinkName == "" && l.LinkPath != "" {
+		if u, err := url.Parse(l.LinkPath); err == nil {
+			l.LinkName = strings.TrimPrefix(u.Hostname(), "www.")
+		}
+	}
+	if l.Type == linkblock.TypeLink && l.LinkName == "" && len(verr.Fields) == 0 {
+		verr.Add("link_name", fmt.Errorf("link_name is required"))
+	}
+	verr.Fields = append(verr.Fields, row.Invalid.Fields...)
+
+	section := row.Section
+	if section != "" {
+		t, err := sections.NormalizeTitle(section)
+		if err != nil {
+			verr.Add("section", err)
+		}
+		section = t
+	}
+
+	if err := verr.OrNil(); err != nil {
+		return models.ImportedLink{}, err
+	}
+
+	return models.ImportedLink{
+		Link: models.Link{
+			Type:      l.Type,
+			LinkName:  l.LinkName,
+			LinkColor: l.LinkColor,
+			LinkPath:  l.LinkPath,
+			Payload:   l.Payload,
+			Hidden:    l.Hidden,
+		},
+		Section: section,
+	}, nil
+}
+
+// Export writes all of the user's live links, hidden ones included, so the
+// file can be imported back into another account.
+func (s *Service) Export(userID int, format Format, w io.Writer) error {
+	u, err := s.store.UserById(userID)
+	if err != nil {
+		return err
+	}
+
+	secs, err := s.store.Sections(userID)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case FormatCSV:
+		return writeCSV(w, u.Links, secs)
+	case FormatHTML:
+		return writeBookmarks(w, u.Links, secs)
+	case FormatJSON:
+		return writeJSON(w, u.Links, secs)
+	default:
+		return ErrUnknownFormat
+	}
+}
+
+// ExportFile exports the links into blob storage and returns a presigned
+// URL for the file. Each user has one file per format, replaced on every
+// export.
+func (s *Service) ExportFile(ctx context.Context, userID int, format Format) (*blob.Download, error) {
+	var buf bytes.Buffer
+	if err := s.Export(userID, format, &buf); err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("exports/%d/links.%s", userID, format)
+	if err := s.blobs.Put(ctx, key, &buf, format.ContentType()); err != nil {
+		return nil, err
+	}
+
+	u, err := s.blobs.Presign(ctx, key, s.opts.URLTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &blob.Download{URL: u, ExpiresAt: time.Now().Add(s.opts.URLTTL).UTC()}, nil
+}
+
+func parse(format Format, r io.Reader) ([]Row, error) {
+	switch format {
+	case FormatJSON:
+		return parseJSON(r)
+	case FormatCSV:
+		return parseCSV(r)
+	case FormatHTML:
+		return parseBookmarks(r)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
@@ -0,0 +1,249 @@
+package linkio
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/blob"
+	localblob "url_profile/internal/blob/local"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/lib/linkurl"
+)
+
+type fakeStore struct {
+	links    []models.Link
+	sections []models.LinkSection
+	settings models.Settings
+
+	imported [][]models.ImportedLink
+}
+
+func (f *fakeStore) UserById(id int) (*models.User, error) {
+	return &models.User{ID: id, Links: f.links}, nil
+}
+
+func (f *fakeStore) Settings(id int) (*models.Settings, error) {
+	s := f.settings
+	return &s, nil
+}
+
+func (f *fakeStore) Sections(userID int) ([]models.LinkSection, error) {
+	return f.sections, nil
+}
+
+func (f *fakeStore) ImportLinks(userID int, rows []models.ImportedLink) (int, error) {
+	f.imported = append(f.imported, rows)
+	return 0, nil
+}
+
+func newService(t *testing.T, f *fakeStore) (*Service, blob.Storage) {
+	t.Helper()
+
+	blobs, err := localblob.New(t.TempDir(), blob.NewSigner("secret", "https://files.example/files"))
+	if err != nil {
+		t.Fatalf("localblob: %v", err)
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return New(log, f, linkurl.New(nil), blobs, Options{URLTTL: time.Hour}), blobs
+}
+
+func TestImport(t *testing.T) {
+	valid := "url,name,section\n" +
+		"https://a.example/?gclid=x,,Work\n" +
+		"https://www.b.example,B,\n"
+
+	t.Run("valid", func(t *testing.T) {
+		f := &fakeStore{settings: models.Settings{StripTracking: true}}
+		s, _ := newService(t, f)
+
+		res, err := s.Import(1, FormatCSV, strings.NewReader(valid), false)
+		if err != nil {
+			t.Fatalf("Import: %v", err)
+		}
+		if res.Total != 2 || res.Errors != nil {
+			t.Errorf("result = %+v, want 2 rows without errors", res)
+		}
+		if len(f.imported) != 1 {
+			t.Fatalf("ImportLinks called %d times, want 1", len(f.imported))
+		}
+
+		want := []models.ImportedLink{
+			{Link: models.Link{Type: "link", LinkName: "a.example", LinkPath: "https://a.example/"}, Section: "Work"},
+			{Link: models.Link{Type: "link", LinkName: "B", LinkPath: "https://www.b.example"}},
+		}
+		for i, got := range f.imported[0] {
+			if got.Link.Type != want[i].Link.Type || got.Link.LinkName != want[i].Link.LinkName ||
+				got.Link.LinkPath != want[i].Link.LinkPath || got.Section != want[i].Section {
+				t.Errorf("row %d = %+v, want %+v", i, got, want[i])
+			}
+		}
+	})
+
+	invalid := valid +
+		"javascript:alert(1),Bad,\n" +
+		"https://c.example,C,,\n" +
+		"https://d.example,D," + strings.Repeat("x", 200) + "\n"
+	invalidCSV := strings.Replace(invalid, "url,name,section", "url,name,section,hidden", 1)
+	invalidCSV = strings.Replace(invalidCSV, "https://c.example,C,,", "https://c.example,C,,maybe", 1)
+
+	wantFields := []string{"rows[4].link_path", "rows[5].hidden", "rows[6].section"}
+
+	t.Run("one bad row stores nothing", func(t *testing.T) {
+		f := &fakeStore{}
+		s, _ := newService(t, f)
+
+		_, err := s.Import(1, FormatCSV, strings.NewReader(invalidCSV), false)
+		var verr *requestModel.ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("Import() error = %v, want *requestModel.ValidationError", err)
+		}
+		if got := fieldNames(verr); !slices.Equal(got, wantFields) {
+			t.Errorf("error fields = %q, want %q", got, wantFields)
+		}
+		if len(f.imported) != 0 {
+			t.Errorf("ImportLinks was called with %+v", f.imported)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		f := &fakeStore{}
+		s, _ := newService(t, f)
+
+		res, err := s.Import(1, FormatCSV, strings.NewReader(invalidCSV), true)
+		if err != nil {
+			t.Fatalf("Import: %v", err)
+		}
+		if !res.DryRun || res.Total != 5 || len(res.Links) != 2 || res.Errors == nil {
+			t.Errorf("result = %+v, want 5 rows, 2 valid, with errors", res)
+		}
+		if got := fieldNames(res.Errors); !slices.Equal(got, wantFields) {
+			t.Errorf("error fields = %q, want %q", got, wantFields)
+		}
+		if len(f.imported) != 0 {
+			t.Errorf("dry run stored %+v", f.imported)
+		}
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		s, _ := newService(t, &fakeStore{})
+
+		if _, err := s.Import(1, FormatCSV, strings.NewReader("url\n"), false); !errors.Is(err, ErrEmpty) {
+			t.Errorf("empty import: got %v, want ErrEmpty", err)
+		}
+
+		big := "url\n" + strings.Repeat("https://a.example\n", MaxRows+1)
+		if _, err := s.Import(1, FormatCSV, strings.NewReader(big), false); !errors.Is(err, ErrTooManyRows) {
+			t.Errorf("big import: got %v, want ErrTooManyRows", err)
+		}
+
+		if _, err := s.Import(1, Format("xml"), strings.NewReader("<a/>"), false); !errors.Is(err, ErrUnknownFormat) {
+			t.Errorf("unknown format: got %v, want ErrUnknownFormat", err)
+		}
+	})
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	work := 7
+	f := &fakeStore{
+		links: []models.Link{
+			{Type: "link", LinkName: "Site", LinkColor: "#ff0000", LinkPath: "https://a.example", SectionID: &work},
+			{Type: "email", LinkName: "Mail", LinkPath: "mailto:me@example.com", Payload: []byte(`{"address":"me@example.com"}`), Hidden: true},
+			{Type: "text", LinkName: "Heading", Payload: []byte(`{"text":"Hi","style":"heading"}`)},
+		},
+		sections: []models.LinkSection{{ID: work, Title: "Work"}},
+	}
+
+	for _, format := range []Format{FormatJSON, FormatCSV, FormatHTML} {
+		t.Run(string(format), func(t *testing.T) {
+			s, _ := newService(t, f)
+
+			var buf strings.Builder
+			if err := s.Export(1, format, &buf); err != nil {
+				t.Fatalf("Export: %v", err)
+			}
+
+			rows, err := parse(format, strings.NewReader(buf.String()))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			// Bookmarks only carry followable links.
+			want := len(f.links)
+			if format == FormatHTML {
+				want = 2
+			}
+			if len(rows) != want {
+				t.Fatalf("parsed %d rows, want %d:\n%s", len(rows), want, buf.String())
+			}
+			byName := make(map[string]Row, len(rows))
+			for _, r := range rows {
+				byName[r.Link.LinkName] = r
+			}
+			if site := byName["Site"]; site.Link.LinkPath != "https://a.example" || site.Section != "Work" {
+				t.Errorf("Site row = %+v", site)
+			}
+			if mail := byName["Mail"]; format != FormatHTML && !mail.Link.Hidden {
+				t.Errorf("hidden flag was lost: %+v", mail)
+			}
+		})
+	}
+}
+
+func TestExportFile(t *testing.T) {
+	f := &fakeStore{links: []models.Link{{Type: "link", LinkName: "Site", LinkPath: "https://a.example"}}}
+	s, blobs := newService(t, f)
+	ctx := context.Background()
+
+	before := time.Now()
+	d, err := s.ExportFile(ctx, 42, FormatCSV)
+	if err != nil {
+		t.Fatalf("ExportFile: %v", err)
+	}
+
+	if !strings.HasPrefix(d.URL, "https://files.example/files/exports/42/links.csv?") {
+		t.Errorf("URL = %q", d.URL)
+	}
+	if d.ExpiresAt.Before(before.Add(time.Hour)) || d.ExpiresAt.After(time.Now().Add(time.Hour)) {
+		t.Errorf("ExpiresAt = %v, want an hour from now", d.ExpiresAt)
+	}
+
+	rc, info, err := blobs.Get(ctx, "exports/42/links.csv")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer rc.Close()
+	body, _ := io.ReadAll(rc)
+	if !strings.Contains(string(body), "Site,,https://a.example") {
+		t.Errorf("stored file:\n%s", body)
+	}
+	if info.ContentType != FormatCSV.ContentType() {
+		t.Errorf("content type = %q, want %q", info.ContentType, FormatCSV.ContentType())
+	}
+
+	// A second export replaces the file instead of adding one.
+	if _, err := s.ExportFile(ctx, 42, FormatCSV); err != nil {
+		t.Fatalf("ExportFile: %v", err)
+	}
+	objs, err := blobs.List(ctx, "exports/42/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(objs) != 1 {
+		t.Errorf("exports/42/ holds %+v, want one file", objs)
+	}
+}
+
+func fieldNames(verr *requestModel.ValidationError) []string {
+	var res []string
+	for _, f := range verr.Fields {
+		res = append(res, f.Field)
+	}
+	return res
+}
@@ -0,0 +1,251 @@
+package linkio
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Row is one parsed import entry. Line points back into the source file so
+// errors can name the row the user has to fix.
+type Row struct {
+	Line    int
+	Link    requestModel.ReqLink
+	Section string
+	// Invalid holds fields that could not be parsed at all, e.g. a hidden
+	// column that is not a boolean. They are reported with the row's other
+	// validation errors.
+	Invalid requestModel.ValidationError
+}
+
+// jsonLink is the JSON import/export format: a link block plus the title of
+// the section it belongs to.
+type jsonLink struct {
+	Type      string          `json:"type,omitempty"`
+	LinkName  string          `json:"link_name"`
+	LinkColor string          `json:"link_color,omitempty"`
+	LinkPath  string          `json:"link_path,omitempty"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	Hidden    bool            `json:"hidden,omitempty"`
+	Section   string          `json:"section,omitempty"`
+}
+
+func parseJSON(r io.Reader) ([]Row, error) {
+	var items []jsonLink
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+
+	rows := make([]Row, 0, len(items))
+	for i, it := range items {
+		rows = append(rows, Row{
+			Line: i + 1,
+			Link: requestModel.ReqLink{
+				Type:      it.Type,
+				LinkName:  it.LinkName,
+				LinkColor: it.LinkColor,
+				LinkPath:  it.LinkPath,
+				Payload:   it.Payload,
+				Hidden:    it.Hidden,
+			},
+			Section: strings.TrimSpace(it.Section),
+		})
+	}
+
+	return rows, nil
+}
+
+// csvColumns maps accepted header names to the field they fill. Aliases cover
+// the exports of common link-in-bio tools.
+var csvColumns = map[string]string{
+	"type":       "type",
+	"link_name":  "name",
+	"name":       "name",
+	"title":      "name",
+	"link_path":  "path",
+	"url":        "path",
+	"link":       "path",
+	"href":       "path",
+	"link_color": "color",
+	"color":      "color",
+	"payload":    "payload",
+	"hidden":     "hidden",
+	"section":    "section",
+	"folder":     "section",
+}
+
+func parseCSV(r io.Reader) ([]Row, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+
+	cols := make(map[string]int)
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\uFEFF")))
+		if f, ok := csvColumns[h]; ok {
+			if _, dup := cols[f]; !dup {
+				cols[f] = i
+			}
+		}
+	}
+	if _, ok := cols["path"]; !ok {
+		if _, ok := cols["type"]; !ok {
+			return nil, fmt.Errorf("%w: header must contain a url or link_path column", ErrMalformed)
+		}
+	}
+
+	var rows []Row
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+		}
+
+		line, _ := cr.FieldPos(0)
+		get := func(f string) string {
+			i, ok := cols[f]
+			if !ok || i >= len(rec) {
+				return ""
+			}
+			return strings.TrimSpace(rec[i])
+		}
+
+		if strings.Join(rec, "") == "" {
+			continue
+		}
+
+		row := Row{
+			Line: line,
+			Link: requestModel.ReqLink{
+				Type:      get("type"),
+				LinkName:  get("name"),
+				LinkColor: get("color"),
+				LinkPath:  get("path"),
+			},
+			Section: get("section"),
+		}
+		if p := get("payload"); p != "" {
+			row.Link.Payload = json.RawMessage(p)
+		}
+		if h := get("hidden"); h != "" {
+			hidden, err := strconv.ParseBool(h)
+			if err != nil {
+				row.Invalid.Add("hidden", errors.New("hidden must be true or false"))
+			}
+			row.Link.Hidden = hidden
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// parseBookmarks reads the Netscape bookmark file format that every browser
+// exports. Folder names become section titles; nested folders use the
+// innermost name.
+func parseBookmarks(r io.Reader) ([]Row, error) {
+	z := html.NewTokenizer(r)
+
+	var (
+		rows    []Row
+		folders []string
+		pending string // folder title seen, waiting for its <DL>
+		inTitle bool
+		inLink  bool
+		cur     Row
+		text    strings.Builder
+		line    int
+	)
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if errors.Is(z.Err(), io.EOF) {
+				return rows, nil
+			}
+			return nil, fmt.Errorf("%w: %w", ErrMalformed, z.Err())
+
+		case html.StartTagToken:
+			name, hasAttr := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.H3:
+				inTitle = true
+				text.Reset()
+			case atom.Dl:
+				folders = append(folders, pending)
+				pending = ""
+			case atom.A:
+				inLink = true
+				text.Reset()
+				line++
+				cur = Row{Line: line}
+				for hasAttr {
+					var k, v []byte
+					k, v, hasAttr = z.TagAttr()
+					if string(k) == "href" {
+						cur.Link.LinkPath = strings.TrimSpace(string(v))
+					}
+				}
+			}
+
+		case html.TextToken:
+			if inTitle || inLink {
+				text.Write(z.Text())
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.H3:
+				inTitle = false
+				pending = strings.TrimSpace(text.String())
+			case atom.Dl:
+				if len(folders) > 0 {
+					folders = folders[:len(folders)-1]
+				}
+			case atom.A:
+				inLink = false
+				if skipBookmark(cur.Link.LinkPath) {
+					continue
+				}
+				cur.Link.LinkName = strings.TrimSpace(text.String())
+				for i := len(folders) - 1; i >= 0; i-- {
+					if folders[i] != "" {
+						cur.Section = folders[i]
+						break
+					}
+				}
+				rows = append(rows, cur)
+			}
+		}
+	}
+}
+
+// skipBookmark drops browser-internal entries (smart folders, bookmarklets)
+// that can never be valid profile links.
+func skipBookmark(href string) bool {
+	h := strings.ToLower(href)
+	return h == "" || strings.HasPrefix(h, "place:") || strings.HasPrefix(h, "javascript:") ||
+		strings.HasPrefix(h, "chrome:") || strings.HasPrefix(h, "about:")
+}
@@ -0,0 +1,149 @@
+package linkio
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+)
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []Row
+		wantErr error
+	}{
+		{
+			name: "export format",
+			in: "type,link_name,link_color,link_path,payload,hidden,section\n" +
+				"link,Site,#ff0000,https://a.example,,false,Work\n" +
+				`email,Mail,,,"{""address"":""me@example.com""}",true,` + "\n",
+			want: []Row{
+				{Line: 2, Link: requestModel.ReqLink{Type: "link", LinkName: "Site", LinkColor: "#ff0000", LinkPath: "https://a.example"}, Section: "Work"},
+				{Line: 3, Link: requestModel.ReqLink{Type: "email", LinkName: "Mail", Payload: []byte(`{"address":"me@example.com"}`), Hidden: true}},
+			},
+		},
+		{
+			name: "aliases, bom and spacing",
+			in:   "\uFEFFTitle, URL ,Folder,notes\n Site , https://a.example ,Work,ignored\n",
+			want: []Row{
+				{Line: 2, Link: requestModel.ReqLink{LinkName: "Site", LinkPath: "https://a.example"}, Section: "Work"},
+			},
+		},
+		{
+			name: "first of duplicate columns wins",
+			in:   "url,link,name\nhttps://a.example,https://b.example,A\n",
+			want: []Row{
+				{Line: 2, Link: requestModel.ReqLink{LinkName: "A", LinkPath: "https://a.example"}},
+			},
+		},
+		{
+			name: "blank and short rows",
+			in:   "url,name,section\n\n,,\nhttps://a.example\n",
+			want: []Row{
+				{Line: 4, Link: requestModel.ReqLink{LinkPath: "https://a.example"}},
+			},
+		},
+		{
+			name: "type without url column",
+			in:   "type,payload\ntext,\"{\"\"text\"\":\"\"Hi\"\"}\"\n",
+			want: []Row{
+				{Line: 2, Link: requestModel.ReqLink{Type: "text", Payload: []byte(`{"text":"Hi"}`)}},
+			},
+		},
+		{
+			name: "hidden is not a boolean",
+			in:   "url,hidden\nhttps://a.example,yes\nhttps://b.example,1\n",
+			want: []Row{
+				{Line: 2, Link: requestModel.ReqLink{LinkPath: "https://a.example"},
+					Invalid: requestModel.ValidationError{Fields: []requestModel.FieldError{{Field: "hidden", Message: "hidden must be true or false"}}}},
+				{Line: 3, Link: requestModel.ReqLink{LinkPath: "https://b.example", Hidden: true}},
+			},
+		},
+		{name: "empty file", in: ""},
+		{name: "no url column", in: "name,section\nSite,Work\n", wantErr: ErrMalformed},
+		{name: "broken quoting", in: "url\n\"https://a.example\n", wantErr: ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCSV(strings.NewReader(tt.in))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseCSV() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCSV() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	in := `[
+		{"link_name": "Site", "link_path": "https://a.example", "section": " Work "},
+		{"type": "text", "link_name": "", "payload": {"text": "Hi"}, "hidden": true}
+	]`
+
+	got, err := parseJSON(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("parseJSON: %v", err)
+	}
+
+	want := []Row{
+		{Line: 1, Link: requestModel.ReqLink{LinkName: "Site", LinkPath: "https://a.example"}, Section: "Work"},
+		{Line: 2, Link: requestModel.ReqLink{Type: "text", Payload: []byte(`{"text": "Hi"}`), Hidden: true}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseJSON() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	for _, in := range []string{`{"link_path": "https://a.example"}`, `[{"hidden": "yes"}]`, `[`} {
+		if _, err := parseJSON(strings.NewReader(in)); !errors.Is(err, ErrMalformed) {
+			t.Errorf("parseJSON(%s) error = %v, want ErrMalformed", in, err)
+		}
+	}
+}
+
+func TestParseBookmarks(t *testing.T) {
+	in := `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+    <DT><A HREF="https://top.example" ADD_DATE="1">Top &amp; more</A>
+    <DT><H3>Work</H3>
+    <DL><p>
+        <DT><A HREF=" https://work.example ">Work site</A>
+        <DT><A HREF="javascript:alert(1)">Bookmarklet</A>
+        <DT><H3>Nested</H3>
+        <DL><p>
+            <DT><A HREF="https://nested.example"></A>
+        </DL><p>
+        <DT><A HREF="https://after-nested.example">After</A>
+    </DL><p>
+    <DT><A HREF="place:sort=8">Recent</A>
+    <DT><A>No href</A>
+    <DT><A HREF="https://bottom.example">Bottom</A>
+</DL><p>
+`
+
+	got, err := parseBookmarks(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("parseBookmarks: %v", err)
+	}
+
+	// Line counts every <A>, skipped ones included, so the numbers match
+	// what the user sees in the file.
+	want := []Row{
+		{Line: 1, Link: requestModel.ReqLink{LinkName: "Top & more", LinkPath: "https://top.example"}},
+		{Line: 2, Link: requestModel.ReqLink{LinkName: "Work site", LinkPath: "https://work.example"}, Section: "Work"},
+		{Line: 4, Link: requestModel.ReqLink{LinkPath: "https://nested.example"}, Section: "Nested"},
+		{Line: 5, Link: requestModel.ReqLink{LinkName: "After", LinkPath: "https://after-nested.example"}, Section: "Work"},
+		{Line: 8, Link: requestModel.ReqLink{LinkName: "Bottom", LinkPath: "https://bottom.example"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseBookmarks() =\n%+v\nwant\n%+v", got, want)
+	}
+}
@@ -0,0 +1,203 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/lib/netguard"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	ErrBlockedAddress = netguard.ErrBlockedAddress
+	ErrNotHTML        = errors.New("target is not an html page")
+	ErrBadScheme      = errors.New("only http and https links can be previewed")
+)
+
+type fetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+func newFetcher(timeout time.Duration, maxBytes int64, maxRedirects int, allowPrivate bool) *fetcher {
+	// Redirects dial through the same guard, so they cannot reach internal
+	// hosts either.
+	dialer := netguard.Dialer(timeout, allowPrivate)
+
+	return &fetcher{
+		maxBytes: maxBytes,
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:                 nil,
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   timeout,
+				ResponseHeaderTimeout: timeout,
+				MaxIdleConns:          10,
+				IdleConnTimeout:       30 * time.Second,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > maxRedirects {
+					return fmt.Errorf("stopped after %d redirects", maxRedirects)
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return ErrBadScheme
+				}
+				return nil
+			},
+		},
+	}
+}
+
+func (f *fetcher) fetch(ctx context.Context, target string) (*models.LinkPreview, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, ErrBadScheme
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "url_profile-preview/1.0")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mt != "text/html" && mt != "application/xhtml+xml" {
+		return nil, ErrNotHTML
+	}
+
+	p := parseHead(io.LimitReader(resp.Body, f.maxBytes), resp.Request.URL)
+	p.URL = target
+	p.FetchedAt = time.Now().UTC()
+
+	return p, nil
+}
+
+// parseHead extracts title, description, favicon and OpenGraph image from
+// the document head. Relative URLs are resolved against base.
+func parseHead(r io.Reader, base *url.URL) *models.LinkPreview {
+	var (
+		p              = &models.LinkPreview{}
+		title, ogTitle string
+		desc, ogDesc   string
+		inTitle        bool
+	)
+
+	z := html.NewTokenizer(r)
+loop:
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			break loop
+
+		case html.TextToken:
+			if inTitle && title == "" {
+				title = strings.TrimSpace(string(z.Text()))
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				break loop
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			attrs := map[string]string{}
+			for hasAttr {
+				var k, v []byte
+				k, v, hasAttr = z.TagAttr()
+				attrs[strings.ToLower(string(k))] = string(v)
+			}
+
+			switch string(name) {
+			case "title":
+				inTitle = tt == html.StartTagToken
+			case "body":
+				break loop
+			case "meta":
+				key := strings.ToLower(attrs["property"])
+				if key == "" {
+					key = strings.ToLower(attrs["name"])
+				}
+				content := strings.TrimSpace(attrs["content"])
+				switch key {
+				case "og:title":
+					ogTitle = content
+				case "og:description":
+					ogDesc = content
+				case "description":
+					desc = content
+				case "og:image", "og:image:url":
+					if p.Image == "" {
+						p.Image = resolve(base, content)
+					}
+				}
+			case "link":
+				rel := strings.ToLower(attrs["rel"])
+				if p.Favicon == "" && (rel == "icon" || rel == "shortcut icon" || rel == "apple-touch-icon") {
+					p.Favicon = resolve(base, attrs["href"])
+				}
+			}
+		}
+	}
+
+	p.Title = firstNonEmpty(ogTitle, title)
+	p.Description = firstNonEmpty(ogDesc, desc)
+	if p.Favicon == "" {
+		p.Favicon = resolve(base, "/favicon.ico")
+	}
+
+	return p
+}
+
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+
+	return u.String()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,130 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHead(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post")
+
+	tests := []struct {
+		name                      string
+		html                      string
+		title, desc, favicon, img string
+	}{
+		{
+			name:    "plain head",
+			html:    `<html><head><title> Hello </title><meta name="description" content="About me"></head></html>`,
+			title:   "Hello",
+			desc:    "About me",
+			favicon: "https://example.com/favicon.ico",
+		},
+		{
+			name: "opengraph wins",
+			html: `<head><title>Plain</title>
+				<meta name="description" content="plain desc">
+				<meta property="og:title" content="OG title">
+				<meta property="og:description" content="OG desc">
+				<meta property="og:image" content="/img/cover.png">
+				<link rel="icon" href="icons/fav.png"></head>`,
+			title:   "OG title",
+			desc:    "OG desc",
+			favicon: "https://example.com/blog/icons/fav.png",
+			img:     "https://example.com/img/cover.png",
+		},
+		{
+			name:    "case-insensitive keys and first icon",
+			html:    `<head><META NAME="Description" CONTENT="x"><link rel="Shortcut Icon" href="//cdn.example.com/a.ico"><link rel="icon" href="/b.ico"></head>`,
+			desc:    "x",
+			favicon: "https://cdn.example.com/a.ico",
+		},
+		{
+			name:    "unsafe urls are dropped",
+			html:    `<head><meta property="og:image" content="javascript:alert(1)"><link rel="icon" href="data:image/png;base64,AA=="></head>`,
+			favicon: "https://example.com/favicon.ico",
+		},
+		{
+			name:    "body ends the scan",
+			html:    `<head><title>Head</title></head><body><meta property="og:title" content="late"><title>Body</title></body>`,
+			title:   "Head",
+			favicon: "https://example.com/favicon.ico",
+		},
+		{
+			name:    "no head element",
+			html:    `<title>Bare</title><p>text</p>`,
+			title:   "Bare",
+			favicon: "https://example.com/favicon.ico",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := parseHead(strings.NewReader(tt.html), base)
+			if p.Title != tt.title || p.Description != tt.desc || p.Favicon != tt.favicon || p.Image != tt.img {
+				t.Errorf("got title %q desc %q favicon %q image %q, want %q %q %q %q",
+					p.Title, p.Description, p.Favicon, p.Image, tt.title, tt.desc, tt.favicon, tt.img)
+			}
+		})
+	}
+}
+
+func TestFetchRefusesPrivateTargets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<title>internal</title>"))
+	}))
+	defer srv.Close()
+
+	f := newFetcher(time.Second, 1<<16, 3, false)
+
+	for _, target := range []string{srv.URL, "http://10.0.0.1/", "http://169.254.169.254/", "http://[::ffff:127.0.0.1]/"} {
+		if _, err := f.fetch(context.Background(), target); !errors.Is(err, ErrBlockedAddress) {
+			t.Errorf("fetch(%s): got %v, want ErrBlockedAddress", target, err)
+		}
+	}
+}
+
+func TestFetchRefusesRedirectToPrivate(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the redirect reached the internal host")
+	}))
+	defer internal.Close()
+
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/scheme":
+			http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+		default:
+			http.Redirect(w, r, internal.URL, http.StatusFound)
+		}
+	}))
+	defer public.Close()
+
+	// The test servers both listen on loopback, so let the first hop through
+	// as if it were a public host; every other dial goes through the guard.
+	f := newFetcher(time.Second, 1<<16, 3, false)
+	tr := f.client.Transport.(*http.Transport)
+	guarded := tr.DialContext
+	tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if addr == public.Listener.Addr().String() {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		}
+		return guarded(ctx, network, addr)
+	}
+
+	if _, err := f.fetch(context.Background(), public.URL); !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("redirect to loopback: got %v, want ErrBlockedAddress", err)
+	}
+	if _, err := f.fetch(context.Background(), public.URL+"/scheme"); !errors.Is(err, ErrBadScheme) {
+		t.Errorf("redirect to file://: got %v, want ErrBadScheme", err)
+	}
+}
@@ -0,0 +1,100 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+)
+
+var ErrDisabled = errors.New("link previews are disabled")
+
+type PreviewStore interface {
+	LinkPreview(url string) (*models.LinkPreview, error)
+	SaveLinkPreview(preview models.LinkPreview) error
+	LinkPreviews(userID int) ([]models.LinkPreview, error)
+}
+
+type Options struct {
+	Enabled      bool
+	Timeout      time.Duration
+	MaxBytes     int64
+	TTL          time.Duration
+	MaxRedirects int
+	AllowPrivate bool
+}
+
+// Service resolves title, description, favicon and og:image for link targets.
+// Results are cached in the store by URL for TTL.
+type Service struct {
+	log     *slog.Logger
+	store   PreviewStore
+	opts    Options
+	fetcher *fetcher
+}
+
+func New(log *slog.Logger, store PreviewStore, opts Options) *Service {
+	return &Service{
+		log:     log,
+		store:   store,
+		opts:    opts,
+		fetcher: newFetcher(opts.Timeout, opts.MaxBytes, opts.MaxRedirects, opts.AllowPrivate),
+	}
+}
+
+// Preview returns the cached preview for target or fetches a fresh one when
+// the cache entry is missing or older than TTL.
+func (s *Service) Preview(ctx context.Context, target string) (*models.LinkPreview, error) {
+	if !s.opts.Enabled {
+		return nil, ErrDisabled
+	}
+
+	p, err := s.store.LinkPreview(target)
+	if err == nil && time.Since(p.FetchedAt) < s.opts.TTL {
+		return p, nil
+	}
+
+	if err != nil && !errors.Is(err, store.ErrPreviewNotFound) {
+		return nil, err
+	}
+
+	return s.Refresh(ctx, target)
+}
+
+// Refresh fetches target bypassing the cache and stores the result.
+func (s *Service) Refresh(ctx context.Context, target string) (*models.LinkPreview, error) {
+	if !s.opts.Enabled {
+		return nil, ErrDisabled
+	}
+
+	p, err := s.fetcher.fetch(ctx, target)
+	if err != nil {
+		s.log.Debug("link preview fetch failed",
+			slog.String("url", target),
+			slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	if err := s.store.SaveLinkPreview(*p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Previews returns stored previews for the user's links keyed by link path.
+func (s *Service) Previews(userID int) (map[string]models.LinkPreview, error) {
+	list, err := s.store.LinkPreviews(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]models.LinkPreview, len(list))
+	for _, p := range list {
+		res[p.URL] = p
+	}
+
+	return res, nil
+}
@@ -0,0 +1,109 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"url_profile/internal/domain/models"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedType = errors.New("only jpeg, png, gif and webp images are supported")
+	ErrTooManyPixels   = errors.New("image dimensions are too large")
+)
+
+const maxPixels = 40_000_000
+
+type variant struct {
+	name   string
+	width  int
+	height int
+}
+
+var variants = map[string][]variant{
+	models.ImageAvatar: {
+		{name: "64", width: 64, height: 64},
+		{name: "256", width: 256, height: 256},
+		{name: "512", width: 512, height: 512},
+	},
+	models.ImageBanner: {
+		{name: "480", width: 480, height: 160},
+		{name: "960", width: 960, height: 320},
+		{name: "1500", width: 1500, height: 500},
+	},
+}
+
+// sniff checks the magic bytes; the client supplied Content-Type is ignored.
+func sniff(data []byte) (string, error) {
+	switch ct := http.DetectContentType(data); ct {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return ct, nil
+	default:
+		return "", ErrUnsupportedType
+	}
+}
+
+// decode checks the header dimensions before decoding so a tiny file cannot
+// expand into gigabytes of pixels.
+func decode(data []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+
+	return img, nil
+}
+
+// render center-crops img to the variant's aspect ratio and scales it down.
+// Re-encoding from decoded pixels drops EXIF and any other metadata.
+func render(img image.Image, v variant, format string) ([]byte, error) {
+	src := cropToAspect(img.Bounds(), v.width, v.height)
+	dst := image.NewRGBA(image.Rect(0, 0, v.width, v.height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, src, xdraw.Src, nil)
+
+	var buf bytes.Buffer
+	var err error
+	if format == "jpg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&buf, dst)
+	}
+
+	return buf.Bytes(), err
+}
+
+func cropToAspect(b image.Rectangle, w, h int) image.Rectangle {
+	bw, bh := b.Dx(), b.Dy()
+	if bw*h > bh*w {
+		cw := bh * w / h
+		x := b.Min.X + (bw-cw)/2
+		return image.Rect(x, b.Min.Y, x+cw, b.Max.Y)
+	}
+
+	ch := bw * h / w
+	y := b.Min.Y + (bh-ch)/2
+	return image.Rect(b.Min.X, y, b.Max.X, y+ch)
+}
+
+func outputFormat(contentType string) string {
+	if contentType == "image/jpeg" {
+		return "jpg"
+	}
+	return "png"
+}
@@ -0,0 +1,181 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("jpeg encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodeGIF(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("gif encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// withExif inserts an APP1 Exif segment carrying marker right after the
+// JPEG start-of-image marker.
+func withExif(data []byte, marker string) []byte {
+	payload := append([]byte("Exif\x00\x00"), marker...)
+	n := len(payload) + 2
+	seg := append([]byte{0xff, 0xe1, byte(n >> 8), byte(n)}, payload...)
+
+	res := append([]byte{}, data[:2]...)
+	res = append(res, seg...)
+	return append(res, data[2:]...)
+}
+
+func TestSniff(t *testing.T) {
+	img := testImage(4, 4)
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+		err  error
+	}{
+		{"png", encodePNG(t, img), "image/png", nil},
+		{"jpeg", encodeJPEG(t, img), "image/jpeg", nil},
+		{"gif", encodeGIF(t, img), "image/gif", nil},
+		{"webp", []byte("RIFF\x24\x00\x00\x00WEBPVP8 \x18\x00\x00\x00"), "image/webp", nil},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"/>`), "", ErrUnsupportedType},
+		{"html", []byte("<!DOCTYPE html><html></html>"), "", ErrUnsupportedType},
+		{"pdf", []byte("%PDF-1.7\n"), "", ErrUnsupportedType},
+		{"png name only", []byte("this is not a png"), "", ErrUnsupportedType},
+		{"empty", nil, "", ErrUnsupportedType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sniff(tt.data)
+			if !errors.Is(err, tt.err) || got != tt.want {
+				t.Errorf("sniff = %q, %v, want %q, %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	if _, err := decode(encodePNG(t, testImage(3, 2))); err != nil {
+		t.Errorf("valid png: %v", err)
+	}
+
+	// A JPEG header that is cut off right after the magic bytes.
+	if _, err := decode(encodeJPEG(t, testImage(8, 8))[:20]); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("truncated jpeg: got %v, want ErrUnsupportedType", err)
+	}
+
+	// A tiny GIF whose header claims 65535x65535 pixels.
+	bomb := encodeGIF(t, testImage(1, 1))
+	bomb[6], bomb[7], bomb[8], bomb[9] = 0xff, 0xff, 0xff, 0xff
+	if _, err := decode(bomb); !errors.Is(err, ErrTooManyPixels) {
+		t.Errorf("decompression bomb: got %v, want ErrTooManyPixels", err)
+	}
+}
+
+func TestRenderResizes(t *testing.T) {
+	src := testImage(300, 100)
+
+	for _, format := range []string{"png", "jpg"} {
+		for _, v := range append(variants["avatar"], variants["banner"]...) {
+			out, err := render(src, v, format)
+			if err != nil {
+				t.Fatalf("render %s %s: %v", format, v.name, err)
+			}
+
+			cfg, got, err := image.DecodeConfig(bytes.NewReader(out))
+			if err != nil {
+				t.Fatalf("decode %s %s: %v", format, v.name, err)
+			}
+			if want := map[string]string{"png": "png", "jpg": "jpeg"}[format]; got != want {
+				t.Errorf("%s variant encoded as %s", v.name, got)
+			}
+			if cfg.Width != v.width || cfg.Height != v.height {
+				t.Errorf("%s %s: got %dx%d, want %dx%d", format, v.name, cfg.Width, cfg.Height, v.width, v.height)
+			}
+		}
+	}
+}
+
+func TestRenderStripsExif(t *testing.T) {
+	const marker = "GPS 55.7558N 37.6173E"
+
+	data := withExif(encodeJPEG(t, testImage(64, 64)), marker)
+	if !bytes.Contains(data, []byte(marker)) {
+		t.Fatal("test image has no EXIF")
+	}
+
+	img, err := decode(data)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	out, err := render(img, variants["avatar"][0], "jpg")
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if bytes.Contains(out, []byte("Exif")) || bytes.Contains(out, []byte(marker)) {
+		t.Error("rendered variant still carries EXIF")
+	}
+}
+
+func TestCropToAspect(t *testing.T) {
+	tests := []struct {
+		name string
+		b    image.Rectangle
+		w, h int
+		want image.Rectangle
+	}{
+		{"wide to square", image.Rect(0, 0, 300, 100), 1, 1, image.Rect(100, 0, 200, 100)},
+		{"tall to square", image.Rect(0, 0, 100, 300), 1, 1, image.Rect(0, 100, 100, 200)},
+		{"square to banner", image.Rect(0, 0, 300, 300), 3, 1, image.Rect(0, 100, 300, 200)},
+		{"already banner", image.Rect(0, 0, 1500, 500), 3, 1, image.Rect(0, 0, 1500, 500)},
+		{"offset bounds", image.Rect(10, 20, 310, 120), 1, 1, image.Rect(110, 20, 210, 120)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cropToAspect(tt.b, tt.w, tt.h); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strconv"
+	"strings"
+	"url_profile/internal/blob"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownKind = errors.New("unknown image kind")
+	ErrTooLarge    = errors.New("image file is too large")
+)
+
+type ImageStore interface {
+	ProfileImage(userID int, kind string) (*models.ProfileImage, error)
+	ProfileImages(userID int) ([]models.ProfileImage, error)
+	SaveProfileImage(img models.ProfileImage) error
+	DeleteProfileImage(userID int, kind string) error
+}
+
+type Options struct {
+	MaxBytes int64
+	BaseURL  string
+}
+
+// Service turns uploaded avatars and banners into resized variants kept in
+// blob storage.
+type Service struct {
+	log   *slog.Logger
+	store ImageStore
+	blobs blob.Storage
+	opts  Options
+}
+
+func New(log *slog.Logger, store ImageStore, blobs blob.Storage, opts Options) *Service {
+	return &Service{
+		log:   log,
+		store: store,
+		blobs: blobs,
+		opts:  opts,
+	}
+}
+
+// Upload validates and processes an image and replaces the user's current
+// image of that kind. It returns the URLs of the new variants.
+func (s *Service) Upload(ctx context.Context, userID int, kind string, r io.Reader) (map[string]string, error) {
+	if _, ok := variants[kind]; !ok {
+		return nil, ErrUnknownKind
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, s.opts.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > s.opts.MaxBytes {
+		return nil, ErrTooLarge
+	}
+
+	ct, err := sniff(data)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	next := models.ProfileImage{
+		UserID:  userID,
+		Kind:    kind,
+		Version: uuid.NewString(),
+		Format:  outputFormat(ct),
+	}
+
+	for _, v := range variants[kind] {
+		out, err := render(img, v, next.Format)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s variant: %w", v.name, err)
+		}
+
+		if err := s.blobs.Put(ctx, variantKey(next, v), bytes.NewReader(out), "image/"+next.Format); err != nil {
+			s.log.Error("failed to store image variant",
+				slog.Int("user_id", userID),
+				slog.String("error", err.Error()))
+			return nil, err
+		}
+	}
+
+	prev, err := s.store.ProfileImage(userID, kind)
+	if err != nil && !errors.Is(err, store.ErrImageNotFound) {
+		return nil, err
+	}
+
+	if err := s.store.SaveProfileImage(next); err != nil {
+		s.removeVariants(ctx, next)
+		return nil, err
+	}
+
+	if prev != nil {
+		s.removeVariants(ctx, *prev)
+	}
+
+	return s.urls(next), nil
+}
+
+func (s *Service) Delete(ctx context.Context, userID int, kind string) error {
+	if _, ok := variants[kind]; !ok {
+		return ErrUnknownKind
+	}
+
+	prev, err := s.store.ProfileImage(userID, kind)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.DeleteProfileImage(userID, kind); err != nil {
+		return err
+	}
+
+	s.removeVariants(ctx, *prev)
+	return nil
+}
+
+// ImageURLs returns variant URLs keyed by image kind and then variant size.
+func (s *Service) ImageURLs(userID int) (map[string]map[string]string, error) {
+	images, err := s.store.ProfileImages(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]map[string]string, len(images))
+	for _, img := range images {
+		res[img.Kind] = s.urls(img)
+	}
+
+	return res, nil
+}
+
+// Open reads an image variant. The bucket also holds exports and backups,
+// so any key that is not shaped like a variant is refused.
+func (s *Service) Open(ctx context.Context, key string) (io.ReadCloser, *blob.Info, error) {
+	if !isVariantKey(key) {
+		return nil, nil, blob.ErrInvalidKey
+	}
+
+	return s.blobs.Get(ctx, key)
+}
+
+func (s *Service) urls(img models.ProfileImage) map[string]string {
+	res := make(map[string]string, len(variants[img.Kind]))
+	for _, v := range variants[img.Kind] {
+		res[v.name] = s.opts.BaseURL + "/" + variantKey(img, v)
+	}
+
+	return res
+}
+
+func (s *Service) removeVariants(ctx context.Context, img models.ProfileImage) {
+	for _, v := range variants[img.Kind] {
+		if err := s.blobs.Delete(ctx, variantKey(img, v)); err != nil {
+			s.log.Warn("failed to remove image variant",
+				slog.String("key", variantKey(img, v)),
+				slog.String("error", err.Error()))
+		}
+	}
+}
+
+func variantKey(img models.ProfileImage, v variant) string {
+	return path.Join(img.Kind, fmt.Sprint(img.UserID), img.Version, v.name+"."+img.Format)
+}
+
+// isVariantKey reports whether key is one variantKey can produce.
+func isVariantKey(key string) bool {
+	parts := strings.Split(key, "/")
+	if len(parts) != 4 {
+		return false
+	}
+	kind, userID, version, file := parts[0], parts[1], parts[2], parts[3]
+
+	if id, err := strconv.Atoi(userID); err != nil || id <= 0 || strconv.Itoa(id) != userID {
+		return false
+	}
+	if _, err := uuid.Parse(version); err != nil || len(version) != 36 {
+		return false
+	}
+
+	name, format, ok := strings.Cut(file, ".")
+	if !ok || (format != "jpg" && format != "png") {
+		return false
+	}
+
+	for _, v := range variants[kind] {
+		if v.name == name {
+			return true
+		}
+	}
+
+	return false
+}
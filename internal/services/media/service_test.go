@@ -0,0 +1,143 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"url_profile/internal/blob"
+	localblob "url_profile/internal/blob/local"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+)
+
+type fakeStore struct {
+	images map[string]models.ProfileImage
+}
+
+func (f *fakeStore) key(userID int, kind string) string {
+	return fmt.Sprintf("%s/%d", kind, userID)
+}
+
+func (f *fakeStore) ProfileImage(userID int, kind string) (*models.ProfileImage, error) {
+	img, ok := f.images[f.key(userID, kind)]
+	if !ok {
+		return nil, store.ErrImageNotFound
+	}
+	return &img, nil
+}
+
+func (f *fakeStore) ProfileImages(userID int) ([]models.ProfileImage, error) {
+	var res []models.ProfileImage
+	for _, img := range f.images {
+		if img.UserID == userID {
+			res = append(res, img)
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeStore) SaveProfileImage(img models.ProfileImage) error {
+	f.images[f.key(img.UserID, img.Kind)] = img
+	return nil
+}
+
+func (f *fakeStore) DeleteProfileImage(userID int, kind string) error {
+	delete(f.images, f.key(userID, kind))
+	return nil
+}
+
+func newService(t *testing.T) (*Service, blob.Storage) {
+	t.Helper()
+
+	blobs, err := localblob.New(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("blob storage: %v", err)
+	}
+
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), &fakeStore{images: map[string]models.ProfileImage{}}, blobs, Options{
+		MaxBytes: 1 << 20,
+		BaseURL:  "/media",
+	})
+	return s, blobs
+}
+
+func TestUploadAndOpen(t *testing.T) {
+	s, _ := newService(t)
+	ctx := context.Background()
+
+	urls, err := s.Upload(ctx, 1, models.ImageAvatar, bytes.NewReader(encodeJPEG(t, testImage(100, 80))))
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if len(urls) != len(variants[models.ImageAvatar]) {
+		t.Fatalf("got %d urls, want one per variant", len(urls))
+	}
+
+	for name, url := range urls {
+		key := strings.TrimPrefix(url, "/media/")
+		if !strings.HasSuffix(key, "/"+name+".jpg") {
+			t.Errorf("variant %s has key %s", name, key)
+		}
+
+		rc, info, err := s.Open(ctx, key)
+		if err != nil {
+			t.Fatalf("Open(%s): %v", key, err)
+		}
+		rc.Close()
+		if info.ContentType != "image/jpeg" {
+			t.Errorf("%s served as %s", key, info.ContentType)
+		}
+	}
+
+	if _, err := s.Upload(ctx, 1, models.ImageAvatar, strings.NewReader("<svg/>")); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("svg upload: got %v, want ErrUnsupportedType", err)
+	}
+	if _, err := s.Upload(ctx, 1, "cover", bytes.NewReader(encodePNG(t, testImage(4, 4)))); !errors.Is(err, ErrUnknownKind) {
+		t.Errorf("unknown kind: got %v, want ErrUnknownKind", err)
+	}
+}
+
+func TestOpenOnlyServesVariants(t *testing.T) {
+	s, blobs := newService(t)
+	ctx := context.Background()
+
+	const version = "0b7e6a42-6f3c-4b8e-9a55-1c2d3e4f5a6b"
+	for _, key := range []string{
+		"exports/1/profile.json",
+		"backups/2024-01-01.db",
+		"avatar/1/" + version + "/64.jpg",
+	} {
+		if err := blobs.Put(ctx, key, strings.NewReader("data"), "application/octet-stream"); err != nil {
+			t.Fatalf("Put(%s): %v", key, err)
+		}
+	}
+
+	if rc, _, err := s.Open(ctx, "avatar/1/"+version+"/64.jpg"); err != nil {
+		t.Errorf("variant key: %v", err)
+	} else {
+		rc.Close()
+	}
+
+	for _, key := range []string{
+		"exports/1/profile.json",
+		"backups/2024-01-01.db",
+		"avatar/1/" + version + "/65.jpg",
+		"avatar/1/" + version + "/64.gif",
+		"avatar/1/" + version + "/1500.jpg",
+		"banner/1/" + version + "/64.jpg",
+		"avatar/01/" + version + "/64.jpg",
+		"avatar/x/" + version + "/64.jpg",
+		"avatar/1/not-a-version/64.jpg",
+		"avatar/1/" + version + "/../../../exports/1/profile.json",
+		"avatar/1/" + version,
+	} {
+		if _, _, err := s.Open(ctx, key); !errors.Is(err, blob.ErrInvalidKey) {
+			t.Errorf("Open(%s): got %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
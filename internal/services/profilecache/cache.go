@@ -0,0 +1,217 @@
+// Package profilecache keeps public profiles (UserByUsername) in memory or
+// in Redis so popular profiles do not hit the database on every view.
+// Entries expire after a TTL and are dropped explicitly whenever their user
+// changes something the public profile shows.
+package profilecache
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+	"url_profile/internal/domain/models"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// Backend stores profiles by username.
+type Backend interface {
+	Get(ctx context.Context, name string) (*models.User, bool, error)
+	Set(ctx context.Context, name string, u *models.User, ttl time.Duration) error
+	Delete(ctx context.Context, name string) error
+}
+
+type Options struct {
+	// Size caps the in-process LRU; it is ignored with Redis.
+	Size int
+	TTL  time.Duration
+	// Redis, when set, replaces the in-process LRU so all instances share
+	// one cache and see each other's invalidations.
+	Redis *redis.Client
+	// Prefix namespaces the Redis keys.
+	Prefix string
+}
+
+type Stats struct {
+	Hits   int64
+	Misses int64
+}
+
+// Loader reads a profile from the database.
+type Loader func(name string) (*models.User, error)
+
+// UsernameFunc resolves a user ID to the username the profile is cached
+// under.
+type UsernameFunc func(userID int) (string, error)
+
+type Cache struct {
+	log     *slog.Logger
+	backend Backend
+	// local is the backend when it is the in-process LRU, nil with Redis.
+	local    *lru
+	ttl      time.Duration
+	username UsernameFunc
+	group    singleflight.Group
+
+	mu sync.Mutex
+	// loading holds the names with a load in flight and whether a write
+	// invalidated them meanwhile, so a load that raced with a write does
+	// not cache what it read. Entries live only as long as the load.
+	loading map[string]bool
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+func New(log *slog.Logger, username UsernameFunc, opts Options) *Cache {
+	c := &Cache{
+		log:      log,
+		ttl:      opts.TTL,
+		username: username,
+		loading:  make(map[string]bool),
+	}
+
+	if opts.Redis != nil {
+		c.backend = newRedisBackend(opts.Redis, opts.Prefix)
+	} else {
+		c.local = newLRU(opts.Size)
+		c.backend = c.local
+	}
+
+	return c
+}
+
+// Profile returns the cached profile for name, or loads it. Concurrent
+// misses for the same name share one load. Errors, including
+// store.ErrUserNotFound, are not cached.
+func (c *Cache) Profile(name string, load Loader) (*models.User, error) {
+	ctx := context.Background()
+
+	u, ok, err := c.backend.Get(ctx, name)
+	if err != nil {
+		c.log.Warn("profile cache: get failed", slog.String("error", err.Error()))
+	}
+	if ok {
+		c.hits.Add(1)
+		return clone(u), nil
+	}
+	c.misses.Add(1)
+
+	v, err, _ := c.group.Do(name, func() (any, error) {
+		c.mu.Lock()
+		c.loading[name] = false
+		c.mu.Unlock()
+		defer func() {
+			c.mu.Lock()
+			delete(c.loading, name)
+			c.mu.Unlock()
+		}()
+
+		u, err := load(name)
+		if err != nil {
+			return nil, err
+		}
+
+		u = public(u)
+		if !c.stale(name) {
+			if err := c.backend.Set(ctx, name, u, c.ttl); err != nil {
+				c.log.Warn("profile cache: set failed", slog.String("error", err.Error()))
+			}
+			// A write that landed while Set ran may have deleted the
+			// entry before Set wrote it.
+			if c.stale(name) {
+				c.delete(name)
+			}
+		}
+
+		return u, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return clone(v.(*models.User)), nil
+}
+
+// Invalidate drops the cached profile of userID.
+func (c *Cache) Invalidate(userID int) {
+	var (
+		name string
+		ok   bool
+	)
+	if c.local != nil {
+		name, ok = c.local.Name(userID)
+	}
+
+	if !ok {
+		// The local LRU only holds profiles this process loaded; without
+		// one cached, only a load in flight can be stale. Redis may hold
+		// profiles other instances loaded, so it always needs the name.
+		if c.local != nil && !c.anyLoading() {
+			return
+		}
+
+		var err error
+		name, err = c.username(userID)
+		if err != nil {
+			c.log.Warn("profile cache: failed to resolve user",
+				slog.Int("user_id", userID),
+				slog.String("error", err.Error()))
+			return
+		}
+	}
+
+	c.mu.Lock()
+	if _, ok := c.loading[name]; ok {
+		c.loading[name] = true
+	}
+	c.mu.Unlock()
+
+	c.delete(name)
+}
+
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+}
+
+func (c *Cache) stale(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.loading[name]
+}
+
+func (c *Cache) anyLoading() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.loading) > 0
+}
+
+func (c *Cache) delete(name string) {
+	if err := c.backend.Delete(context.Background(), name); err != nil {
+		c.log.Warn("profile cache: delete failed",
+			slog.String("username", name),
+			slog.String("error", err.Error()))
+	}
+}
+
+// public drops what the public profile never shows, so password hashes and
+// emails do not end up in the cache.
+func public(u *models.User) *models.User {
+	res := *u
+	res.Email = ""
+	res.HashedPassword = nil
+	return &res
+}
+
+// clone copies u deep enough that callers cannot change the cached value.
+func clone(u *models.User) *models.User {
+	res := *u
+	res.Links = slices.Clone(u.Links)
+	return &res
+}
@@ -0,0 +1,288 @@
+package profilecache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func newCache(t *testing.T, opts Options) *Cache {
+	t.Helper()
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), func(int) (string, error) {
+		return "alice", nil
+	}, opts)
+}
+
+// loader counts database reads and serves alice with about text from
+// about.
+type loader struct {
+	calls atomic.Int64
+	about atomic.Value
+}
+
+func (l *loader) load(name string) (*models.User, error) {
+	l.calls.Add(1)
+	if name != "alice" {
+		return nil, store.ErrUserNotFound
+	}
+
+	about, _ := l.about.Load().(string)
+	return &models.User{
+		ID:             1,
+		Email:          "alice@example.com",
+		Username:       "alice",
+		HashedPassword: []byte("hash"),
+		AboutText:      about,
+		Links:          []models.Link{{ID: 1, LinkName: "site"}},
+	}, nil
+}
+
+func TestHitsAndInvalidation(t *testing.T) {
+	c := newCache(t, Options{Size: 10, TTL: time.Minute})
+	l := &loader{}
+	l.about.Store("v1")
+
+	for i := 0; i < 3; i++ {
+		u, err := c.Profile("alice", l.load)
+		if err != nil {
+			t.Fatalf("Profile: %v", err)
+		}
+		if u.AboutText != "v1" {
+			t.Fatalf("about = %q, want v1", u.AboutText)
+		}
+	}
+	if got := l.calls.Load(); got != 1 {
+		t.Errorf("loaded %d times, want 1", got)
+	}
+	if got := c.Stats(); got != (Stats{Hits: 2, Misses: 1}) {
+		t.Errorf("Stats = %+v, want 2 hits and 1 miss", got)
+	}
+
+	l.about.Store("v2")
+	c.Invalidate(1)
+
+	u, err := c.Profile("alice", l.load)
+	if err != nil {
+		t.Fatalf("Profile: %v", err)
+	}
+	if u.AboutText != "v2" {
+		t.Errorf("about = %q after invalidation, want v2", u.AboutText)
+	}
+}
+
+func TestPublicOnly(t *testing.T) {
+	c := newCache(t, Options{Size: 10, TTL: time.Minute})
+	l := &loader{}
+
+	u, err := c.Profile("alice", l.load)
+	if err != nil {
+		t.Fatalf("Profile: %v", err)
+	}
+	if u.Email != "" || u.HashedPassword != nil {
+		t.Errorf("cached profile keeps email %q and hash %q", u.Email, u.HashedPassword)
+	}
+
+	// Callers get copies.
+	u.Links[0].LinkName = "changed"
+	again, _ := c.Profile("alice", l.load)
+	if again.Links[0].LinkName != "site" {
+		t.Errorf("a caller changed the cached profile")
+	}
+}
+
+func TestErrorsAreNotCached(t *testing.T) {
+	c := newCache(t, Options{Size: 10, TTL: time.Minute})
+	l := &loader{}
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Profile("bob", l.load); !errors.Is(err, store.ErrUserNotFound) {
+			t.Fatalf("Profile: got %v, want store.ErrUserNotFound", err)
+		}
+	}
+	if got := l.calls.Load(); got != 2 {
+		t.Errorf("loaded %d times, want 2", got)
+	}
+}
+
+func TestConcurrentMissesCoalesce(t *testing.T) {
+	c := newCache(t, Options{Size: 10, TTL: time.Minute})
+
+	release := make(chan struct{})
+	var calls atomic.Int64
+	load := func(name string) (*models.User, error) {
+		calls.Add(1)
+		<-release
+		return &models.User{ID: 1, Username: name}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Profile("alice", load); err != nil {
+				t.Errorf("Profile: %v", err)
+			}
+		}()
+	}
+
+	// Let every goroutine reach the singleflight before the load returns.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("loaded %d times, want 1", got)
+	}
+}
+
+// A load that read the database before a write must not cache what it read
+// once the write has invalidated the entry.
+func TestInvalidationDuringLoad(t *testing.T) {
+	// alice was never cached, so Invalidate has to resolve the name of
+	// the user whose load is in flight.
+	c := newCache(t, Options{Size: 10, TTL: time.Minute})
+
+	l := &loader{}
+	l.about.Store("old")
+	load := func(name string) (*models.User, error) {
+		u, err := l.load(name)
+		c.Invalidate(1) // the write lands while the load is in flight
+		return u, err
+	}
+
+	if _, err := c.Profile("alice", load); err != nil {
+		t.Fatalf("Profile: %v", err)
+	}
+
+	l.about.Store("new")
+	u, err := c.Profile("alice", l.load)
+	if err != nil {
+		t.Fatalf("Profile: %v", err)
+	}
+	if u.AboutText != "new" {
+		t.Errorf("about = %q, the racing load was cached", u.AboutText)
+	}
+}
+
+// The bookkeeping for invalidation must not outgrow the LRU however many
+// profiles pass through it.
+func TestBookkeepingIsBounded(t *testing.T) {
+	c := newCache(t, Options{Size: 2, TTL: time.Minute})
+
+	load := func(name string) (*models.User, error) {
+		id, _ := strconv.Atoi(strings.TrimPrefix(name, "user"))
+		return &models.User{ID: id, Username: name}, nil
+	}
+	for i := 1; i <= 100; i++ {
+		if _, err := c.Profile(fmt.Sprintf("user%d", i), load); err != nil {
+			t.Fatalf("Profile: %v", err)
+		}
+		c.Invalidate(i - 1)
+	}
+
+	if n := len(c.local.ids); n > 2 {
+		t.Errorf("LRU indexes %d user IDs, want at most 2", n)
+	}
+	if n := len(c.loading); n != 0 {
+		t.Errorf("%d loads are still tracked", n)
+	}
+
+	// user100 is cached and can be invalidated by ID; user1 was evicted
+	// long ago and needs nothing.
+	if name, ok := c.local.Name(100); !ok || name != "user100" {
+		t.Errorf("Name(100) = %q, %v; want user100", name, ok)
+	}
+	c.Invalidate(100)
+	if _, ok := c.local.Name(100); ok {
+		t.Error("user100 is still cached after Invalidate")
+	}
+	c.Invalidate(1)
+}
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	c := newLRU(2)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	for _, name := range []string{"a", "b"} {
+		c.Set(ctx, name, &models.User{Username: name}, time.Minute)
+	}
+	c.Get(ctx, "a") // b is now the least recently used
+	c.Set(ctx, "c", &models.User{Username: "c"}, time.Minute)
+
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Errorf("b was not evicted")
+	}
+	for _, name := range []string{"a", "c"} {
+		if _, ok, _ := c.Get(ctx, name); !ok {
+			t.Errorf("%s was evicted", name)
+		}
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Errorf("a outlived its TTL")
+	}
+	if c.order.Len() != 1 || len(c.items) != 1 || len(c.ids) != 1 {
+		t.Errorf("expired entry was not removed")
+	}
+}
+
+// Set REDIS_TEST_ADDR to a disposable Redis to run this.
+func TestRedis(t *testing.T) {
+	addr := os.Getenv("REDIS_TEST_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_TEST_ADDR is not set")
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	defer client.Close()
+
+	prefix := "profilecache_test:" + t.Name() + ":"
+	c := newCache(t, Options{TTL: time.Minute, Redis: client, Prefix: prefix})
+	t.Cleanup(func() { client.Del(context.Background(), prefix+"profile:alice") })
+
+	l := &loader{}
+	l.about.Store("v1")
+	for i := 0; i < 2; i++ {
+		u, err := c.Profile("alice", l.load)
+		if err != nil {
+			t.Fatalf("Profile: %v", err)
+		}
+		if u.AboutText != "v1" || len(u.Links) != 1 || u.Links[0].Payload != nil {
+			t.Fatalf("Profile = %+v", u)
+		}
+	}
+	if got := l.calls.Load(); got != 1 {
+		t.Errorf("loaded %d times, want 1", got)
+	}
+
+	// Another instance never loaded alice, yet its invalidation reaches
+	// the shared entry.
+	other := newCache(t, Options{TTL: time.Minute, Redis: client, Prefix: prefix})
+	other.Invalidate(1)
+
+	l.about.Store("v2")
+	u, err := c.Profile("alice", l.load)
+	if err != nil {
+		t.Fatalf("Profile: %v", err)
+	}
+	if u.AboutText != "v2" {
+		t.Errorf("about = %q after invalidation, want v2", u.AboutText)
+	}
+}
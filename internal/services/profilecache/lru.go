@@ -0,0 +1,112 @@
+package profilecache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+	"url_profile/internal/domain/models"
+)
+
+// lru is the in-process backend: at most size profiles, least recently
+// used evicted first, each dropped after its TTL. ids indexes the same
+// entries by user ID for invalidation, so it never outgrows items.
+type lru struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // front is most recently used
+	items map[string]*list.Element
+	ids   map[int]*list.Element
+	now   func() time.Time
+}
+
+type lruEntry struct {
+	name    string
+	user    *models.User
+	expires time.Time
+}
+
+func newLRU(size int) *lru {
+	return &lru{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+		ids:   make(map[int]*list.Element),
+		now:   time.Now,
+	}
+}
+
+func (c *lru) Get(_ context.Context, name string) (*models.User, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[name]
+	if !ok {
+		return nil, false, nil
+	}
+
+	e := el.Value.(*lruEntry)
+	if !c.now().Before(e.expires) {
+		c.remove(el)
+		return nil, false, nil
+	}
+	c.order.MoveToFront(el)
+
+	return e.user, true, nil
+}
+
+func (c *lru) Set(_ context.Context, name string, u *models.User, ttl time.Duration) error {
+	if c.size <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[name]; ok {
+		c.remove(el)
+	}
+
+	el := c.order.PushFront(&lruEntry{name: name, user: u, expires: c.now().Add(ttl)})
+	c.items[name] = el
+	c.ids[u.ID] = el
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+
+	return nil
+}
+
+func (c *lru) Delete(_ context.Context, name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[name]; ok {
+		c.remove(el)
+	}
+
+	return nil
+}
+
+// Name returns the username userID is cached under, expired entries
+// included: they still have to be dropped.
+func (c *lru) Name(userID int) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.ids[userID]
+	if !ok {
+		return "", false
+	}
+	return el.Value.(*lruEntry).name, true
+}
+
+// remove must be called with mu held.
+func (c *lru) remove(el *list.Element) {
+	e := el.Value.(*lruEntry)
+	c.order.Remove(el)
+	delete(c.items, e.name)
+	if c.ids[e.user.ID] == el {
+		delete(c.ids, e.user.ID)
+	}
+}
@@ -0,0 +1,58 @@
+package profilecache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"time"
+	"url_profile/internal/domain/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisBackend keeps profiles gob-encoded under <prefix>profile:<username>
+// and leaves expiry to Redis. gob rather than JSON keeps an empty link
+// payload empty instead of turning it into "null".
+type redisBackend struct {
+	client *redis.Client
+	prefix string
+}
+
+func newRedisBackend(client *redis.Client, prefix string) *redisBackend {
+	return &redisBackend{client: client, prefix: prefix}
+}
+
+func (b *redisBackend) key(name string) string {
+	return b.prefix + "profile:" + name
+}
+
+func (b *redisBackend) Get(ctx context.Context, name string) (*models.User, bool, error) {
+	data, err := b.client.Get(ctx, b.key(name)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var u models.User
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&u); err != nil {
+		return nil, false, err
+	}
+
+	return &u, true, nil
+}
+
+func (b *redisBackend) Set(ctx context.Context, name string, u *models.User, ttl time.Duration) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(u); err != nil {
+		return err
+	}
+
+	return b.client.Set(ctx, b.key(name), buf.Bytes(), ttl).Err()
+}
+
+func (b *redisBackend) Delete(ctx context.Context, name string) error {
+	return b.client.Del(ctx, b.key(name)).Err()
+}
@@ -0,0 +1,126 @@
+package sections
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/domain/models"
+)
+
+const maxTitleLen = 64
+
+type SectionStore interface {
+	Sections(userID int) ([]models.LinkSection, error)
+	Section(userID int, sectionID int) (*models.LinkSection, error)
+	CreateSection(userID int, title string, position *int, collapsed bool) (*models.LinkSection, error)
+	UpdateSection(section models.LinkSection) error
+	DeleteSection(userID int, sectionID int) error
+}
+
+type Service struct {
+	log   *slog.Logger
+	store SectionStore
+}
+
+func New(log *slog.Logger, store SectionStore) *Service {
+	return &Service{
+		log:   log,
+		store: store,
+	}
+}
+
+func (s *Service) Sections(userID int) ([]models.LinkSection, error) {
+	return s.store.Sections(userID)
+}
+
+func (s *Service) Create(userID int, req requestModel.ReqSection) (*models.LinkSection, error) {
+	verr := &requestModel.ValidationError{}
+
+	var title string
+	if req.Title == nil {
+		verr.Add("title", fmt.Errorf("title is required"))
+	} else if t, err := NormalizeTitle(*req.Title); err != nil {
+		verr.Add("title", err)
+	} else {
+		title = t
+	}
+
+	if req.Position != nil && *req.Position < 0 {
+		verr.Add("position", fmt.Errorf("position must not be negative"))
+	}
+
+	if err := verr.OrNil(); err != nil {
+		return nil, err
+	}
+
+	collapsed := req.Collapsed != nil && *req.Collapsed
+
+	sec, err := s.store.CreateSection(userID, title, req.Position, collapsed)
+	if err != nil {
+		s.log.Debug("Failed to create section", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	return sec, nil
+}
+
+// Update changes only the fields present in req.
+func (s *Service) Update(userID int, sectionID int, req requestModel.ReqSection) (*models.LinkSection, error) {
+	sec, err := s.store.Section(userID, sectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	verr := &requestModel.ValidationError{}
+
+	if req.Title != nil {
+		t, err := NormalizeTitle(*req.Title)
+		if err != nil {
+			verr.Add("title", err)
+		}
+		sec.Title = t
+	}
+
+	if req.Position != nil {
+		if *req.Position < 0 {
+			verr.Add("position", fmt.Errorf("position must not be negative"))
+		}
+		sec.Position = *req.Position
+	}
+
+	if req.Collapsed != nil {
+		sec.Collapsed = *req.Collapsed
+	}
+
+	if err := verr.OrNil(); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.UpdateSection(*sec); err != nil {
+		s.log.Debug("Failed to update section", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	return sec, nil
+}
+
+// Delete removes the section; its links become ungrouped.
+func (s *Service) Delete(userID int, sectionID int) error {
+	return s.store.DeleteSection(userID, sectionID)
+}
+
+// NormalizeTitle trims a section title and checks its length.
+func NormalizeTitle(t string) (string, error) {
+	t = strings.TrimSpace(t)
+	if t == "" {
+		return "", fmt.Errorf("title is required")
+	}
+
+	if utf8.RuneCountInString(t) > maxTitleLen {
+		return "", fmt.Errorf("title must be at most %d characters", maxTitleLen)
+	}
+
+	return t, nil
+}
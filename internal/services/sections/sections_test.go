@@ -0,0 +1,177 @@
+package sections
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+)
+
+type fakeStore struct {
+	sections map[int]models.LinkSection
+	nextID   int
+	writes   int
+}
+
+func (f *fakeStore) Sections(userID int) ([]models.LinkSection, error) {
+	var res []models.LinkSection
+	for _, s := range f.sections {
+		if s.UserID == userID {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeStore) Section(userID int, sectionID int) (*models.LinkSection, error) {
+	s, ok := f.sections[sectionID]
+	if !ok || s.UserID != userID {
+		return nil, store.ErrSectionNotFound
+	}
+	return &s, nil
+}
+
+func (f *fakeStore) CreateSection(userID int, title string, position *int, collapsed bool) (*models.LinkSection, error) {
+	f.writes++
+	f.nextID++
+	s := models.LinkSection{ID: f.nextID, UserID: userID, Title: title, Collapsed: collapsed}
+	if position != nil {
+		s.Position = *position
+	}
+	f.sections[s.ID] = s
+	return &s, nil
+}
+
+func (f *fakeStore) UpdateSection(s models.LinkSection) error {
+	f.writes++
+	f.sections[s.ID] = s
+	return nil
+}
+
+func (f *fakeStore) DeleteSection(userID int, sectionID int) error {
+	f.writes++
+	delete(f.sections, sectionID)
+	return nil
+}
+
+func newService() (*Service, *fakeStore) {
+	st := &fakeStore{sections: map[int]models.LinkSection{}}
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), st), st
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func fields(t *testing.T, err error) []string {
+	t.Helper()
+
+	var verr *requestModel.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("got %v, want a validation error", err)
+	}
+	res := make([]string, 0, len(verr.Fields))
+	for _, f := range verr.Fields {
+		res = append(res, f.Field)
+	}
+	return res
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"Work", "Work", false},
+		{"  Music \n", "Music", false},
+		{strings.Repeat("я", maxTitleLen), strings.Repeat("я", maxTitleLen), false},
+		{"", "", true},
+		{" \t ", "", true},
+		{strings.Repeat("я", maxTitleLen+1), "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeTitle(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("NormalizeTitle(%q) = %q, %v, want %q (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	s, st := newService()
+
+	sec, err := s.Create(1, requestModel.ReqSection{Title: ptr("  Work "), Position: ptr(3), Collapsed: ptr(true)})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if sec.Title != "Work" || sec.Position != 3 || !sec.Collapsed {
+		t.Errorf("created %+v", sec)
+	}
+
+	tests := []struct {
+		name string
+		req  requestModel.ReqSection
+		want []string
+	}{
+		{"missing title", requestModel.ReqSection{}, []string{"title"}},
+		{"blank title", requestModel.ReqSection{Title: ptr("  ")}, []string{"title"}},
+		{"negative position", requestModel.ReqSection{Title: ptr("x"), Position: ptr(-1)}, []string{"position"}},
+		{"both", requestModel.ReqSection{Title: ptr(strings.Repeat("x", maxTitleLen+1)), Position: ptr(-2)}, []string{"title", "position"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writes := st.writes
+			_, err := s.Create(1, tt.req)
+			if got := fields(t, err); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("invalid fields %v, want %v", got, tt.want)
+			}
+			if st.writes != writes {
+				t.Error("an invalid section was written")
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	s, st := newService()
+
+	sec, err := s.Create(1, requestModel.ReqSection{Title: ptr("Work"), Position: ptr(2)})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// Only the fields present change.
+	got, err := s.Update(1, sec.ID, requestModel.ReqSection{Collapsed: ptr(true)})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got.Title != "Work" || got.Position != 2 || !got.Collapsed {
+		t.Errorf("after collapsing: %+v", got)
+	}
+
+	got, err = s.Update(1, sec.ID, requestModel.ReqSection{Title: ptr(" Jobs "), Position: ptr(0)})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got.Title != "Jobs" || got.Position != 0 || !got.Collapsed {
+		t.Errorf("after renaming: %+v", got)
+	}
+
+	writes := st.writes
+	_, err = s.Update(1, sec.ID, requestModel.ReqSection{Title: ptr(""), Position: ptr(-1)})
+	if got := fields(t, err); len(got) != 2 {
+		t.Errorf("invalid fields %v, want title and position", got)
+	}
+	if st.writes != writes || st.sections[sec.ID].Title != "Jobs" {
+		t.Error("an invalid update was written")
+	}
+
+	if _, err := s.Update(2, sec.ID, requestModel.ReqSection{Title: ptr("mine")}); !errors.Is(err, store.ErrSectionNotFound) {
+		t.Errorf("Update of another user's section: got %v, want store.ErrSectionNotFound", err)
+	}
+}
@@ -0,0 +1,158 @@
+package theme
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/lib/color"
+	"url_profile/internal/store"
+)
+
+const (
+	DefaultPreset = "default"
+	// CustomPreset names a theme whose fields no longer match any preset.
+	CustomPreset = "custom"
+)
+
+var (
+	Fonts        = []string{"system", "inter", "roboto", "serif", "monospace"}
+	ButtonStyles = []string{"filled", "outline", "rounded", "pill", "shadow"}
+)
+
+var presets = []models.Theme{
+	{Preset: DefaultPreset, Background: "#ffffff", Font: "system", ButtonStyle: "filled", LinkColor: "#222222"},
+	{Preset: "dark", Background: "#121212", Font: "inter", ButtonStyle: "outline", LinkColor: "#f5f5f5"},
+	{Preset: "ocean", Background: "#0b3d91", Font: "roboto", ButtonStyle: "rounded", LinkColor: "#7fdbff"},
+	{Preset: "sunset", Background: "#ff7e5f", Font: "serif", ButtonStyle: "pill", LinkColor: "#2d132c"},
+	{Preset: "forest", Background: "#1b4332", Font: "inter", ButtonStyle: "shadow", LinkColor: "#b7e4c7"},
+	{Preset: "mono", Background: "#f0f0f0", Font: "monospace", ButtonStyle: "outline", LinkColor: "#000000"},
+}
+
+type ThemeStore interface {
+	Theme(userID int) (*models.Theme, error)
+	SaveTheme(userID int, theme models.Theme) error
+}
+
+type Service struct {
+	log   *slog.Logger
+	store ThemeStore
+}
+
+func New(log *slog.Logger, store ThemeStore) *Service {
+	return &Service{
+		log:   log,
+		store: store,
+	}
+}
+
+func (s *Service) Presets() []models.Theme {
+	return slices.Clone(presets)
+}
+
+// Theme returns the user's theme, or the default preset if none was saved.
+func (s *Service) Theme(userID int) (*models.Theme, error) {
+	t, err := s.store.Theme(userID)
+	if err != nil {
+		if errors.Is(err, store.ErrThemeNotFound) {
+			def, _ := preset(DefaultPreset)
+			return &def, nil
+		}
+
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// UpdateTheme applies req on top of the chosen preset (or the current theme
+// when no preset or "custom" is given). Empty fields keep their previous
+// value, and a theme that ends up differing from its preset is saved as
+// "custom".
+func (s *Service) UpdateTheme(userID int, req requestModel.ReqTheme) (*models.Theme, error) {
+	t, err := s.ResolveTheme(userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.store.SaveTheme(userID, *t); err != nil {
+		s.log.Debug("Failed to save theme", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// ResolveTheme validates req and returns the theme UpdateTheme would save,
+// without saving it.
+func (s *Service) ResolveTheme(userID int, req requestModel.ReqTheme) (*models.Theme, error) {
+	verr := &requestModel.ValidationError{}
+
+	var base models.Theme
+	if req.Preset != "" && req.Preset != CustomPreset {
+		p, ok := preset(req.Preset)
+		if !ok {
+			verr.Add("preset", fmt.Errorf("unknown preset %q", req.Preset))
+			return nil, verr
+		}
+		base = p
+	} else {
+		cur, err := s.Theme(userID)
+		if err != nil {
+			return nil, err
+		}
+		base = *cur
+	}
+
+	if req.Background != "" {
+		c, err := color.Normalize(req.Background)
+		if err != nil {
+			verr.Add("background", err)
+		}
+		base.Background = c
+	}
+
+	if req.LinkColor != "" {
+		c, err := color.Normalize(req.LinkColor)
+		if err != nil {
+			verr.Add("link_color", err)
+		}
+		base.LinkColor = c
+	}
+
+	if req.Font != "" {
+		if !slices.Contains(Fonts, req.Font) {
+			verr.Add("font", fmt.Errorf("font must be one of %v", Fonts))
+		}
+		base.Font = req.Font
+	}
+
+	if req.ButtonStyle != "" {
+		if !slices.Contains(ButtonStyles, req.ButtonStyle) {
+			verr.Add("button_style", fmt.Errorf("button_style must be one of %v", ButtonStyles))
+		}
+		base.ButtonStyle = req.ButtonStyle
+	}
+
+	if err := verr.OrNil(); err != nil {
+		return nil, err
+	}
+
+	if p, ok := preset(base.Preset); !ok || p != base {
+		base.Preset = CustomPreset
+	}
+
+	return &base, nil
+}
+
+func preset(name string) (models.Theme, bool) {
+	for _, p := range presets {
+		if p.Preset == name {
+			return p, true
+		}
+	}
+
+	return models.Theme{}, false
+}
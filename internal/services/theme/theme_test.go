@@ -0,0 +1,150 @@
+package theme
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+)
+
+type fakeStore struct {
+	themes map[int]models.Theme
+}
+
+func (f *fakeStore) Theme(userID int) (*models.Theme, error) {
+	t, ok := f.themes[userID]
+	if !ok {
+		return nil, store.ErrThemeNotFound
+	}
+	return &t, nil
+}
+
+func (f *fakeStore) SaveTheme(userID int, t models.Theme) error {
+	f.themes[userID] = t
+	return nil
+}
+
+func newService() (*Service, *fakeStore) {
+	st := &fakeStore{themes: map[int]models.Theme{}}
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), st), st
+}
+
+func TestThemeDefaultsToPreset(t *testing.T) {
+	s, _ := newService()
+
+	got, err := s.Theme(1)
+	if err != nil {
+		t.Fatalf("Theme: %v", err)
+	}
+	if def, _ := preset(DefaultPreset); *got != def {
+		t.Errorf("got %+v, want the default preset", *got)
+	}
+}
+
+func TestUpdateTheme(t *testing.T) {
+	dark, _ := preset("dark")
+	ocean, _ := preset("ocean")
+
+	tests := []struct {
+		name    string
+		current *models.Theme
+		req     requestModel.ReqTheme
+		want    models.Theme
+	}{
+		{
+			name: "preset",
+			req:  requestModel.ReqTheme{Preset: "dark"},
+			want: dark,
+		},
+		{
+			name: "override on a preset becomes custom",
+			req:  requestModel.ReqTheme{Preset: "dark", Background: "#000"},
+			want: models.Theme{Preset: CustomPreset, Background: "#000", Font: "inter", ButtonStyle: "outline", LinkColor: "#f5f5f5"},
+		},
+		{
+			name: "override equal to the preset keeps its name",
+			req:  requestModel.ReqTheme{Preset: "dark", Background: "#121212", Font: "inter"},
+			want: dark,
+		},
+		{
+			name:    "fields apply on top of the current theme",
+			current: &ocean,
+			req:     requestModel.ReqTheme{LinkColor: "White"},
+			want:    models.Theme{Preset: CustomPreset, Background: "#0b3d91", Font: "roboto", ButtonStyle: "rounded", LinkColor: "white"},
+		},
+		{
+			name:    "custom keeps the current fields",
+			current: &models.Theme{Preset: CustomPreset, Background: "#000", Font: "inter", ButtonStyle: "outline", LinkColor: "#f5f5f5"},
+			req:     requestModel.ReqTheme{Preset: CustomPreset, Font: "serif"},
+			want:    models.Theme{Preset: CustomPreset, Background: "#000", Font: "serif", ButtonStyle: "outline", LinkColor: "#f5f5f5"},
+		},
+		{
+			name:    "custom matching a preset stays custom",
+			current: &models.Theme{Preset: CustomPreset, Background: "#000", Font: "inter", ButtonStyle: "outline", LinkColor: "#f5f5f5"},
+			req:     requestModel.ReqTheme{Background: "#121212"},
+			want:    models.Theme{Preset: CustomPreset, Background: "#121212", Font: "inter", ButtonStyle: "outline", LinkColor: "#f5f5f5"},
+		},
+		{
+			name:    "picking a preset drops custom fields",
+			current: &models.Theme{Preset: CustomPreset, Background: "#000", Font: "serif", ButtonStyle: "pill", LinkColor: "red"},
+			req:     requestModel.ReqTheme{Preset: "ocean"},
+			want:    ocean,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, st := newService()
+			if tt.current != nil {
+				st.themes[1] = *tt.current
+			}
+
+			got, err := s.UpdateTheme(1, tt.req)
+			if err != nil {
+				t.Fatalf("UpdateTheme: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+			if st.themes[1] != tt.want {
+				t.Errorf("saved %+v, want %+v", st.themes[1], tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateThemeValidation(t *testing.T) {
+	s, st := newService()
+
+	_, err := s.UpdateTheme(1, requestModel.ReqTheme{
+		Background:  "url(x)",
+		LinkColor:   "#12",
+		Font:        "comic sans",
+		ButtonStyle: "blink",
+	})
+
+	var verr *requestModel.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("got %v, want a validation error", err)
+	}
+	fields := map[string]bool{}
+	for _, f := range verr.Fields {
+		fields[f.Field] = true
+	}
+	for _, f := range []string{"background", "link_color", "font", "button_style"} {
+		if !fields[f] {
+			t.Errorf("no error for %s in %v", f, verr)
+		}
+	}
+	if len(st.themes) != 0 {
+		t.Error("an invalid theme was saved")
+	}
+
+	_, err = s.UpdateTheme(1, requestModel.ReqTheme{Preset: "neon"})
+	if !errors.As(err, &verr) || verr.Fields[0].Field != "preset" {
+		t.Errorf("unknown preset: got %v, want a preset validation error", err)
+	}
+}
@@ -0,0 +1,123 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+)
+
+var ErrExpired = errors.New("link was purged from the trash")
+
+type TrashStore interface {
+	SetLinkHidden(userID int, linkID int, hidden bool) error
+	TrashedLinks(userID int) ([]models.Link, error)
+	RestoreLink(userID int, linkID int, deletedAfter time.Time) error
+	PurgeLink(userID int, linkID int) error
+	PurgeDeletedLinks(before time.Time) (int64, error)
+}
+
+type Options struct {
+	Retention     time.Duration
+	PurgeInterval time.Duration
+}
+
+// Service keeps deleted links restorable for Retention and purges them
+// afterwards.
+type Service struct {
+	log   *slog.Logger
+	store TrashStore
+	opts  Options
+	now   func() time.Time
+}
+
+func New(log *slog.Logger, store TrashStore, opts Options) *Service {
+	return &Service{
+		log:   log,
+		store: store,
+		opts:  opts,
+		now:   time.Now,
+	}
+}
+
+func (s *Service) Retention() time.Duration {
+	return s.opts.Retention
+}
+
+func (s *Service) SetHidden(userID int, linkID int, hidden bool) error {
+	return s.store.SetLinkHidden(userID, linkID, hidden)
+}
+
+// Trash lists links that can still be restored.
+func (s *Service) Trash(userID int) ([]models.Link, error) {
+	links, err := s.store.TrashedLinks(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := s.cutoff()
+	res := links[:0]
+	for _, l := range links {
+		if l.DeletedAt.After(cutoff) {
+			res = append(res, l)
+		}
+	}
+
+	return res, nil
+}
+
+func (s *Service) Restore(userID int, linkID int) error {
+	err := s.store.RestoreLink(userID, linkID, s.cutoff())
+	if errors.Is(err, store.ErrLinkNotFound) {
+		// Tell "never existed" apart from "waited too long".
+		links, lerr := s.store.TrashedLinks(userID)
+		if lerr != nil {
+			return err
+		}
+		for _, l := range links {
+			if l.ID == linkID {
+				return ErrExpired
+			}
+		}
+	}
+
+	return err
+}
+
+func (s *Service) Purge(userID int, linkID int) error {
+	return s.store.PurgeLink(userID, linkID)
+}
+
+// Run purges expired links every PurgeInterval until ctx is cancelled.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		s.PurgeExpired()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) PurgeExpired() {
+	n, err := s.store.PurgeDeletedLinks(s.cutoff())
+	if err != nil {
+		s.log.Error("trash: failed to purge links", slog.String("error", err.Error()))
+		return
+	}
+
+	if n > 0 {
+		s.log.Info("trash: purged links", slog.Int64("count", n))
+	}
+}
+
+func (s *Service) cutoff() time.Time {
+	return s.now().Add(-s.opts.Retention)
+}
@@ -0,0 +1,185 @@
+package trash
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+)
+
+// fakeStore keeps trashed links in memory and records the cutoffs it is
+// given.
+type fakeStore struct {
+	links map[int]models.Link
+
+	restoreCutoff time.Time
+	purgeCutoff   time.Time
+	purgeErr      error
+}
+
+func (f *fakeStore) SetLinkHidden(userID int, linkID int, hidden bool) error {
+	l, ok := f.links[linkID]
+	if !ok || l.UserID != userID || l.DeletedAt != nil {
+		return store.ErrLinkNotFound
+	}
+	l.Hidden = hidden
+	f.links[linkID] = l
+	return nil
+}
+
+func (f *fakeStore) TrashedLinks(userID int) ([]models.Link, error) {
+	var res []models.Link
+	for _, l := range f.links {
+		if l.UserID == userID && l.DeletedAt != nil {
+			res = append(res, l)
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeStore) RestoreLink(userID int, linkID int, deletedAfter time.Time) error {
+	f.restoreCutoff = deletedAfter
+	l, ok := f.links[linkID]
+	if !ok || l.UserID != userID || l.DeletedAt == nil || l.DeletedAt.Before(deletedAfter) {
+		return store.ErrLinkNotFound
+	}
+	l.DeletedAt = nil
+	f.links[linkID] = l
+	return nil
+}
+
+func (f *fakeStore) PurgeLink(userID int, linkID int) error {
+	l, ok := f.links[linkID]
+	if !ok || l.UserID != userID || l.DeletedAt == nil {
+		return store.ErrLinkNotFound
+	}
+	delete(f.links, linkID)
+	return nil
+}
+
+func (f *fakeStore) PurgeDeletedLinks(before time.Time) (int64, error) {
+	f.purgeCutoff = before
+	if f.purgeErr != nil {
+		return 0, f.purgeErr
+	}
+
+	var n int64
+	for id, l := range f.links {
+		if l.DeletedAt != nil && l.DeletedAt.Before(before) {
+			delete(f.links, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+var now = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+const retention = 30 * 24 * time.Hour
+
+// newService returns a service whose clock is stopped at now, with three
+// links of user 1: live (1), trashed a day ago (2) and trashed past the
+// retention (3).
+func newService(t *testing.T) (*Service, *fakeStore) {
+	t.Helper()
+
+	ago := func(d time.Duration) *time.Time {
+		at := now.Add(-d)
+		return &at
+	}
+	f := &fakeStore{links: map[int]models.Link{
+		1: {ID: 1, UserID: 1},
+		2: {ID: 2, UserID: 1, DeletedAt: ago(24 * time.Hour)},
+		3: {ID: 3, UserID: 1, DeletedAt: ago(retention + time.Minute)},
+		4: {ID: 4, UserID: 2, DeletedAt: ago(time.Hour)},
+	}}
+
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), f, Options{Retention: retention, PurgeInterval: time.Hour})
+	s.now = func() time.Time { return now }
+	return s, f
+}
+
+func TestTrash(t *testing.T) {
+	s, _ := newService(t)
+
+	links, err := s.Trash(1)
+	if err != nil {
+		t.Fatalf("Trash: %v", err)
+	}
+	if len(links) != 1 || links[0].ID != 2 {
+		t.Errorf("Trash = %+v, want only link 2", links)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID int
+		linkID int
+		want   error
+	}{
+		{"within retention", 1, 2, nil},
+		{"past retention", 1, 3, ErrExpired},
+		{"live link", 1, 1, store.ErrLinkNotFound},
+		{"unknown link", 1, 42, store.ErrLinkNotFound},
+		{"someone else's link", 1, 4, store.ErrLinkNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, f := newService(t)
+
+			if err := s.Restore(tt.userID, tt.linkID); !errors.Is(err, tt.want) {
+				t.Fatalf("Restore() error = %v, want %v", err, tt.want)
+			}
+			if want := now.Add(-retention); !f.restoreCutoff.Equal(want) {
+				t.Errorf("store got cutoff %v, want %v", f.restoreCutoff, want)
+			}
+			if tt.want == nil && f.links[tt.linkID].DeletedAt != nil {
+				t.Error("link is still in the trash")
+			}
+		})
+	}
+}
+
+func TestPurge(t *testing.T) {
+	s, f := newService(t)
+
+	if err := s.Purge(1, 2); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if _, ok := f.links[2]; ok {
+		t.Error("purged link is still stored")
+	}
+	if err := s.Purge(1, 1); !errors.Is(err, store.ErrLinkNotFound) {
+		t.Errorf("Purge of a live link: got %v, want store.ErrLinkNotFound", err)
+	}
+	if err := s.Restore(1, 2); !errors.Is(err, store.ErrLinkNotFound) {
+		t.Errorf("Restore of a purged link: got %v, want store.ErrLinkNotFound", err)
+	}
+}
+
+func TestPurgeExpired(t *testing.T) {
+	s, f := newService(t)
+
+	s.PurgeExpired()
+
+	if want := now.Add(-retention); !f.purgeCutoff.Equal(want) {
+		t.Errorf("store got cutoff %v, want %v", f.purgeCutoff, want)
+	}
+	if _, ok := f.links[3]; ok {
+		t.Error("expired link was not purged")
+	}
+	for _, id := range []int{1, 2, 4} {
+		if _, ok := f.links[id]; !ok {
+			t.Errorf("link %d was purged", id)
+		}
+	}
+
+	// A failed purge is logged and retried on the next tick.
+	f.purgeErr = store.ErrDatabaseOperation
+	s.PurgeExpired()
+}
@@ -0,0 +1,432 @@
+// Package memorystore keeps users, their links and themes in memory. It
+// implements the same UserSaver/UserProvider, ThemeStore and UnitOfWork
+// contracts as the database stores, so services and handlers can be tested
+// without a database file. It has no sections: a link that names one is
+// rejected with store.ErrSectionNotFound.
+package memorystore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/lib/linkblock"
+	"url_profile/internal/store"
+)
+
+type user struct {
+	models.User
+	settings models.Settings
+}
+
+// Store is safe for concurrent use. It hands out copies, so callers cannot
+// change stored data behind its back.
+type Store struct {
+	mu         sync.RWMutex
+	users      map[int]*user
+	links      map[int]*models.Link
+	themes     map[int]models.Theme
+	lastUserID int
+	lastLinkID int
+}
+
+func New() *Store {
+	return &Store{
+		users:  make(map[int]*user),
+		links:  make(map[int]*models.Link),
+		themes: make(map[int]models.Theme),
+	}
+}
+
+// InTx runs fn on a copy of the store and keeps the copy only if fn
+// succeeds. The store stays locked until then, so units of work run one
+// at a time.
+func (s *Store) InTx(ctx context.Context, fn func(tx store.Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &Store{
+		users:      make(map[int]*user, len(s.users)),
+		links:      make(map[int]*models.Link, len(s.links)),
+		themes:     maps.Clone(s.themes),
+		lastUserID: s.lastUserID,
+		lastLinkID: s.lastLinkID,
+	}
+	for id, u := range s.users {
+		c := *u
+		tx.users[id] = &c
+	}
+	for id, l := range s.links {
+		tx.links[id] = copyLink(l)
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	s.users, s.links, s.themes = tx.users, tx.links, tx.themes
+	s.lastUserID, s.lastLinkID = tx.lastUserID, tx.lastLinkID
+
+	return nil
+}
+
+// CreateUser adds the user and all of their links, or nothing if any link
+// is rejected.
+func (s *Store) CreateUser(email string, username string, pass []byte, about string, links []requestModel.ReqLink) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Email == email {
+			return nil, &store.ConflictError{Column: "email", Err: store.ErrUserAlreadyExists}
+		}
+		if u.Username == username {
+			return nil, &store.ConflictError{Column: "username", Err: store.ErrUserAlreadyExists}
+		}
+	}
+
+	invalid := &store.InvalidLinksError{}
+	for i, l := range links {
+		if l.SectionID != nil {
+			invalid.Links = append(invalid.Links, store.LinkError{Index: i, Field: "section_id", Err: store.ErrSectionNotFound})
+		}
+	}
+	if len(invalid.Links) > 0 {
+		return nil, invalid
+	}
+
+	s.lastUserID++
+	u := &user{User: models.User{
+		ID:             s.lastUserID,
+		Email:          email,
+		Username:       username,
+		HashedPassword: bytes.Clone(pass),
+		AboutText:      about,
+		Version:        1,
+		UpdatedAt:      now(),
+	}}
+	s.users[u.ID] = u
+
+	for _, l := range links {
+		s.insertLink(u.ID, l)
+	}
+
+	return &models.User{ID: u.ID, Email: u.Email, Username: u.Username}, nil
+}
+
+func (s *Store) User(email string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.Email == email {
+			return s.withLinks(u, true), nil
+		}
+	}
+
+	return nil, store.ErrUserNotFound
+}
+
+func (s *Store) UserById(id int) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, store.ErrUserNotFound
+	}
+
+	return s.withLinks(u, true), nil
+}
+
+// UserByUsername returns the public view of a profile: hidden links are
+// left out.
+func (s *Store) UserByUsername(name string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.Username == name {
+			return s.withLinks(u, false), nil
+		}
+	}
+
+	return nil, store.ErrUserNotFound
+}
+
+// UpdateAboutMe changes the about text. A non-zero ifVersion makes it
+// conditional on the profile version.
+func (s *Store) UpdateAboutMe(id int, text string, ifVersion int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return store.ErrNoRowsAffected
+	}
+	if ifVersion != 0 && ifVersion != u.Version {
+		return store.ErrVersionMismatch
+	}
+	u.AboutText = text
+	touch(u)
+
+	return nil
+}
+
+// LockProfile fails with store.ErrVersionMismatch when a non-zero
+// ifVersion is not the profile version. InTx holds the store, so nothing
+// else can change the profile within a unit of work.
+func (s *Store) LockProfile(id int, ifVersion int) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return store.ErrUserNotFound
+	}
+	if ifVersion != 0 && ifVersion != u.Version {
+		return store.ErrVersionMismatch
+	}
+
+	return nil
+}
+
+func (s *Store) Settings(id int) (*models.Settings, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, store.ErrUserNotFound
+	}
+	settings := u.settings
+
+	return &settings, nil
+}
+
+func (s *Store) UpdateSettings(id int, settings models.Settings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return store.ErrNoRowsAffected
+	}
+	u.settings = settings
+
+	return nil
+}
+
+func (s *Store) Theme(userID int) (*models.Theme, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.themes[userID]
+	if !ok {
+		return nil, store.ErrThemeNotFound
+	}
+
+	return &t, nil
+}
+
+func (s *Store) SaveTheme(userID int, theme models.Theme) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[userID]
+	if !ok {
+		return store.ErrUserNotFound
+	}
+	s.themes[userID] = theme
+	touch(u)
+
+	return nil
+}
+
+func (s *Store) Link(linkID int) (*models.Link, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l, ok := s.links[linkID]
+	if !ok {
+		return nil, store.ErrLinkNotFound
+	}
+
+	return copyLink(l), nil
+}
+
+// AddLink saves the link and returns its ID.
+func (s *Store) AddLink(userID int, link requestModel.ReqLink) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userID]; !ok {
+		return 0, store.ErrUserNotFound
+	}
+
+	if link.SectionID != nil {
+		return 0, store.ErrSectionNotFound
+	}
+
+	id := s.insertLink(userID, link)
+	touch(s.users[userID])
+
+	return id, nil
+}
+
+// UpdateLink replaces the link's fields. A non-zero ifVersion makes it
+// conditional on the link version.
+func (s *Store) UpdateLink(userID int, link *requestModel.ReqUpdateLink, ifVersion int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.links[link.LinkID]
+	if !ok || l.UserID != userID {
+		return store.ErrLinkNotFound
+	}
+
+	if link.SectionID != nil {
+		return store.ErrSectionNotFound
+	}
+
+	if ifVersion != 0 && ifVersion != l.Version {
+		return store.ErrVersionMismatch
+	}
+
+	l.Type = linkType(link.Type)
+	l.LinkName = link.LinkName
+	l.LinkColor = link.LinkColor
+	l.LinkPath = link.LinkPath
+	l.Payload = bytes.Clone(link.Payload)
+	l.Version++
+	touch(s.users[userID])
+
+	return nil
+}
+
+// DeleteLink removes the link. A non-zero ifVersion makes it conditional on
+// the link version.
+func (s *Store) DeleteLink(userID int, linkID int, ifVersion int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.links[linkID]
+	if !ok || l.UserID != userID {
+		return store.ErrLinkNotFound
+	}
+	if ifVersion != 0 && ifVersion != l.Version {
+		return store.ErrVersionMismatch
+	}
+	delete(s.links, linkID)
+	touch(s.users[userID])
+
+	return nil
+}
+
+// ChangeLinks applies a batch of link edits, all of them or none: every
+// change is checked before any is applied.
+func (s *Store) ChangeLinks(userID int, changes []models.LinkChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range changes {
+		var err error
+		l, ok := s.links[c.Link.ID]
+		switch {
+		case !ok || l.UserID != userID:
+			err = store.ErrLinkNotFound
+		case !c.Delete && c.Link.SectionID != nil:
+			err = store.ErrSectionNotFound
+		case c.IfVersion != 0 && c.IfVersion != l.Version:
+			err = store.ErrVersionMismatch
+		}
+		if err != nil {
+			return fmt.Errorf("link %d: %w", c.Link.ID, err)
+		}
+	}
+
+	for _, c := range changes {
+		if c.Delete {
+			delete(s.links, c.Link.ID)
+			continue
+		}
+
+		l := s.links[c.Link.ID]
+		l.Type = linkType(c.Link.Type)
+		l.LinkName = c.Link.LinkName
+		l.LinkColor = c.Link.LinkColor
+		l.LinkPath = c.Link.LinkPath
+		l.Payload = bytes.Clone(c.Link.Payload)
+		l.Version++
+	}
+	if len(changes) > 0 {
+		touch(s.users[userID])
+	}
+
+	return nil
+}
+
+// insertLink must be called with mu held. It returns the new link's ID.
+func (s *Store) insertLink(userID int, link requestModel.ReqLink) int {
+	s.lastLinkID++
+	s.links[s.lastLinkID] = &models.Link{
+		ID:        s.lastLinkID,
+		UserID:    userID,
+		Type:      linkType(link.Type),
+		LinkName:  link.LinkName,
+		LinkColor: link.LinkColor,
+		LinkPath:  link.LinkPath,
+		Payload:   bytes.Clone(link.Payload),
+		Hidden:    link.Hidden,
+		Version:   1,
+	}
+
+	return s.lastLinkID
+}
+
+// withLinks copies u together with its links in ID order. It must be called
+// with mu held.
+func (s *Store) withLinks(u *user, hidden bool) *models.User {
+	res := u.User
+	res.HashedPassword = bytes.Clone(u.HashedPassword)
+
+	for _, l := range s.links {
+		if l.UserID == u.ID && (hidden || !l.Hidden) {
+			res.Links = append(res.Links, *copyLink(l))
+		}
+	}
+	slices.SortFunc(res.Links, func(a, b models.Link) int { return a.ID - b.ID })
+
+	return &res
+}
+
+// touch records a change to what u's profile shows. It must be called with
+// mu held.
+func touch(u *user) {
+	u.Version++
+	u.UpdatedAt = now()
+}
+
+// now matches the second precision the database stores keep.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+func copyLink(l *models.Link) *models.Link {
+	c := *l
+	c.Payload = bytes.Clone(l.Payload)
+	return &c
+}
+
+// linkType defaults blocks created without an explicit type to plain links.
+func linkType(t string) string {
+	if t == "" {
+		return linkblock.TypeLink
+	}
+	return t
+}
@@ -0,0 +1,55 @@
+package memorystore
+
+import (
+	"sync"
+	"testing"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/store/storetest"
+)
+
+func TestStore(t *testing.T) {
+	storetest.RunUsers(t, func(t *testing.T) storetest.UserStore {
+		return New()
+	})
+}
+
+// Run with -race.
+func TestConcurrentAccess(t *testing.T) {
+	s := New()
+	u, err := s.CreateUser("alice@example.com", "alice", []byte("x"), "", nil)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := s.AddLink(u.ID, requestModel.ReqLink{LinkName: "l", LinkPath: "https://example.com"}); err != nil {
+					t.Errorf("AddLink: %v", err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := s.UserByUsername("alice"); err != nil {
+					t.Errorf("UserByUsername: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := s.UserById(u.ID)
+	if err != nil {
+		t.Fatalf("UserById: %v", err)
+	}
+	if len(got.Links) != 8*50 {
+		t.Errorf("user has %d links, want %d", len(got.Links), 8*50)
+	}
+}
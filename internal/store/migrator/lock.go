@@ -0,0 +1,47 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// lock takes a lock shared by every process that migrates the same
+// database and returns the function that releases it.
+func lock(ctx context.Context, driver string, storage string) (func(), error) {
+	switch driver {
+	case "", "sqlite":
+		path, _, _ := strings.Cut(storage, "?")
+		return lockFile(path + ".migrate.lock")
+	case "postgres":
+		return lockPostgres(ctx, storage)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+}
+
+// lockPostgres holds a session advisory lock on a connection of its own,
+// so the lock lives exactly as long as the migration.
+func lockPostgres(ctx context.Context, dsn string) (func(), error) {
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte("url_profile:" + Table))
+	key := int64(h.Sum64())
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+		conn.Close(ctx)
+		return nil, err
+	}
+
+	return func() {
+		conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", key)
+		conn.Close(context.Background())
+	}, nil
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package migrator
+
+// lockFile is a no-op where flock is missing; run one instance per sqlite
+// file there.
+func lockFile(path string) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,27 @@
+//go:build unix
+
+package migrator
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an flock on path, creating the file if needed. The kernel
+// drops the lock if the process dies.
+func lockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,47 @@
+//go:build unix
+
+package migrator
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	unlock, err := lock(context.Background(), "sqlite", path+"?_busy_timeout=5000")
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		second, err := lock(context.Background(), "sqlite", path)
+		if err != nil {
+			t.Errorf("second lock: %v", err)
+			close(acquired)
+			return
+		}
+		acquired <- second
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second lock was taken while the first was held")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	unlock()
+
+	select {
+	case second, ok := <-acquired:
+		if ok {
+			second()
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("second lock was not taken after the first was released")
+	}
+}
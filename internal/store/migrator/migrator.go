@@ -0,0 +1,205 @@
+// Package migrator applies the embedded schema migrations. Only one process
+// migrates at a time: the others wait on an advisory lock and then find
+// nothing left to do.
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"strings"
+	"url_profile/migrations"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// Table is where applied versions are recorded.
+const Table = "migrations"
+
+var (
+	ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+	ErrSchemaBehind = errors.New("database schema has pending migrations")
+	ErrDirty        = errors.New("database schema is dirty")
+)
+
+// DatabaseURL returns the golang-migrate URL for a sqlite file or a
+// postgres DSN.
+func DatabaseURL(driver string, storage string, table string) (string, error) {
+	switch driver {
+	case "", "sqlite":
+		return fmt.Sprintf("sqlite3://%s?x-migrations-table=%s&_busy_timeout=5000", storage, table), nil
+	case "postgres":
+		dsn := strings.TrimPrefix(storage, "postgresql://")
+		dsn = strings.TrimPrefix(dsn, "postgres://")
+
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+
+		return "pgx5://" + dsn + sep + "x-migrations-table=" + table, nil
+	default:
+		return "", fmt.Errorf("unknown storage driver %q", driver)
+	}
+}
+
+// Up applies every pending migration under the lock. It refuses to touch a
+// schema that is dirty or newer than the embedded migrations.
+func Up(ctx context.Context, driver string, storage string, log *slog.Logger) error {
+	unlock, err := lock(ctx, driver, storage)
+	if err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer unlock()
+
+	m, latest, err := open(driver, storage)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	from, err := current(m, latest)
+	if err != nil {
+		return err
+	}
+
+	if err := m.Up(); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			log.Info("database schema is up to date", slog.Uint64("version", uint64(from)))
+			return nil
+		}
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	log.Info("migrations applied",
+		slog.Uint64("from", uint64(from)),
+		slog.Uint64("to", uint64(latest)))
+
+	return nil
+}
+
+// Check verifies that the schema is exactly the one this binary runs on,
+// without applying anything. A schema with pending migrations is refused
+// too: the stores prepare their statements against the latest schema.
+func Check(driver string, storage string) error {
+	m, latest, err := open(driver, storage)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	v, err := current(m, latest)
+	if err != nil {
+		return err
+	}
+
+	if v < latest {
+		return fmt.Errorf("%w: database is at version %d, this binary needs %d; run the migrator or set storage.migrate_on_start",
+			ErrSchemaBehind, v, latest)
+	}
+
+	return nil
+}
+
+// Version returns the applied and the latest embedded version. Like Up it
+// fails on a schema that is dirty or newer than the binary.
+func Version(driver string, storage string) (uint, uint, error) {
+	m, latest, err := open(driver, storage)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer m.Close()
+
+	v, err := current(m, latest)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return v, latest, nil
+}
+
+// Source returns the embedded migrations for driver.
+func Source(driver string) (fs.FS, string, error) {
+	switch driver {
+	case "", "sqlite":
+		return migrations.SQLite, ".", nil
+	case "postgres":
+		return migrations.Postgres, "postgres", nil
+	default:
+		return nil, "", fmt.Errorf("unknown storage driver %q", driver)
+	}
+}
+
+func open(driver string, storage string) (*migrate.Migrate, uint, error) {
+	fsys, dir, err := Source(driver)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	src, err := iofs.New(fsys, dir)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+
+	latest, err := latestVersion(src)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	databaseURL, err := DatabaseURL(driver, storage, Table)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", src, databaseURL)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open database for migrations: %w", err)
+	}
+
+	return m, latest, nil
+}
+
+// current returns the applied version, 0 for a fresh database.
+func current(m *migrate.Migrate, latest uint) (uint, error) {
+	v, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	if v > latest {
+		return 0, fmt.Errorf("%w: database is at version %d, this binary knows up to %d", ErrSchemaTooNew, v, latest)
+	}
+
+	if dirty {
+		return 0, fmt.Errorf("%w at version %d; fix it and run the migrator's force command", ErrDirty, v)
+	}
+
+	return v, nil
+}
+
+func latestVersion(src source.Driver) (uint, error) {
+	v, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("no embedded migrations: %w", err)
+	}
+
+	for {
+		next, err := src.Next(v)
+		if errors.Is(err, fs.ErrNotExist) {
+			return v, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		v = next
+	}
+}
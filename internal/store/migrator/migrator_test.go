@@ -0,0 +1,124 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// migrated returns a sqlite file with every embedded migration applied and
+// the latest version.
+func migrated(t *testing.T) (string, uint) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.db")
+	if err := Up(context.Background(), "sqlite", path, discard); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	v, latest, err := Version("sqlite", path)
+	if err != nil {
+		t.Fatalf("Version: %v", err)
+	}
+	if v != latest {
+		t.Fatalf("Up left the schema at %d, latest is %d", v, latest)
+	}
+
+	return path, latest
+}
+
+// exec runs a statement on the sqlite file outside the migrator.
+func exec(t *testing.T, path string, query string, args ...any) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatalf("exec %q: %v", query, err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	if err := Check("sqlite", path); !errors.Is(err, ErrSchemaBehind) {
+		t.Errorf("Check on an empty database: got %v, want ErrSchemaBehind", err)
+	}
+
+	path, latest := migrated(t)
+	if err := Check("sqlite", path); err != nil {
+		t.Errorf("Check on a current schema: %v", err)
+	}
+
+	exec(t, path, "UPDATE "+Table+" SET version = ?", latest-1)
+	if err := Check("sqlite", path); !errors.Is(err, ErrSchemaBehind) {
+		t.Errorf("Check one version behind: got %v, want ErrSchemaBehind", err)
+	}
+}
+
+func TestSchemaTooNew(t *testing.T) {
+	path, latest := migrated(t)
+	exec(t, path, "UPDATE "+Table+" SET version = ?", latest+1)
+
+	if err := Check("sqlite", path); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Check: got %v, want ErrSchemaTooNew", err)
+	}
+	if _, _, err := Version("sqlite", path); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Version: got %v, want ErrSchemaTooNew", err)
+	}
+
+	// Up must not try to "fix" a newer schema by running anything.
+	if err := Up(context.Background(), "sqlite", path, discard); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Up: got %v, want ErrSchemaTooNew", err)
+	}
+}
+
+func TestDirty(t *testing.T) {
+	path, _ := migrated(t)
+	exec(t, path, "UPDATE "+Table+" SET dirty = 1")
+
+	if err := Check("sqlite", path); !errors.Is(err, ErrDirty) {
+		t.Errorf("Check: got %v, want ErrDirty", err)
+	}
+	if err := Up(context.Background(), "sqlite", path, discard); !errors.Is(err, ErrDirty) {
+		t.Errorf("Up: got %v, want ErrDirty", err)
+	}
+}
+
+func TestConcurrentUp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	// Without the lock the instances race on the version table and some
+	// fail halfway or leave the schema dirty.
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for range cap(errs) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- Up(context.Background(), "sqlite", path, discard)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Up: %v", err)
+		}
+	}
+	if err := Check("sqlite", path); err != nil {
+		t.Errorf("Check after concurrent Up: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package postgresstore
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLSTATE codes the store maps to domain errors, see
+// https://www.postgresql.org/docs/current/errcodes-appendix.html.
+const (
+	codeUniqueViolation     = "23505"
+	codeForeignKeyViolation = "23503"
+)
+
+// pgError returns the server error behind err, if there is one.
+func pgError(err error) (*pgconn.PgError, bool) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr, true
+	}
+	return nil, false
+}
+
+// uniqueColumns names the column behind each unique constraint, so a
+// conflict can be reported against the field the client sent.
+var uniqueColumns = map[string]string{
+	"users_email_key":    "email",
+	"users_username_key": "username",
+}
+
+// uniqueViolation reports a unique violation and the offending column. For
+// constraints missing from uniqueColumns it falls back to the column the
+// server reports, which may be empty.
+func uniqueViolation(err error) (string, bool) {
+	pgErr, ok := pgError(err)
+	if !ok || pgErr.Code != codeUniqueViolation {
+		return "", false
+	}
+
+	if column, ok := uniqueColumns[pgErr.ConstraintName]; ok {
+		return column, true
+	}
+	return pgErr.ColumnName, true
+}
+
+func isForeignKeyViolation(err error) bool {
+	pgErr, ok := pgError(err)
+	return ok && pgErr.Code == codeForeignKeyViolation
+}
@@ -0,0 +1,58 @@
+package postgresstore
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+	"url_profile/internal/store/postgres/query"
+)
+
+func (s *Store) upsertLinkHealth(h models.LinkHealth) error {
+	_, err := s.db.Exec(s.ctx, query.UpsertLinkHealth, h.LinkID, h.Status, h.StatusCode, h.Error, h.CheckedAt)
+	if err != nil {
+		s.log.Error("failed to save link health",
+			slog.Int("link_id", h.LinkID),
+			slog.String("error", err.Error()))
+		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return nil
+}
+
+func (s *Store) linkHealthByUser(userID int) ([]models.LinkHealth, error) {
+	rows, err := s.db.Query(s.ctx, query.LinkHealthByUser, userID)
+	if err != nil {
+		s.log.Error("failed to query link health",
+			slog.Int("user_id", userID),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+	defer rows.Close()
+
+	var res []models.LinkHealth
+	for rows.Next() {
+		var (
+			h         models.LinkHealth
+			checkedAt *time.Time
+		)
+		if err := rows.Scan(&h.LinkID, &h.Status, &h.StatusCode, &h.Error, &checkedAt, &h.FailStreak); err != nil {
+			s.log.Error("failed to scan link health",
+				slog.String("error", err.Error()))
+			return nil, fmt.Errorf("%w: %v", store.ErrDataScanFailed, err)
+		}
+		if checkedAt != nil {
+			h.CheckedAt = *checkedAt
+		}
+		res = append(res, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("rows iteration error",
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: rows iteration failed", store.ErrDatabaseOperation)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,222 @@
+package postgresstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/lib/linkblock"
+	"url_profile/internal/store"
+	"url_profile/internal/store/postgres/query"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Store) insertLink(userID int, link requestModel.ReqLink) (int, error) {
+	var id int
+	err := s.db.QueryRow(s.ctx, query.InsertLink, userID, linkType(link.Type), link.LinkName, link.LinkColor,
+		link.LinkPath, string(link.Payload), link.SectionID, link.Hidden).Scan(&id)
+	if err != nil {
+		if column, ok := uniqueViolation(err); ok {
+			s.log.Warn("duplicate link path",
+				slog.Int("user_id", userID),
+				slog.String("path", link.LinkPath))
+			return 0, &store.ConflictError{Column: column, Err: store.ErrLinkAlreadyExists}
+		}
+
+		if isForeignKeyViolation(err) {
+			s.log.Warn("invalid user reference",
+				slog.Int("user_id", userID))
+			return 0, store.ErrUserNotFound
+		}
+
+		s.log.Error("failed to insert link",
+			slog.Int("user_id", userID),
+			slog.Any("link", link),
+			slog.String("error", err.Error()))
+		return 0, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return id, nil
+}
+
+func (s *Store) linkByID(linkID int) (*models.Link, error) {
+	l := &models.Link{}
+	var payload string
+	err := s.db.QueryRow(s.ctx, query.LinkByID, linkID).Scan(
+		&l.ID,
+		&l.UserID,
+		&l.Type,
+		&l.LinkName,
+		&l.LinkColor,
+		&l.LinkPath,
+		&payload,
+		&l.SectionID,
+		&l.Hidden,
+		&l.Version,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrLinkNotFound
+		}
+
+		s.log.Error("failed to query link",
+			slog.Int("link_id", linkID),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+	l.Payload = rawPayload(payload)
+
+	return l, nil
+}
+
+func (s *Store) existsLink(userID int, linkID int) error {
+	var exists bool
+	if err := s.db.QueryRow(s.ctx, query.ExistsLink, linkID, userID).Scan(&exists); err != nil {
+		s.log.Error("failed to check link existence",
+			slog.Int("user_id", userID),
+			slog.Int("link_id", linkID),
+			slog.String("error", err.Error()))
+		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	if !exists {
+		s.log.Warn("link not found or doesn't belong to user",
+			slog.Int("user_id", userID),
+			slog.Int("link_id", linkID))
+		return store.ErrLinkNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) updateLink(userID int, link *requestModel.ReqUpdateLink, ifVersion int) error {
+	tag, err := s.db.Exec(s.ctx, query.UpdateLink,
+		linkType(link.Type),
+		link.LinkName,
+		link.LinkColor,
+		link.LinkPath,
+		string(link.Payload),
+		link.SectionID,
+		userID,
+		link.LinkID,
+		ifVersion,
+	)
+	if err != nil {
+		s.log.Error("failed to execute link update",
+			slog.Int("user_id", userID),
+			slog.Int("link_id", link.LinkID),
+			slog.String("error", err.Error()))
+		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return s.versionCheck(tag.RowsAffected(), userID, link.LinkID, ifVersion)
+}
+
+// deleteLink moves the link to the trash; purgeLink removes it for good.
+func (s *Store) deleteLink(userID int, linkID int, ifVersion int) error {
+	tag, err := s.db.Exec(s.ctx, query.DeleteLink, time.Now().UTC(), linkID, userID, ifVersion)
+	if err != nil {
+		s.log.Error("failed to execute delete link",
+			slog.Int("user_id", userID),
+			slog.Int("link_id", linkID),
+			slog.String("error", err.Error()))
+		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return s.versionCheck(tag.RowsAffected(), userID, linkID, ifVersion)
+}
+
+// versionCheck turns a link write that matched no row into an error:
+// store.ErrLinkNotFound when the link is gone, store.ErrVersionMismatch
+// when only a non-zero ifVersion is stale. Under read committed the link
+// may go between the caller's existence check and the write, so it is
+// checked again.
+func (s *Store) versionCheck(n int64, userID int, linkID int, ifVersion int) error {
+	if n > 0 {
+		return nil
+	}
+
+	if ifVersion == 0 {
+		return store.ErrLinkNotFound
+	}
+	if err := s.existsLink(userID, linkID); err != nil {
+		return err
+	}
+	return store.ErrVersionMismatch
+}
+
+func (s *Store) allLinks() ([]models.Link, error) {
+	rows, err := s.db.Query(s.ctx, query.AllLinks)
+	if err != nil {
+		s.log.Error("failed to query links",
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+	defer rows.Close()
+
+	var links []models.Link
+	for rows.Next() {
+		var l models.Link
+		if err := rows.Scan(&l.ID, &l.UserID, &l.LinkName, &l.LinkColor, &l.LinkPath); err != nil {
+			s.log.Error("failed to scan link",
+				slog.String("error", err.Error()))
+			return nil, fmt.Errorf("%w: %v", store.ErrDataScanFailed, err)
+		}
+		links = append(links, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("rows iteration error",
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: rows iteration failed", store.ErrDatabaseOperation)
+	}
+
+	return links, nil
+}
+
+// linkType defaults blocks created without an explicit type to plain links.
+func linkType(t string) string {
+	if t == "" {
+		return linkblock.TypeLink
+	}
+	return t
+}
+
+func rawPayload(p string) json.RawMessage {
+	if p == "" {
+		return nil
+	}
+	return json.RawMessage(p)
+}
+
+// changeLinks runs every change in one transaction, so a failing change,
+// stale version included, rolls back the ones before it.
+func (s *Store) changeLinks(userID int, changes []models.LinkChange) error {
+	return s.WithTx(s.ctx, func(tx *Store) error {
+		for _, c := range changes {
+			var err error
+			if c.Delete {
+				err = tx.DeleteLink(userID, c.Link.ID, c.IfVersion)
+			} else {
+				err = tx.UpdateLink(userID, &requestModel.ReqUpdateLink{
+					LinkID:    c.Link.ID,
+					Type:      c.Link.Type,
+					LinkName:  c.Link.LinkName,
+					LinkColor: c.Link.LinkColor,
+					LinkPath:  c.Link.LinkPath,
+					Payload:   c.Link.Payload,
+					SectionID: c.Link.SectionID,
+				}, c.IfVersion)
+			}
+			if err != nil {
+				return fmt.Errorf("link %d: %w", c.Link.ID, err)
+			}
+		}
+
+		return nil
+	})
+}
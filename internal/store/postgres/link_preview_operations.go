@@ -0,0 +1,108 @@
+package postgresstore
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+	"url_profile/internal/store/postgres/query"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Store) linkPreviewByURL(url string) (*models.LinkPreview, error) {
+	p := &models.LinkPreview{}
+	err := s.db.QueryRow(s.ctx, query.LinkPreviewByURL, url).Scan(
+		&p.URL,
+		&p.Title,
+		&p.Description,
+		&p.Favicon,
+		&p.Image,
+		&p.FetchedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrPreviewNotFound
+		}
+
+		s.log.Error("failed to query link preview",
+			slog.String("url", url),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return p, nil
+}
+
+func (s *Store) upsertLinkPreview(p models.LinkPreview) error {
+	_, err := s.db.Exec(s.ctx, query.UpsertLinkPreview, p.URL, p.Title, p.Description, p.Favicon, p.Image, p.FetchedAt)
+	if err != nil {
+		s.log.Error("failed to save link preview",
+			slog.String("url", p.URL),
+			slog.String("error", err.Error()))
+		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return nil
+}
+
+func (s *Store) linkPreviewsByUser(userID int) ([]models.LinkPreview, error) {
+	rows, err := s.db.Query(s.ctx, query.LinkPreviewsByUser, userID)
+	if err != nil {
+		s.log.Error("failed to query link previews",
+			slog.Int("user_id", userID),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+	defer rows.Close()
+
+	var res []models.LinkPreview
+	for rows.Next() {
+		var p models.LinkPreview
+		if err := rows.Scan(&p.URL, &p.Title, &p.Description, &p.Favicon, &p.Image, &p.FetchedAt); err != nil {
+			s.log.Error("failed to scan link preview",
+				slog.String("error", err.Error()))
+			return nil, fmt.Errorf("%w: %v", store.ErrDataScanFailed, err)
+		}
+		res = append(res, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("rows iteration error",
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: rows iteration failed", store.ErrDatabaseOperation)
+	}
+
+	return res, nil
+}
+
+func (s *Store) previewOwners(url string) ([]int, error) {
+	rows, err := s.db.Query(s.ctx, query.PreviewOwners, url)
+	if err != nil {
+		s.log.Error("failed to query preview owners",
+			slog.String("url", url),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+	defer rows.Close()
+
+	var res []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			s.log.Error("failed to scan preview owner",
+				slog.String("error", err.Error()))
+			return nil, fmt.Errorf("%w: %v", store.ErrDataScanFailed, err)
+		}
+		res = append(res, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("rows iteration error",
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: rows iteration failed", store.ErrDatabaseOperation)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,157 @@
+package query
+
+const (
+	InsertUser = "INSERT INTO users (email, username, pass_hash, about_text) VALUES ($1, $2, $3, $4) RETURNING id"
+
+	CreatedUser = "SELECT id, email, username FROM users WHERE id = $1"
+
+	UsersRowsByEmail = `
+		SELECT
+			u.id, u.email, u.username, u.pass_hash, u.about_text, u.version, u.preview_version, u.updated_at,
+			l.id, l.type, l.link_name, l.link_color, l.link_path, l.payload, l.section_id, l.hidden, l.version
+		FROM users u
+		LEFT JOIN links l ON u.id = l.user_id AND l.deleted_at IS NULL
+		WHERE u.email = $1
+		ORDER BY l.id`
+
+	UsersRowsByID = `
+		SELECT
+			u.id, u.email, u.username, u.pass_hash, u.about_text, u.version, u.preview_version, u.updated_at,
+			l.id, l.type, l.link_name, l.link_color, l.link_path, l.payload, l.section_id, l.hidden, l.version
+		FROM users u
+		LEFT JOIN links l ON u.id = l.user_id AND l.deleted_at IS NULL
+		WHERE u.id = $1
+		ORDER BY l.id`
+
+	UsersRowsByUsername = `
+		SELECT
+			u.id, u.email, u.username, u.pass_hash, u.about_text, u.version, u.preview_version, u.updated_at,
+			l.id, l.type, l.link_name, l.link_color, l.link_path, l.payload, l.section_id, l.hidden, l.version
+		FROM users u
+		LEFT JOIN links l ON u.id = l.user_id AND l.deleted_at IS NULL AND NOT l.hidden
+		WHERE u.username = $1
+		ORDER BY l.id`
+
+	// ($n = 0 OR version = $n) makes a write conditional on the version the
+	// caller read; 0 writes unconditionally.
+	UpdateAboutMe = "UPDATE users SET about_text = $1 WHERE id = $2 AND ($3 = 0 OR version = $3)"
+
+	UserSettings = "SELECT strip_tracking FROM users WHERE id = $1"
+
+	LockProfile = "SELECT version FROM users WHERE id = $1 FOR UPDATE"
+
+	UpdateSettings = "UPDATE users SET strip_tracking = $1 WHERE id = $2"
+
+	LinkByID = "SELECT id, user_id, type, link_name, link_color, link_path, payload, section_id, hidden, version FROM links WHERE id = $1 AND deleted_at IS NULL"
+
+	InsertLink = "INSERT INTO links (user_id, type, link_name, link_color, link_path, payload, section_id, hidden) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id"
+
+	ExistsLink = "SELECT EXISTS(SELECT 1 FROM links WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL)"
+
+	UpdateLink = "UPDATE links SET type = $1, link_name = $2, link_color = $3, link_path = $4, payload = $5, section_id = $6 WHERE user_id = $7 AND id = $8 AND deleted_at IS NULL AND ($9 = 0 OR version = $9)"
+
+	DeleteLink = "UPDATE links SET deleted_at = $1 WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL AND ($4 = 0 OR version = $4)"
+
+	SetLinkHidden = "UPDATE links SET hidden = $1 WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL"
+
+	TrashedLinks = `
+		SELECT id, user_id, type, link_name, link_color, link_path, payload, section_id, hidden, deleted_at
+		FROM links
+		WHERE user_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC`
+
+	RestoreLink = "UPDATE links SET deleted_at = NULL WHERE id = $1 AND user_id = $2 AND deleted_at >= $3"
+
+	PurgeLinkHealth = "DELETE FROM link_health WHERE link_id IN (SELECT id FROM links WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL)"
+
+	PurgeLink = "DELETE FROM links WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL"
+
+	PurgeExpiredLinkHealth = "DELETE FROM link_health WHERE link_id IN (SELECT id FROM links WHERE deleted_at < $1)"
+
+	PurgeExpiredLinks = "DELETE FROM links WHERE deleted_at < $1"
+
+	AllLinks = "SELECT id, user_id, link_name, link_color, link_path FROM links WHERE deleted_at IS NULL"
+
+	UpsertLinkHealth = `
+		INSERT INTO link_health (link_id, status, status_code, error, checked_at, fail_streak)
+		VALUES ($1, $2, $3, $4, $5, CASE WHEN $2 = 'ok' THEN 0 ELSE 1 END)
+		ON CONFLICT (link_id) DO UPDATE SET
+			status = excluded.status,
+			status_code = excluded.status_code,
+			error = excluded.error,
+			checked_at = excluded.checked_at,
+			fail_streak = CASE WHEN excluded.status = 'ok' THEN 0 ELSE link_health.fail_streak + 1 END`
+
+	LinkHealthByUser = `
+		SELECT h.link_id, h.status, h.status_code, h.error, h.checked_at, h.fail_streak
+		FROM link_health h
+		JOIN links l ON l.id = h.link_id
+		WHERE l.user_id = $1`
+
+	LinkPreviewByURL = "SELECT url, title, description, favicon, image, fetched_at FROM link_previews WHERE url = $1"
+
+	UpsertLinkPreview = `
+		INSERT INTO link_previews (url, title, description, favicon, image, fetched_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (url) DO UPDATE SET
+			title = excluded.title,
+			description = excluded.description,
+			favicon = excluded.favicon,
+			image = excluded.image,
+			fetched_at = excluded.fetched_at`
+
+	LinkPreviewsByUser = `
+		SELECT DISTINCT p.url, p.title, p.description, p.favicon, p.image, p.fetched_at
+		FROM link_previews p
+		JOIN links l ON l.link_path = p.url
+		WHERE l.user_id = $1`
+
+	// PreviewOwners picks the users a preview of the URL shows up for, the
+	// same ones the link_previews triggers touch.
+	PreviewOwners = "SELECT DISTINCT user_id FROM links WHERE link_path = $1"
+
+	ThemeByUser = "SELECT preset, background, font, button_style, link_color FROM profile_themes WHERE user_id = $1"
+
+	UpsertTheme = `
+		INSERT INTO profile_themes (user_id, preset, background, font, button_style, link_color)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE SET
+			preset = excluded.preset,
+			background = excluded.background,
+			font = excluded.font,
+			button_style = excluded.button_style,
+			link_color = excluded.link_color`
+
+	ProfileImage = "SELECT user_id, kind, version, format FROM profile_images WHERE user_id = $1 AND kind = $2"
+
+	ProfileImagesByUser = "SELECT user_id, kind, version, format FROM profile_images WHERE user_id = $1"
+
+	UpsertProfileImage = `
+		INSERT INTO profile_images (user_id, kind, version, format)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, kind) DO UPDATE SET
+			version = excluded.version,
+			format = excluded.format,
+			created_at = now()`
+
+	DeleteProfileImage = "DELETE FROM profile_images WHERE user_id = $1 AND kind = $2"
+
+	SectionsByUser = "SELECT id, user_id, title, position, collapsed FROM link_sections WHERE user_id = $1 ORDER BY position, id"
+
+	SectionByID = "SELECT id, user_id, title, position, collapsed FROM link_sections WHERE id = $1 AND user_id = $2"
+
+	ExistsSection = "SELECT EXISTS(SELECT 1 FROM link_sections WHERE id = $1 AND user_id = $2)"
+
+	InsertSection = `
+		INSERT INTO link_sections (user_id, title, position, collapsed)
+		VALUES ($1, $2, COALESCE($3::integer, (SELECT COALESCE(MAX(position), -1) + 1 FROM link_sections WHERE user_id = $1)), $4)
+		RETURNING id, position`
+
+	UpdateSection = "UPDATE link_sections SET title = $1, position = $2, collapsed = $3 WHERE id = $4 AND user_id = $5"
+
+	UngroupSectionLinks = "UPDATE links SET section_id = NULL WHERE section_id = $1 AND user_id = $2"
+
+	DeleteSection = "DELETE FROM link_sections WHERE id = $1 AND user_id = $2"
+
+	SectionIDsByUser = "SELECT id, title FROM link_sections WHERE user_id = $1 ORDER BY id"
+)
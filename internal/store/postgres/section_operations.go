@@ -0,0 +1,143 @@
+package postgresstore
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+	"url_profile/internal/store/postgres/query"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Store) sectionsByUser(userID int) ([]models.LinkSection, error) {
+	rows, err := s.db.Query(s.ctx, query.SectionsByUser, userID)
+	if err != nil {
+		s.log.Error("failed to query link sections",
+			slog.Int("user_id", userID),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+	defer rows.Close()
+
+	var res []models.LinkSection
+	for rows.Next() {
+		var sec models.LinkSection
+		if err := rows.Scan(&sec.ID, &sec.UserID, &sec.Title, &sec.Position, &sec.Collapsed); err != nil {
+			s.log.Error("failed to scan link section",
+				slog.String("error", err.Error()))
+			return nil, fmt.Errorf("%w: %v", store.ErrDataScanFailed, err)
+		}
+		res = append(res, sec)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("rows iteration error",
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: rows iteration failed", store.ErrDatabaseOperation)
+	}
+
+	return res, nil
+}
+
+func (s *Store) sectionByID(userID int, sectionID int) (*models.LinkSection, error) {
+	sec := &models.LinkSection{}
+	err := s.db.QueryRow(s.ctx, query.SectionByID, sectionID, userID).Scan(
+		&sec.ID,
+		&sec.UserID,
+		&sec.Title,
+		&sec.Position,
+		&sec.Collapsed,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrSectionNotFound
+		}
+
+		s.log.Error("failed to query link section",
+			slog.Int("user_id", userID),
+			slog.Int("section_id", sectionID),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return sec, nil
+}
+
+func (s *Store) existsSection(userID int, sectionID int) error {
+	var exists bool
+	if err := s.db.QueryRow(s.ctx, query.ExistsSection, sectionID, userID).Scan(&exists); err != nil {
+		s.log.Error("failed to check section existence",
+			slog.Int("user_id", userID),
+			slog.Int("section_id", sectionID),
+			slog.String("error", err.Error()))
+		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	if !exists {
+		s.log.Warn("section not found or doesn't belong to user",
+			slog.Int("user_id", userID),
+			slog.Int("section_id", sectionID))
+		return store.ErrSectionNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) insertSection(userID int, title string, position *int, collapsed bool) (*models.LinkSection, error) {
+	sec := &models.LinkSection{
+		UserID:    userID,
+		Title:     title,
+		Collapsed: collapsed,
+	}
+
+	err := s.db.QueryRow(s.ctx, query.InsertSection, userID, title, position, collapsed).Scan(&sec.ID, &sec.Position)
+	if err != nil {
+		s.log.Error("failed to insert link section",
+			slog.Int("user_id", userID),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return sec, nil
+}
+
+func (s *Store) updateSection(sec models.LinkSection) (int64, error) {
+	tag, err := s.db.Exec(s.ctx, query.UpdateSection, sec.Title, sec.Position, sec.Collapsed, sec.ID, sec.UserID)
+	if err != nil {
+		s.log.Error("failed to update link section",
+			slog.Int("section_id", sec.ID),
+			slog.String("error", err.Error()))
+		return 0, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// deleteSection removes the section and moves its links back to the
+// ungrouped list in one transaction.
+func (s *Store) deleteSection(userID int, sectionID int) error {
+	return s.WithTx(s.ctx, func(tx *Store) error {
+		if _, err := tx.db.Exec(tx.ctx, query.UngroupSectionLinks, sectionID, userID); err != nil {
+			s.log.Error("failed to ungroup section links",
+				slog.Int("section_id", sectionID),
+				slog.String("error", err.Error()))
+			return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+		}
+
+		tag, err := tx.db.Exec(tx.ctx, query.DeleteSection, sectionID, userID)
+		if err != nil {
+			s.log.Error("failed to delete link section",
+				slog.Int("section_id", sectionID),
+				slog.String("error", err.Error()))
+			return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+		}
+
+		if tag.RowsAffected() == 0 {
+			return store.ErrSectionNotFound
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,379 @@
+// Package postgresstore is the PostgreSQL storage backend. It implements the
+// same contracts as sqlitestore and uses the schema in migrations/postgres.
+package postgresstore
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+	"url_profile/internal/store/postgres/query"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Config holds the connection string and pool limits. Zero values keep the
+// pgxpool defaults.
+type Config struct {
+	DSN               string
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	ConnectTimeout    time.Duration
+}
+
+type Store struct {
+	pool *pgxpool.Pool
+	// db is pool, or the transaction of a store handed out by WithTx; ctx
+	// is the context that transaction was started with.
+	db  querier
+	ctx context.Context
+	log *slog.Logger
+}
+
+func New(ctx context.Context, cfg Config, log *slog.Logger) (*Store, error) {
+	pcfg, err := pgxpool.ParseConfig(cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid postgres dsn: %w", err)
+	}
+
+	if cfg.MaxConns > 0 {
+		pcfg.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		pcfg.MinConns = cfg.MinConns
+	}
+	if cfg.MaxConnLifetime > 0 {
+		pcfg.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		pcfg.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		pcfg.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+	if cfg.ConnectTimeout > 0 {
+		pcfg.ConnConfig.ConnectTimeout = cfg.ConnectTimeout
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, pcfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres pool: %w", err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+
+	return &Store{
+		pool: pool,
+		db:   pool,
+		ctx:  context.Background(),
+		log:  log,
+	}, nil
+}
+
+// Close waits for checked-out connections to be returned and closes the
+// pool.
+func (s *Store) Close() {
+	s.pool.Close()
+}
+
+// CreateUser writes the user and all of their links in one transaction.
+// If any link is rejected nothing is written and the error is a
+// *store.InvalidLinksError listing every rejected link.
+func (s *Store) CreateUser(email string, username string, pass []byte, about string, links []requestModel.ReqLink) (*models.User, error) {
+	var u *models.User
+	err := s.WithTx(context.Background(), func(tx *Store) error {
+		userID, err := tx.insertUser(email, username, pass, about)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.insertUserLinks(userID, links); err != nil {
+			return err
+		}
+
+		u, err = tx.createdUser(userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+func (s *Store) User(email string) (*models.User, error) {
+	return s.userBy(query.UsersRowsByEmail, email)
+}
+
+func (s *Store) UserById(id int) (*models.User, error) {
+	return s.userBy(query.UsersRowsByID, id)
+}
+
+// UserByUsername returns the public view of a profile: hidden links are
+// left out.
+func (s *Store) UserByUsername(name string) (*models.User, error) {
+	return s.userBy(query.UsersRowsByUsername, name)
+}
+
+// UpdateAboutMe changes the about text. A non-zero ifVersion makes it
+// conditional on the profile version, see models.User.
+func (s *Store) UpdateAboutMe(id int, text string, ifVersion int) error {
+	n, err := s.updateAboutMe(id, text, ifVersion)
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		if ifVersion != 0 {
+			if _, err := s.userSettings(id); err == nil {
+				return store.ErrVersionMismatch
+			}
+		}
+		return store.ErrNoRowsAffected
+	}
+
+	return nil
+}
+
+// LockProfile fails with store.ErrVersionMismatch when a non-zero
+// ifVersion is not the profile version. Inside a unit of work the profile
+// then stays as checked until the unit ends.
+func (s *Store) LockProfile(id int, ifVersion int) error {
+	version, err := s.lockProfile(id)
+	if err != nil {
+		return err
+	}
+
+	if ifVersion != 0 && ifVersion != version {
+		return store.ErrVersionMismatch
+	}
+
+	return nil
+}
+
+func (s *Store) Settings(id int) (*models.Settings, error) {
+	return s.userSettings(id)
+}
+
+func (s *Store) UpdateSettings(id int, settings models.Settings) error {
+	n, err := s.updateSettings(id, settings)
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return store.ErrNoRowsAffected
+	}
+
+	return nil
+}
+
+func (s *Store) Link(linkID int) (*models.Link, error) {
+	return s.linkByID(linkID)
+}
+
+// AddLink saves the link and returns its ID.
+func (s *Store) AddLink(userID int, link requestModel.ReqLink) (int, error) {
+	if link.SectionID != nil {
+		if err := s.existsSection(userID, *link.SectionID); err != nil {
+			return 0, err
+		}
+	}
+
+	return s.insertLink(userID, link)
+}
+
+// UpdateLink replaces the link's fields. A non-zero ifVersion makes it
+// conditional on the link version. The checks and the write share one
+// transaction.
+func (s *Store) UpdateLink(userID int, link *requestModel.ReqUpdateLink, ifVersion int) error {
+	return s.WithTx(s.ctx, func(tx *Store) error {
+		if err := tx.existsLink(userID, link.LinkID); err != nil {
+			return err
+		}
+
+		if link.SectionID != nil {
+			if err := tx.existsSection(userID, *link.SectionID); err != nil {
+				return err
+			}
+		}
+
+		return tx.updateLink(userID, link, ifVersion)
+	})
+}
+
+// DeleteLink moves the link to the trash. A non-zero ifVersion makes it
+// conditional on the link version.
+func (s *Store) DeleteLink(userID int, linkID int, ifVersion int) error {
+	return s.WithTx(s.ctx, func(tx *Store) error {
+		if err := tx.existsLink(userID, linkID); err != nil {
+			return err
+		}
+
+		return tx.deleteLink(userID, linkID, ifVersion)
+	})
+}
+
+// ChangeLinks applies a batch of link edits, all of them or none.
+func (s *Store) ChangeLinks(userID int, changes []models.LinkChange) error {
+	return s.changeLinks(userID, changes)
+}
+
+func (s *Store) AllLinks() ([]models.Link, error) {
+	return s.allLinks()
+}
+
+func (s *Store) SaveLinkHealth(health models.LinkHealth) error {
+	return s.upsertLinkHealth(health)
+}
+
+func (s *Store) LinkHealth(userID int) ([]models.LinkHealth, error) {
+	return s.linkHealthByUser(userID)
+}
+
+func (s *Store) LinkPreview(url string) (*models.LinkPreview, error) {
+	return s.linkPreviewByURL(url)
+}
+
+func (s *Store) SaveLinkPreview(preview models.LinkPreview) error {
+	return s.upsertLinkPreview(preview)
+}
+
+// PreviewOwners returns the users who link to url, whose profiles show its
+// preview.
+func (s *Store) PreviewOwners(url string) ([]int, error) {
+	return s.previewOwners(url)
+}
+
+func (s *Store) LinkPreviews(userID int) ([]models.LinkPreview, error) {
+	return s.linkPreviewsByUser(userID)
+}
+
+func (s *Store) Theme(userID int) (*models.Theme, error) {
+	return s.themeByUser(userID)
+}
+
+func (s *Store) SaveTheme(userID int, theme models.Theme) error {
+	return s.upsertTheme(userID, theme)
+}
+
+func (s *Store) ProfileImage(userID int, kind string) (*models.ProfileImage, error) {
+	return s.profileImage(userID, kind)
+}
+
+func (s *Store) ProfileImages(userID int) ([]models.ProfileImage, error) {
+	return s.profileImagesByUser(userID)
+}
+
+func (s *Store) SaveProfileImage(img models.ProfileImage) error {
+	return s.upsertProfileImage(img)
+}
+
+func (s *Store) DeleteProfileImage(userID int, kind string) error {
+	n, err := s.deleteProfileImage(userID, kind)
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return store.ErrImageNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) Sections(userID int) ([]models.LinkSection, error) {
+	return s.sectionsByUser(userID)
+}
+
+func (s *Store) Section(userID int, sectionID int) (*models.LinkSection, error) {
+	return s.sectionByID(userID, sectionID)
+}
+
+// CreateSection appends the section after the user's last one when position
+// is nil.
+func (s *Store) CreateSection(userID int, title string, position *int, collapsed bool) (*models.LinkSection, error) {
+	return s.insertSection(userID, title, position, collapsed)
+}
+
+func (s *Store) UpdateSection(section models.LinkSection) error {
+	n, err := s.updateSection(section)
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return store.ErrSectionNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteSection(userID int, sectionID int) error {
+	return s.deleteSection(userID, sectionID)
+}
+
+func (s *Store) SetLinkHidden(userID int, linkID int, hidden bool) error {
+	n, err := s.setLinkHidden(userID, linkID, hidden)
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return store.ErrLinkNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) TrashedLinks(userID int) ([]models.Link, error) {
+	return s.trashedLinks(userID)
+}
+
+// RestoreLink takes a link out of the trash if it was deleted after
+// deletedAfter.
+func (s *Store) RestoreLink(userID int, linkID int, deletedAfter time.Time) error {
+	n, err := s.restoreLink(userID, linkID, deletedAfter)
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return store.ErrLinkNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) PurgeLink(userID int, linkID int) error {
+	n, err := s.purgeLink(userID, linkID)
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return store.ErrLinkNotFound
+	}
+
+	return nil
+}
+
+// PurgeDeletedLinks permanently removes links trashed before the given time.
+func (s *Store) PurgeDeletedLinks(before time.Time) (int64, error) {
+	return s.purgeExpiredLinks(before)
+}
+
+// ImportLinks adds all rows or none and reports how many sections it had to
+// create.
+func (s *Store) ImportLinks(userID int, rows []models.ImportedLink) (int, error) {
+	return s.importLinks(userID, rows)
+}
@@ -0,0 +1,47 @@
+package postgresstore
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+	"url_profile/internal/store/postgres/query"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Store) themeByUser(userID int) (*models.Theme, error) {
+	t := &models.Theme{}
+	err := s.db.QueryRow(s.ctx, query.ThemeByUser, userID).Scan(
+		&t.Preset,
+		&t.Background,
+		&t.Font,
+		&t.ButtonStyle,
+		&t.LinkColor,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrThemeNotFound
+		}
+
+		s.log.Error("failed to query theme",
+			slog.Int("user_id", userID),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return t, nil
+}
+
+func (s *Store) upsertTheme(userID int, t models.Theme) error {
+	_, err := s.db.Exec(s.ctx, query.UpsertTheme, userID, t.Preset, t.Background, t.Font, t.ButtonStyle, t.LinkColor)
+	if err != nil {
+		s.log.Error("failed to save theme",
+			slog.Int("user_id", userID),
+			slog.String("error", err.Error()))
+		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return nil
+}
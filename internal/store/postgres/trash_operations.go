@@ -0,0 +1,125 @@
+package postgresstore
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+	"url_profile/internal/store/postgres/query"
+)
+
+func (s *Store) setLinkHidden(userID int, linkID int, hidden bool) (int64, error) {
+	tag, err := s.db.Exec(s.ctx, query.SetLinkHidden, hidden, linkID, userID)
+	if err != nil {
+		s.log.Error("failed to update link visibility",
+			slog.Int("user_id", userID),
+			slog.Int("link_id", linkID),
+			slog.String("error", err.Error()))
+		return 0, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (s *Store) trashedLinks(userID int) ([]models.Link, error) {
+	rows, err := s.db.Query(s.ctx, query.TrashedLinks, userID)
+	if err != nil {
+		s.log.Error("failed to query trashed links",
+			slog.Int("user_id", userID),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+	defer rows.Close()
+
+	var res []models.Link
+	for rows.Next() {
+		var (
+			l         models.Link
+			payload   string
+			deletedAt time.Time
+		)
+		err := rows.Scan(&l.ID, &l.UserID, &l.Type, &l.LinkName, &l.LinkColor, &l.LinkPath,
+			&payload, &l.SectionID, &l.Hidden, &deletedAt)
+		if err != nil {
+			s.log.Error("failed to scan trashed link",
+				slog.String("error", err.Error()))
+			return nil, fmt.Errorf("%w: %v", store.ErrDataScanFailed, err)
+		}
+		l.Payload = rawPayload(payload)
+		deletedAt = deletedAt.UTC()
+		l.DeletedAt = &deletedAt
+		res = append(res, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("rows iteration error",
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: rows iteration failed", store.ErrDatabaseOperation)
+	}
+
+	return res, nil
+}
+
+func (s *Store) restoreLink(userID int, linkID int, deletedAfter time.Time) (int64, error) {
+	tag, err := s.db.Exec(s.ctx, query.RestoreLink, linkID, userID, deletedAfter.UTC())
+	if err != nil {
+		s.log.Error("failed to restore link",
+			slog.Int("user_id", userID),
+			slog.Int("link_id", linkID),
+			slog.String("error", err.Error()))
+		return 0, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// purgeLink permanently removes one trashed link together with its health
+// record.
+func (s *Store) purgeLink(userID int, linkID int) (int64, error) {
+	var n int64
+	err := s.WithTx(s.ctx, func(tx *Store) error {
+		if _, err := tx.db.Exec(tx.ctx, query.PurgeLinkHealth, linkID, userID); err != nil {
+			s.log.Error("failed to purge link health",
+				slog.Int("link_id", linkID),
+				slog.String("error", err.Error()))
+			return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+		}
+
+		tag, err := tx.db.Exec(tx.ctx, query.PurgeLink, linkID, userID)
+		if err != nil {
+			s.log.Error("failed to purge link",
+				slog.Int("link_id", linkID),
+				slog.String("error", err.Error()))
+			return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+		}
+
+		n = tag.RowsAffected()
+		return nil
+	})
+
+	return n, err
+}
+
+func (s *Store) purgeExpiredLinks(before time.Time) (int64, error) {
+	var n int64
+	err := s.WithTx(s.ctx, func(tx *Store) error {
+		if _, err := tx.db.Exec(tx.ctx, query.PurgeExpiredLinkHealth, before.UTC()); err != nil {
+			s.log.Error("failed to purge expired link health",
+				slog.String("error", err.Error()))
+			return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+		}
+
+		tag, err := tx.db.Exec(tx.ctx, query.PurgeExpiredLinks, before.UTC())
+		if err != nil {
+			s.log.Error("failed to purge expired links",
+				slog.String("error", err.Error()))
+			return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+		}
+
+		n = tag.RowsAffected()
+		return nil
+	})
+
+	return n, err
+}
@@ -0,0 +1,75 @@
+package postgresstore
+
+import (
+	"context"
+	"fmt"
+	"url_profile/internal/store"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// querier is implemented by both *pgxpool.Pool and pgx.Tx, so every
+// operation runs the same way inside and outside a transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// WithTx runs fn as one unit of work. Every call fn makes on tx runs in the
+// same transaction, which is committed if fn returns nil and rolled back
+// otherwise. On a store that is already inside a transaction WithTx joins it,
+// so operations that need atomicity can be composed.
+func (s *Store) WithTx(ctx context.Context, fn func(tx *Store) error) error {
+	if _, ok := s.db.(pgx.Tx); ok {
+		return fn(s)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&Store{pool: s.pool, db: tx, ctx: ctx, log: s.log}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return nil
+}
+
+// InTx is WithTx for service code, see store.UnitOfWork.
+func (s *Store) InTx(ctx context.Context, fn func(tx store.Tx) error) error {
+	return s.WithTx(ctx, func(tx *Store) error { return fn(tx) })
+}
+
+// savepoint runs fn so that its failure rolls back only fn's statements and
+// leaves the surrounding transaction usable. Outside a transaction it just
+// calls fn.
+func (s *Store) savepoint(fn func(sp *Store) error) error {
+	tx, ok := s.db.(pgx.Tx)
+	if !ok {
+		return fn(s)
+	}
+
+	sp, err := tx.Begin(s.ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+	defer sp.Rollback(s.ctx)
+
+	if err := fn(&Store{pool: s.pool, db: sp, ctx: s.ctx, log: s.log}); err != nil {
+		return err
+	}
+
+	if err := sp.Commit(s.ctx); err != nil {
+		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return nil
+}
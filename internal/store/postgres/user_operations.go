@@ -0,0 +1,207 @@
+package postgresstore
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+	"url_profile/internal/store/postgres/query"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Store) insertUser(email string, username string, pass []byte, about string) (int, error) {
+	var userID int
+	err := s.db.QueryRow(s.ctx, query.InsertUser, email, username, pass, about).Scan(&userID)
+	if err != nil {
+		if column, ok := uniqueViolation(err); ok {
+			s.log.Warn("user already exists", slog.String("err", err.Error()))
+			return 0, &store.ConflictError{Column: column, Err: store.ErrUserAlreadyExists}
+		}
+
+		s.log.Error("failed to insert user", slog.String("err", err.Error()))
+		return 0, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	s.log.Debug("user created",
+		slog.Int("user_id", userID),
+		slog.String("email", email))
+
+	return userID, nil
+}
+
+// insertUserLinks adds the sign-up links. Every link is tried so the caller
+// learns about all rejected links at once; any other failure stops early.
+// Each link runs under a savepoint because a failed statement aborts the
+// whole transaction in PostgreSQL.
+func (s *Store) insertUserLinks(userID int, links []requestModel.ReqLink) error {
+	invalid := &store.InvalidLinksError{}
+	for i, l := range links {
+		err := s.savepoint(func(sp *Store) error {
+			_, err := sp.AddLink(userID, l)
+			return err
+		})
+		switch {
+		case err == nil:
+		case errors.Is(err, store.ErrSectionNotFound):
+			invalid.Links = append(invalid.Links, store.LinkError{Index: i, Field: "section_id", Err: err})
+		case errors.Is(err, store.ErrLinkAlreadyExists):
+			invalid.Links = append(invalid.Links, store.LinkError{Index: i, Field: "link_path", Err: err})
+		default:
+			return err
+		}
+	}
+
+	if len(invalid.Links) > 0 {
+		return invalid
+	}
+
+	return nil
+}
+
+func (s *Store) createdUser(userID int) (*models.User, error) {
+	u := &models.User{}
+	err := s.db.QueryRow(s.ctx, query.CreatedUser, userID).Scan(&u.ID, &u.Email, &u.Username)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.log.Error("newly created user not found - data inconsistency",
+				slog.Int("user_id", userID))
+			return nil, store.ErrUserRetrievalFailed
+		}
+
+		s.log.Error("failed to retrieve created user",
+			slog.Int("user_id", userID),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return u, nil
+}
+
+// userBy runs one of the UsersRowsBy* queries: one row per link, or a
+// single row with NULL link columns for a user without links.
+func (s *Store) userBy(q string, arg any) (*models.User, error) {
+	rows, err := s.db.Query(s.ctx, q, arg)
+	if err != nil {
+		s.log.Error("failed to query user data",
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+	defer rows.Close()
+
+	var (
+		user  models.User
+		found bool
+	)
+	for rows.Next() {
+		var (
+			linkID    *int
+			blockType *string
+			linkName  *string
+			linkColor *string
+			linkPath  *string
+			payload   *string
+			sectionID *int
+			hidden    *bool
+			version   *int
+		)
+
+		err := rows.Scan(
+			&user.ID, &user.Email, &user.Username, &user.HashedPassword, &user.AboutText, &user.Version, &user.PreviewVersion, &user.UpdatedAt,
+			&linkID, &blockType, &linkName, &linkColor, &linkPath, &payload, &sectionID, &hidden, &version,
+		)
+		if err != nil {
+			s.log.Error("failed to scan user data",
+				slog.String("error", err.Error()))
+			return nil, fmt.Errorf("%w: failed to scan user data", store.ErrDataScanFailed)
+		}
+		found = true
+
+		if linkID != nil {
+			user.Links = append(user.Links, models.Link{
+				ID:        *linkID,
+				UserID:    user.ID,
+				Type:      *blockType,
+				LinkName:  *linkName,
+				LinkColor: *linkColor,
+				LinkPath:  *linkPath,
+				Payload:   rawPayload(*payload),
+				SectionID: sectionID,
+				Hidden:    *hidden,
+				Version:   *version,
+			})
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("rows iteration error",
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: rows iteration failed", store.ErrDatabaseOperation)
+	}
+
+	if !found {
+		s.log.Debug("user not found")
+		return nil, store.ErrUserNotFound
+	}
+
+	return &user, nil
+}
+
+func (s *Store) updateAboutMe(id int, text string, ifVersion int) (int64, error) {
+	tag, err := s.db.Exec(s.ctx, query.UpdateAboutMe, text, id, ifVersion)
+	if err != nil {
+		s.log.Error("failed to update about text",
+			slog.Int("user_id", id),
+			slog.String("error", err.Error()))
+		return 0, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (s *Store) lockProfile(id int) (int, error) {
+	var version int
+	if err := s.db.QueryRow(s.ctx, query.LockProfile, id).Scan(&version); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, store.ErrUserNotFound
+		}
+
+		s.log.Error("failed to lock profile",
+			slog.Int("user_id", id),
+			slog.String("error", err.Error()))
+		return 0, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return version, nil
+}
+
+func (s *Store) userSettings(id int) (*models.Settings, error) {
+	settings := &models.Settings{}
+	err := s.db.QueryRow(s.ctx, query.UserSettings, id).Scan(&settings.StripTracking)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrUserNotFound
+		}
+
+		s.log.Error("failed to query user settings",
+			slog.Int("user_id", id),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return settings, nil
+}
+
+func (s *Store) updateSettings(id int, settings models.Settings) (int64, error) {
+	tag, err := s.db.Exec(s.ctx, query.UpdateSettings, settings.StripTracking, id)
+	if err != nil {
+		s.log.Error("failed to update user settings",
+			slog.Int("user_id", id),
+			slog.String("error", err.Error()))
+		return 0, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
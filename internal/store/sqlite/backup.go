@@ -0,0 +1,77 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"url_profile/internal/store"
+)
+
+var ErrCorrupt = errors.New("database failed the integrity check")
+
+// Snapshot writes a consistent copy of the database to path with VACUUM
+// INTO. It runs on a read connection, so writers carry on meanwhile. path
+// must not exist.
+func (s *Store) Snapshot(ctx context.Context, path string) error {
+	conn, err := s.readConn.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+	defer conn.Close()
+
+	// Read connections are query_only, which VACUUM INTO counts as a write.
+	if _, err := conn.ExecContext(ctx, "PRAGMA query_only = off"); err != nil {
+		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "PRAGMA query_only = on"); err != nil {
+			// Never hand a writable connection back to the read pool.
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return nil
+}
+
+// Verify opens the database file at path read-only and runs
+// PRAGMA integrity_check on it.
+func Verify(ctx context.Context, path string) error {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return err
+		}
+		if msg != "ok" {
+			problems = append(problems, msg)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrCorrupt, strings.Join(problems, "; "))
+	}
+
+	return nil
+}
@@ -1,17 +1,54 @@
+// Package errshandle classifies sqlite errors by their result codes.
 package errshandle
 
-import "strings"
+import (
+	"errors"
+	"strings"
 
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteError returns the driver error behind err, if there is one.
+func sqliteError(err error) (sqlite3.Error, bool) {
+	var serr sqlite3.Error
+	if errors.As(err, &serr) {
+		return serr, true
+	}
+	return serr, false
+}
+
+// IsDuplicateKeyError reports a UNIQUE or PRIMARY KEY violation.
 func IsDuplicateKeyError(err error) bool {
-	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+	serr, ok := sqliteError(err)
+	return ok && (serr.ExtendedCode == sqlite3.ErrConstraintUnique ||
+		serr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
 }
 
+// IsForeignKeyError reports a FOREIGN KEY violation. It only happens on
+// connections with foreign key enforcement turned on.
 func IsForeignKeyError(err error) bool {
-	if err == nil {
-		return false
+	serr, ok := sqliteError(err)
+	return ok && serr.ExtendedCode == sqlite3.ErrConstraintForeignKey
+}
+
+// Column returns the first column named by a constraint violation, e.g.
+// "email" for "UNIQUE constraint failed: users.email". sqlite reports the
+// columns in the message only, so this is the one place that reads it.
+func Column(err error) string {
+	serr, ok := sqliteError(err)
+	if !ok || serr.Code != sqlite3.ErrConstraint {
+		return ""
 	}
-	errorMsg := err.Error()
-	return strings.Contains(errorMsg, "FOREIGN KEY constraint failed") ||
-		strings.Contains(errorMsg, "SQLITE_CONSTRAINT_FOREIGNKEY") ||
-		strings.Contains(errorMsg, "no such table:")
+
+	_, cols, ok := strings.Cut(serr.Error(), "constraint failed: ")
+	if !ok {
+		return ""
+	}
+
+	col, _, _ := strings.Cut(cols, ",")
+	if _, name, ok := strings.Cut(col, "."); ok {
+		col = name
+	}
+
+	return strings.TrimSpace(col)
 }
@@ -0,0 +1,60 @@
+package errshandle
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestClassify(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	for _, q := range []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT UNIQUE, username TEXT UNIQUE)",
+		"CREATE TABLE links (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users (id))",
+		"INSERT INTO users (id, email, username) VALUES (1, 'a@b.cc', 'alice')",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		duplicate bool
+		foreign   bool
+		column    string
+	}{
+		{"duplicate email", "INSERT INTO users (email, username) VALUES ('a@b.cc', 'bob')", true, false, "email"},
+		{"duplicate username", "INSERT INTO users (email, username) VALUES ('c@d.ee', 'alice')", true, false, "username"},
+		{"duplicate primary key", "INSERT INTO users (id, email, username) VALUES (1, 'x@y.zz', 'x')", true, false, "id"},
+		{"unknown user", "INSERT INTO links (user_id) VALUES (42)", false, true, ""},
+		{"no such table", "INSERT INTO missing (id) VALUES (1)", false, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := db.Exec(tt.query)
+			if err == nil {
+				t.Fatalf("query succeeded")
+			}
+
+			if got := IsDuplicateKeyError(err); got != tt.duplicate {
+				t.Errorf("IsDuplicateKeyError = %v, want %v (%v)", got, tt.duplicate, err)
+			}
+			if got := IsForeignKeyError(err); got != tt.foreign {
+				t.Errorf("IsForeignKeyError = %v, want %v (%v)", got, tt.foreign, err)
+			}
+			if got := Column(err); got != tt.column {
+				t.Errorf("Column = %q, want %q (%v)", got, tt.column, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+	"url_profile/internal/store/sqlite/query"
+)
+
+func (s *Store) profileImage(userID int, kind string) (*models.ProfileImage, error) {
+	img := &models.ProfileImage{}
+	err := s.reader.QueryRow(query.ProfileImage, userID, kind).Scan(
+		&img.UserID,
+		&img.Kind,
+		&img.Version,
+		&img.Format,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrImageNotFound
+		}
+
+		s.log.Error("failed to query profile image",
+			slog.Int("user_id", userID),
+			slog.String("kind", kind),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return img, nil
+}
+
+func (s *Store) profileImagesByUser(userID int) ([]models.ProfileImage, error) {
+	rows, err := s.reader.Query(query.ProfileImagesByUser, userID)
+	if err != nil {
+		s.log.Error("failed to query profile images",
+			slog.Int("user_id", userID),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+	defer rows.Close()
+
+	var res []models.ProfileImage
+	for rows.Next() {
+		var img models.ProfileImage
+		if err := rows.Scan(&img.UserID, &img.Kind, &img.Version, &img.Format); err != nil {
+			s.log.Error("failed to scan profile image",
+				slog.String("error", err.Error()))
+			return nil, fmt.Errorf("%w: %v", store.ErrDataScanFailed, err)
+		}
+		res = append(res, img)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("rows iteration error",
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: rows iteration failed", store.ErrDatabaseOperation)
+	}
+
+	return res, nil
+}
+
+func (s *Store) upsertProfileImage(img models.ProfileImage) error {
+	if _, err := s.db.Exec(query.UpsertProfileImage, img.UserID, img.Kind, img.Version, img.Format); err != nil {
+		s.log.Error("failed to save profile image",
+			slog.Int("user_id", img.UserID),
+			slog.String("error", err.Error()))
+		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return nil
+}
+
+func (s *Store) deleteProfileImage(userID int, kind string) (sql.Result, error) {
+	res, err := s.db.Exec(query.DeleteProfileImage, userID, kind)
+	if err != nil {
+		s.log.Error("failed to delete profile image",
+			slog.Int("user_id", userID),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,94 @@
+package sqlitestore
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+	errshandle "url_profile/internal/store/sqlite/errs"
+	"url_profile/internal/store/sqlite/query"
+)
+
+// importLinks writes all rows in one transaction, creating missing sections
+// by title. Nothing is written if any row fails.
+func (s *Store) importLinks(userID int, rows []models.ImportedLink) (int, error) {
+	created := 0
+	err := s.WithTx(context.Background(), func(tx *Store) error {
+		sections, err := tx.sectionIDsByTitle(userID)
+		if err != nil {
+			return err
+		}
+
+		for i, row := range rows {
+			l := row.Link
+			l.SectionID = nil
+
+			if row.Section != "" {
+				id, ok := sections[row.Section]
+				if !ok {
+					var position int
+					err := tx.db.QueryRow(query.InsertSection, userID, row.Section, nil, userID, false).Scan(&id, &position)
+					if err != nil {
+						s.log.Error("failed to create section during import",
+							slog.Int("user_id", userID),
+							slog.String("error", err.Error()))
+						return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+					}
+					sections[row.Section] = id
+					created++
+				}
+				l.SectionID = &id
+			}
+
+			_, err := tx.db.Exec(query.InsertLink, userID, linkType(l.Type), l.LinkName, l.LinkColor, l.LinkPath, string(l.Payload), l.SectionID, l.Hidden)
+			if err != nil {
+				if errshandle.IsDuplicateKeyError(err) {
+					return &store.ConflictError{Column: errshandle.Column(err), Err: store.ErrLinkAlreadyExists}
+				}
+
+				s.log.Error("failed to import link",
+					slog.Int("user_id", userID),
+					slog.Int("row", i),
+					slog.String("error", err.Error()))
+				return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return created, nil
+}
+
+// sectionIDsByTitle maps the user's section titles to their IDs. With
+// duplicate titles the first section wins.
+func (s *Store) sectionIDsByTitle(userID int) (map[string]int, error) {
+	rows, err := s.reader.Query(query.SectionIDsByUser, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+	defer rows.Close()
+
+	sections := make(map[string]int)
+	for rows.Next() {
+		var (
+			id    int
+			title string
+		)
+		if err := rows.Scan(&id, &title); err != nil {
+			return nil, fmt.Errorf("%w: %v", store.ErrDataScanFailed, err)
+		}
+		if _, ok := sections[title]; !ok {
+			sections[title] = id
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return sections, nil
+}
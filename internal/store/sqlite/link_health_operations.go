@@ -0,0 +1,85 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+	"url_profile/internal/store/sqlite/query"
+)
+
+func (s *Store) allLinks() ([]models.Link, error) {
+	rows, err := s.reader.Query(query.AllLinks)
+	if err != nil {
+		s.log.Error("failed to query links",
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+	defer rows.Close()
+
+	var links []models.Link
+	for rows.Next() {
+		var l models.Link
+		if err := rows.Scan(&l.ID, &l.UserID, &l.LinkName, &l.LinkColor, &l.LinkPath); err != nil {
+			s.log.Error("failed to scan link",
+				slog.String("error", err.Error()))
+			return nil, fmt.Errorf("%w: %v", store.ErrDataScanFailed, err)
+		}
+		links = append(links, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("rows iteration error",
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: rows iteration failed", store.ErrDatabaseOperation)
+	}
+
+	return links, nil
+}
+
+func (s *Store) upsertLinkHealth(h models.LinkHealth) error {
+	_, err := s.db.Exec(query.UpsertLinkHealth, h.LinkID, h.Status, h.StatusCode, h.Error, h.CheckedAt, h.Status)
+	if err != nil {
+		s.log.Error("failed to save link health",
+			slog.Int("link_id", h.LinkID),
+			slog.String("error", err.Error()))
+		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return nil
+}
+
+func (s *Store) linkHealthByUser(userID int) ([]models.LinkHealth, error) {
+	rows, err := s.reader.Query(query.LinkHealthByUser, userID)
+	if err != nil {
+		s.log.Error("failed to query link health",
+			slog.Int("user_id", userID),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+	defer rows.Close()
+
+	var res []models.LinkHealth
+	for rows.Next() {
+		var (
+			h         models.LinkHealth
+			checkedAt sql.NullTime
+		)
+		if err := rows.Scan(&h.LinkID, &h.Status, &h.StatusCode, &h.Error, &checkedAt, &h.FailStreak); err != nil {
+			s.log.Error("failed to scan link health",
+				slog.String("error", err.Error()))
+			return nil, fmt.Errorf("%w: %v", store.ErrDataScanFailed, err)
+		}
+		h.CheckedAt = checkedAt.Time
+		res = append(res, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("rows iteration error",
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: rows iteration failed", store.ErrDatabaseOperation)
+	}
+
+	return res, nil
+}
@@ -1,9 +1,16 @@
 package sqlitestore
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/lib/linkblock"
 	"url_profile/internal/store"
 	errshandle "url_profile/internal/store/sqlite/errs"
 	"url_profile/internal/store/sqlite/query"
@@ -11,44 +18,72 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-func (s *Store) insertLink(userID int, link requestModel.ReqLink) error {
-	stmt, err := s.db.Prepare(query.InsertLink)
-	if err != nil {
-		s.log.Error("failed to prepare update statement",
-			slog.Int("user_id", userID),
-			slog.Any("link", link))
-		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
-	}
-
-	_, err = stmt.Exec(userID, link.LinkName, link.LinkColor, link.LinkPath)
+func (s *Store) insertLink(userID int, link requestModel.ReqLink) (int, error) {
+	res, err := s.db.Exec(query.InsertLink, userID, linkType(link.Type), link.LinkName, link.LinkColor, link.LinkPath, string(link.Payload), link.SectionID, link.Hidden)
 	if err != nil {
 		if errshandle.IsDuplicateKeyError(err) {
 			s.log.Warn("duplicate link path",
 				slog.Int("user_id", userID),
 				slog.String("path", link.LinkPath))
-			return store.ErrLinkAlreadyExists
+			return 0, &store.ConflictError{Column: errshandle.Column(err), Err: store.ErrLinkAlreadyExists}
 		}
 
 		if errshandle.IsForeignKeyError(err) {
 			s.log.Warn("invalid user reference",
 				slog.Int("user_id", userID))
-			return store.ErrUserNotFound
+			return 0, store.ErrUserNotFound
 		}
 
 		s.log.Error("failed to insert link",
 			slog.Int("user_id", userID),
 			slog.Any("link", link),
 			slog.String("error", err.Error()))
-		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+		return 0, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
 	}
 
-	return nil
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return int(id), nil
+}
+
+func (s *Store) linkByID(linkID int) (*models.Link, error) {
+	l := &models.Link{}
+	var payload string
+	err := s.reader.QueryRow(query.LinkByID, linkID).Scan(
+		&l.ID,
+		&l.UserID,
+		&l.Type,
+		&l.LinkName,
+		&l.LinkColor,
+		&l.LinkPath,
+		&payload,
+		&l.SectionID,
+		&l.Hidden,
+		&l.Version,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrLinkNotFound
+		}
+
+		s.log.Error("failed to query link",
+			slog.Int("link_id", linkID),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+	l.Payload = rawPayload(payload)
+
+	return l, nil
 }
 
 func (s *Store) existsLink(userID int, linkID int) error {
 	var exists bool
 
-	err := s.db.QueryRow(query.ExistsLink,
+	err := s.reader.QueryRow(query.ExistsLink,
 		linkID, userID).Scan(&exists)
 
 	if err != nil {
@@ -69,23 +104,17 @@ func (s *Store) existsLink(userID int, linkID int) error {
 	return nil
 }
 
-func (s *Store) updateLink(userID int, link *requestModel.ReqUpdateLink) error {
-	stmt, err := s.db.Prepare(query.UpdateLink)
-	if err != nil {
-		s.log.Error("failed to prepare update statement",
-			slog.Int("user_id", userID),
-			slog.Int("link_id", link.LinkID),
-			slog.String("error", err.Error()))
-		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
-	}
-	defer stmt.Close()
-
-	_, err = stmt.Exec(
+func (s *Store) updateLink(userID int, link *requestModel.ReqUpdateLink, ifVersion int) error {
+	res, err := s.db.Exec(query.UpdateLink,
+		linkType(link.Type),
 		link.LinkName,
 		link.LinkColor,
 		link.LinkPath,
+		string(link.Payload),
+		link.SectionID,
 		userID,
 		link.LinkID,
+		ifVersion, ifVersion,
 	)
 	if err != nil {
 		s.log.Error("failed to execute link update",
@@ -95,6 +124,10 @@ func (s *Store) updateLink(userID int, link *requestModel.ReqUpdateLink) error {
 		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
 	}
 
+	if err := s.versionCheck(res, userID, link.LinkID, ifVersion); err != nil {
+		return err
+	}
+
 	s.log.Debug("link updated successfully",
 		slog.Int("user_id", userID),
 		slog.Int("link_id", link.LinkID))
@@ -102,25 +135,80 @@ func (s *Store) updateLink(userID int, link *requestModel.ReqUpdateLink) error {
 	return nil
 }
 
-func (s *Store) deleteLink(userID int, linkID int) error {
-	stmt, err := s.db.Prepare(query.DeleteLink)
+// deleteLink moves the link to the trash; purgeLink removes it for good.
+func (s *Store) deleteLink(userID int, linkID int, ifVersion int) error {
+	res, err := s.db.Exec(query.DeleteLink, deletedAt(time.Now()), linkID, userID, ifVersion, ifVersion)
 	if err != nil {
-		s.log.Error("failed to prepare delete statement",
+		s.log.Error("failed to execute delete link",
 			slog.Int("user_id", userID),
 			slog.Int("link_id", linkID),
 			slog.String("error", err.Error()))
 		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
 	}
-	defer stmt.Close()
 
-	_, err = stmt.Exec(linkID, userID)
+	return s.versionCheck(res, userID, linkID, ifVersion)
+}
+
+// changeLinks runs every change in one transaction, so a failing change,
+// stale version included, rolls back the ones before it.
+func (s *Store) changeLinks(userID int, changes []models.LinkChange) error {
+	return s.WithTx(context.Background(), func(tx *Store) error {
+		for _, c := range changes {
+			var err error
+			if c.Delete {
+				err = tx.DeleteLink(userID, c.Link.ID, c.IfVersion)
+			} else {
+				err = tx.UpdateLink(userID, &requestModel.ReqUpdateLink{
+					LinkID:    c.Link.ID,
+					Type:      c.Link.Type,
+					LinkName:  c.Link.LinkName,
+					LinkColor: c.Link.LinkColor,
+					LinkPath:  c.Link.LinkPath,
+					Payload:   c.Link.Payload,
+					SectionID: c.Link.SectionID,
+				}, c.IfVersion)
+			}
+			if err != nil {
+				return fmt.Errorf("link %d: %w", c.Link.ID, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// versionCheck turns a link write that matched no row into an error:
+// store.ErrLinkNotFound when the link is gone, store.ErrVersionMismatch
+// when only a non-zero ifVersion is stale.
+func (s *Store) versionCheck(res sql.Result, userID int, linkID int, ifVersion int) error {
+	n, err := res.RowsAffected()
 	if err != nil {
-		s.log.Error("failed to execute delete link",
-			slog.Int("user_id", userID),
-			slog.Int("link_id", linkID),
-			slog.String("error", err.Error()))
-		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+		return fmt.Errorf("%w: cannot verify update", store.ErrDatabaseOperation)
+	}
+	if n > 0 {
+		return nil
 	}
 
-	return nil
+	if ifVersion == 0 {
+		return store.ErrLinkNotFound
+	}
+	if err := s.existsLink(userID, linkID); err != nil {
+		return err
+	}
+	return store.ErrVersionMismatch
+}
+
+// linkType defaults blocks created without an explicit type to plain links.
+func linkType(t string) string {
+	if t == "" {
+		return linkblock.TypeLink
+	}
+	return t
+}
+
+func rawPayload(p string) json.RawMessage {
+	if p == "" {
+		return nil
+	}
+	return json.RawMessage(p)
 }
@@ -7,35 +7,196 @@ const (
 
 	UsersRowsByEmail = `
 		SELECT 
-			u.id, u.email, u.username, u.pass_hash, u.about_text,
-			l.id, l.user_id, l.link_name, l.link_color, l.link_path
+			u.id, u.email, u.username, u.pass_hash, u.about_text, u.version, u.preview_version, u.updated_at,
+			l.id, l.user_id, l.type, l.link_name, l.link_color, l.link_path, l.payload, l.section_id, l.hidden, l.version
 		FROM users u
-		LEFT JOIN links l ON u.id = l.user_id
+		LEFT JOIN links l ON u.id = l.user_id AND l.deleted_at IS NULL
 		WHERE u.email = ?`
 
 	UsersRowsByID = `
 		SELECT 
-			u.id, u.email, u.username, u.pass_hash, u.about_text,
-			l.id, l.user_id, l.link_name, l.link_color, l.link_path
+			u.id, u.email, u.username, u.pass_hash, u.about_text, u.version, u.preview_version, u.updated_at,
+			l.id, l.user_id, l.type, l.link_name, l.link_color, l.link_path, l.payload, l.section_id, l.hidden, l.version
 		FROM users u
-		LEFT JOIN links l ON u.id = l.user_id
+		LEFT JOIN links l ON u.id = l.user_id AND l.deleted_at IS NULL
 		WHERE u.id = ?`
 
 	UsersRowsByUsername = `
 		SELECT 
-			u.id, u.email, u.username, u.pass_hash, u.about_text,
-			l.link_name, l.link_color, l.link_path
+			u.id, u.email, u.username, u.pass_hash, u.about_text, u.version, u.preview_version, u.updated_at,
+			l.id, l.type, l.link_name, l.link_color, l.link_path, l.payload, l.section_id
 		FROM users u
-		LEFT JOIN links l ON u.id = l.user_id
+		LEFT JOIN links l ON u.id = l.user_id AND l.deleted_at IS NULL AND l.hidden = 0
 		WHERE u.username = ?`
 
-	UpdateAboutMe = "UPDATE users SET about_text = ? WHERE id = ?"
+	// The ? = 0 OR version = ? pairs make a write conditional on the version
+	// the caller read; 0 writes unconditionally.
+	UpdateAboutMe = "UPDATE users SET about_text = ? WHERE id = ? AND (? = 0 OR version = ?)"
 
-	InsertLink = "INSERT INTO links (user_id, link_name, link_color, link_path) VALUES (?, ?, ?, ?)"
+	UserSettings = "SELECT strip_tracking FROM users WHERE id = ?"
 
-	ExistsLink = "SELECT EXISTS(SELECT 1 FROM links WHERE id = ? AND user_id = ?)"
+	// The writer starts every transaction with BEGIN IMMEDIATE, so a plain
+	// read is enough to hold the version until the transaction ends.
+	LockProfile = "SELECT version FROM users WHERE id = ?"
 
-	UpdateLink = "UPDATE links SET link_name = ?, link_color = ?, link_path = ? WHERE user_id = ? AND id = ?"
+	UpdateSettings = "UPDATE users SET strip_tracking = ? WHERE id = ?"
 
-	DeleteLink = "DELETE FROM links WHERE id = ? AND user_id = ?"
+	LinkByID = "SELECT id, user_id, type, link_name, link_color, link_path, payload, section_id, hidden, version FROM links WHERE id = ? AND deleted_at IS NULL"
+
+	InsertLink = "INSERT INTO links (user_id, type, link_name, link_color, link_path, payload, section_id, hidden) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+
+	ExistsLink = "SELECT EXISTS(SELECT 1 FROM links WHERE id = ? AND user_id = ? AND deleted_at IS NULL)"
+
+	UpdateLink = "UPDATE links SET type = ?, link_name = ?, link_color = ?, link_path = ?, payload = ?, section_id = ? WHERE user_id = ? AND id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)"
+
+	DeleteLink = "UPDATE links SET deleted_at = ? WHERE id = ? AND user_id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)"
+
+	SetLinkHidden = "UPDATE links SET hidden = ? WHERE id = ? AND user_id = ? AND deleted_at IS NULL"
+
+	TrashedLinks = `
+		SELECT id, user_id, type, link_name, link_color, link_path, payload, section_id, hidden, deleted_at
+		FROM links
+		WHERE user_id = ? AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC`
+
+	RestoreLink = "UPDATE links SET deleted_at = NULL WHERE id = ? AND user_id = ? AND deleted_at >= ?"
+
+	PurgeLinkHealth = "DELETE FROM link_health WHERE link_id IN (SELECT id FROM links WHERE id = ? AND user_id = ? AND deleted_at IS NOT NULL)"
+
+	PurgeLink = "DELETE FROM links WHERE id = ? AND user_id = ? AND deleted_at IS NOT NULL"
+
+	PurgeExpiredLinkHealth = "DELETE FROM link_health WHERE link_id IN (SELECT id FROM links WHERE deleted_at < ?)"
+
+	PurgeExpiredLinks = "DELETE FROM links WHERE deleted_at < ?"
+
+	AllLinks = "SELECT id, user_id, link_name, link_color, link_path FROM links WHERE deleted_at IS NULL"
+
+	UpsertLinkHealth = `
+		INSERT INTO link_health (link_id, status, status_code, error, checked_at, fail_streak)
+		VALUES (?, ?, ?, ?, ?, CASE WHEN ? = 'ok' THEN 0 ELSE 1 END)
+		ON CONFLICT(link_id) DO UPDATE SET
+			status = excluded.status,
+			status_code = excluded.status_code,
+			error = excluded.error,
+			checked_at = excluded.checked_at,
+			fail_streak = CASE WHEN excluded.status = 'ok' THEN 0 ELSE link_health.fail_streak + 1 END`
+
+	LinkHealthByUser = `
+		SELECT h.link_id, h.status, h.status_code, h.error, h.checked_at, h.fail_streak
+		FROM link_health h
+		JOIN links l ON l.id = h.link_id
+		WHERE l.user_id = ?`
+
+	LinkPreviewByURL = "SELECT url, title, description, favicon, image, fetched_at FROM link_previews WHERE url = ?"
+
+	UpsertLinkPreview = `
+		INSERT INTO link_previews (url, title, description, favicon, image, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(url) DO UPDATE SET
+			title = excluded.title,
+			description = excluded.description,
+			favicon = excluded.favicon,
+			image = excluded.image,
+			fetched_at = excluded.fetched_at`
+
+	LinkPreviewsByUser = `
+		SELECT DISTINCT p.url, p.title, p.description, p.favicon, p.image, p.fetched_at
+		FROM link_previews p
+		JOIN links l ON l.link_path = p.url
+		WHERE l.user_id = ?`
+
+	// PreviewOwners picks the users a preview of the URL shows up for, the
+	// same ones the link_previews triggers touch.
+	PreviewOwners = "SELECT DISTINCT user_id FROM links WHERE link_path = ?"
+
+	ThemeByUser = "SELECT preset, background, font, button_style, link_color FROM profile_themes WHERE user_id = ?"
+
+	UpsertTheme = `
+		INSERT INTO profile_themes (user_id, preset, background, font, button_style, link_color)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			preset = excluded.preset,
+			background = excluded.background,
+			font = excluded.font,
+			button_style = excluded.button_style,
+			link_color = excluded.link_color`
+
+	ProfileImage = "SELECT user_id, kind, version, format FROM profile_images WHERE user_id = ? AND kind = ?"
+
+	ProfileImagesByUser = "SELECT user_id, kind, version, format FROM profile_images WHERE user_id = ?"
+
+	UpsertProfileImage = `
+		INSERT INTO profile_images (user_id, kind, version, format)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, kind) DO UPDATE SET
+			version = excluded.version,
+			format = excluded.format,
+			created_at = CURRENT_TIMESTAMP`
+
+	DeleteProfileImage = "DELETE FROM profile_images WHERE user_id = ? AND kind = ?"
+
+	SectionsByUser = "SELECT id, user_id, title, position, collapsed FROM link_sections WHERE user_id = ? ORDER BY position, id"
+
+	SectionByID = "SELECT id, user_id, title, position, collapsed FROM link_sections WHERE id = ? AND user_id = ?"
+
+	ExistsSection = "SELECT EXISTS(SELECT 1 FROM link_sections WHERE id = ? AND user_id = ?)"
+
+	InsertSection = `
+		INSERT INTO link_sections (user_id, title, position, collapsed)
+		VALUES (?, ?, COALESCE(?, (SELECT COALESCE(MAX(position), -1) + 1 FROM link_sections WHERE user_id = ?)), ?)
+		RETURNING id, position`
+
+	UpdateSection = "UPDATE link_sections SET title = ?, position = ?, collapsed = ? WHERE id = ? AND user_id = ?"
+
+	UngroupSectionLinks = "UPDATE links SET section_id = NULL WHERE section_id = ? AND user_id = ?"
+
+	DeleteSection = "DELETE FROM link_sections WHERE id = ? AND user_id = ?"
+
+	SectionIDsByUser = "SELECT id, title FROM link_sections WHERE user_id = ? ORDER BY id"
 )
+
+// All lists every query above; the store prepares them once when it opens.
+var All = []string{
+	InsertUser,
+	CreatedUser,
+	UsersRowsByEmail,
+	UsersRowsByID,
+	UsersRowsByUsername,
+	UpdateAboutMe,
+	UserSettings,
+	LockProfile,
+	UpdateSettings,
+	LinkByID,
+	InsertLink,
+	ExistsLink,
+	UpdateLink,
+	DeleteLink,
+	SetLinkHidden,
+	TrashedLinks,
+	RestoreLink,
+	PurgeLinkHealth,
+	PurgeLink,
+	PurgeExpiredLinkHealth,
+	PurgeExpiredLinks,
+	AllLinks,
+	UpsertLinkHealth,
+	LinkHealthByUser,
+	LinkPreviewByURL,
+	UpsertLinkPreview,
+	LinkPreviewsByUser,
+	PreviewOwners,
+	ThemeByUser,
+	UpsertTheme,
+	ProfileImage,
+	ProfileImagesByUser,
+	UpsertProfileImage,
+	DeleteProfileImage,
+	SectionsByUser,
+	SectionByID,
+	ExistsSection,
+	InsertSection,
+	UpdateSection,
+	UngroupSectionLinks,
+	DeleteSection,
+	SectionIDsByUser,
+}
@@ -0,0 +1,115 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"url_profile/internal/store/sqlite/query"
+)
+
+// statements holds the prepared form of every query in query.All for one
+// pool, keyed by the query text.
+type statements map[string]*sql.Stmt
+
+func prepareAll(db *sql.DB) (statements, error) {
+	stmts := make(statements, len(query.All))
+	for _, q := range query.All {
+		if _, ok := stmts[q]; ok {
+			continue
+		}
+
+		stmt, err := db.Prepare(q)
+		if err != nil {
+			stmts.Close()
+			return nil, fmt.Errorf("prepare %q: %w", q, err)
+		}
+		stmts[q] = stmt
+	}
+
+	return stmts, nil
+}
+
+func (st statements) Close() error {
+	var errs []error
+	for _, stmt := range st {
+		errs = append(errs, stmt.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// preparedDB runs the queries it has a statement for through that
+// statement and anything else directly on the pool.
+type preparedDB struct {
+	*sql.DB
+	stmts statements
+}
+
+func (p preparedDB) Exec(q string, args ...any) (sql.Result, error) {
+	if stmt, ok := p.stmts[q]; ok {
+		return stmt.Exec(args...)
+	}
+	return p.DB.Exec(q, args...)
+}
+
+func (p preparedDB) Query(q string, args ...any) (*sql.Rows, error) {
+	if stmt, ok := p.stmts[q]; ok {
+		return stmt.Query(args...)
+	}
+	return p.DB.Query(q, args...)
+}
+
+func (p preparedDB) QueryRow(q string, args ...any) *sql.Row {
+	if stmt, ok := p.stmts[q]; ok {
+		return stmt.QueryRow(args...)
+	}
+	return p.DB.QueryRow(q, args...)
+}
+
+// preparedTx is preparedDB inside a transaction. tx.Stmt reuses the
+// statement already prepared on the writer connection; the bound copies are
+// kept for the rest of the transaction and released when it ends.
+type preparedTx struct {
+	*sql.Tx
+	stmts statements
+	bound statements
+}
+
+func newPreparedTx(tx *sql.Tx, stmts statements) *preparedTx {
+	return &preparedTx{Tx: tx, stmts: stmts, bound: make(statements)}
+}
+
+func (p *preparedTx) stmt(q string) (*sql.Stmt, bool) {
+	if stmt, ok := p.bound[q]; ok {
+		return stmt, true
+	}
+
+	stmt, ok := p.stmts[q]
+	if !ok {
+		return nil, false
+	}
+	stmt = p.Tx.Stmt(stmt)
+	p.bound[q] = stmt
+
+	return stmt, true
+}
+
+func (p *preparedTx) Exec(q string, args ...any) (sql.Result, error) {
+	if stmt, ok := p.stmt(q); ok {
+		return stmt.Exec(args...)
+	}
+	return p.Tx.Exec(q, args...)
+}
+
+func (p *preparedTx) Query(q string, args ...any) (*sql.Rows, error) {
+	if stmt, ok := p.stmt(q); ok {
+		return stmt.Query(args...)
+	}
+	return p.Tx.Query(q, args...)
+}
+
+func (p *preparedTx) QueryRow(q string, args ...any) *sql.Row {
+	if stmt, ok := p.stmt(q); ok {
+		return stmt.QueryRow(args...)
+	}
+	return p.Tx.QueryRow(q, args...)
+}
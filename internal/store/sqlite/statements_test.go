@@ -0,0 +1,111 @@
+package sqlitestore
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"slices"
+	"strconv"
+	"testing"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/store/sqlite/query"
+)
+
+// A query missing from query.All still works, but is prepared on every call.
+func TestAllQueriesPrepared(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "query/querys.go", nil, 0)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				lit, ok := vs.Values[i].(*ast.BasicLit)
+				if !ok {
+					continue
+				}
+				q, err := strconv.Unquote(lit.Value)
+				if err != nil {
+					t.Fatalf("query.%s: %v", name.Name, err)
+				}
+				if !slices.Contains(query.All, q) {
+					t.Errorf("query.%s is missing from query.All", name.Name)
+				}
+			}
+		}
+	}
+
+	s := newStore(t)
+	if len(s.stmts) == 0 || len(s.readStmts) != len(s.stmts) {
+		t.Errorf("prepared %d writer and %d reader statements", len(s.stmts), len(s.readStmts))
+	}
+}
+
+// unprepared returns a view of s that prepares every statement per call, as
+// the store did before statements were cached.
+func unprepared(s *Store) *Store {
+	u := *s
+	u.db = preparedDB{DB: s.conn}
+	u.reader = preparedDB{DB: s.readConn}
+	return &u
+}
+
+func benchStores(b *testing.B, fn func(b *testing.B, s *Store)) {
+	for _, bc := range []struct {
+		name string
+		view func(*Store) *Store
+	}{
+		{"prepared", func(s *Store) *Store { return s }},
+		{"unprepared", unprepared},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			fn(b, bc.view(newStore(b)))
+		})
+	}
+}
+
+func BenchmarkProfileRead(b *testing.B) {
+	benchStores(b, func(b *testing.B, s *Store) {
+		links := make([]requestModel.ReqLink, 10)
+		for i := range links {
+			links[i] = requestModel.ReqLink{LinkName: "link", LinkPath: fmt.Sprintf("https://example.com/%d", i)}
+		}
+		if _, err := s.CreateUser("alice@example.com", "alice", []byte("x"), "", links); err != nil {
+			b.Fatalf("CreateUser: %v", err)
+		}
+
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := s.UserByUsername("alice"); err != nil {
+					b.Errorf("UserByUsername: %v", err)
+					return
+				}
+			}
+		})
+	})
+}
+
+func BenchmarkAddLink(b *testing.B) {
+	benchStores(b, func(b *testing.B, s *Store) {
+		u, err := s.CreateUser("alice@example.com", "alice", []byte("x"), "", nil)
+		if err != nil {
+			b.Fatalf("CreateUser: %v", err)
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			link := requestModel.ReqLink{LinkName: "link", LinkPath: fmt.Sprintf("https://example.com/%d", i)}
+			if _, err := s.AddLink(u.ID, link); err != nil {
+				b.Fatalf("AddLink: %v", err)
+			}
+		}
+	})
+}
@@ -1,44 +1,191 @@
 package sqlitestore
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
 	"url_profile/internal/app/server/http/handlers/requestModel"
 	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// Config holds the database path and connection tuning. Empty strings and
+// zero values keep the SQLite defaults, except ForeignKeys, which is always
+// set explicitly.
+type Config struct {
+	Path string
+	// JournalMode is one of DELETE, TRUNCATE, PERSIST, MEMORY, WAL or OFF.
+	// WAL lets readers run alongside the writer.
+	JournalMode string
+	// Synchronous is one of OFF, NORMAL, FULL or EXTRA.
+	Synchronous string
+	// BusyTimeout is how long a connection waits for a lock before failing
+	// with SQLITE_BUSY.
+	BusyTimeout time.Duration
+	ForeignKeys bool
+	// CacheSize is the page cache per connection: pages when positive,
+	// KiB when negative.
+	CacheSize int
+	// MaxOpenConns and MaxIdleConns size the read pool; zero means the
+	// number of CPUs. Writes always go through a single connection.
+	MaxOpenConns int
+	MaxIdleConns int
+}
+
 type Store struct {
-	db  *sql.DB
-	log *slog.Logger
+	conn     *sql.DB
+	readConn *sql.DB
+	// stmts and readStmts are prepared once in New on conn and readConn.
+	stmts     statements
+	readStmts statements
+	// db is conn, or tx for a store handed out by WithTx. reader is
+	// readConn outside a transaction and tx inside one, so a transaction
+	// sees its own writes.
+	db     querier
+	reader querier
+	tx     *sql.Tx
+	log    *slog.Logger
 }
 
-func New(dbPath string, log *slog.Logger) *Store {
+func New(cfg Config, log *slog.Logger) *Store {
+	// The writer starts transactions with BEGIN IMMEDIATE, so a
+	// transaction that reads before it writes cannot fail on lock upgrade.
+	conn, err := sql.Open("sqlite3", dsn(cfg, "_txlock=immediate"))
+	if err != nil {
+		panic(err)
+	}
+	conn.SetMaxOpenConns(1)
+	conn.SetMaxIdleConns(1)
+	conn.SetConnMaxLifetime(0)
+
+	// The writer must open the database first: it is the connection that
+	// switches it to WAL, which readers cannot do with query_only set.
+	if err := conn.Ping(); err != nil {
+		panic(err)
+	}
 
-	conn, err := sql.Open("sqlite3", dbPath)
+	readConn, err := sql.Open("sqlite3", dsn(cfg, "_query_only=on"))
 	if err != nil {
+		conn.Close()
 		panic(err)
+	}
 
+	maxOpen := cfg.MaxOpenConns
+	if maxOpen <= 0 {
+		maxOpen = runtime.NumCPU()
+	}
+	maxIdle := cfg.MaxIdleConns
+	if maxIdle <= 0 || maxIdle > maxOpen {
+		maxIdle = maxOpen
 	}
+	readConn.SetMaxOpenConns(maxOpen)
+	readConn.SetMaxIdleConns(maxIdle)
 
-	if err := conn.Ping(); err != nil {
+	if err := readConn.Ping(); err != nil {
+		conn.Close()
+		panic(err)
+	}
+
+	// Preparing needs the schema, so the migrations must have run by now.
+	stmts, err := prepareAll(conn)
+	if err != nil {
+		readConn.Close()
+		conn.Close()
+		panic(err)
+	}
+	readStmts, err := prepareAll(readConn)
+	if err != nil {
+		stmts.Close()
+		readConn.Close()
+		conn.Close()
 		panic(err)
 	}
 
 	return &Store{
-		db:  conn,
-		log: log,
+		conn:      conn,
+		readConn:  readConn,
+		stmts:     stmts,
+		readStmts: readStmts,
+		db:        preparedDB{DB: conn, stmts: stmts},
+		reader:    preparedDB{DB: readConn, stmts: readStmts},
+		log:       log,
 	}
 }
 
-func (s *Store) CreateUser(email string, username string, pass []byte, about string, links []requestModel.ReqLink) (*models.User, error) {
-	userID, err := s.insertUser(email, username, pass, about, links)
-	if err != nil {
-		return nil, err
+// dsn builds a go-sqlite3 connection string from cfg. The pragmas are
+// passed as DSN parameters so every connection in a pool gets them, not
+// just the first one.
+func dsn(cfg Config, extra ...string) string {
+	params := []string{
+		"_foreign_keys=" + onOff(cfg.ForeignKeys),
+	}
+	if cfg.JournalMode != "" {
+		params = append(params, "_journal_mode="+url.QueryEscape(strings.ToUpper(cfg.JournalMode)))
+	}
+	if cfg.Synchronous != "" {
+		params = append(params, "_synchronous="+url.QueryEscape(strings.ToUpper(cfg.Synchronous)))
+	}
+	if cfg.BusyTimeout > 0 {
+		params = append(params, "_busy_timeout="+strconv.FormatInt(cfg.BusyTimeout.Milliseconds(), 10))
+	}
+	if cfg.CacheSize != 0 {
+		params = append(params, "_cache_size="+strconv.Itoa(cfg.CacheSize))
+	}
+	params = append(params, extra...)
+
+	sep := "?"
+	if strings.Contains(cfg.Path, "?") {
+		sep = "&"
 	}
 
-	u, err := s.createdUser(userID)
+	return fmt.Sprintf("file:%s%s%s", strings.TrimPrefix(cfg.Path, "file:"), sep, strings.Join(params, "&"))
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+// Close releases the prepared statements and both pools. It must not be
+// called while requests are still using the store.
+func (s *Store) Close() error {
+	return errors.Join(
+		s.readStmts.Close(),
+		s.stmts.Close(),
+		s.readConn.Close(),
+		s.conn.Close(),
+	)
+}
+
+// CreateUser writes the user and all of their links in one transaction.
+// If any link is rejected nothing is written and the error is a
+// *store.InvalidLinksError listing every rejected link.
+func (s *Store) CreateUser(email string, username string, pass []byte, about string, links []requestModel.ReqLink) (*models.User, error) {
+	var u *models.User
+	err := s.WithTx(context.Background(), func(tx *Store) error {
+		userID, err := tx.insertUser(email, username, pass, about)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.insertUserLinks(userID, links); err != nil {
+			return err
+		}
+
+		u, err = tx.createdUser(userID)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -92,49 +239,361 @@ func (s *Store) UserByUsername(name string) (*models.User, error) {
 	return u, nil
 }
 
-func (s *Store) UpdateAboutMe(id int, text string) error {
-	res, err := s.updateAboutMe(id, text)
+// UpdateAboutMe changes the about text. A non-zero ifVersion makes it
+// conditional on the profile version, see models.User.
+func (s *Store) UpdateAboutMe(id int, text string, ifVersion int) error {
+	res, err := s.updateAboutMe(id, text, ifVersion)
 	if err != nil {
 		return err
 	}
 
 	if err := s.rowsAffectedCheck(res); err != nil {
+		if ifVersion != 0 && errors.Is(err, store.ErrNoRowsAffected) {
+			if _, err := s.userSettings(id); err == nil {
+				return store.ErrVersionMismatch
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// LockProfile fails with store.ErrVersionMismatch when a non-zero
+// ifVersion is not the profile version. Inside a unit of work the profile
+// then stays as checked until the unit ends.
+func (s *Store) LockProfile(id int, ifVersion int) error {
+	version, err := s.lockProfile(id)
+	if err != nil {
+		return err
+	}
+
+	if ifVersion != 0 && ifVersion != version {
+		return store.ErrVersionMismatch
+	}
+
+	return nil
+}
+
+func (s *Store) Settings(id int) (*models.Settings, error) {
+	settings, err := s.userSettings(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+func (s *Store) UpdateSettings(id int, settings models.Settings) error {
+	res, err := s.updateSettings(id, settings)
+	if err != nil {
+		return err
+	}
+
+	if err := s.rowsAffectedCheck(res); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) Link(linkID int) (*models.Link, error) {
+	l, err := s.linkByID(linkID)
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// AddLink saves the link and returns its ID.
+func (s *Store) AddLink(userID int, link requestModel.ReqLink) (int, error) {
+	if link.SectionID != nil {
+		if err := s.existsSection(userID, *link.SectionID); err != nil {
+			return 0, err
+		}
+	}
+
+	id, err := s.insertLink(userID, link)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// UpdateLink replaces the link's fields. A non-zero ifVersion makes it
+// conditional on the link version. The checks and the write share one
+// transaction, so the link cannot go away in between.
+func (s *Store) UpdateLink(userID int, link *requestModel.ReqUpdateLink, ifVersion int) error {
+	return s.WithTx(context.Background(), func(tx *Store) error {
+		if err := tx.existsLink(userID, link.LinkID); err != nil {
+			return err
+		}
+
+		if link.SectionID != nil {
+			if err := tx.existsSection(userID, *link.SectionID); err != nil {
+				return err
+			}
+		}
+
+		return tx.updateLink(userID, link, ifVersion)
+	})
+}
+
+// DeleteLink moves the link to the trash. A non-zero ifVersion makes it
+// conditional on the link version.
+func (s *Store) DeleteLink(userID int, linkID int, ifVersion int) error {
+	return s.WithTx(context.Background(), func(tx *Store) error {
+		if err := tx.existsLink(userID, linkID); err != nil {
+			return err
+		}
+
+		return tx.deleteLink(userID, linkID, ifVersion)
+	})
+}
+
+// ChangeLinks applies a batch of link edits, all of them or none.
+func (s *Store) ChangeLinks(userID int, changes []models.LinkChange) error {
+	return s.changeLinks(userID, changes)
+}
+
+func (s *Store) AllLinks() ([]models.Link, error) {
+	links, err := s.allLinks()
+	if err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+func (s *Store) SaveLinkHealth(health models.LinkHealth) error {
+	if err := s.upsertLinkHealth(health); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) LinkHealth(userID int) ([]models.LinkHealth, error) {
+	health, err := s.linkHealthByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return health, nil
+}
+
+func (s *Store) LinkPreview(url string) (*models.LinkPreview, error) {
+	p, err := s.linkPreviewByURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (s *Store) SaveLinkPreview(preview models.LinkPreview) error {
+	if err := s.upsertLinkPreview(preview); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (s *Store) AddLink(userID int, link requestModel.ReqLink) error {
-	if err := s.insertLink(userID, link); err != nil {
+// PreviewOwners returns the users who link to url, whose profiles show its
+// preview.
+func (s *Store) PreviewOwners(url string) ([]int, error) {
+	owners, err := s.previewOwners(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return owners, nil
+}
+
+func (s *Store) LinkPreviews(userID int) ([]models.LinkPreview, error) {
+	previews, err := s.linkPreviewsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return previews, nil
+}
+
+func (s *Store) Theme(userID int) (*models.Theme, error) {
+	t, err := s.themeByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (s *Store) SaveTheme(userID int, theme models.Theme) error {
+	if err := s.upsertTheme(userID, theme); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (s *Store) UpdateLink(userID int, link *requestModel.ReqUpdateLink) error {
+func (s *Store) ProfileImage(userID int, kind string) (*models.ProfileImage, error) {
+	img, err := s.profileImage(userID, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	return img, nil
+}
+
+func (s *Store) ProfileImages(userID int) ([]models.ProfileImage, error) {
+	images, err := s.profileImagesByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
 
-	if err := s.existsLink(userID, link.LinkID); err != nil {
+func (s *Store) SaveProfileImage(img models.ProfileImage) error {
+	if err := s.upsertProfileImage(img); err != nil {
 		return err
 	}
 
-	if err := s.updateLink(userID, link); err != nil {
+	return nil
+}
+
+func (s *Store) DeleteProfileImage(userID int, kind string) error {
+	res, err := s.deleteProfileImage(userID, kind)
+	if err != nil {
 		return err
 	}
 
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return store.ErrImageNotFound
+	}
+
 	return nil
 }
 
-func (s *Store) DeleteLink(userID int, linkID int) error {
+func (s *Store) Sections(userID int) ([]models.LinkSection, error) {
+	sections, err := s.sectionsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := s.existsLink(userID, linkID); err != nil {
+	return sections, nil
+}
+
+func (s *Store) Section(userID int, sectionID int) (*models.LinkSection, error) {
+	sec, err := s.sectionByID(userID, sectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return sec, nil
+}
+
+// CreateSection appends the section after the user's last one when position
+// is nil.
+func (s *Store) CreateSection(userID int, title string, position *int, collapsed bool) (*models.LinkSection, error) {
+	sec, err := s.insertSection(userID, title, position, collapsed)
+	if err != nil {
+		return nil, err
+	}
+
+	return sec, nil
+}
+
+func (s *Store) UpdateSection(section models.LinkSection) error {
+	res, err := s.updateSection(section)
+	if err != nil {
 		return err
 	}
 
-	if err := s.deleteLink(userID, linkID); err != nil {
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return store.ErrSectionNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteSection(userID int, sectionID int) error {
+	if err := s.deleteSection(userID, sectionID); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func (s *Store) SetLinkHidden(userID int, linkID int, hidden bool) error {
+	res, err := s.setLinkHidden(userID, linkID, hidden)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return store.ErrLinkNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) TrashedLinks(userID int) ([]models.Link, error) {
+	links, err := s.trashedLinks(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// RestoreLink takes a link out of the trash if it was deleted after
+// deletedAfter.
+func (s *Store) RestoreLink(userID int, linkID int, deletedAfter time.Time) error {
+	res, err := s.restoreLink(userID, linkID, deletedAfter)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return store.ErrLinkNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) PurgeLink(userID int, linkID int) error {
+	n, err := s.purgeLink(userID, linkID)
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return store.ErrLinkNotFound
+	}
+
+	return nil
+}
+
+// PurgeDeletedLinks permanently removes links trashed before the given time.
+func (s *Store) PurgeDeletedLinks(before time.Time) (int64, error) {
+	n, err := s.purgeExpiredLinks(before)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// ImportLinks adds all rows or none and reports how many sections it had to
+// create.
+func (s *Store) ImportLinks(userID int, rows []models.ImportedLink) (int, error) {
+	created, err := s.importLinks(userID, rows)
+	if err != nil {
+		return 0, err
+	}
+
+	return created, nil
+}
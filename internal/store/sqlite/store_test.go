@@ -0,0 +1,362 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+	"url_profile/internal/store/storetest"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+func newStore(t testing.TB) *Store {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	m, err := migrate.New("file://../../../migrations", "sqlite3://"+path)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	m.Close()
+
+	s := New(Config{
+		Path:         path,
+		JournalMode:  "WAL",
+		Synchronous:  "NORMAL",
+		BusyTimeout:  5 * time.Second,
+		ForeignKeys:  true,
+		CacheSize:    -2000,
+		MaxOpenConns: 4,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Store {
+		return newStore(t)
+	})
+}
+
+func TestPragmas(t *testing.T) {
+	s := newStore(t)
+
+	for name, db := range map[string]querier{"writer": s.conn, "reader": s.readConn} {
+		var mode string
+		if err := db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+			t.Fatalf("%s: journal_mode: %v", name, err)
+		}
+		if mode != "wal" {
+			t.Errorf("%s: journal_mode = %q, want wal", name, mode)
+		}
+
+		var fk, timeout, cache int
+		if err := db.QueryRow("PRAGMA foreign_keys").Scan(&fk); err != nil {
+			t.Fatalf("%s: foreign_keys: %v", name, err)
+		}
+		if fk != 1 {
+			t.Errorf("%s: foreign_keys = %d, want 1", name, fk)
+		}
+		if err := db.QueryRow("PRAGMA busy_timeout").Scan(&timeout); err != nil {
+			t.Fatalf("%s: busy_timeout: %v", name, err)
+		}
+		if timeout != 5000 {
+			t.Errorf("%s: busy_timeout = %d, want 5000", name, timeout)
+		}
+		if err := db.QueryRow("PRAGMA cache_size").Scan(&cache); err != nil {
+			t.Fatalf("%s: cache_size: %v", name, err)
+		}
+		if cache != -2000 {
+			t.Errorf("%s: cache_size = %d, want -2000", name, cache)
+		}
+	}
+
+	_, err := s.readConn.Exec("DELETE FROM users")
+	if err == nil || !strings.Contains(err.Error(), "readonly") {
+		t.Errorf("write through the read pool: got %v, want a readonly error", err)
+	}
+}
+
+func TestDeleteUserCascades(t *testing.T) {
+	s := newStore(t)
+	u, err := s.CreateUser("alice@example.com", "alice", []byte("x"), "", []requestModel.ReqLink{
+		{LinkName: "site", LinkPath: "https://alice.dev"},
+	})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	if _, err := s.conn.Exec("DELETE FROM users WHERE id = ?", u.ID); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+
+	var n int
+	if err := s.readConn.QueryRow("SELECT COUNT(*) FROM links WHERE user_id = ?", u.ID).Scan(&n); err != nil {
+		t.Fatalf("count links: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("%d links left after deleting their user, want 0", n)
+	}
+}
+
+// Without a single writer and a busy timeout concurrent writers fail with
+// SQLITE_BUSY.
+func TestConcurrentWrites(t *testing.T) {
+	s := newStore(t)
+	u, err := s.CreateUser("alice@example.com", "alice", []byte("x"), "", nil)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				if _, err := s.AddLink(u.ID, requestModel.ReqLink{LinkName: "l", LinkPath: "https://example.com"}); err != nil {
+					t.Errorf("AddLink: %v", err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				if _, err := s.UserByUsername("alice"); err != nil {
+					t.Errorf("UserByUsername: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := s.UserById(u.ID)
+	if err != nil {
+		t.Fatalf("UserById: %v", err)
+	}
+	if len(got.Links) != 8*25 {
+		t.Errorf("user has %d links, want %d", len(got.Links), 8*25)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	s := newStore(t)
+	if _, err := s.CreateUser("alice@example.com", "alice", []byte("x"), "", nil); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "snapshot.db")
+	if err := s.Snapshot(context.Background(), path); err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	if err := Verify(context.Background(), path); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+
+	snap := New(Config{Path: path, ForeignKeys: true}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer snap.Close()
+	if _, err := snap.UserByUsername("alice"); err != nil {
+		t.Errorf("UserByUsername on the snapshot: %v", err)
+	}
+
+	// The connection that ran VACUUM INTO is read-only again: hold every
+	// open connection of the read pool at once and check each.
+	ctx := context.Background()
+	for i := s.readConn.Stats().OpenConnections; i > 0; i-- {
+		conn, err := s.readConn.Conn(ctx)
+		if err != nil {
+			t.Fatalf("Conn: %v", err)
+		}
+		defer conn.Close()
+
+		var queryOnly int
+		if err := conn.QueryRowContext(ctx, "PRAGMA query_only").Scan(&queryOnly); err != nil {
+			t.Fatalf("query_only: %v", err)
+		}
+		if queryOnly != 1 {
+			t.Errorf("read connection has query_only = %d after a snapshot", queryOnly)
+		}
+	}
+}
+
+func TestVerifyCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corrupt.db")
+	if err := os.WriteFile(path, []byte("not a database"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Verify(context.Background(), path); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Verify: got %v, want ErrCorrupt", err)
+	}
+}
+
+// Themes, images, health and previews are bumped by triggers the shared
+// suite does not reach.
+func TestProfileVersionTriggers(t *testing.T) {
+	s := newStore(t)
+	u, err := s.CreateUser("alice@example.com", "alice", []byte("x"), "", []requestModel.ReqLink{
+		{LinkName: "site", LinkPath: "https://alice.dev"},
+	})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	got, err := s.UserById(u.ID)
+	if err != nil {
+		t.Fatalf("UserById: %v", err)
+	}
+	linkID, v, pv := got.Links[0].ID, got.Version, got.PreviewVersion
+
+	fetched := time.Now()
+	for _, w := range []struct {
+		name  string
+		write func() error
+		// bumps is the counter the write moves: "version" for the
+		// user's own writes, "previews" for a preview fetch, "" for none.
+		bumps string
+	}{
+		{"SaveTheme", func() error { return s.SaveTheme(u.ID, models.Theme{Preset: "dark"}) }, "version"},
+		{"UpdateLink", func() error {
+			return s.UpdateLink(u.ID, &requestModel.ReqUpdateLink{LinkID: linkID, LinkName: "home", LinkPath: "https://alice.dev"}, 0)
+		}, "version"},
+		{"SaveProfileImage", func() error {
+			return s.SaveProfileImage(models.ProfileImage{UserID: u.ID, Kind: models.ImageAvatar, Version: "v1", Format: "webp"})
+		}, "version"},
+		{"DeleteProfileImage", func() error { return s.DeleteProfileImage(u.ID, models.ImageAvatar) }, "version"},
+		{"SaveLinkHealth", func() error {
+			return s.SaveLinkHealth(models.LinkHealth{LinkID: linkID, Status: models.LinkHealthOK, CheckedAt: time.Now()})
+		}, ""},
+		{"SaveLinkHealth again", func() error {
+			return s.SaveLinkHealth(models.LinkHealth{LinkID: linkID, Status: models.LinkHealthBroken, StatusCode: 404, CheckedAt: time.Now()})
+		}, ""},
+		{"SaveLinkPreview", func() error {
+			return s.SaveLinkPreview(models.LinkPreview{URL: "https://alice.dev", Title: "Alice", FetchedAt: fetched})
+		}, "previews"},
+		{"SaveLinkPreview refetch", func() error {
+			return s.SaveLinkPreview(models.LinkPreview{URL: "https://alice.dev", Title: "Alice", FetchedAt: fetched.Add(time.Hour)})
+		}, ""},
+		{"SaveLinkPreview new title", func() error {
+			return s.SaveLinkPreview(models.LinkPreview{URL: "https://alice.dev", Title: "Alice Doe", FetchedAt: fetched.Add(2 * time.Hour)})
+		}, "previews"},
+	} {
+		if err := w.write(); err != nil {
+			t.Fatalf("%s: %v", w.name, err)
+		}
+		got, err := s.UserById(u.ID)
+		if err != nil {
+			t.Fatalf("UserById: %v", err)
+		}
+		want := v
+		if w.bumps == "version" {
+			want++
+		}
+		if got.Version != want {
+			t.Errorf("version went from %d to %d after %s, want %d", v, got.Version, w.name, want)
+		}
+		if moved := got.PreviewVersion != pv; moved != (w.bumps == "previews") || got.PreviewVersion < pv {
+			t.Errorf("preview version went from %d to %d after %s", pv, got.PreviewVersion, w.name)
+		}
+		v, pv = got.Version, got.PreviewVersion
+	}
+}
+
+// TestLinkWriteMisses covers a link that goes away between the existence
+// check and the write.
+func TestLinkWriteMisses(t *testing.T) {
+	s := newStore(t)
+	u, err := s.CreateUser("alice@example.com", "alice", []byte("x"), "", []requestModel.ReqLink{
+		{LinkName: "gone", LinkPath: "https://alice.dev"},
+		{LinkName: "live", LinkPath: "https://alice.blog"},
+	})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	got, err := s.UserById(u.ID)
+	if err != nil {
+		t.Fatalf("UserById: %v", err)
+	}
+	gone, live := got.Links[0], got.Links[1]
+	if err := s.DeleteLink(u.ID, gone.ID, 0); err != nil {
+		t.Fatalf("DeleteLink: %v", err)
+	}
+
+	update := func(l models.Link, ifVersion int) error {
+		return s.updateLink(u.ID, &requestModel.ReqUpdateLink{LinkID: l.ID, LinkName: "x", LinkPath: l.LinkPath}, ifVersion)
+	}
+	for _, tt := range []struct {
+		name  string
+		write func() error
+		want  error
+	}{
+		{"update unconditionally", func() error { return update(gone, 0) }, store.ErrLinkNotFound},
+		{"update at its version", func() error { return update(gone, gone.Version) }, store.ErrLinkNotFound},
+		{"delete unconditionally", func() error { return s.deleteLink(u.ID, gone.ID, 0) }, store.ErrLinkNotFound},
+		{"delete at its version", func() error { return s.deleteLink(u.ID, gone.ID, gone.Version) }, store.ErrLinkNotFound},
+		{"update a live link at a stale version", func() error { return update(live, live.Version+1) }, store.ErrVersionMismatch},
+	} {
+		if err := tt.write(); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestTrashUTCMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	m, err := migrate.New("file://../../../migrations", "sqlite3://"+path)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	defer m.Close()
+	if err := m.Migrate(11); err != nil {
+		t.Fatalf("migrate to 11: %v", err)
+	}
+
+	// The store's queries need the latest schema, so the rows are written
+	// by hand.
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	// Written the way the driver formats a time.Time in a non-UTC location.
+	legacy := "2026-01-02 06:04:05.123456789+03:00"
+	for _, q := range []string{
+		"INSERT INTO users (id, email, username, pass_hash, about_text) VALUES (1, 'alice@example.com', 'alice', 'x', '')",
+		"INSERT INTO links (user_id, link_name, link_path, deleted_at) VALUES (1, 'site', 'https://alice.dev', '" + legacy + "')",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+
+	var got string
+	if err := db.QueryRow("SELECT deleted_at FROM links WHERE user_id = 1").Scan(&got); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if want := "2026-01-02T03:04:05.123Z"; got != want {
+		t.Errorf("deleted_at = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,47 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+	"url_profile/internal/store/sqlite/query"
+)
+
+func (s *Store) themeByUser(userID int) (*models.Theme, error) {
+	t := &models.Theme{}
+	err := s.reader.QueryRow(query.ThemeByUser, userID).Scan(
+		&t.Preset,
+		&t.Background,
+		&t.Font,
+		&t.ButtonStyle,
+		&t.LinkColor,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrThemeNotFound
+		}
+
+		s.log.Error("failed to query theme",
+			slog.Int("user_id", userID),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return t, nil
+}
+
+func (s *Store) upsertTheme(userID int, t models.Theme) error {
+	_, err := s.db.Exec(query.UpsertTheme, userID, t.Preset, t.Background, t.Font, t.ButtonStyle, t.LinkColor)
+	if err != nil {
+		s.log.Error("failed to save theme",
+			slog.Int("user_id", userID),
+			slog.String("error", err.Error()))
+		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return nil
+}
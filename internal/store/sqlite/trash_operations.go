@@ -0,0 +1,136 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+	"url_profile/internal/domain/models"
+	"url_profile/internal/store"
+	"url_profile/internal/store/sqlite/query"
+)
+
+// deletedAtLayout is how deleted_at is written: UTC without an offset and
+// with a fixed number of digits, the same text strftime('%Y-%m-%d %H:%M:%f')
+// produces. Cutoffs are compared as strings, so every value has to share it
+// whatever location the time.Time was in.
+const deletedAtLayout = "2006-01-02 15:04:05.000"
+
+func deletedAt(t time.Time) string {
+	return t.UTC().Format(deletedAtLayout)
+}
+
+func (s *Store) setLinkHidden(userID int, linkID int, hidden bool) (sql.Result, error) {
+	res, err := s.db.Exec(query.SetLinkHidden, hidden, linkID, userID)
+	if err != nil {
+		s.log.Error("failed to update link visibility",
+			slog.Int("user_id", userID),
+			slog.Int("link_id", linkID),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return res, nil
+}
+
+func (s *Store) trashedLinks(userID int) ([]models.Link, error) {
+	rows, err := s.reader.Query(query.TrashedLinks, userID)
+	if err != nil {
+		s.log.Error("failed to query trashed links",
+			slog.Int("user_id", userID),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+	defer rows.Close()
+
+	var res []models.Link
+	for rows.Next() {
+		var (
+			l         models.Link
+			payload   string
+			deletedAt time.Time
+		)
+		err := rows.Scan(&l.ID, &l.UserID, &l.Type, &l.LinkName, &l.LinkColor, &l.LinkPath,
+			&payload, &l.SectionID, &l.Hidden, &deletedAt)
+		if err != nil {
+			s.log.Error("failed to scan trashed link",
+				slog.String("error", err.Error()))
+			return nil, fmt.Errorf("%w: %v", store.ErrDataScanFailed, err)
+		}
+		l.Payload = rawPayload(payload)
+		l.DeletedAt = &deletedAt
+		res = append(res, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("rows iteration error",
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: rows iteration failed", store.ErrDatabaseOperation)
+	}
+
+	return res, nil
+}
+
+func (s *Store) restoreLink(userID int, linkID int, deletedAfter time.Time) (sql.Result, error) {
+	res, err := s.db.Exec(query.RestoreLink, linkID, userID, deletedAt(deletedAfter))
+	if err != nil {
+		s.log.Error("failed to restore link",
+			slog.Int("user_id", userID),
+			slog.Int("link_id", linkID),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return res, nil
+}
+
+// purgeLink permanently removes one trashed link together with its health
+// record.
+func (s *Store) purgeLink(userID int, linkID int) (int64, error) {
+	var n int64
+	err := s.WithTx(context.Background(), func(tx *Store) error {
+		if _, err := tx.db.Exec(query.PurgeLinkHealth, linkID, userID); err != nil {
+			s.log.Error("failed to purge link health",
+				slog.Int("link_id", linkID),
+				slog.String("error", err.Error()))
+			return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+		}
+
+		res, err := tx.db.Exec(query.PurgeLink, linkID, userID)
+		if err != nil {
+			s.log.Error("failed to purge link",
+				slog.Int("link_id", linkID),
+				slog.String("error", err.Error()))
+			return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+		}
+
+		n, _ = res.RowsAffected()
+		return nil
+	})
+
+	return n, err
+}
+
+func (s *Store) purgeExpiredLinks(before time.Time) (int64, error) {
+	var n int64
+	err := s.WithTx(context.Background(), func(tx *Store) error {
+		if _, err := tx.db.Exec(query.PurgeExpiredLinkHealth, deletedAt(before)); err != nil {
+			s.log.Error("failed to purge expired link health",
+				slog.String("error", err.Error()))
+			return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+		}
+
+		res, err := tx.db.Exec(query.PurgeExpiredLinks, deletedAt(before))
+		if err != nil {
+			s.log.Error("failed to purge expired links",
+				slog.String("error", err.Error()))
+			return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+		}
+
+		n, _ = res.RowsAffected()
+		return nil
+	})
+
+	return n, err
+}
@@ -0,0 +1,57 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"url_profile/internal/store"
+)
+
+// querier is implemented by preparedDB and preparedTx, so every operation
+// runs the same way inside and outside a transaction.
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// WithTx runs fn as one unit of work. Every call fn makes on tx runs in the
+// same transaction, which is committed if fn returns nil and rolled back
+// otherwise. On a store that is already inside a transaction WithTx joins it,
+// so operations that need atomicity can be composed.
+func (s *Store) WithTx(ctx context.Context, fn func(tx *Store) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+	defer tx.Rollback()
+
+	ptx := newPreparedTx(tx, s.stmts)
+	if err := fn(&Store{
+		conn:      s.conn,
+		readConn:  s.readConn,
+		stmts:     s.stmts,
+		readStmts: s.readStmts,
+		db:        ptx,
+		reader:    ptx,
+		tx:        tx,
+		log:       s.log,
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return nil
+}
+
+// InTx is WithTx for service code, see store.UnitOfWork.
+func (s *Store) InTx(ctx context.Context, fn func(tx store.Tx) error) error {
+	return s.WithTx(ctx, func(tx *Store) error { return fn(tx) })
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 	"url_profile/internal/app/server/http/handlers/requestModel"
 	"url_profile/internal/domain/models"
 	"url_profile/internal/store"
@@ -14,52 +15,55 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-func (s *Store) insertUser(email string, username string, pass []byte, about string, links []requestModel.ReqLink) (int64, error) {
-
-	stmt, err := s.db.Prepare(query.InsertUser)
-	if err != nil {
-		s.log.Error("error from PREPARE SQL USERS", slog.String("err", err.Error()))
-		return 0, store.ErrDatabaseOperation
-	}
-	defer stmt.Close()
-
-	res, err := stmt.Exec(email, username, pass, about)
+func (s *Store) insertUser(email string, username string, pass []byte, about string) (int64, error) {
+	var userID int64
+	err := s.db.QueryRow(query.InsertUser, email, username, pass, about).Scan(&userID)
 	if err != nil {
 		if errshandle.IsDuplicateKeyError(err) {
-			s.log.Error("User Already Exists", slog.String("err", err.Error()))
-			return 0, store.ErrUserAlreadyExists
+			s.log.Warn("user already exists", slog.String("err", err.Error()))
+			return 0, &store.ConflictError{Column: errshandle.Column(err), Err: store.ErrUserAlreadyExists}
 		}
 		s.log.Error("error from EXEC SQL USERS", slog.String("err", err.Error()))
-		return 0, store.ErrDatabaseOperation
-	}
-
-	userID, err := res.LastInsertId()
-	if err != nil {
-		if exists, _ := s.User(email); exists != nil {
-			return 0, store.ErrUserAlreadyExists
-		}
-		return 0, store.ErrDatabaseOperation
+		return 0, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
 	}
 
 	s.log.Debug("user created",
 		slog.Int64("user_id", userID),
 		slog.String("email", email))
 
-	if len(links) > 0 {
-		for _, l := range links {
-			s.insertLink(int(userID), l)
+	return userID, nil
+}
+
+// insertUserLinks adds the sign-up links. Every link is tried so the caller
+// learns about all rejected links at once; any other failure stops early.
+func (s *Store) insertUserLinks(userID int64, links []requestModel.ReqLink) error {
+	invalid := &store.InvalidLinksError{}
+	for i, l := range links {
+		_, err := s.AddLink(int(userID), l)
+		switch {
+		case err == nil:
+		case errors.Is(err, store.ErrSectionNotFound):
+			invalid.Links = append(invalid.Links, store.LinkError{Index: i, Field: "section_id", Err: err})
+		case errors.Is(err, store.ErrLinkAlreadyExists):
+			invalid.Links = append(invalid.Links, store.LinkError{Index: i, Field: "link_path", Err: err})
+		default:
+			return err
 		}
 	}
 
-	return userID, nil
+	if len(invalid.Links) > 0 {
+		return invalid
+	}
+
+	return nil
 }
 
 func (s *Store) createdUser(userID int64) (*models.User, error) {
 	u := &models.User{}
-	err := s.db.QueryRow(query.CreatedUser, userID).Scan(
+	err := s.reader.QueryRow(query.CreatedUser, userID).Scan(
 		&u.ID,
-		&u.Username,
 		&u.Email,
+		&u.Username,
 	)
 
 	if err != nil {
@@ -81,7 +85,7 @@ func (s *Store) createdUser(userID int64) (*models.User, error) {
 
 func (s *Store) userRowsByEmail(email string) (*sql.Rows, error) {
 
-	rows, err := s.db.Query(query.UsersRowsByEmail, email)
+	rows, err := s.reader.Query(query.UsersRowsByEmail, email)
 	if err != nil {
 		s.log.Error("failed to query user data",
 			slog.String("error", err.Error()))
@@ -92,7 +96,7 @@ func (s *Store) userRowsByEmail(email string) (*sql.Rows, error) {
 }
 
 func (s *Store) userRowsByID(id int) (*sql.Rows, error) {
-	rows, err := s.db.Query(query.UsersRowsByID, id)
+	rows, err := s.reader.Query(query.UsersRowsByID, id)
 	if err != nil {
 		s.log.Error("failed to query user data",
 			slog.String("error", err.Error()))
@@ -103,7 +107,7 @@ func (s *Store) userRowsByID(id int) (*sql.Rows, error) {
 }
 
 func (s *Store) userRowsByUsername(name string) (*sql.Rows, error) {
-	rows, err := s.db.Query(query.UsersRowsByUsername, name)
+	rows, err := s.reader.Query(query.UsersRowsByUsername, name)
 	if err != nil {
 		s.log.Error("failed to query user data",
 			slog.String("error", err.Error()))
@@ -123,15 +127,20 @@ func (s *Store) scanUserRows(rows *sql.Rows) (*models.User, error) {
 			link       models.Link
 			linkID     sql.NullInt64
 			linkUserID sql.NullInt64
+			blockType  sql.NullString
 			linkName   sql.NullString
 			linkColor  sql.NullString
 			linkPath   sql.NullString
+			payload    sql.NullString
+			sectionID  sql.NullInt64
+			hidden     sql.NullBool
+			version    sql.NullInt64
 		)
 
 		if !userFound {
 			err := rows.Scan(
-				&user.ID, &user.Email, &user.Username, &user.HashedPassword, &user.AboutText,
-				&linkID, &linkUserID, &linkName, &linkColor, &linkPath,
+				&user.ID, &user.Email, &user.Username, &user.HashedPassword, &user.AboutText, &user.Version, &user.PreviewVersion, &user.UpdatedAt,
+				&linkID, &linkUserID, &blockType, &linkName, &linkColor, &linkPath, &payload, &sectionID, &hidden, &version,
 			)
 			if err != nil {
 				s.log.Error("failed to scan user data",
@@ -142,9 +151,11 @@ func (s *Store) scanUserRows(rows *sql.Rows) (*models.User, error) {
 		} else {
 			var discardID int
 			var discardEmail, discardUsername, discardAboutText string
+			var discardVersion, discardPreviewVersion int
+			var discardUpdatedAt time.Time
 			err := rows.Scan(
-				&discardID, &discardEmail, &discardUsername, &user.HashedPassword, &discardAboutText,
-				&linkID, &linkUserID, &linkName, &linkColor, &linkPath,
+				&discardID, &discardEmail, &discardUsername, &user.HashedPassword, &discardAboutText, &discardVersion, &discardPreviewVersion, &discardUpdatedAt,
+				&linkID, &linkUserID, &blockType, &linkName, &linkColor, &linkPath, &payload, &sectionID, &hidden, &version,
 			)
 			if err != nil {
 				s.log.Error("failed to scan link data",
@@ -159,6 +170,11 @@ func (s *Store) scanUserRows(rows *sql.Rows) (*models.User, error) {
 			link.LinkName = linkName.String
 			link.LinkColor = linkColor.String
 			link.LinkPath = linkPath.String
+			link.Type = blockType.String
+			link.Payload = rawPayload(payload.String)
+			link.SectionID = nullIntPtr(sectionID)
+			link.Hidden = hidden.Bool
+			link.Version = int(version.Int64)
 
 			links = append(links, link)
 		}
@@ -192,14 +208,21 @@ func (s *Store) scanUserRowsByUsername(rows *sql.Rows) (*models.User, error) {
 			username  string
 			passHash  string
 			aboutText string
+			version   int
+			previews  int
+			updatedAt time.Time
+			linkID    sql.NullInt64
+			blockType sql.NullString
 			linkName  sql.NullString
 			linkColor sql.NullString
 			linkPath  sql.NullString
+			payload   sql.NullString
+			sectionID sql.NullInt64
 		)
 
 		err := rows.Scan(
-			&id, &email, &username, &passHash, &aboutText,
-			&linkName, &linkColor, &linkPath,
+			&id, &email, &username, &passHash, &aboutText, &version, &previews, &updatedAt,
+			&linkID, &blockType, &linkName, &linkColor, &linkPath, &payload, &sectionID,
 		)
 		if err != nil {
 			s.log.Error("failed to scan row", slog.String("error", err.Error()))
@@ -213,15 +236,23 @@ func (s *Store) scanUserRowsByUsername(rows *sql.Rows) (*models.User, error) {
 				Username:       username,
 				HashedPassword: []byte(passHash),
 				AboutText:      aboutText,
+				Version:        version,
+				PreviewVersion: previews,
+				UpdatedAt:      updatedAt,
 			}
 			userFound = true
 		}
 
-		if linkName.Valid || linkColor.Valid || linkPath.Valid {
+		if linkID.Valid {
 			links = append(links, models.Link{
+				ID:        int(linkID.Int64),
+				UserID:    id,
+				Type:      blockType.String,
 				LinkName:  linkName.String,
 				LinkColor: linkColor.String,
 				LinkPath:  linkPath.String,
+				Payload:   rawPayload(payload.String),
+				SectionID: nullIntPtr(sectionID),
 			})
 		}
 	}
@@ -241,20 +272,56 @@ func (s *Store) scanUserRowsByUsername(rows *sql.Rows) (*models.User, error) {
 	return &user, nil
 }
 
-func (s *Store) updateAboutMe(id int, text string) (sql.Result, error) {
-	stmt, err := s.db.Prepare(query.UpdateAboutMe)
+func (s *Store) updateAboutMe(id int, text string, ifVersion int) (sql.Result, error) {
+	res, err := s.db.Exec(query.UpdateAboutMe, text, id, ifVersion, ifVersion)
 	if err != nil {
-		s.log.Error("failed to prepare update statement",
+		s.log.Debug("error from EXEC SQL Update TextAbout", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (s *Store) lockProfile(id int) (int, error) {
+	var version int
+	if err := s.reader.QueryRow(query.LockProfile, id).Scan(&version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, store.ErrUserNotFound
+		}
+
+		s.log.Error("failed to lock profile",
+			slog.Int("user_id", id),
+			slog.String("error", err.Error()))
+		return 0, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
+	}
+
+	return version, nil
+}
+
+func (s *Store) userSettings(id int) (*models.Settings, error) {
+	settings := &models.Settings{}
+	err := s.reader.QueryRow(query.UserSettings, id).Scan(&settings.StripTracking)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrUserNotFound
+		}
+
+		s.log.Error("failed to query user settings",
 			slog.Int("user_id", id),
 			slog.String("error", err.Error()))
 		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
 	}
-	defer stmt.Close()
 
-	res, err := stmt.Exec(text, id)
+	return settings, nil
+}
+
+func (s *Store) updateSettings(id int, settings models.Settings) (sql.Result, error) {
+	res, err := s.db.Exec(query.UpdateSettings, settings.StripTracking, id)
 	if err != nil {
-		s.log.Debug("error from EXEC SQL Update TextAbout", slog.String("err", err.Error()))
-		return nil, err
+		s.log.Error("failed to update user settings",
+			slog.Int("user_id", id),
+			slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%w: %v", store.ErrDatabaseOperation, err)
 	}
 
 	return res, nil
@@ -277,3 +344,12 @@ func (s *Store) rowsAffectedCheck(res sql.Result) error {
 
 	return nil
 }
+
+func nullIntPtr(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+
+	v := int(n.Int64)
+	return &v
+}
@@ -1,6 +1,10 @@
 package store
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var (
 	ErrUserNotFound        = errors.New("user not found")
@@ -12,4 +16,61 @@ var (
 	ErrUserRetrievalFailed = errors.New("failed to retrieve created user")
 	ErrDataScanFailed      = errors.New("failed to scan rows")
 	ErrNoRowsAffected      = errors.New("no rows were affected by the operation")
+	ErrPreviewNotFound     = errors.New("link preview not found")
+	ErrThemeNotFound       = errors.New("theme not found")
+	ErrImageNotFound       = errors.New("image not found")
+	ErrSectionNotFound     = errors.New("section not found")
+	// ErrVersionMismatch means a conditional write lost: the row changed
+	// since the caller read the version it pinned.
+	ErrVersionMismatch = errors.New("version mismatch")
 )
+
+// LinkError is a link of a batch write that the database rejected.
+// Index is the link's position in the batch, Field the request field at
+// fault.
+type LinkError struct {
+	Index int
+	Field string
+	Err   error
+}
+
+// InvalidLinksError lists every rejected link of a batch. When it is
+// returned nothing of the batch was written.
+type InvalidLinksError struct {
+	Links []LinkError
+}
+
+func (e *InvalidLinksError) Error() string {
+	msgs := make([]string, 0, len(e.Links))
+	for _, l := range e.Links {
+		msgs = append(msgs, fmt.Sprintf("links[%d].%s: %v", l.Index, l.Field, l.Err))
+	}
+	return "invalid links: " + strings.Join(msgs, "; ")
+}
+
+func (e *InvalidLinksError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Links))
+	for _, l := range e.Links {
+		errs = append(errs, l.Err)
+	}
+	return errs
+}
+
+// ConflictError is a write rejected by a unique constraint. Column is the
+// offending column, e.g. "email" or "username"; Err is the domain sentinel
+// such as ErrUserAlreadyExists.
+type ConflictError struct {
+	Column string
+	Err    error
+}
+
+func (e *ConflictError) Error() string {
+	if e.Column == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v: %s", e.Err, e.Column)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
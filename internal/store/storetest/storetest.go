@@ -0,0 +1,1048 @@
+// Package storetest holds the behaviour every storage backend must share.
+package storetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/domain/models"
+	authservice "url_profile/internal/services/auth"
+	"url_profile/internal/services/sections"
+	"url_profile/internal/services/trash"
+	"url_profile/internal/store"
+)
+
+// UserStore is what AuthService needs from a backend. RunUsers checks it.
+type UserStore interface {
+	authservice.UserSaver
+	authservice.UserProvider
+}
+
+// Store is a full database backend. Run checks it.
+type Store interface {
+	UserStore
+	sections.SectionStore
+	trash.TrashStore
+	ImportLinks(userID int, rows []models.ImportedLink) (int, error)
+	PreviewOwners(url string) ([]int, error)
+}
+
+type test[S any] struct {
+	name string
+	fn   func(t *testing.T, s S)
+}
+
+var userTests = []test[UserStore]{
+	{"create user", testCreateUser},
+	{"user not found", testUserNotFound},
+	{"duplicate user", testDuplicateUser},
+	{"create user with invalid links", testCreateUserInvalidLinks},
+	{"about and settings", testAboutAndSettings},
+	{"links", testLinks},
+	{"link ownership", testLinkOwnership},
+	{"hidden links", testHiddenLinks},
+	{"profile version", testProfileVersion},
+	{"conditional writes", testConditionalWrites},
+	{"change links", testChangeLinks},
+	{"unit of work", testUnitOfWork},
+}
+
+var storeTests = []test[Store]{
+	{"link visibility", testLinkVisibility},
+	{"sections", testSections},
+	{"section ordering", testSectionOrdering},
+	{"section ownership", testSectionOwnership},
+	{"delete section ungroups links", testDeleteSectionUngroupsLinks},
+	{"trash", testTrash},
+	{"trash cutoff time zones", testTrashTimeZones},
+	{"import links", testImportLinks},
+	{"profile version follows sections and visibility", testProfileVersionSections},
+	{"preview owners", testPreviewOwners},
+}
+
+// RunUsers checks the user and link contracts AuthService relies on.
+// newStore is called once per subtest and must return an empty store.
+func RunUsers(t *testing.T, newStore func(t *testing.T) UserStore) {
+	for _, tt := range userTests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+// Run checks everything RunUsers does plus sections, trash and import.
+// newStore is called once per subtest and must return an empty store.
+func Run(t *testing.T, newStore func(t *testing.T) Store) {
+	RunUsers(t, func(t *testing.T) UserStore { return newStore(t) })
+
+	for _, tt := range storeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func newUser(t *testing.T, s UserStore, name string, links ...requestModel.ReqLink) *models.User {
+	t.Helper()
+
+	u, err := s.CreateUser(name+"@example.com", name, []byte("hash-"+name), "about "+name, links)
+	if err != nil {
+		t.Fatalf("CreateUser(%q): %v", name, err)
+	}
+
+	return u
+}
+
+func addLink(t *testing.T, s UserStore, userID int, link requestModel.ReqLink) models.Link {
+	t.Helper()
+
+	id, err := s.AddLink(userID, link)
+	if err != nil {
+		t.Fatalf("AddLink(%q): %v", link.LinkPath, err)
+	}
+
+	u, err := s.UserById(userID)
+	if err != nil {
+		t.Fatalf("UserById(%d): %v", userID, err)
+	}
+
+	for _, l := range u.Links {
+		if l.ID == id {
+			if l.LinkPath != link.LinkPath {
+				t.Fatalf("AddLink(%q) returned ID %d of link %q", link.LinkPath, id, l.LinkPath)
+			}
+			return l
+		}
+	}
+
+	t.Fatalf("link %d not found after AddLink(%q)", id, link.LinkPath)
+	return models.Link{}
+}
+
+func testCreateUser(t *testing.T, s UserStore) {
+	u := newUser(t, s, "alice",
+		requestModel.ReqLink{LinkName: "site", LinkColor: "#fff", LinkPath: "https://alice.dev"},
+		requestModel.ReqLink{Type: "text", LinkName: "note", Payload: json.RawMessage(`{"text":"hi"}`)},
+	)
+	if u.ID == 0 || u.Email != "alice@example.com" || u.Username != "alice" {
+		t.Fatalf("CreateUser returned %+v", u)
+	}
+
+	for name, get := range map[string]func() (*models.User, error){
+		"User":           func() (*models.User, error) { return s.User("alice@example.com") },
+		"UserById":       func() (*models.User, error) { return s.UserById(u.ID) },
+		"UserByUsername": func() (*models.User, error) { return s.UserByUsername("alice") },
+	} {
+		got, err := get()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if got.ID != u.ID || got.Email != "alice@example.com" || got.Username != "alice" ||
+			got.AboutText != "about alice" || string(got.HashedPassword) != "hash-alice" {
+			t.Errorf("%s returned %+v", name, got)
+		}
+
+		if len(got.Links) != 2 {
+			t.Fatalf("%s returned %d links, want 2", name, len(got.Links))
+		}
+
+		site, note := got.Links[0], got.Links[1]
+		if site.LinkPath != "https://alice.dev" || site.LinkColor != "#fff" || site.Type != "link" || site.UserID != u.ID {
+			t.Errorf("%s: first link is %+v", name, site)
+		}
+		if note.Type != "text" || string(note.Payload) != `{"text":"hi"}` {
+			t.Errorf("%s: second link is %+v", name, note)
+		}
+	}
+}
+
+func testUserNotFound(t *testing.T, s UserStore) {
+	newUser(t, s, "alice")
+
+	// AuthService compares against the sentinel directly, so a wrapped
+	// error is not good enough.
+	if _, err := s.User("nobody@example.com"); err != store.ErrUserNotFound {
+		t.Errorf("User: got %v, want store.ErrUserNotFound", err)
+	}
+	if _, err := s.UserById(1 << 30); err != store.ErrUserNotFound {
+		t.Errorf("UserById: got %v, want store.ErrUserNotFound", err)
+	}
+	if _, err := s.UserByUsername("nobody"); err != store.ErrUserNotFound {
+		t.Errorf("UserByUsername: got %v, want store.ErrUserNotFound", err)
+	}
+	if _, err := s.Settings(1 << 30); !errors.Is(err, store.ErrUserNotFound) {
+		t.Errorf("Settings: got %v, want store.ErrUserNotFound", err)
+	}
+}
+
+func testDuplicateUser(t *testing.T, s UserStore) {
+	newUser(t, s, "alice")
+
+	for column, dup := range map[string][2]string{
+		"email":    {"alice@example.com", "other"},
+		"username": {"other@example.com", "alice"},
+	} {
+		_, err := s.CreateUser(dup[0], dup[1], []byte("x"), "", nil)
+		if !errors.Is(err, store.ErrUserAlreadyExists) {
+			t.Errorf("duplicate %s: got %v, want store.ErrUserAlreadyExists", column, err)
+			continue
+		}
+
+		var conflict *store.ConflictError
+		if !errors.As(err, &conflict) || conflict.Column != column {
+			t.Errorf("duplicate %s: got %#v, want a *store.ConflictError for %q", column, err, column)
+		}
+	}
+}
+
+func testCreateUserInvalidLinks(t *testing.T, s UserStore) {
+	missing := 1 << 30
+	_, err := s.CreateUser("alice@example.com", "alice", []byte("x"), "", []requestModel.ReqLink{
+		{LinkName: "ok", LinkPath: "https://ok.example"},
+		{LinkName: "bad", LinkPath: "https://bad.example", SectionID: &missing},
+		{LinkName: "also ok", LinkPath: "https://also-ok.example"},
+		{LinkName: "also bad", LinkPath: "https://also-bad.example", SectionID: &missing},
+	})
+
+	// Every rejected link is reported by its index in the request.
+	var invalid *store.InvalidLinksError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("got %v, want *store.InvalidLinksError", err)
+	}
+	if len(invalid.Links) != 2 {
+		t.Fatalf("rejected links: %+v, want links 1 and 3", invalid.Links)
+	}
+	for i, want := range []int{1, 3} {
+		if l := invalid.Links[i]; l.Index != want || l.Field != "section_id" {
+			t.Errorf("rejected link %d: %+v, want index %d on section_id", i, l, want)
+		}
+	}
+	if !errors.Is(err, store.ErrSectionNotFound) {
+		t.Errorf("error does not wrap store.ErrSectionNotFound: %v", err)
+	}
+
+	if _, err := s.User("alice@example.com"); err != store.ErrUserNotFound {
+		t.Errorf("user was written despite rejected links: %v", err)
+	}
+}
+
+func testAboutAndSettings(t *testing.T, s UserStore) {
+	u := newUser(t, s, "alice")
+
+	if err := s.UpdateAboutMe(u.ID, "new about", 0); err != nil {
+		t.Fatalf("UpdateAboutMe: %v", err)
+	}
+	got, err := s.UserById(u.ID)
+	if err != nil {
+		t.Fatalf("UserById: %v", err)
+	}
+	if got.AboutText != "new about" {
+		t.Errorf("about is %q, want %q", got.AboutText, "new about")
+	}
+
+	if err := s.UpdateAboutMe(1<<30, "x", 0); !errors.Is(err, store.ErrNoRowsAffected) {
+		t.Errorf("UpdateAboutMe of unknown user: got %v, want store.ErrNoRowsAffected", err)
+	}
+
+	settings, err := s.Settings(u.ID)
+	if err != nil {
+		t.Fatalf("Settings: %v", err)
+	}
+	if settings.StripTracking {
+		t.Errorf("StripTracking is on by default")
+	}
+
+	if err := s.UpdateSettings(u.ID, models.Settings{StripTracking: true}); err != nil {
+		t.Fatalf("UpdateSettings: %v", err)
+	}
+	settings, err = s.Settings(u.ID)
+	if err != nil {
+		t.Fatalf("Settings: %v", err)
+	}
+	if !settings.StripTracking {
+		t.Errorf("StripTracking was not saved")
+	}
+}
+
+func testLinks(t *testing.T, s UserStore) {
+	u := newUser(t, s, "alice")
+	l := addLink(t, s, u.ID, requestModel.ReqLink{LinkName: "site", LinkColor: "#000", LinkPath: "https://alice.dev"})
+
+	got, err := s.Link(l.ID)
+	if err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	if got.UserID != u.ID || got.LinkName != "site" || got.Type != "link" {
+		t.Errorf("Link returned %+v", got)
+	}
+
+	err = s.UpdateLink(u.ID, &requestModel.ReqUpdateLink{
+		LinkID:   l.ID,
+		Type:     "link",
+		LinkName: "blog",
+		LinkPath: "https://alice.dev/blog",
+	}, 0)
+	if err != nil {
+		t.Fatalf("UpdateLink: %v", err)
+	}
+	got, err = s.Link(l.ID)
+	if err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	if got.LinkName != "blog" || got.LinkPath != "https://alice.dev/blog" {
+		t.Errorf("updated link is %+v", got)
+	}
+
+	if err := s.DeleteLink(u.ID, l.ID, 0); err != nil {
+		t.Fatalf("DeleteLink: %v", err)
+	}
+	if _, err := s.Link(l.ID); !errors.Is(err, store.ErrLinkNotFound) {
+		t.Errorf("Link after delete: got %v, want store.ErrLinkNotFound", err)
+	}
+	if err := s.DeleteLink(u.ID, l.ID, 0); !errors.Is(err, store.ErrLinkNotFound) {
+		t.Errorf("second DeleteLink: got %v, want store.ErrLinkNotFound", err)
+	}
+
+	missing := 1 << 30
+	_, err = s.AddLink(u.ID, requestModel.ReqLink{LinkName: "x", LinkPath: "https://x.example", SectionID: &missing})
+	if !errors.Is(err, store.ErrSectionNotFound) {
+		t.Errorf("AddLink to unknown section: got %v, want store.ErrSectionNotFound", err)
+	}
+
+	_, err = s.AddLink(u.ID+1000, requestModel.ReqLink{LinkName: "orphan", LinkPath: "https://example.com"})
+	if !errors.Is(err, store.ErrUserNotFound) {
+		t.Errorf("AddLink for unknown user: got %v, want store.ErrUserNotFound", err)
+	}
+}
+
+func testLinkOwnership(t *testing.T, s UserStore) {
+	alice := newUser(t, s, "alice")
+	bob := newUser(t, s, "bob")
+	l := addLink(t, s, alice.ID, requestModel.ReqLink{LinkName: "site", LinkPath: "https://alice.dev"})
+
+	err := s.UpdateLink(bob.ID, &requestModel.ReqUpdateLink{LinkID: l.ID, LinkName: "mine", LinkPath: "https://bob.dev"}, 0)
+	if !errors.Is(err, store.ErrLinkNotFound) {
+		t.Errorf("UpdateLink of another user's link: got %v, want store.ErrLinkNotFound", err)
+	}
+	if err := s.DeleteLink(bob.ID, l.ID, 0); !errors.Is(err, store.ErrLinkNotFound) {
+		t.Errorf("DeleteLink of another user's link: got %v, want store.ErrLinkNotFound", err)
+	}
+}
+
+func testHiddenLinks(t *testing.T, s UserStore) {
+	u := newUser(t, s, "alice")
+	addLink(t, s, u.ID, requestModel.ReqLink{LinkName: "public", LinkPath: "https://public.example"})
+	hidden := addLink(t, s, u.ID, requestModel.ReqLink{LinkName: "hidden", LinkPath: "https://hidden.example", Hidden: true})
+
+	public, err := s.UserByUsername("alice")
+	if err != nil {
+		t.Fatalf("UserByUsername: %v", err)
+	}
+	if len(public.Links) != 1 || public.Links[0].LinkName != "public" {
+		t.Errorf("public profile shows %+v", public.Links)
+	}
+
+	owner, err := s.UserById(u.ID)
+	if err != nil {
+		t.Fatalf("UserById: %v", err)
+	}
+	if len(owner.Links) != 2 || owner.Links[1].ID != hidden.ID || !owner.Links[1].Hidden {
+		t.Errorf("owner sees %+v", owner.Links)
+	}
+}
+
+func testLinkVisibility(t *testing.T, s Store) {
+	alice := newUser(t, s, "alice")
+	bob := newUser(t, s, "bob")
+	l := addLink(t, s, alice.ID, requestModel.ReqLink{LinkName: "site", LinkPath: "https://alice.dev"})
+
+	if err := s.SetLinkHidden(bob.ID, l.ID, true); !errors.Is(err, store.ErrLinkNotFound) {
+		t.Errorf("SetLinkHidden of another user's link: got %v, want store.ErrLinkNotFound", err)
+	}
+
+	if err := s.SetLinkHidden(alice.ID, l.ID, true); err != nil {
+		t.Fatalf("SetLinkHidden: %v", err)
+	}
+	public, err := s.UserByUsername("alice")
+	if err != nil {
+		t.Fatalf("UserByUsername: %v", err)
+	}
+	if len(public.Links) != 0 {
+		t.Errorf("hidden link is public: %+v", public.Links)
+	}
+
+	if err := s.SetLinkHidden(alice.ID, l.ID, false); err != nil {
+		t.Fatalf("SetLinkHidden: %v", err)
+	}
+	public, err = s.UserByUsername("alice")
+	if err != nil {
+		t.Fatalf("UserByUsername: %v", err)
+	}
+	if len(public.Links) != 1 {
+		t.Errorf("unhidden link is not public: %+v", public.Links)
+	}
+}
+
+func testSections(t *testing.T, s Store) {
+	u := newUser(t, s, "alice")
+
+	first, err := s.CreateSection(u.ID, "Work", nil, false)
+	if err != nil {
+		t.Fatalf("CreateSection: %v", err)
+	}
+	second, err := s.CreateSection(u.ID, "Music", nil, true)
+	if err != nil {
+		t.Fatalf("CreateSection: %v", err)
+	}
+	if first.Position != 0 || second.Position != 1 {
+		t.Errorf("appended positions are %d and %d, want 0 and 1", first.Position, second.Position)
+	}
+
+	pos := 5
+	third, err := s.CreateSection(u.ID, "Pinned", &pos, false)
+	if err != nil {
+		t.Fatalf("CreateSection: %v", err)
+	}
+	if third.Position != 5 {
+		t.Errorf("explicit position is %d, want 5", third.Position)
+	}
+
+	second.Title = "Sounds"
+	second.Position = 9
+	second.Collapsed = false
+	if err := s.UpdateSection(*second); err != nil {
+		t.Fatalf("UpdateSection: %v", err)
+	}
+	got, err := s.Section(u.ID, second.ID)
+	if err != nil {
+		t.Fatalf("Section: %v", err)
+	}
+	if got.Title != "Sounds" || got.Position != 9 || got.Collapsed {
+		t.Errorf("updated section is %+v", got)
+	}
+
+	all, err := s.Sections(u.ID)
+	if err != nil {
+		t.Fatalf("Sections: %v", err)
+	}
+	if len(all) != 3 || all[0].ID != first.ID || all[1].ID != third.ID || all[2].ID != second.ID {
+		t.Errorf("sections are not ordered by position: %+v", all)
+	}
+
+	other := newUser(t, s, "bob")
+	if _, err := s.Section(other.ID, first.ID); !errors.Is(err, store.ErrSectionNotFound) {
+		t.Errorf("Section of another user: got %v, want store.ErrSectionNotFound", err)
+	}
+	if err := s.UpdateSection(models.LinkSection{ID: first.ID, UserID: other.ID, Title: "x"}); !errors.Is(err, store.ErrSectionNotFound) {
+		t.Errorf("UpdateSection of another user: got %v, want store.ErrSectionNotFound", err)
+	}
+	if err := s.DeleteSection(other.ID, first.ID); !errors.Is(err, store.ErrSectionNotFound) {
+		t.Errorf("DeleteSection of another user: got %v, want store.ErrSectionNotFound", err)
+	}
+}
+
+func testSectionOrdering(t *testing.T, s Store) {
+	u := newUser(t, s, "alice")
+	other := newUser(t, s, "bob")
+
+	pos := func(p int) *int { return &p }
+	create := func(userID int, title string, position *int) *models.LinkSection {
+		t.Helper()
+		sec, err := s.CreateSection(userID, title, position, false)
+		if err != nil {
+			t.Fatalf("CreateSection(%q): %v", title, err)
+		}
+		return sec
+	}
+
+	// Another user's sections do not move the next appended position.
+	create(other.ID, "far", pos(40))
+
+	a := create(u.ID, "a", pos(3))
+	b := create(u.ID, "b", nil)
+	c := create(u.ID, "c", pos(3))
+	d := create(u.ID, "d", pos(0))
+	if b.Position != 4 {
+		t.Errorf("appended after position 3 at %d, want 4", b.Position)
+	}
+
+	all, err := s.Sections(u.ID)
+	if err != nil {
+		t.Fatalf("Sections: %v", err)
+	}
+
+	// Equal positions keep creation order.
+	want := []int{d.ID, a.ID, c.ID, b.ID}
+	if len(all) != len(want) {
+		t.Fatalf("got %d sections, want %d", len(all), len(want))
+	}
+	for i, sec := range all {
+		if sec.ID != want[i] {
+			t.Fatalf("sections are %+v, want ids %v", all, want)
+		}
+	}
+}
+
+func testSectionOwnership(t *testing.T, s Store) {
+	alice := newUser(t, s, "alice")
+	bob := newUser(t, s, "bob")
+
+	sec, err := s.CreateSection(bob.ID, "bob's", nil, false)
+	if err != nil {
+		t.Fatalf("CreateSection: %v", err)
+	}
+
+	_, err = s.AddLink(alice.ID, requestModel.ReqLink{LinkName: "x", LinkPath: "https://x.example", SectionID: &sec.ID})
+	if !errors.Is(err, store.ErrSectionNotFound) {
+		t.Errorf("AddLink to another user's section: got %v, want store.ErrSectionNotFound", err)
+	}
+
+	l := addLink(t, s, alice.ID, requestModel.ReqLink{LinkName: "site", LinkPath: "https://alice.dev"})
+	err = s.UpdateLink(alice.ID, &requestModel.ReqUpdateLink{LinkID: l.ID, LinkName: "site", LinkPath: "https://alice.dev", SectionID: &sec.ID}, 0)
+	if !errors.Is(err, store.ErrSectionNotFound) {
+		t.Errorf("UpdateLink into another user's section: got %v, want store.ErrSectionNotFound", err)
+	}
+}
+
+func testDeleteSectionUngroupsLinks(t *testing.T, s Store) {
+	u := newUser(t, s, "alice")
+	sec, err := s.CreateSection(u.ID, "Work", nil, false)
+	if err != nil {
+		t.Fatalf("CreateSection: %v", err)
+	}
+	l := addLink(t, s, u.ID, requestModel.ReqLink{LinkName: "site", LinkPath: "https://alice.dev", SectionID: &sec.ID})
+	if l.SectionID == nil || *l.SectionID != sec.ID {
+		t.Fatalf("link section is %v, want %d", l.SectionID, sec.ID)
+	}
+
+	if err := s.DeleteSection(u.ID, sec.ID); err != nil {
+		t.Fatalf("DeleteSection: %v", err)
+	}
+	if _, err := s.Section(u.ID, sec.ID); !errors.Is(err, store.ErrSectionNotFound) {
+		t.Errorf("Section after delete: got %v, want store.ErrSectionNotFound", err)
+	}
+
+	got, err := s.Link(l.ID)
+	if err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	if got.SectionID != nil {
+		t.Errorf("link still points at deleted section %d", *got.SectionID)
+	}
+}
+
+func testTrash(t *testing.T, s Store) {
+	u := newUser(t, s, "alice")
+	kept := addLink(t, s, u.ID, requestModel.ReqLink{LinkName: "kept", LinkPath: "https://kept.example"})
+	purged := addLink(t, s, u.ID, requestModel.ReqLink{LinkName: "purged", LinkPath: "https://purged.example"})
+	expired := addLink(t, s, u.ID, requestModel.ReqLink{LinkName: "expired", LinkPath: "https://expired.example"})
+
+	if err := s.PurgeLink(u.ID, kept.ID); !errors.Is(err, store.ErrLinkNotFound) {
+		t.Errorf("PurgeLink of a live link: got %v, want store.ErrLinkNotFound", err)
+	}
+
+	for _, l := range []models.Link{kept, purged, expired} {
+		if err := s.DeleteLink(u.ID, l.ID, 0); err != nil {
+			t.Fatalf("DeleteLink: %v", err)
+		}
+	}
+
+	trashed, err := s.TrashedLinks(u.ID)
+	if err != nil {
+		t.Fatalf("TrashedLinks: %v", err)
+	}
+	if len(trashed) != 3 {
+		t.Fatalf("trash holds %d links, want 3", len(trashed))
+	}
+	for _, l := range trashed {
+		if l.DeletedAt == nil {
+			t.Errorf("trashed link %d has no DeletedAt", l.ID)
+		}
+	}
+
+	if err := s.RestoreLink(u.ID, kept.ID, time.Now().Add(time.Hour)); !errors.Is(err, store.ErrLinkNotFound) {
+		t.Errorf("RestoreLink past retention: got %v, want store.ErrLinkNotFound", err)
+	}
+	if err := s.RestoreLink(u.ID, kept.ID, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("RestoreLink: %v", err)
+	}
+	if _, err := s.Link(kept.ID); err != nil {
+		t.Errorf("restored link: %v", err)
+	}
+
+	if err := s.PurgeLink(u.ID, purged.ID); err != nil {
+		t.Fatalf("PurgeLink: %v", err)
+	}
+	if err := s.RestoreLink(u.ID, purged.ID, time.Time{}); !errors.Is(err, store.ErrLinkNotFound) {
+		t.Errorf("RestoreLink of a purged link: got %v, want store.ErrLinkNotFound", err)
+	}
+
+	n, err := s.PurgeDeletedLinks(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeDeletedLinks: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("PurgeDeletedLinks removed %d links, want 1", n)
+	}
+
+	trashed, err = s.TrashedLinks(u.ID)
+	if err != nil {
+		t.Fatalf("TrashedLinks: %v", err)
+	}
+	if len(trashed) != 0 {
+		t.Errorf("trash still holds %+v", trashed)
+	}
+}
+
+// testTrashTimeZones passes cutoffs in zones far from UTC on either side;
+// the location of a time.Time must not move the cutoff.
+func testTrashTimeZones(t *testing.T, s Store) {
+	east := time.FixedZone("UTC+14", 14*60*60)
+	west := time.FixedZone("UTC-12", -12*60*60)
+
+	u := newUser(t, s, "alice")
+	l := addLink(t, s, u.ID, requestModel.ReqLink{LinkName: "a", LinkPath: "https://a.example"})
+
+	deleted := time.Now()
+	if err := s.DeleteLink(u.ID, l.ID, 0); err != nil {
+		t.Fatalf("DeleteLink: %v", err)
+	}
+
+	trashed, err := s.TrashedLinks(u.ID)
+	if err != nil {
+		t.Fatalf("TrashedLinks: %v", err)
+	}
+	if len(trashed) != 1 || trashed[0].DeletedAt == nil {
+		t.Fatalf("trash holds %+v, want the deleted link", trashed)
+	}
+	if d := trashed[0].DeletedAt.Sub(deleted); d < -time.Second || d > time.Second {
+		t.Errorf("DeletedAt = %v, want about %v", trashed[0].DeletedAt, deleted)
+	}
+
+	if n, err := s.PurgeDeletedLinks(deleted.Add(-time.Minute).In(west)); err != nil || n != 0 {
+		t.Errorf("PurgeDeletedLinks before deletion = %d, %v; want 0, nil", n, err)
+	}
+	if err := s.RestoreLink(u.ID, l.ID, deleted.Add(time.Minute).In(east)); !errors.Is(err, store.ErrLinkNotFound) {
+		t.Errorf("RestoreLink with a later cutoff: got %v, want store.ErrLinkNotFound", err)
+	}
+	if err := s.RestoreLink(u.ID, l.ID, deleted.Add(-time.Minute).In(west)); err != nil {
+		t.Fatalf("RestoreLink with an earlier cutoff: %v", err)
+	}
+
+	if err := s.DeleteLink(u.ID, l.ID, 0); err != nil {
+		t.Fatalf("DeleteLink: %v", err)
+	}
+	if n, err := s.PurgeDeletedLinks(time.Now().Add(time.Minute).In(east)); err != nil || n != 1 {
+		t.Errorf("PurgeDeletedLinks after deletion = %d, %v; want 1, nil", n, err)
+	}
+}
+
+func testImportLinks(t *testing.T, s Store) {
+	u := newUser(t, s, "alice")
+	work, err := s.CreateSection(u.ID, "Work", nil, false)
+	if err != nil {
+		t.Fatalf("CreateSection: %v", err)
+	}
+
+	created, err := s.ImportLinks(u.ID, []models.ImportedLink{
+		{Link: models.Link{LinkName: "a", LinkPath: "https://a.example"}},
+		{Link: models.Link{LinkName: "b", LinkPath: "https://b.example"}, Section: "Work"},
+		{Link: models.Link{LinkName: "c", LinkPath: "https://c.example"}, Section: "Fun"},
+		{Link: models.Link{LinkName: "d", LinkPath: "https://d.example"}, Section: "Fun"},
+	})
+	if err != nil {
+		t.Fatalf("ImportLinks: %v", err)
+	}
+	if created != 1 {
+		t.Errorf("ImportLinks created %d sections, want 1", created)
+	}
+
+	all, err := s.Sections(u.ID)
+	if err != nil {
+		t.Fatalf("Sections: %v", err)
+	}
+	if len(all) != 2 || all[1].Title != "Fun" {
+		t.Fatalf("sections after import: %+v", all)
+	}
+
+	got, err := s.UserById(u.ID)
+	if err != nil {
+		t.Fatalf("UserById: %v", err)
+	}
+	if len(got.Links) != 4 {
+		t.Fatalf("user has %d links after import, want 4", len(got.Links))
+	}
+
+	want := map[string]*int{"a": nil, "b": &work.ID, "c": &all[1].ID, "d": &all[1].ID}
+	for _, l := range got.Links {
+		w := want[l.LinkName]
+		if (w == nil) != (l.SectionID == nil) || (w != nil && *w != *l.SectionID) {
+			t.Errorf("link %s is in section %v, want %v", l.LinkName, l.SectionID, w)
+		}
+		if l.Type != "link" {
+			t.Errorf("link %s has type %q, want link", l.LinkName, l.Type)
+		}
+	}
+}
+
+// version returns the profile version of userID and checks that the owner
+// and the public view agree on it.
+func version(t *testing.T, s UserStore, userID int) int {
+	t.Helper()
+
+	u, err := s.UserById(userID)
+	if err != nil {
+		t.Fatalf("UserById: %v", err)
+	}
+	public, err := s.UserByUsername(u.Username)
+	if err != nil {
+		t.Fatalf("UserByUsername: %v", err)
+	}
+	if public.Version != u.Version || public.PreviewVersion != u.PreviewVersion || !public.UpdatedAt.Equal(u.UpdatedAt) {
+		t.Errorf("UserByUsername has version %d.%d at %v, UserById %d.%d at %v",
+			public.Version, public.PreviewVersion, public.UpdatedAt, u.Version, u.PreviewVersion, u.UpdatedAt)
+	}
+	if u.UpdatedAt.IsZero() {
+		t.Errorf("UpdatedAt is not set")
+	}
+
+	return u.Version
+}
+
+func testProfileVersion(t *testing.T, s UserStore) {
+	u := newUser(t, s, "alice")
+	other := newUser(t, s, "bob")
+
+	v := version(t, s, u.ID)
+	bump := func(what string, write func() error) {
+		t.Helper()
+		if err := write(); err != nil {
+			t.Fatalf("%s: %v", what, err)
+		}
+		next := version(t, s, u.ID)
+		if next <= v {
+			t.Errorf("version is %d after %s, want more than %d", next, what, v)
+		}
+		v = next
+	}
+
+	bump("UpdateAboutMe", func() error { return s.UpdateAboutMe(u.ID, "new about", 0) })
+	bump("AddLink", func() error {
+		_, err := s.AddLink(u.ID, requestModel.ReqLink{LinkName: "site", LinkPath: "https://alice.dev"})
+		return err
+	})
+
+	l := addLink(t, s, u.ID, requestModel.ReqLink{LinkName: "blog", LinkPath: "https://alice.blog"})
+	v = version(t, s, u.ID)
+	bump("UpdateLink", func() error {
+		return s.UpdateLink(u.ID, &requestModel.ReqUpdateLink{LinkID: l.ID, LinkName: "Blog", LinkPath: l.LinkPath}, 0)
+	})
+	bump("DeleteLink", func() error { return s.DeleteLink(u.ID, l.ID, 0) })
+
+	// Settings are not part of the profile.
+	if err := s.UpdateSettings(u.ID, models.Settings{StripTracking: true}); err != nil {
+		t.Fatalf("UpdateSettings: %v", err)
+	}
+	if got := version(t, s, u.ID); got != v {
+		t.Errorf("version is %d after UpdateSettings, want %d", got, v)
+	}
+
+	otherVersion := version(t, s, other.ID)
+	if err := s.UpdateAboutMe(u.ID, "again", 0); err != nil {
+		t.Fatalf("UpdateAboutMe: %v", err)
+	}
+	if got := version(t, s, other.ID); got != otherVersion {
+		t.Errorf("another user's write moved bob's version from %d to %d", otherVersion, got)
+	}
+}
+
+func testProfileVersionSections(t *testing.T, s Store) {
+	u := newUser(t, s, "alice")
+	l := addLink(t, s, u.ID, requestModel.ReqLink{LinkName: "site", LinkPath: "https://alice.dev"})
+
+	v := version(t, s, u.ID)
+	bump := func(what string, write func() error) {
+		t.Helper()
+		if err := write(); err != nil {
+			t.Fatalf("%s: %v", what, err)
+		}
+		next := version(t, s, u.ID)
+		if next <= v {
+			t.Errorf("version is %d after %s, want more than %d", next, what, v)
+		}
+		v = next
+	}
+
+	var sec *models.LinkSection
+	bump("CreateSection", func() error {
+		var err error
+		sec, err = s.CreateSection(u.ID, "Work", nil, false)
+		return err
+	})
+	bump("UpdateSection", func() error {
+		sec.Title = "Play"
+		return s.UpdateSection(*sec)
+	})
+	bump("DeleteSection", func() error { return s.DeleteSection(u.ID, sec.ID) })
+	bump("SetLinkHidden", func() error { return s.SetLinkHidden(u.ID, l.ID, true) })
+	bump("ImportLinks", func() error {
+		_, err := s.ImportLinks(u.ID, []models.ImportedLink{{Link: models.Link{LinkName: "new", LinkPath: "https://new.dev"}}})
+		return err
+	})
+}
+
+func testConditionalWrites(t *testing.T, s UserStore) {
+	u := newUser(t, s, "alice")
+	l := addLink(t, s, u.ID, requestModel.ReqLink{LinkName: "site", LinkColor: "#000", LinkPath: "https://alice.dev"})
+	if l.Version == 0 {
+		t.Fatalf("link has no version")
+	}
+
+	update := func(name string, ifVersion int) error {
+		return s.UpdateLink(u.ID, &requestModel.ReqUpdateLink{LinkID: l.ID, LinkName: name, LinkPath: l.LinkPath}, ifVersion)
+	}
+
+	if err := update("stale", l.Version+1); !errors.Is(err, store.ErrVersionMismatch) {
+		t.Errorf("UpdateLink with a wrong version: got %v, want store.ErrVersionMismatch", err)
+	}
+	if got, _ := s.Link(l.ID); got == nil || got.LinkName != "site" || got.Version != l.Version {
+		t.Errorf("a rejected UpdateLink changed the link to %+v", got)
+	}
+
+	if err := update("first", l.Version); err != nil {
+		t.Fatalf("UpdateLink with the current version: %v", err)
+	}
+	got, err := s.Link(l.ID)
+	if err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	if got.LinkName != "first" || got.Version <= l.Version {
+		t.Errorf("after UpdateLink the link is %+v, want name first and a version above %d", got, l.Version)
+	}
+
+	// The other tab still holds the old version.
+	if err := update("second", l.Version); !errors.Is(err, store.ErrVersionMismatch) {
+		t.Errorf("UpdateLink with a used version: got %v, want store.ErrVersionMismatch", err)
+	}
+	if err := s.DeleteLink(u.ID, l.ID, l.Version); !errors.Is(err, store.ErrVersionMismatch) {
+		t.Errorf("DeleteLink with a used version: got %v, want store.ErrVersionMismatch", err)
+	}
+	if err := s.DeleteLink(u.ID, l.ID, got.Version); err != nil {
+		t.Errorf("DeleteLink with the current version: %v", err)
+	}
+
+	v := version(t, s, u.ID)
+	if err := s.UpdateAboutMe(u.ID, "stale", v+1); !errors.Is(err, store.ErrVersionMismatch) {
+		t.Errorf("UpdateAboutMe with a wrong version: got %v, want store.ErrVersionMismatch", err)
+	}
+	if err := s.UpdateAboutMe(u.ID, "fresh", v); err != nil {
+		t.Errorf("UpdateAboutMe with the current version: %v", err)
+	}
+	if err := s.UpdateAboutMe(u.ID, "again", v); !errors.Is(err, store.ErrVersionMismatch) {
+		t.Errorf("UpdateAboutMe with a used version: got %v, want store.ErrVersionMismatch", err)
+	}
+	if err := s.UpdateAboutMe(1<<30, "x", 1); !errors.Is(err, store.ErrNoRowsAffected) {
+		t.Errorf("UpdateAboutMe of unknown user: got %v, want store.ErrNoRowsAffected", err)
+	}
+}
+
+func testUnitOfWork(t *testing.T, s UserStore) {
+	ctx := context.Background()
+	u := newUser(t, s, "alice")
+	v := version(t, s, u.ID)
+	dark := models.Theme{Preset: "dark", Background: "#000000", Font: "inter", ButtonStyle: "rounded", LinkColor: "#ffffff"}
+
+	// Every write of a failed unit is rolled back, and its error comes
+	// back as is.
+	boom := errors.New("boom")
+	err := s.InTx(ctx, func(tx store.Tx) error {
+		if err := tx.UpdateAboutMe(u.ID, "rolled back", v); err != nil {
+			return err
+		}
+		if err := tx.SaveTheme(u.ID, dark); err != nil {
+			return err
+		}
+		if _, err := tx.AddLink(u.ID, requestModel.ReqLink{LinkName: "site", LinkPath: "https://alice.dev"}); err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("InTx: got %v, want the error fn returned", err)
+	}
+	got, err := s.UserById(u.ID)
+	if err != nil {
+		t.Fatalf("UserById: %v", err)
+	}
+	if got.AboutText != "about alice" || len(got.Links) != 0 || got.Version != v {
+		t.Errorf("after a failed unit the user is %+v, want it unchanged at version %d", got, v)
+	}
+	err = s.InTx(ctx, func(tx store.Tx) error {
+		_, err := tx.Theme(u.ID)
+		return err
+	})
+	if !errors.Is(err, store.ErrThemeNotFound) {
+		t.Errorf("theme of a failed unit: got %v, want store.ErrThemeNotFound", err)
+	}
+
+	// A stale version fails the unit like any other error.
+	err = s.InTx(ctx, func(tx store.Tx) error {
+		if err := tx.SaveTheme(u.ID, dark); err != nil {
+			return err
+		}
+		return tx.UpdateAboutMe(u.ID, "stale", v)
+	})
+	if !errors.Is(err, store.ErrVersionMismatch) {
+		t.Errorf("InTx with a stale version: got %v, want store.ErrVersionMismatch", err)
+	}
+	if got := version(t, s, u.ID); got != v {
+		t.Errorf("a failed unit moved the version from %d to %d", v, got)
+	}
+
+	// LockProfile pins the unit to the version the caller read.
+	err = s.InTx(ctx, func(tx store.Tx) error {
+		if err := tx.LockProfile(u.ID, v+1); err != nil {
+			return err
+		}
+		return tx.SaveTheme(u.ID, dark)
+	})
+	if !errors.Is(err, store.ErrVersionMismatch) {
+		t.Errorf("LockProfile with a wrong version: got %v, want store.ErrVersionMismatch", err)
+	}
+	err = s.InTx(ctx, func(tx store.Tx) error { return tx.LockProfile(1<<30, 0) })
+	if !errors.Is(err, store.ErrUserNotFound) {
+		t.Errorf("LockProfile of unknown user: got %v, want store.ErrUserNotFound", err)
+	}
+
+	// A unit sees its own writes, and all of them land together.
+	err = s.InTx(ctx, func(tx store.Tx) error {
+		if err := tx.LockProfile(u.ID, v); err != nil {
+			return err
+		}
+		if err := tx.UpdateAboutMe(u.ID, "committed", v); err != nil {
+			return err
+		}
+		if _, err := tx.AddLink(u.ID, requestModel.ReqLink{LinkName: "site", LinkPath: "https://alice.dev"}); err != nil {
+			return err
+		}
+		in, err := tx.UserById(u.ID)
+		if err != nil {
+			return err
+		}
+		if in.AboutText != "committed" || len(in.Links) != 1 {
+			t.Errorf("inside the unit the user is %+v, want its writes", in)
+		}
+		return tx.SaveTheme(u.ID, dark)
+	})
+	if err != nil {
+		t.Fatalf("InTx: %v", err)
+	}
+	got, err = s.UserById(u.ID)
+	if err != nil {
+		t.Fatalf("UserById: %v", err)
+	}
+	if got.AboutText != "committed" || len(got.Links) != 1 || got.Version <= v {
+		t.Errorf("after the unit the user is %+v, want its writes and a version above %d", got, v)
+	}
+	err = s.InTx(ctx, func(tx store.Tx) error {
+		theme, err := tx.Theme(u.ID)
+		if err == nil && *theme != dark {
+			t.Errorf("theme = %+v, want %+v", *theme, dark)
+		}
+		return err
+	})
+	if err != nil {
+		t.Errorf("theme after the unit: %v", err)
+	}
+}
+
+func testChangeLinks(t *testing.T, s UserStore) {
+	u := newUser(t, s, "alice")
+	a := addLink(t, s, u.ID, requestModel.ReqLink{LinkName: "a", LinkColor: "#000", LinkPath: "https://a.dev"})
+	b := addLink(t, s, u.ID, requestModel.ReqLink{LinkName: "b", LinkColor: "#111", LinkPath: "https://b.dev"})
+	c := addLink(t, s, u.ID, requestModel.ReqLink{LinkName: "c", LinkColor: "#222", LinkPath: "https://c.dev"})
+
+	renamed := a
+	renamed.LinkName = "renamed"
+
+	// b's version is stale, so nothing of the batch may land.
+	err := s.ChangeLinks(u.ID, []models.LinkChange{
+		{Link: renamed, IfVersion: a.Version},
+		{Link: b, Delete: true, IfVersion: b.Version + 1},
+	})
+	if !errors.Is(err, store.ErrVersionMismatch) {
+		t.Fatalf("ChangeLinks with a stale version: got %v, want store.ErrVersionMismatch", err)
+	}
+	if got, _ := s.Link(a.ID); got == nil || got.LinkName != "a" || got.Version != a.Version {
+		t.Errorf("a rejected batch changed link a to %+v", got)
+	}
+
+	other := newUser(t, s, "bob")
+	if err := s.ChangeLinks(other.ID, []models.LinkChange{{Link: renamed}}); !errors.Is(err, store.ErrLinkNotFound) {
+		t.Errorf("ChangeLinks on another user's link: got %v, want store.ErrLinkNotFound", err)
+	}
+
+	v := version(t, s, u.ID)
+	err = s.ChangeLinks(u.ID, []models.LinkChange{
+		{Link: renamed, IfVersion: a.Version},
+		{Link: b, Delete: true, IfVersion: b.Version},
+	})
+	if err != nil {
+		t.Fatalf("ChangeLinks: %v", err)
+	}
+
+	got, err := s.Link(a.ID)
+	if err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	if got.LinkName != "renamed" || got.LinkColor != "#000" || got.Version <= a.Version {
+		t.Errorf("after ChangeLinks link a is %+v", got)
+	}
+	if got, _ := s.Link(c.ID); got == nil || got.Version != c.Version {
+		t.Errorf("ChangeLinks touched link c: %+v", got)
+	}
+
+	owner, err := s.UserById(u.ID)
+	if err != nil {
+		t.Fatalf("UserById: %v", err)
+	}
+	for _, l := range owner.Links {
+		if l.ID == b.ID {
+			t.Errorf("link b is still on the profile after ChangeLinks deleted it")
+		}
+	}
+	if owner.Version <= v {
+		t.Errorf("profile version %d did not go up from %d", owner.Version, v)
+	}
+}
+
+func testPreviewOwners(t *testing.T, s Store) {
+	alice := newUser(t, s, "alice",
+		requestModel.ReqLink{LinkName: "shared", LinkPath: "https://shared.example"},
+		requestModel.ReqLink{LinkName: "again", LinkPath: "https://shared.example"},
+	)
+	bob := newUser(t, s, "bob", requestModel.ReqLink{LinkName: "shared", LinkPath: "https://shared.example"})
+	newUser(t, s, "carol", requestModel.ReqLink{LinkName: "own", LinkPath: "https://carol.example"})
+
+	got, err := s.PreviewOwners("https://shared.example")
+	if err != nil {
+		t.Fatalf("PreviewOwners: %v", err)
+	}
+	slices.Sort(got)
+	if want := []int{alice.ID, bob.ID}; !slices.Equal(got, want) {
+		t.Errorf("PreviewOwners = %v, want %v", got, want)
+	}
+
+	got, err = s.PreviewOwners("https://nobody.example")
+	if err != nil {
+		t.Fatalf("PreviewOwners: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("PreviewOwners of an unlinked URL = %v, want none", got)
+	}
+}
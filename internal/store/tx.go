@@ -0,0 +1,30 @@
+package store
+
+import (
+	"context"
+	"url_profile/internal/app/server/http/handlers/requestModel"
+	"url_profile/internal/domain/models"
+)
+
+// Tx is what service code can do inside a unit of work. Every call runs in
+// the transaction the unit of work opened and sees its earlier writes.
+type Tx interface {
+	// LockProfile checks a non-zero ifVersion against the profile version,
+	// failing with ErrVersionMismatch, and keeps the profile from changing
+	// under the unit until it ends. Called first, it pins every later
+	// write of the unit to the version the caller read.
+	LockProfile(id int, ifVersion int) error
+	UserById(id int) (*models.User, error)
+	UpdateAboutMe(id int, text string, ifVersion int) error
+	Theme(userID int) (*models.Theme, error)
+	SaveTheme(userID int, theme models.Theme) error
+	AddLink(userID int, link requestModel.ReqLink) (int, error)
+}
+
+// UnitOfWork is implemented by every backend. InTx calls fn with a Tx bound
+// to a new transaction, commits it if fn returns nil and rolls it back
+// otherwise, returning fn's error as is. fn must make its calls on tx: the
+// store InTx was called on may be blocked until the transaction ends.
+type UnitOfWork interface {
+	InTx(ctx context.Context, fn func(tx Tx) error) error
+}
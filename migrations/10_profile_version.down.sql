@@ -0,0 +1,22 @@
+DROP TRIGGER IF EXISTS link_previews_touch_update;
+DROP TRIGGER IF EXISTS link_previews_touch_insert;
+DROP TRIGGER IF EXISTS profile_images_touch_delete;
+DROP TRIGGER IF EXISTS profile_images_touch_update;
+DROP TRIGGER IF EXISTS profile_images_touch_insert;
+DROP TRIGGER IF EXISTS profile_themes_touch_delete;
+DROP TRIGGER IF EXISTS profile_themes_touch_update;
+DROP TRIGGER IF EXISTS profile_themes_touch_insert;
+DROP TRIGGER IF EXISTS link_sections_touch_delete;
+DROP TRIGGER IF EXISTS link_sections_touch_update;
+DROP TRIGGER IF EXISTS link_sections_touch_insert;
+DROP TRIGGER IF EXISTS links_touch_delete;
+DROP TRIGGER IF EXISTS links_touch_update;
+DROP TRIGGER IF EXISTS links_touch_insert;
+DROP TRIGGER IF EXISTS users_touch;
+DROP TRIGGER IF EXISTS users_created;
+
+DROP INDEX IF EXISTS idx_links_link_path;
+
+ALTER TABLE users DROP COLUMN updated_at;
+ALTER TABLE users DROP COLUMN preview_version;
+ALTER TABLE users DROP COLUMN version;
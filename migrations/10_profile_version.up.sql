@@ -0,0 +1,108 @@
+-- version and updated_at change whenever anything a profile shows changes.
+-- They back the profile ETag and Last-Modified headers. Triggers keep them
+-- current, so no write path can forget to bump them.
+ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+-- Previews change in the background, so they get their own counter:
+-- version then only moves on the user's own writes, and If-Match on it
+-- does not fail because a preview was fetched in between.
+ALTER TABLE users ADD COLUMN preview_version INTEGER NOT NULL DEFAULT 0;
+-- SQLite does not accept CURRENT_TIMESTAMP as the default of an added
+-- column; users_created fills it in instead.
+ALTER TABLE users ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+
+UPDATE users SET updated_at = CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_links_link_path ON links (link_path);
+
+CREATE TRIGGER IF NOT EXISTS users_created AFTER INSERT ON users
+BEGIN
+    UPDATE users SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_touch AFTER UPDATE OF email, username, about_text ON users
+BEGIN
+    UPDATE users SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS links_touch_insert AFTER INSERT ON links
+BEGIN
+    UPDATE users SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = NEW.user_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS links_touch_update AFTER UPDATE ON links
+BEGIN
+    UPDATE users SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id IN (OLD.user_id, NEW.user_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS links_touch_delete AFTER DELETE ON links
+BEGIN
+    UPDATE users SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = OLD.user_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS link_sections_touch_insert AFTER INSERT ON link_sections
+BEGIN
+    UPDATE users SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = NEW.user_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS link_sections_touch_update AFTER UPDATE ON link_sections
+BEGIN
+    UPDATE users SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id IN (OLD.user_id, NEW.user_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS link_sections_touch_delete AFTER DELETE ON link_sections
+BEGIN
+    UPDATE users SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = OLD.user_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS profile_themes_touch_insert AFTER INSERT ON profile_themes
+BEGIN
+    UPDATE users SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = NEW.user_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS profile_themes_touch_update AFTER UPDATE ON profile_themes
+BEGIN
+    UPDATE users SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id IN (OLD.user_id, NEW.user_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS profile_themes_touch_delete AFTER DELETE ON profile_themes
+BEGIN
+    UPDATE users SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = OLD.user_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS profile_images_touch_insert AFTER INSERT ON profile_images
+BEGIN
+    UPDATE users SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = NEW.user_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS profile_images_touch_update AFTER UPDATE ON profile_images
+BEGIN
+    UPDATE users SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id IN (OLD.user_id, NEW.user_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS profile_images_touch_delete AFTER DELETE ON profile_images
+BEGIN
+    UPDATE users SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = OLD.user_id;
+END;
+
+-- Link health is only on the owner's profile, which folds it into its own
+-- ETag, so a checker run does not touch the profile.
+
+-- Previews are shared by URL, so a new preview touches every profile that
+-- links to it. A refetch rewrites the whole row; only the fields the public
+-- profile renders count as a change.
+CREATE TRIGGER IF NOT EXISTS link_previews_touch_insert AFTER INSERT ON link_previews
+BEGIN
+    UPDATE users SET preview_version = preview_version + 1, updated_at = CURRENT_TIMESTAMP
+    WHERE id IN (SELECT user_id FROM links WHERE link_path = NEW.url);
+END;
+
+CREATE TRIGGER IF NOT EXISTS link_previews_touch_update
+AFTER UPDATE OF title, description, favicon, image ON link_previews
+WHEN NEW.title IS NOT OLD.title
+    OR NEW.description IS NOT OLD.description
+    OR NEW.favicon IS NOT OLD.favicon
+    OR NEW.image IS NOT OLD.image
+BEGIN
+    UPDATE users SET preview_version = preview_version + 1, updated_at = CURRENT_TIMESTAMP
+    WHERE id IN (SELECT user_id FROM links WHERE link_path = NEW.url);
+END;
@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS links_version;
+DROP TRIGGER IF EXISTS links_touch_update;
+
+CREATE TRIGGER IF NOT EXISTS links_touch_update AFTER UPDATE ON links
+BEGIN
+    UPDATE users SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id IN (OLD.user_id, NEW.user_id);
+END;
+
+ALTER TABLE links DROP COLUMN version;
@@ -0,0 +1,19 @@
+-- version backs the link ETag and If-Match on link writes. Every update
+-- bumps it, whichever query ran.
+ALTER TABLE links ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+CREATE TRIGGER IF NOT EXISTS links_version AFTER UPDATE ON links
+WHEN NEW.version = OLD.version
+BEGIN
+    UPDATE links SET version = version + 1 WHERE id = NEW.id;
+END;
+
+-- links_version's own UPDATE would fire links_touch_update a second time.
+-- A row whose version moved is that nested update, not a user write.
+DROP TRIGGER IF EXISTS links_touch_update;
+
+CREATE TRIGGER IF NOT EXISTS links_touch_update AFTER UPDATE ON links
+WHEN NEW.version = OLD.version
+BEGIN
+    UPDATE users SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id IN (OLD.user_id, NEW.user_id);
+END;
@@ -0,0 +1,2 @@
+-- The UTC layout reads back fine on older versions; nothing to undo.
+SELECT 1;
@@ -0,0 +1,4 @@
+-- deleted_at is compared as text, so rewrite the values stored with an
+-- offset ("...+00:00") into the UTC layout the store writes now.
+UPDATE links SET deleted_at = strftime('%Y-%m-%d %H:%M:%f', deleted_at)
+WHERE deleted_at IS NOT NULL;
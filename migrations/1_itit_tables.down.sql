@@ -1,2 +1,2 @@
-DROP TABLE users IF EXISTS;
-DROP TABLE links IF EXISTS;
\ No newline at end of file
+DROP TABLE IF EXISTS links;
+DROP TABLE IF EXISTS users;
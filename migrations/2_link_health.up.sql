@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS link_health(
+    link_id INTEGER PRIMARY KEY,
+    status TEXT NOT NULL DEFAULT '',
+    status_code INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    checked_at TIMESTAMP,
+    fail_streak INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (link_id) REFERENCES links (id) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN strip_tracking;
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN strip_tracking INTEGER NOT NULL DEFAULT 0;
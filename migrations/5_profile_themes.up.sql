@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS profile_themes(
+    user_id INTEGER PRIMARY KEY,
+    preset TEXT NOT NULL DEFAULT '',
+    background TEXT NOT NULL DEFAULT '',
+    font TEXT NOT NULL DEFAULT '',
+    button_style TEXT NOT NULL DEFAULT '',
+    link_color TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS profile_images(
+    user_id INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    version TEXT NOT NULL,
+    format TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, kind),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
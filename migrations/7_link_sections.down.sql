@@ -0,0 +1,19 @@
+-- section_id is part of a foreign key, which SQLite cannot DROP COLUMN, so
+-- the links table is rebuilt without it.
+CREATE TABLE links_without_sections(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    link_name TEXT NOT NULL DEFAULT '',
+    link_color TEXT NOT NULL DEFAULT '',
+    link_path TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+INSERT INTO links_without_sections (id, user_id, link_name, link_color, link_path)
+SELECT id, user_id, link_name, link_color, link_path FROM links;
+
+DROP TABLE links;
+ALTER TABLE links_without_sections RENAME TO links;
+CREATE INDEX IF NOT EXISTS idx_links_user_id ON links (user_id);
+
+DROP TABLE IF EXISTS link_sections;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS link_sections(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    collapsed INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_sections_user_id ON link_sections (user_id);
+
+ALTER TABLE links ADD COLUMN section_id INTEGER REFERENCES link_sections (id) ON DELETE SET NULL;
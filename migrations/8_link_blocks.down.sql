@@ -0,0 +1,2 @@
+ALTER TABLE links DROP COLUMN payload;
+ALTER TABLE links DROP COLUMN type;
@@ -0,0 +1,2 @@
+ALTER TABLE links ADD COLUMN type TEXT NOT NULL DEFAULT 'link';
+ALTER TABLE links ADD COLUMN payload TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE links ADD COLUMN hidden INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE links ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_links_deleted_at ON links (deleted_at);
@@ -0,0 +1,15 @@
+// Package migrations embeds the SQL migrations, so the app can apply them
+// without the files being shipped next to the binary.
+package migrations
+
+import "embed"
+
+// SQLite holds the migrations in this directory.
+//
+//go:embed *.sql
+var SQLite embed.FS
+
+// Postgres holds the migrations in ./postgres.
+//
+//go:embed postgres/*.sql
+var Postgres embed.FS
@@ -0,0 +1,17 @@
+DROP TRIGGER IF EXISTS link_previews_touch_update ON link_previews;
+DROP TRIGGER IF EXISTS link_previews_touch_insert ON link_previews;
+DROP TRIGGER IF EXISTS profile_images_touch ON profile_images;
+DROP TRIGGER IF EXISTS profile_themes_touch ON profile_themes;
+DROP TRIGGER IF EXISTS link_sections_touch ON link_sections;
+DROP TRIGGER IF EXISTS links_touch ON links;
+DROP TRIGGER IF EXISTS users_touch ON users;
+
+DROP FUNCTION IF EXISTS touch_preview_owners();
+DROP FUNCTION IF EXISTS touch_owner();
+DROP FUNCTION IF EXISTS users_touch();
+
+DROP INDEX IF EXISTS idx_links_link_path;
+
+ALTER TABLE users DROP COLUMN IF EXISTS preview_version;
+ALTER TABLE users DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE users DROP COLUMN IF EXISTS version;
@@ -0,0 +1,69 @@
+-- version and updated_at change whenever anything a profile shows changes.
+-- They back the profile ETag and Last-Modified headers. Triggers keep them
+-- current, so no write path can forget to bump them.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+-- Previews change in the background, so they get their own counter:
+-- version then only moves on the user's own writes, and If-Match on it
+-- does not fail because a preview was fetched in between.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS preview_version INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_links_link_path ON links (link_path);
+
+CREATE OR REPLACE FUNCTION users_touch() RETURNS trigger AS $$
+BEGIN
+    NEW.version := OLD.version + 1;
+    NEW.updated_at := now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_touch BEFORE UPDATE OF email, username, about_text ON users
+    FOR EACH ROW EXECUTE FUNCTION users_touch();
+
+-- touch_owner bumps the user a row belongs to, for tables with a user_id.
+CREATE OR REPLACE FUNCTION touch_owner() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE users SET version = version + 1, updated_at = now() WHERE id = OLD.user_id;
+    END IF;
+    IF TG_OP = 'INSERT' THEN
+        UPDATE users SET version = version + 1, updated_at = now() WHERE id = NEW.user_id;
+    ELSIF TG_OP = 'UPDATE' AND NEW.user_id IS DISTINCT FROM OLD.user_id THEN
+        UPDATE users SET version = version + 1, updated_at = now() WHERE id = NEW.user_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER links_touch AFTER INSERT OR UPDATE OR DELETE ON links
+    FOR EACH ROW EXECUTE FUNCTION touch_owner();
+CREATE TRIGGER link_sections_touch AFTER INSERT OR UPDATE OR DELETE ON link_sections
+    FOR EACH ROW EXECUTE FUNCTION touch_owner();
+CREATE TRIGGER profile_themes_touch AFTER INSERT OR UPDATE OR DELETE ON profile_themes
+    FOR EACH ROW EXECUTE FUNCTION touch_owner();
+CREATE TRIGGER profile_images_touch AFTER INSERT OR UPDATE OR DELETE ON profile_images
+    FOR EACH ROW EXECUTE FUNCTION touch_owner();
+
+-- Link health is only on the owner's profile, which folds it into its own
+-- ETag, so a checker run does not touch the profile.
+
+-- Previews are shared by URL, so a new preview touches every profile that
+-- links to it. A refetch rewrites the whole row; only the fields the public
+-- profile renders count as a change.
+CREATE OR REPLACE FUNCTION touch_preview_owners() RETURNS trigger AS $$
+BEGIN
+    UPDATE users SET preview_version = preview_version + 1, updated_at = now()
+    WHERE id IN (SELECT user_id FROM links WHERE link_path = NEW.url);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER link_previews_touch_insert AFTER INSERT ON link_previews
+    FOR EACH ROW EXECUTE FUNCTION touch_preview_owners();
+
+CREATE TRIGGER link_previews_touch_update AFTER UPDATE ON link_previews
+    FOR EACH ROW
+    WHEN ((OLD.title, OLD.description, OLD.favicon, OLD.image)
+        IS DISTINCT FROM (NEW.title, NEW.description, NEW.favicon, NEW.image))
+    EXECUTE FUNCTION touch_preview_owners();
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS links_version ON links;
+DROP FUNCTION IF EXISTS links_version();
+
+ALTER TABLE links DROP COLUMN IF EXISTS version;
@@ -0,0 +1,13 @@
+-- version backs the link ETag and If-Match on link writes. Every update
+-- bumps it, whichever query ran.
+ALTER TABLE links ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+CREATE OR REPLACE FUNCTION links_version() RETURNS trigger AS $$
+BEGIN
+    NEW.version := OLD.version + 1;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER links_version BEFORE UPDATE ON links
+    FOR EACH ROW EXECUTE FUNCTION links_version();
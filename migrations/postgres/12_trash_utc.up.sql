@@ -0,0 +1,3 @@
+-- deleted_at is TIMESTAMPTZ here already; this keeps the versions in step
+-- with SQLite.
+SELECT 1;
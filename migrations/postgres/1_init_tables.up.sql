@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS users(
+    id SERIAL PRIMARY KEY,
+    email TEXT NOT NULL,
+    username TEXT NOT NULL,
+    pass_hash BYTEA NOT NULL,
+    about_text TEXT NOT NULL DEFAULT '',
+    CONSTRAINT users_email_key UNIQUE (email),
+    CONSTRAINT users_username_key UNIQUE (username)
+);
+
+CREATE TABLE IF NOT EXISTS links(
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    link_name TEXT NOT NULL DEFAULT '',
+    link_color TEXT NOT NULL DEFAULT '',
+    link_path TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_links_user_id ON links (user_id);
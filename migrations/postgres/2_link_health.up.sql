@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS link_health(
+    link_id INTEGER PRIMARY KEY REFERENCES links (id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT '',
+    status_code INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    checked_at TIMESTAMPTZ,
+    fail_streak INTEGER NOT NULL DEFAULT 0
+);
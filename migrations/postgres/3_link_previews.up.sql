@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS link_previews(
+    url TEXT PRIMARY KEY,
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    favicon TEXT NOT NULL DEFAULT '',
+    image TEXT NOT NULL DEFAULT '',
+    fetched_at TIMESTAMPTZ NOT NULL
+);
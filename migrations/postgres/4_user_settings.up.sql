@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS strip_tracking BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS profile_themes(
+    user_id INTEGER PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    preset TEXT NOT NULL DEFAULT '',
+    background TEXT NOT NULL DEFAULT '',
+    font TEXT NOT NULL DEFAULT '',
+    button_style TEXT NOT NULL DEFAULT '',
+    link_color TEXT NOT NULL DEFAULT ''
+);
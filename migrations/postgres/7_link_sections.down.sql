@@ -0,0 +1,2 @@
+ALTER TABLE links DROP COLUMN IF EXISTS section_id;
+DROP TABLE IF EXISTS link_sections;
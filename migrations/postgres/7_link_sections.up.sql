@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS link_sections(
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    collapsed BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_sections_user_id ON link_sections (user_id);
+
+ALTER TABLE links ADD COLUMN IF NOT EXISTS section_id INTEGER REFERENCES link_sections (id) ON DELETE SET NULL;
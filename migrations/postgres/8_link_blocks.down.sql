@@ -0,0 +1,2 @@
+ALTER TABLE links DROP COLUMN IF EXISTS payload;
+ALTER TABLE links DROP COLUMN IF EXISTS type;
@@ -0,0 +1,2 @@
+ALTER TABLE links ADD COLUMN IF NOT EXISTS type TEXT NOT NULL DEFAULT 'link';
+ALTER TABLE links ADD COLUMN IF NOT EXISTS payload TEXT NOT NULL DEFAULT '';
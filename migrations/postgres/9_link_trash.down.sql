@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_links_deleted_at;
+
+-- Trashed links would otherwise reappear on profiles.
+DELETE FROM links WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE links DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE links DROP COLUMN IF EXISTS hidden;